@@ -26,6 +26,8 @@ import (
 	"OntologyWithPOC/smartcontract/service/native/governance"
 	"fmt"
 	"github.com/urfave/cli"
+	"strconv"
+	"strings"
 )
 
 func SetOntologyConfig(ctx *cli.Context) (*config.OntologyConfig, error) {
@@ -35,12 +37,15 @@ func SetOntologyConfig(ctx *cli.Context) (*config.OntologyConfig, error) {
 		return nil, fmt.Errorf("setGenesis error:%s", err)
 	}
 	setCommonConfig(ctx, cfg.Common)
-	setConsensusConfig(ctx, cfg.Consensus)
+	err = setConsensusConfig(ctx, cfg.Consensus)
+	if err != nil {
+		return nil, fmt.Errorf("setConsensusConfig error:%s", err)
+	}
 	setP2PNodeConfig(ctx, cfg.P2PNode)
 	setRpcConfig(ctx, cfg.Rpc)
 	setRestfulConfig(ctx, cfg.Restful)
 	setWebSocketConfig(ctx, cfg.Ws)
-	if cfg.Genesis.ConsensusType == config.CONSENSUS_TYPE_SOLO {
+	if cfg.Genesis.ConsensusType == config.CONSENSUS_TYPE_SOLO || ctx.Bool(utils.GetFlagName(utils.EnablePocTestModeFlag)) {
 		cfg.Ws.EnableHttpWs = true
 		cfg.Restful.EnableHttpRestful = true
 		cfg.Consensus.EnableConsensus = true
@@ -49,6 +54,9 @@ func SetOntologyConfig(ctx *cli.Context) (*config.OntologyConfig, error) {
 		cfg.P2PNode.NetworkMagic = config.GetNetworkMagic(cfg.P2PNode.NetworkId)
 		cfg.Common.GasPrice = 0
 	}
+	if ctx.Bool(utils.GetFlagName(utils.EnablePocTestModeFlag)) {
+		cfg.P2PNode.EnableDiscovery = false
+	}
 	if cfg.P2PNode.NetworkId == config.NETWORK_ID_MAIN_NET ||
 		cfg.P2PNode.NetworkId == config.NETWORK_ID_POLARIS_NET {
 		defNetworkId, err := cfg.GetDefaultNetworkId()
@@ -81,6 +89,11 @@ func setGenesis(ctx *cli.Context, cfg *config.OntologyConfig) error {
 		}
 		return nil
 	}
+	if ctx.Bool(utils.GetFlagName(utils.EnablePocTestModeFlag)) {
+		blockTime := ctx.Uint(utils.GetFlagName(utils.TestModeGenBlockTimeFlag))
+		cfg.Genesis = config.NewPOCTestModeGenesisConfig(uint32(blockTime))
+		return nil
+	}
 
 	if !ctx.IsSet(utils.GetFlagName(utils.ConfigFlag)) {
 		return nil
@@ -115,6 +128,11 @@ func setGenesis(ctx *cli.Context, cfg *config.OntologyConfig) error {
 		if len(cfg.Genesis.VBFT.Peers) < config.VBFT_MIN_NODE_NUM {
 			return fmt.Errorf("VBFT consensus at least need %d peers in config", config.VBFT_MIN_NODE_NUM)
 		}
+	case config.CONSENSUS_TYPE_POC:
+		err = governance.CheckPOCConfig(cfg.Genesis.POC)
+		if err != nil {
+			return fmt.Errorf("POC config error %v", err)
+		}
 	default:
 		return fmt.Errorf("Unknow consensus:%s", cfg.Genesis.ConsensusType)
 	}
@@ -130,9 +148,54 @@ func setCommonConfig(ctx *cli.Context, cfg *config.CommonConfig) {
 	cfg.DataDir = ctx.String(utils.GetFlagName(utils.DataDirFlag))
 }
 
-func setConsensusConfig(ctx *cli.Context, cfg *config.ConsensusConfig) {
+func setConsensusConfig(ctx *cli.Context, cfg *config.ConsensusConfig) error {
 	cfg.EnableConsensus = ctx.Bool(utils.GetFlagName(utils.EnableConsensusFlag))
 	cfg.MaxTxInBlock = ctx.Uint(utils.GetFlagName(utils.MaxTxInBlockFlag))
+	cfg.EnablePoolServer = ctx.Bool(utils.GetFlagName(utils.EnablePoolServerFlag))
+	cfg.PoolServerPort = ctx.Uint(utils.GetFlagName(utils.PoolServerPortFlag))
+	cfg.PlotCheckInterval = ctx.Uint(utils.GetFlagName(utils.PlotCheckIntervalFlag))
+	cfg.PlotRepair = ctx.Bool(utils.GetFlagName(utils.PlotRepairFlag))
+	cfg.ScanWorkers = ctx.Uint(utils.GetFlagName(utils.ScanWorkersFlag))
+	cfg.ScanRateLimit = ctx.Uint(utils.GetFlagName(utils.ScanRateLimitFlag))
+	cfg.ZKProvingKey = ctx.String(utils.GetFlagName(utils.ZKProvingKeyFlag))
+	plotDirs, err := parsePlotDirs(ctx.String(utils.GetFlagName(utils.PlotDirsFlag)))
+	if err != nil {
+		return err
+	}
+	cfg.PlotDirs = plotDirs
+	return nil
+}
+
+// parsePlotDirs parses comma separated <path>[:<capacity MB>[:<priority>]]
+func parsePlotDirs(value string) ([]*config.POCPlotDir, error) {
+	var dirs []*config.POCPlotDir
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		fields := strings.Split(item, ":")
+		if len(fields) > 3 || fields[0] == "" {
+			return nil, fmt.Errorf("invalid plot dir %s", item)
+		}
+		dir := &config.POCPlotDir{Path: fields[0]}
+		if len(fields) > 1 {
+			capacity, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid capacity of plot dir %s", item)
+			}
+			dir.Capacity = capacity
+		}
+		if len(fields) > 2 {
+			priority, err := strconv.ParseUint(fields[2], 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid priority of plot dir %s", item)
+			}
+			dir.Priority = uint32(priority)
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs, nil
 }
 
 func setP2PNodeConfig(ctx *cli.Context, cfg *config.P2PNodeConfig) {
@@ -145,6 +208,8 @@ func setP2PNodeConfig(ctx *cli.Context, cfg *config.P2PNodeConfig) {
 	cfg.MaxConnInBound = ctx.Uint(utils.GetFlagName(utils.MaxConnInBoundFlag))
 	cfg.MaxConnOutBound = ctx.Uint(utils.GetFlagName(utils.MaxConnOutBoundFlag))
 	cfg.MaxConnInBoundForSingleIP = ctx.Uint(utils.GetFlagName(utils.MaxConnInBoundForSingleIPFlag))
+	cfg.EnableDiscovery = ctx.Bool(utils.GetFlagName(utils.EnableDiscoveryFlag))
+	cfg.DiscoveryPort = ctx.Uint(utils.GetFlagName(utils.DiscoveryPortFlag))
 
 	rsvfile := ctx.String(utils.GetFlagName(utils.ReservedPeersFileFlag))
 	if cfg.ReservedPeersOnly {
@@ -189,3 +254,9 @@ func SetRpcPort(ctx *cli.Context) {
 		config.DefConfig.Rpc.HttpJsonPort = ctx.Uint(utils.GetFlagName(utils.RPCPortFlag))
 	}
 }
+
+func SetLocalRpcPort(ctx *cli.Context) {
+	if ctx.IsSet(utils.GetFlagName(utils.RPCLocalProtFlag)) {
+		config.DefConfig.Rpc.HttpLocalPort = ctx.Uint(utils.GetFlagName(utils.RPCLocalProtFlag))
+	}
+}
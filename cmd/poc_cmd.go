@@ -0,0 +1,802 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"OntologyWithPOC/cmd/common"
+	"OntologyWithPOC/cmd/utils"
+	"OntologyWithPOC/consensus"
+	"OntologyWithPOC/consensus/poc"
+	"OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/consensus/poc/history"
+	"OntologyWithPOC/consensus/poc/plot"
+	"OntologyWithPOC/consensus/poc/shabal"
+	"OntologyWithPOC/consensus/poc/zkproof"
+	"github.com/gosuri/uiprogress"
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/urfave/cli"
+)
+
+var PocCommand = cli.Command{
+	Action:      cli.ShowSubcommandHelp,
+	Name:        "poc",
+	Usage:       "Manage PoC plots and mining",
+	ArgsUsage:   "[arguments...]",
+	Description: "PoC management commands can be used to prepare the plot files of an account before mining.",
+	Subcommands: []cli.Command{
+		{
+			Action:    plotCreate,
+			Name:      "plot",
+			Usage:     "Plot disk space for an account",
+			ArgsUsage: "[sub-command options]",
+			Flags: []cli.Flag{
+				utils.WalletFileFlag,
+				utils.AccountAddressFlag,
+				utils.PlotDirFlag,
+				utils.PlotSizeFlag,
+				utils.PlotStartNonceFlag,
+				utils.PlotWorkersFlag,
+				utils.PlotDryRunFlag,
+			},
+			Description: `Plot --size MB of nonces for the account into --plot-dir.
+   If an incomplete plot of the account is found in --plot-dir, it is resumed instead of creating a new one.`,
+		},
+		{
+			Action:    plotOptimize,
+			Name:      "optimize",
+			Usage:     "Convert the plots of plot directories to scoop order",
+			ArgsUsage: "[plot-dir...]",
+			Flags: []cli.Flag{
+				utils.PlotDirFlag,
+				utils.PlotLayoutFlag,
+			},
+			Description: `Rewrite the complete plots in --plot-dir and the extra plot dirs given as arguments in --layout order.
+   Scoop ordered plots are scanned with one sequential read per block instead of one read per nonce.
+   Every plot is converted through a copy next to it, so the disk needs as much free space as the largest plot.
+   An interrupted conversion is resumed by running the command again.`,
+		},
+		{
+			Action:    plotCheck,
+			Name:      "check",
+			Usage:     "Verify the plots of plot directories",
+			ArgsUsage: "[plot-dir...]",
+			Flags: []cli.Flag{
+				utils.PlotDirFlag,
+				utils.PlotCheckSamplesFlag,
+				utils.PlotExcludeFlag,
+				utils.PlotRepairNoncesFlag,
+			},
+			Description: `Regenerate the nonces of the plots in --plot-dir and the extra plot dirs given as arguments, and compare them
+   to the plot files. Every nonce is verified unless --samples is given. The corrupted nonce ranges are reported,
+   --repair replots them and --exclude renames the plots left corrupted so that the node no longer mines them.
+   Excluded plots are verified as well, and included back once repaired.`,
+		},
+		{
+			Action:    poolMine,
+			Name:      "mine",
+			Usage:     "Mine the plots of an account for a pool",
+			ArgsUsage: "[plot-dir...]",
+			Flags: []cli.Flag{
+				utils.WalletFileFlag,
+				utils.AccountAddressFlag,
+				utils.PlotDirFlag,
+				utils.PoolFlag,
+			},
+			Description: `Scan the plots of the account in --plot-dir and the extra plot dirs given as arguments,
+   and submit the best deadline of every round to the pool server at --pool, until interrupted.
+   The account must have bound its rewards to the account of the pool node in the plot binding contract.`,
+		},
+		{
+			Action:    pocSimulate,
+			Name:      "simulate",
+			Usage:     "Simulate a PoC network to tune its parameters",
+			ArgsUsage: "<nonces>...",
+			Flags: []cli.Flag{
+				utils.SimBlocksFlag,
+				utils.SimTargetBlockTimeFlag,
+				utils.SimInitialBaseTargetFlag,
+				utils.SimLatencyFlag,
+				utils.SimJitterFlag,
+				utils.SimSeedFlag,
+			},
+			Description: `Simulate a network of one node per argument, each with a plot of the given number of nonces,
+   until the chain reaches --blocks. The block time distribution, the share of the blocks each node proposed
+   against its share of the capacity, and the fork rate are reported. Runs with the same arguments are identical.`,
+		},
+		{
+			Action:    zkKeygen,
+			Name:      "zk-keygen",
+			Usage:     "Generate the keys of the zk deadline proofs",
+			ArgsUsage: "[sub-command options]",
+			Flags: []cli.Flag{
+				utils.ZKSeedFlag,
+				utils.ZKDepthFlag,
+				utils.ZKRoundsFlag,
+				utils.ZKOutputDirFlag,
+			},
+			Description: `Derive the proving and verifying keys of the zk deadline proofs of plot trees of --depth from --seed,
+   and write them to --output. The same seed always yields the same keys, so the keys of a genesis config can be
+   regenerated and checked from it. Whoever knows the seed can forge proofs, so it must be kept secret and discarded
+   once the keys are generated.
+   The verifying key printed goes to zk_verifying_key of the PoC genesis config, the proving key file is given
+   to the mining nodes with --zk-proving-key.`,
+		},
+		{
+			Action:    zkCommit,
+			Name:      "zk-commit",
+			Usage:     "Build the plot trees zk deadline proofs are made against",
+			ArgsUsage: "[plot-dir...]",
+			Flags: []cli.Flag{
+				utils.PlotDirFlag,
+				utils.ZKDepthFlag,
+				utils.ZKRoundsFlag,
+				utils.PlotWorkersFlag,
+			},
+			Description: `Build the plot tree of every complete plot in --plot-dir and the extra plot dirs given as arguments,
+   next to the plot, and print its root and nonce range. Every scoop of the plot is hashed, which takes far longer
+   than plotting. The root and nonce range must be registered with registerPlotRoot of the plot binding contract,
+   signed by the plot account, and can be proved against 64 blocks later. Every proof opens random leaves of the
+   tree against the nonces of the plot account in that range.`,
+		},
+		{
+			Action:    exportHistory,
+			Name:      "history",
+			Usage:     "Export the deadline history of the running node",
+			ArgsUsage: "[sub-command options]",
+			Flags: []cli.Flag{
+				utils.RPCPortFlag,
+				utils.HistoryStartFlag,
+				utils.HistoryEndFlag,
+				utils.HistoryAccountFlag,
+				utils.HistoryFormatFlag,
+				utils.HistoryOutputFlag,
+			},
+			Description: `Export the deadlines of the blocks sealed from --start to --end as csv or json, fetched from the rpc server
+   of the node. Every block has the verified deadline of its proposer and the deadlines the node received for the
+   round. The csv has a row per deadline, the one of the proposer flagged as winner.`,
+		},
+		{
+			Action:      cli.ShowSubcommandHelp,
+			Name:        "mining",
+			Usage:       "Control mining of the running node",
+			ArgsUsage:   "[arguments...]",
+			Description: "Mining control commands are sent to the local rpc server of the node, which must be started with --localrpc.",
+			Subcommands: []cli.Command{
+				{
+					Action: miningControl("startmining"),
+					Name:   "start",
+					Usage:  "Start or resume mining",
+					Flags:  []cli.Flag{utils.RPCLocalProtFlag},
+				},
+				{
+					Action: miningControl("stopmining"),
+					Name:   "stop",
+					Usage:  "Stop mining, it stays stopped across restarts",
+					Flags:  []cli.Flag{utils.RPCLocalProtFlag},
+				},
+				{
+					Action: miningControl("pausemining"),
+					Name:   "pause",
+					Usage:  "Pause mining until it is started again or the node restarts",
+					Flags:  []cli.Flag{utils.RPCLocalProtFlag},
+				},
+				{
+					Action: miningControl("getminingstatus"),
+					Name:   "status",
+					Usage:  "Show mining status",
+					Flags:  []cli.Flag{utils.RPCLocalProtFlag},
+				},
+				{
+					Action:    miningCapacity,
+					Name:      "capacity",
+					Usage:     "Change the capacity of a plot directory",
+					ArgsUsage: "<plot-dir> <size in MB>",
+					Flags:     []cli.Flag{utils.RPCLocalProtFlag},
+				},
+				{
+					Action:    miningRewardAddress,
+					Name:      "rewardaddress",
+					Usage:     "Bind the rewards of the node's plot account to an address",
+					ArgsUsage: "<address>",
+					Flags:     []cli.Flag{utils.RPCLocalProtFlag},
+				},
+			},
+		},
+	},
+}
+
+func miningControl(method string) func(ctx *cli.Context) error {
+	return func(ctx *cli.Context) error {
+		SetLocalRpcPort(ctx)
+		status, err := utils.MiningControl(method)
+		if err != nil {
+			return err
+		}
+		PrintJsonObject(status)
+		return nil
+	}
+}
+
+func miningCapacity(ctx *cli.Context) error {
+	SetLocalRpcPort(ctx)
+	if ctx.NArg() < 2 {
+		PrintErrorMsg("Missing plot dir or size argument.")
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	size, err := strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid size %s: %s", ctx.Args().Get(1), err)
+	}
+	status, err := utils.MiningControl("setminingcapacity", ctx.Args().First(), size)
+	if err != nil {
+		return err
+	}
+	PrintJsonObject(status)
+	return nil
+}
+
+func miningRewardAddress(ctx *cli.Context) error {
+	SetLocalRpcPort(ctx)
+	if ctx.NArg() < 1 {
+		PrintErrorMsg("Missing address argument.")
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	status, err := utils.MiningControl("setrewardaddress", ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	PrintJsonObject(status)
+	return nil
+}
+
+func poolMine(ctx *cli.Context) error {
+	pool := ctx.String(utils.GetFlagName(utils.PoolFlag))
+	if pool == "" {
+		PrintErrorMsg("Missing %s argument.", utils.PoolFlag.Name)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	accountID, err := getPlotAccountID(ctx)
+	if err != nil {
+		return err
+	}
+	dirs := append([]string{ctx.String(utils.GetFlagName(utils.PlotDirFlag))}, ctx.Args()...)
+
+	miner := poc.NewPoolMiner(pool, accountID, dirs)
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sc
+		miner.Stop()
+	}()
+	PrintInfoMsg("Mining plots of %v for pool %s.", dirs, pool)
+	miner.Run()
+	return nil
+}
+
+func pocSimulate(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		PrintErrorMsg("Missing nonces argument.")
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	cfg := &poc.SimConfig{
+		Blocks:            uint32(ctx.Uint(utils.GetFlagName(utils.SimBlocksFlag))),
+		TargetBlockTime:   uint32(ctx.Uint(utils.GetFlagName(utils.SimTargetBlockTimeFlag))),
+		InitialBaseTarget: ctx.Uint64(utils.GetFlagName(utils.SimInitialBaseTargetFlag)),
+		Latency:           time.Duration(ctx.Uint(utils.GetFlagName(utils.SimLatencyFlag))) * time.Millisecond,
+		Jitter:            time.Duration(ctx.Uint(utils.GetFlagName(utils.SimJitterFlag))) * time.Millisecond,
+		Seed:              ctx.Int64(utils.GetFlagName(utils.SimSeedFlag)),
+	}
+	for _, arg := range ctx.Args() {
+		nonces, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid nonces %s: %s", arg, err)
+		}
+		cfg.Nonces = append(cfg.Nonces, nonces)
+	}
+	report, err := poc.Simulate(cfg)
+	if err != nil {
+		return err
+	}
+
+	PrintInfoMsg("Height: %d", report.Height)
+	PrintInfoMsg("Block time: mean %.1fs, median %ds, 90th percentile %ds, max %ds", report.MeanBlockTime(),
+		report.BlockTimePercentile(50), report.BlockTimePercentile(90), report.BlockTimePercentile(100))
+	if n := len(report.BaseTargets); n > 0 {
+		PrintInfoMsg("Base target: %d", report.BaseTargets[n-1])
+	}
+	for i := range cfg.Nonces {
+		PrintInfoMsg("Node %d: %d nonces, %.1f%% of the capacity, %.1f%% of the blocks", i, cfg.Nonces[i],
+			report.CapacityShare[i]*100, report.ProposerShare[i]*100)
+	}
+	PrintInfoMsg("Forks: %d of %d blocks orphaned (%.1f%%), %d reorgs", report.Orphaned, report.Produced,
+		report.ForkRate()*100, report.Reorgs)
+	if report.Rejected > 0 || report.Splits > 0 {
+		return fmt.Errorf("%d blocks rejected, %d nodes on another chain", report.Rejected, report.Splits)
+	}
+	return nil
+}
+
+func zkKeygen(ctx *cli.Context) error {
+	seed := ctx.String(utils.GetFlagName(utils.ZKSeedFlag))
+	if seed == "" {
+		PrintErrorMsg("Missing --seed argument.")
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	params := zkproof.Params{
+		Depth:  ctx.Int(utils.GetFlagName(utils.ZKDepthFlag)),
+		Rounds: ctx.Int(utils.GetFlagName(utils.ZKRoundsFlag)),
+	}
+	PrintInfoMsg("Generate zk keys of depth %d, %d rounds.", params.Depth, params.Rounds)
+	pk, err := zkproof.Setup(params, []byte(seed))
+	if err != nil {
+		return err
+	}
+	dir := ctx.String(utils.GetFlagName(utils.ZKOutputDirFlag))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, "zk_proving_key.json")
+	if err := pk.Save(path); err != nil {
+		return fmt.Errorf("save proving key error:%s", err)
+	}
+	vk, err := pk.Vk.Bytes()
+	if err != nil {
+		return err
+	}
+	vkHex := hex.EncodeToString(vk)
+	if err := ioutil.WriteFile(filepath.Join(dir, "zk_verifying_key.hex"), []byte(vkHex), 0644); err != nil {
+		return fmt.Errorf("save verifying key error:%s", err)
+	}
+	PrintInfoMsg("Proving key: %s", path)
+	PrintInfoMsg("Verifying key: %s", vkHex)
+	return nil
+}
+
+func zkCommit(ctx *cli.Context) error {
+	params := zkproof.Params{
+		Depth:  ctx.Int(utils.GetFlagName(utils.ZKDepthFlag)),
+		Rounds: ctx.Int(utils.GetFlagName(utils.ZKRoundsFlag)),
+	}
+	workers := ctx.Int(utils.GetFlagName(utils.PlotWorkersFlag))
+	dirs := append([]string{ctx.String(utils.GetFlagName(utils.PlotDirFlag))}, ctx.Args()...)
+	for _, dir := range dirs {
+		plots, err := plot.List(dir)
+		if err != nil {
+			return fmt.Errorf("list plots of %s error:%s", dir, err)
+		}
+		for _, path := range plots {
+			tree, start, err := commitPlot(path, params, workers)
+			if err != nil {
+				return fmt.Errorf("build plot tree of %s error:%s", path, err)
+			}
+			if tree != nil {
+				PrintInfoMsg("Plot %s root: %064x, start nonce: %d, nonces: %d", path, tree.Root, start, tree.Nonces)
+			}
+		}
+	}
+	return nil
+}
+
+// commitPlot builds the plot tree of the complete plot at path unless it has
+// one already, and returns it with the first nonce of the plot, nil if the
+// plot is incomplete. The tree is closed.
+func commitPlot(path string, params zkproof.Params, workers int) (*zkproof.PlotTree, uint64, error) {
+	p, err := plot.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer p.Close()
+	if tree, err := zkproof.OpenTree(zkproof.TreeFile(path)); err == nil {
+		defer tree.Close()
+		if tree.Params == params {
+			return tree, p.StartNonce, nil
+		}
+	}
+	if written, err := p.NoncesWritten(); err != nil || written < p.NonceCount {
+		PrintInfoMsg("Skip incomplete plot %s.", path)
+		return nil, 0, err
+	}
+
+	uiprogress.Start()
+	bar := uiprogress.AddBar(shabal.ScoopCount).
+		AppendCompleted().
+		AppendElapsed().
+		PrependFunc(func(b *uiprogress.Bar) string {
+			return fmt.Sprintf("Scoop(%d/%d)", b.Current(), shabal.ScoopCount)
+		})
+	PrintInfoMsg("Build plot tree of %s.", path)
+	tree, err := zkproof.BuildTree(p, params, workers, func(scoops int) {
+		bar.Set(scoops)
+	})
+	uiprogress.Stop()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tree.Close()
+	return tree, p.StartNonce, nil
+}
+
+func exportHistory(ctx *cli.Context) error {
+	SetRpcPort(ctx)
+	format := ctx.String(utils.GetFlagName(utils.HistoryFormatFlag))
+	if format != "csv" && format != "json" {
+		return fmt.Errorf("invalid format %s", format)
+	}
+	start := uint32(ctx.Uint(utils.GetFlagName(utils.HistoryStartFlag)))
+	end := uint32(ctx.Uint(utils.GetFlagName(utils.HistoryEndFlag)))
+	if !ctx.IsSet(utils.GetFlagName(utils.HistoryEndFlag)) {
+		count, err := utils.GetBlockCount()
+		if err != nil {
+			return fmt.Errorf("get block count error:%s", err)
+		}
+		end = count - 1
+	}
+	if start > end {
+		return fmt.Errorf("invalid height range %d-%d", start, end)
+	}
+	accountID := ctx.String(utils.GetFlagName(utils.HistoryAccountFlag))
+
+	var recs []*history.Record
+	for from := start; ; from += history.MaxQueryRange {
+		to := end
+		if end-from >= history.MaxQueryRange {
+			to = from + history.MaxQueryRange - 1
+		}
+		part, err := utils.GetDeadlineHistory(from, to, accountID)
+		if err != nil {
+			return fmt.Errorf("get deadline history %d-%d error:%s", from, to, err)
+		}
+		recs = append(recs, part...)
+		if to == end {
+			break
+		}
+	}
+
+	out := os.Stdout
+	if path := ctx.String(utils.GetFlagName(utils.HistoryOutputFlag)); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	if format == "json" {
+		return history.WriteJSON(out, recs)
+	}
+	return history.WriteCSV(out, recs)
+}
+
+func plotOptimize(ctx *cli.Context) error {
+	layout, err := plot.ParseLayout(ctx.String(utils.GetFlagName(utils.PlotLayoutFlag)))
+	if err != nil {
+		return err
+	}
+	dirs := append([]string{ctx.String(utils.GetFlagName(utils.PlotDirFlag))}, ctx.Args()...)
+
+	stopC := make(chan struct{})
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sc
+		close(stopC)
+	}()
+
+	for _, dir := range dirs {
+		plots, err := plot.List(dir)
+		if err != nil {
+			return fmt.Errorf("list plots of %s error:%s", dir, err)
+		}
+		for _, path := range plots {
+			if err := convertPlot(path, layout, stopC); err != nil {
+				if err == plot.ErrStopped {
+					PrintInfoMsg("Conversion stopped, run the command again to resume it.")
+					return nil
+				}
+				return fmt.Errorf("convert plot %s error:%s", path, err)
+			}
+		}
+	}
+	PrintInfoMsg("Plots successfully converted to %s order.", layout)
+	return nil
+}
+
+func convertPlot(path string, layout plot.Layout, stopC <-chan struct{}) error {
+	p, err := plot.Open(path)
+	if err != nil {
+		return err
+	}
+	written, err := p.NoncesWritten()
+	p.Close()
+	if err != nil {
+		return err
+	}
+	if p.Layout == layout {
+		return nil
+	}
+	if written < p.NonceCount {
+		PrintInfoMsg("Skip incomplete plot %s.", path)
+		return nil
+	}
+
+	uiprogress.Start()
+	bar := uiprogress.AddBar(int(p.NonceCount)).
+		AppendCompleted().
+		AppendElapsed().
+		PrependFunc(func(b *uiprogress.Bar) string {
+			return fmt.Sprintf("Nonce(%d/%d)", b.Current(), p.NonceCount)
+		})
+	PrintInfoMsg("Convert plot %s to %s order.", path, layout)
+	err = plot.Convert(path, layout, func(n uint64) {
+		bar.Set(int(n))
+	}, stopC)
+	uiprogress.Stop()
+	return err
+}
+
+func plotCheck(ctx *cli.Context) error {
+	samples := ctx.Uint64(utils.GetFlagName(utils.PlotCheckSamplesFlag))
+	exclude := ctx.Bool(utils.GetFlagName(utils.PlotExcludeFlag))
+	repair := ctx.Bool(utils.GetFlagName(utils.PlotRepairNoncesFlag))
+	dirs := append([]string{ctx.String(utils.GetFlagName(utils.PlotDirFlag))}, ctx.Args()...)
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	corrupted := 0
+	for _, dir := range dirs {
+		plots, err := plot.List(dir)
+		if err != nil {
+			return fmt.Errorf("list plots of %s error:%s", dir, err)
+		}
+		excluded, err := plot.ListExcluded(dir)
+		if err != nil {
+			return fmt.Errorf("list plots of %s error:%s", dir, err)
+		}
+		for _, path := range append(plots, excluded...) {
+			sound, err := checkPlot(path, samples, exclude, repair, rnd)
+			if err != nil {
+				PrintErrorMsg("Check plot %s error:%s", path, err)
+			}
+			if !sound {
+				corrupted++
+			}
+		}
+	}
+	if corrupted > 0 {
+		return fmt.Errorf("%d plots corrupted", corrupted)
+	}
+	PrintInfoMsg("Plots successfully verified.")
+	return nil
+}
+
+// checkPlot verifies the plot at path and reports whether it is sound,
+// repairing, excluding or including it back as requested.
+func checkPlot(path string, samples uint64, exclude, repair bool, rnd *rand.Rand) (bool, error) {
+	open := plot.Open
+	if repair {
+		open = plot.OpenForWrite
+	}
+	p, err := open(path)
+	if err != nil {
+		return false, err
+	}
+	report, err := p.Check(samples, rnd)
+	if err != nil {
+		p.Close()
+		return false, err
+	}
+	PrintInfoMsg("Plot %s: %d nonces verified, %d of %d written.", path, report.Checked, report.Written, p.NonceCount)
+	if report.Corrupted() {
+		PrintErrorMsg("Plot %s: %d nonces corrupted in %v.", path, report.BadNonces(), report.Bad)
+		if repair {
+			if err := p.Repair(report.Bad); err != nil {
+				p.Close()
+				return false, fmt.Errorf("repair error:%s", err)
+			}
+			if report, err = p.Check(samples, rnd); err != nil {
+				p.Close()
+				return false, err
+			}
+			if !report.Corrupted() {
+				PrintInfoMsg("Plot %s repaired.", path)
+			}
+		}
+	}
+	p.Close()
+
+	isExcluded := strings.HasSuffix(path, plot.ExcludedExt)
+	if report.Corrupted() && exclude && !isExcluded {
+		excluded, err := plot.Exclude(path)
+		if err != nil {
+			return false, fmt.Errorf("exclude error:%s", err)
+		}
+		PrintInfoMsg("Plot %s excluded as %s.", path, excluded)
+	} else if !report.Corrupted() && repair && isExcluded {
+		included, err := plot.Include(path)
+		if err != nil {
+			return true, fmt.Errorf("include error:%s", err)
+		}
+		PrintInfoMsg("Plot %s included back as %s.", path, included)
+	}
+	return !report.Corrupted(), nil
+}
+
+func plotCreate(ctx *cli.Context) error {
+	accountID, err := getPlotAccountID(ctx)
+	if err != nil {
+		return err
+	}
+	dir := ctx.String(utils.GetFlagName(utils.PlotDirFlag))
+	workers := ctx.Int(utils.GetFlagName(utils.PlotWorkersFlag))
+	dryRun := ctx.Bool(utils.GetFlagName(utils.PlotDryRunFlag))
+	if workers < 1 {
+		return fmt.Errorf("invalid workers %d", workers)
+	}
+
+	incomplete, err := plot.Incomplete(dir, accountID)
+	if err != nil {
+		return fmt.Errorf("list plots of %s error:%s", dir, err)
+	}
+	if len(incomplete) > 0 {
+		for _, path := range incomplete {
+			if err := resumePlot(path, workers, dryRun); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	size := ctx.Uint64(utils.GetFlagName(utils.PlotSizeFlag))
+	if size == 0 {
+		PrintErrorMsg("Missing %s argument.", utils.PlotSizeFlag.Name)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	nonceCount := size * consensus.MB / shabal.NonceSize
+	if nonceCount == 0 {
+		return fmt.Errorf("plot size should be at least %d bytes", shabal.NonceSize)
+	}
+	startNonce := ctx.Uint64(utils.GetFlagName(utils.PlotStartNonceFlag))
+	if !ctx.IsSet(utils.GetFlagName(utils.PlotStartNonceFlag)) {
+		startNonce = rand.New(rand.NewSource(time.Now().UnixNano())).Uint64() % (math.MaxUint64 - nonceCount)
+	}
+	header := &plot.Header{
+		Layout:     plot.LayoutNonce,
+		AccountID:  accountID,
+		StartNonce: startNonce,
+		NonceCount: nonceCount,
+	}
+
+	if dryRun {
+		estimatePlot(filepath.Join(dir, header.FileName()), header, 0, workers)
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create plot dir %s error:%s", dir, err)
+	}
+	p, err := plot.Create(dir, header)
+	if err != nil {
+		return fmt.Errorf("create plot error:%s", err)
+	}
+	defer p.Close()
+	return fillPlot(p, workers)
+}
+
+func resumePlot(path string, workers int, dryRun bool) error {
+	p, err := plot.OpenForWrite(path)
+	if err != nil {
+		return fmt.Errorf("open plot %s error:%s", path, err)
+	}
+	defer p.Close()
+	written, err := p.NoncesWritten()
+	if err != nil {
+		return fmt.Errorf("read plot %s error:%s", path, err)
+	}
+	if dryRun {
+		estimatePlot(path, &p.Header, written, workers)
+		return nil
+	}
+	PrintInfoMsg("Resume plot %s from nonce %d.", path, written)
+	return fillPlot(p, workers)
+}
+
+func fillPlot(p *plot.Plot, workers int) error {
+	written, err := p.NoncesWritten()
+	if err != nil {
+		return err
+	}
+
+	//progress bar
+	uiprogress.Start()
+	bar := uiprogress.AddBar(int(p.NonceCount)).
+		AppendCompleted().
+		AppendElapsed().
+		PrependFunc(func(b *uiprogress.Bar) string {
+			return fmt.Sprintf("Nonce(%d/%d)", b.Current(), p.NonceCount)
+		})
+	bar.Set(int(written))
+
+	PrintInfoMsg("Start plot.")
+	err = p.Fill(workers, func(n uint64) {
+		bar.Set(int(n))
+	})
+	uiprogress.Stop()
+	if err != nil {
+		return fmt.Errorf("plot %s error:%s", p.Path, err)
+	}
+	PrintInfoMsg("Plot successfully.")
+	PrintInfoMsg("StartNonce:%d", p.StartNonce)
+	PrintInfoMsg("NonceCount:%d", p.NonceCount)
+	PrintInfoMsg("Plot file:%s", p.Path)
+	return nil
+}
+
+func estimatePlot(path string, h *plot.Header, written uint64, workers int) {
+	start := time.Now()
+	shabal.GenNonce256(h.StartNonce, h.AccountID)
+	perNonce := time.Since(start)
+	remain := h.NonceCount - written
+	size := int64(plot.HeaderSize) + int64(h.NonceCount)*shabal.NonceSize
+
+	PrintInfoMsg("Plot file:%s", path)
+	PrintInfoMsg("Account:%s", h.AccountID)
+	PrintInfoMsg("StartNonce:%d", h.StartNonce)
+	PrintInfoMsg("NonceCount:%d, written:%d", h.NonceCount, written)
+	PrintInfoMsg("FileSize:%d bytes (%d MB)", size, size/consensus.MB)
+	PrintInfoMsg("DiskFree:%d MB", consensus.DiskUsage(".").Free/consensus.MB)
+	PrintInfoMsg("EstimatedTime:%s with %d workers", time.Duration(int64(perNonce)*int64(remain)/int64(workers)), workers)
+}
+
+func getPlotAccountID(ctx *cli.Context) (string, error) {
+	wallet, err := common.OpenWallet(ctx)
+	if err != nil {
+		return "", fmt.Errorf("open wallet error:%s", err)
+	}
+	accAddr := ctx.String(utils.GetFlagName(utils.AccountAddressFlag))
+	accMeta := common.GetAccountMetadataMulti(wallet, accAddr)
+	if accMeta == nil {
+		return "", fmt.Errorf("cannot find account info by %s", accAddr)
+	}
+	data, err := hex.DecodeString(accMeta.PubKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid public key of account %s", accMeta.Address)
+	}
+	pubKey, err := keypair.DeserializePublicKey(data)
+	if err != nil {
+		return "", fmt.Errorf("invalid public key of account %s: %s", accMeta.Address, err)
+	}
+	return pocconfig.PubkeyID(pubKey), nil
+}
@@ -132,6 +132,14 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.EnableConsensusFlag,
 			utils.MaxTxInBlockFlag,
+			utils.EnablePoolServerFlag,
+			utils.PoolServerPortFlag,
+			utils.PlotCheckIntervalFlag,
+			utils.PlotRepairFlag,
+			utils.ScanWorkersFlag,
+			utils.ScanRateLimitFlag,
+			utils.PlotDirsFlag,
+			utils.ZKProvingKeyFlag,
 		},
 	},
 	{
@@ -155,6 +163,8 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.MaxConnInBoundFlag,
 			utils.MaxConnOutBoundFlag,
 			utils.MaxConnInBoundForSingleIPFlag,
+			utils.EnableDiscoveryFlag,
+			utils.DiscoveryPortFlag,
 		},
 	},
 	{
@@ -186,6 +196,7 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.EnableTestModeFlag,
 			utils.TestModeGenBlockTimeFlag,
+			utils.EnablePocTestModeFlag,
 		},
 	},
 	{
@@ -252,6 +263,16 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.ImportEndHeightFlag,
 		},
 	},
+	{
+		Name: "POC",
+		Flags: []cli.Flag{
+			utils.PlotDirFlag,
+			utils.PlotSizeFlag,
+			utils.PlotStartNonceFlag,
+			utils.PlotWorkersFlag,
+			utils.PlotDryRunFlag,
+		},
+	},
 	{
 		Name: "MISC",
 	},
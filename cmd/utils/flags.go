@@ -19,9 +19,12 @@
 package utils
 
 import (
+	"runtime"
 	"strings"
 
 	"OntologyWithPOC/common/config"
+	"OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/consensus/poc/zkproof"
 	"OntologyWithPOC/smartcontract/service/neovm"
 	"github.com/urfave/cli"
 )
@@ -31,6 +34,7 @@ const (
 	DEFAULT_ABI_PATH      = "./abi"
 	DEFAULT_EXPORT_HEIGHT = 0
 	DEFAULT_WALLET_PATH   = "./wallet_data"
+	DEFAULT_PLOT_DIR      = "./Cache"
 )
 
 var (
@@ -83,6 +87,41 @@ var (
 		Usage: "Max transaction `<number>` in block",
 		Value: config.DEFAULT_MAX_TX_IN_BLOCK,
 	}
+	EnablePoolServerFlag = cli.BoolFlag{
+		Name:  "enable-pool-server",
+		Usage: "Start PoC pool server, pool miners submit nonces of plots bound to the node account to it",
+	}
+	PoolServerPortFlag = cli.UintFlag{
+		Name:  "pool-server-port",
+		Usage: "PoC pool server listening `<port>`",
+		Value: config.DEFAULT_POOL_SERVER_PORT,
+	}
+	PlotCheckIntervalFlag = cli.UintFlag{
+		Name:  "plot-check-interval",
+		Usage: "Verify a sample of the nonces of every PoC plot each `<minutes>`, 0 disables it. Corrupted plots are excluded from mining",
+		Value: config.DEFAULT_PLOT_CHECK_INTERVAL,
+	}
+	PlotRepairFlag = cli.BoolFlag{
+		Name:  "plot-repair",
+		Usage: "Replot the corrupted nonces found by plot verification instead of excluding the plots",
+	}
+	ScanWorkersFlag = cli.UintFlag{
+		Name:  "scan-workers",
+		Usage: "Number of `<workers>` reading the plots of each PoC plot directory concurrently",
+		Value: config.DEFAULT_SCAN_WORKERS,
+	}
+	ScanRateLimitFlag = cli.UintFlag{
+		Name:  "scan-rate-limit",
+		Usage: "Limit the plot reading of each PoC plot directory to `<MB>` per second, 0 means unlimited",
+	}
+	PlotDirsFlag = cli.StringFlag{
+		Name:  "plot-dirs",
+		Usage: "Comma separated PoC plot directories as `<path>[:<capacity MB>[:<priority>]]`, one per disk. Defaults to the nonce dir of the genesis config",
+	}
+	ZKProvingKeyFlag = cli.StringFlag{
+		Name:  "zk-proving-key",
+		Usage: "Prove the deadlines of the PoC plots having a plot tree with the zk proving key `<file>`",
+	}
 	GasLimitFlag = cli.Uint64Flag{
 		Name:  "gaslimit",
 		Usage: "Min gas limit `<value>` of transaction to be accepted by tx pool.",
@@ -104,6 +143,10 @@ var (
 		Usage: "Block-out `<time>`(s) in test mode.",
 		Value: config.DEFAULT_GEN_BLOCK_TIME,
 	}
+	EnablePocTestModeFlag = cli.BoolFlag{
+		Name:  "poc-testmode",
+		Usage: "Single node PoC network for testing, mining the plots of the node account. Blocks target --testmode-gen-block-time",
+	}
 
 	//P2P setting
 	ReservedPeersOnlyFlag = cli.BoolFlag{
@@ -140,6 +183,15 @@ var (
 		Usage: "Max connection `<number>` out bound",
 		Value: config.DEFAULT_MAX_CONN_OUT_BOUND,
 	}
+	EnableDiscoveryFlag = cli.BoolFlag{
+		Name:  "enable-discovery",
+		Usage: "Enable announcing and discovering peers with signed UDP beacons on the local network",
+	}
+	DiscoveryPortFlag = cli.UintFlag{
+		Name:  "discovery-port",
+		Usage: "UDP `<port>` signed node beacons are broadcast and listened on",
+		Value: config.DEFAULT_DISCOVERY_PORT,
+	}
 	MaxConnInBoundForSingleIPFlag = cli.UintFlag{
 		Name:  "max-conn-in-bound-single-ip",
 		Usage: "Max connection `<number>` in bound for single ip",
@@ -468,6 +520,120 @@ var (
 		Value: "m",
 	}
 
+	//PoC plot setting
+	PlotDirFlag = cli.StringFlag{
+		Name:  "plot-dir",
+		Usage: "Plot files `<path>`",
+		Value: DEFAULT_PLOT_DIR,
+	}
+	PlotSizeFlag = cli.Uint64Flag{
+		Name:  "size",
+		Usage: "Plot `<size>` in MB",
+	}
+	PlotStartNonceFlag = cli.Uint64Flag{
+		Name:  "start-nonce",
+		Usage: "Start `<nonce>` of the plot. If not specific, using a random nonce instead",
+	}
+	PlotWorkersFlag = cli.IntFlag{
+		Name:  "workers",
+		Usage: "`<number>` of plotting goroutines",
+		Value: runtime.NumCPU(),
+	}
+	PlotDryRunFlag = cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "Only estimate the size and time of the plot",
+	}
+	PlotLayoutFlag = cli.StringFlag{
+		Name:  "layout",
+		Usage: "Plot `<layout>` to convert to, scoop or nonce",
+		Value: "scoop",
+	}
+	PlotCheckSamplesFlag = cli.Uint64Flag{
+		Name:  "samples",
+		Usage: "`<number>` of nonces verified per plot, 0 verifies all of them",
+	}
+	PlotExcludeFlag = cli.BoolFlag{
+		Name:  "exclude",
+		Usage: "Exclude corrupted plots from mining",
+	}
+	PlotRepairNoncesFlag = cli.BoolFlag{
+		Name:  "repair",
+		Usage: "Replot the corrupted nonces, and include back the excluded plots repaired",
+	}
+	PoolFlag = cli.StringFlag{
+		Name:  "pool",
+		Usage: "`<url>` of the PoC pool server to mine for, e.g. http://127.0.0.1:20340",
+	}
+	SimBlocksFlag = cli.UintFlag{
+		Name:  "blocks",
+		Usage: "`<number>` of blocks to simulate",
+		Value: 1000,
+	}
+	SimTargetBlockTimeFlag = cli.UintFlag{
+		Name:  "target-block-time",
+		Usage: "Target block interval in `<seconds>`",
+		Value: pocconfig.DefaultTargetBlockTime,
+	}
+	SimInitialBaseTargetFlag = cli.Uint64Flag{
+		Name:  "initial-base-target",
+		Usage: "Base target `<value>` of the genesis block",
+		Value: pocconfig.DefaultInitialBaseTarget,
+	}
+	SimLatencyFlag = cli.UintFlag{
+		Name:  "latency",
+		Usage: "Network latency in `<milliseconds>`",
+		Value: 200,
+	}
+	SimJitterFlag = cli.UintFlag{
+		Name:  "jitter",
+		Usage: "Random extra network latency of up to `<milliseconds>`",
+	}
+	SimSeedFlag = cli.Int64Flag{
+		Name:  "seed",
+		Usage: "Random `<seed>` of the simulation",
+	}
+	ZKSeedFlag = cli.StringFlag{
+		Name:  "seed",
+		Usage: "Secret `<seed>` the zk keys are derived from",
+	}
+	ZKDepthFlag = cli.IntFlag{
+		Name:  "depth",
+		Usage: "Plot tree `<depth>`, a plot tree holds up to 2^depth nonces",
+		Value: zkproof.DefaultParams.Depth,
+	}
+	ZKRoundsFlag = cli.IntFlag{
+		Name:  "rounds",
+		Usage: "`<number>` of MiMC rounds of the plot tree hash",
+		Value: zkproof.DefaultParams.Rounds,
+	}
+	ZKOutputDirFlag = cli.StringFlag{
+		Name:  "output",
+		Usage: "`<dir>` the zk keys are written to",
+		Value: ".",
+	}
+	HistoryStartFlag = cli.UintFlag{
+		Name:  "start",
+		Usage: "First block `<height>` of the history",
+		Value: 1,
+	}
+	HistoryEndFlag = cli.UintFlag{
+		Name:  "end",
+		Usage: "Last block `<height>` of the history, the current block by default",
+	}
+	HistoryAccountFlag = cli.StringFlag{
+		Name:  "plot-account",
+		Usage: "Only export the rounds plot account `<id>` offered a deadline for",
+	}
+	HistoryFormatFlag = cli.StringFlag{
+		Name:  "format",
+		Usage: "Export `<format>`, csv or json",
+		Value: "csv",
+	}
+	HistoryOutputFlag = cli.StringFlag{
+		Name:  "output",
+		Usage: "`<file>` the history is written to, stdout by default",
+	}
+
 	//PreExecute switcher
 	TxpoolPreExecDisableFlag = cli.BoolFlag{
 		Name:  "disable-tx-pool-pre-exec",
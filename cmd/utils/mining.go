@@ -0,0 +1,56 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+
+	cactor "OntologyWithPOC/consensus/actor"
+	"OntologyWithPOC/consensus/poc/history"
+)
+
+//MiningControl calls the mining control method of the local rpc server
+func MiningControl(method string, params ...interface{}) (*cactor.MiningStatus, error) {
+	if params == nil {
+		params = []interface{}{}
+	}
+	data, ontErr := sendLocalRpcRequest(method, params)
+	if ontErr != nil {
+		return nil, ontErr.Error
+	}
+	status := &cactor.MiningStatus{}
+	if err := json.Unmarshal(data, status); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal:%s error:%s", data, err)
+	}
+	return status, nil
+}
+
+//GetDeadlineHistory returns the PoC deadline history of the heights start to end
+func GetDeadlineHistory(start, end uint32, accountID string) ([]*history.Record, error) {
+	data, ontErr := sendRpcRequest("getdeadlinehistory", []interface{}{start, end, accountID})
+	if ontErr != nil {
+		return nil, ontErr.Error
+	}
+	var recs []*history.Record
+	if err := json.Unmarshal(data, &recs); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal:%s error:%s", data, err)
+	}
+	return recs, nil
+}
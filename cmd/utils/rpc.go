@@ -71,6 +71,15 @@ type JsonRpcResponse struct {
 }
 
 func sendRpcRequest(method string, params []interface{}) ([]byte, *OntologyError) {
+	return sendRpcRequestTo(fmt.Sprintf("http://localhost:%d", config.DefConfig.Rpc.HttpJsonPort), method, params)
+}
+
+//sendLocalRpcRequest send request to the local rpc server of the node
+func sendLocalRpcRequest(method string, params []interface{}) ([]byte, *OntologyError) {
+	return sendRpcRequestTo(fmt.Sprintf("http://localhost:%d/local", config.DefConfig.Rpc.HttpLocalPort), method, params)
+}
+
+func sendRpcRequestTo(addr string, method string, params []interface{}) ([]byte, *OntologyError) {
 	rpcReq := &JsonRpcRequest{
 		Version: JSON_RPC_VERSION,
 		Id:      "cli",
@@ -82,7 +91,6 @@ func sendRpcRequest(method string, params []interface{}) ([]byte, *OntologyError
 		return nil, NewOntologyError(fmt.Errorf("JsonRpcRequest json.Marshal error:%s", err))
 	}
 
-	addr := fmt.Sprintf("http://localhost:%d", config.DefConfig.Rpc.HttpJsonPort)
 	resp, err := http.Post(addr, "application/json", strings.NewReader(string(data)))
 	if err != nil {
 		return nil, NewOntologyError(err)
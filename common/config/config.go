@@ -25,6 +25,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 
 	"OntologyWithPOC/common"
 	"OntologyWithPOC/common/constants"
@@ -51,6 +52,16 @@ const (
 	CONSENSUS_TYPE_VBFT = "vbft"
 	CONSENSUS_TYPE_POC  = "poc"
 
+	POC_SCOOP_COUNT        = 4096   //scoops of a nonce, fixed by the plot format
+	POC_NONCE_SIZE         = 262144 //bytes of a nonce, fixed by the plot format
+	POC_MAX_RETARGET_BLOCK = 1024   //max blocks the base target is averaged over
+	POC_MAX_BLOCK_TIME     = 3600   //max target block time in seconds
+	POC_TESTMODE_SPACE     = 64     //plot space in 'M' of the test mode node
+
+	POC_PLOT_VERIFY_NONCE = "nonce" //deadlines proved by revealing their nonce
+	POC_PLOT_VERIFY_ZK    = "zk"    //deadlines proved by zk proofs
+	POC_PLOT_VERIFY_ANY   = "any"   //deadlines proved either way
+
 	DEFAULT_LOG_LEVEL                       = log.InfoLog
 	DEFAULT_MAX_LOG_SIZE                    = 100 //MByte
 	DEFAULT_NODE_PORT                       = uint(20338)
@@ -59,6 +70,10 @@ const (
 	DEFAULT_RPC_LOCAL_PORT                  = uint(20337)
 	DEFAULT_REST_PORT                       = uint(20334)
 	DEFAULT_WS_PORT                         = uint(20335)
+	DEFAULT_POOL_SERVER_PORT                = uint(20340)
+	DEFAULT_DISCOVERY_PORT                  = uint(20341)
+	DEFAULT_PLOT_CHECK_INTERVAL             = uint(60)
+	DEFAULT_SCAN_WORKERS                    = uint(1)
 	DEFAULT_REST_MAX_CONN                   = uint(1024)
 	DEFAULT_MAX_CONN_IN_BOUND               = uint(1024)
 	DEFAULT_MAX_CONN_OUT_BOUND              = uint(1024)
@@ -280,6 +295,13 @@ var MainNetConfig = &GenesisConfig{
 		MinInitStake:         100000,
 		PocSpace:             200,
 		NonceDir:             "./Cache",
+		TargetBlockTime:      30,
+		InitialBaseTarget:    614891469123651,
+		ScoopCount:           POC_SCOOP_COUNT,
+		NonceSize:            POC_NONCE_SIZE,
+		RetargetWindow:       24,
+		RewardSchedule:       []*POCRewardStage{{Height: 0, Ratio: 100}},
+		PlotVerification:     POC_PLOT_VERIFY_NONCE,
 		Peers: []*POCPeerStakeInfo{
 			{
 				Index:      1,
@@ -331,6 +353,46 @@ type GenesisConfig struct {
 	POC           *POCConfig
 }
 
+// NewPOCTestModeGenesisConfig returns the genesis config of a single node PoC
+// network producing a block every blockTime seconds out of the
+// POC_TESTMODE_SPACE plotted by the node. Its peer is the node account, set
+// once the wallet is opened.
+func NewPOCTestModeGenesisConfig(blockTime uint32) *GenesisConfig {
+	if blockTime < MIN_GEN_BLOCK_TIME {
+		blockTime = DEFAULT_GEN_BLOCK_TIME
+	}
+	// the best of n nonces has a mean deadline of about 2^64/(n*baseTarget)
+	nonces := uint64(POC_TESTMODE_SPACE) << 20 / POC_NONCE_SIZE
+	return &GenesisConfig{
+		SeedList:      make([]string, 0),
+		ConsensusType: CONSENSUS_TYPE_POC,
+		VBFT:          &VBFTConfig{},
+		DBFT:          &DBFTConfig{},
+		SOLO:          &SOLOConfig{},
+		POC: &POCConfig{
+			N:                    1,
+			C:                    0,
+			K:                    1,
+			L:                    16,
+			BlockMsgDelay:        10000,
+			HashMsgDelay:         10000,
+			PeerHandshakeTimeout: 10,
+			MaxBlockChangeView:   120000,
+			MinInitStake:         10000,
+			PocSpace:             POC_TESTMODE_SPACE,
+			NonceDir:             "./Cache",
+			TargetBlockTime:      blockTime,
+			InitialBaseTarget:    math.MaxUint64 / (nonces * uint64(blockTime)),
+			ScoopCount:           POC_SCOOP_COUNT,
+			NonceSize:            POC_NONCE_SIZE,
+			RetargetWindow:       24,
+			RewardSchedule:       []*POCRewardStage{{Height: 0, Ratio: 100}},
+			PlotVerification:     POC_PLOT_VERIFY_NONCE,
+			Peers:                make([]*POCPeerStakeInfo, 0),
+		},
+	}
+}
+
 func NewGenesisConfig() *GenesisConfig {
 	return &GenesisConfig{
 		SeedList:      make([]string, 0),
@@ -551,8 +613,55 @@ type POCConfig struct {
 	MinInitStake         uint32              `json:"min_init_stake"`
 	AdminOntID           string              `json:"admin_ont_id"`
 	Peers                []*POCPeerStakeInfo `json:"peers"`
-	PocSpace             uint64              `json:"POC computing space, unit64 'M'"`
-	NonceDir             string              `json:"Nonce position"`
+	PocSpace             uint64              `json:"poc_space"` // unit 'M'
+	NonceDir             string              `json:"nonce_dir"`
+	TargetBlockTime      uint32              `json:"target_block_time"` // seconds
+	InitialBaseTarget    uint64              `json:"initial_base_target"`
+	ZKVerifyingKey       string              `json:"zk_verifying_key"` // hex, empty disables zk deadline proofs
+	ScoopCount           uint32              `json:"scoop_count"`
+	NonceSize            uint32              `json:"nonce_size"`      // bytes
+	RetargetWindow       uint32              `json:"retarget_window"` // blocks
+	RewardSchedule       []*POCRewardStage   `json:"reward_schedule"`
+	PlotVerification     string              `json:"plot_verification"` // nonce, zk or any
+}
+
+// POCRewardStage sets the share of the undistributed transaction fees paid
+// by every block from Height on, in percent.
+type POCRewardStage struct {
+	Height uint32 `json:"height"`
+	Ratio  uint32 `json:"ratio"`
+}
+
+func (this *POCRewardStage) Serialize(w io.Writer) error {
+	if err := serialization.WriteUint32(w, this.Height); err != nil {
+		return errors.NewDetailErr(err, errors.ErrNoCode, "serialization.WriteUint32, serialize height error!")
+	}
+	if err := serialization.WriteUint32(w, this.Ratio); err != nil {
+		return errors.NewDetailErr(err, errors.ErrNoCode, "serialization.WriteUint32, serialize ratio error!")
+	}
+	return nil
+}
+
+func (this *POCRewardStage) Deserialize(r io.Reader) error {
+	height, err := serialization.ReadUint32(r)
+	if err != nil {
+		return errors.NewDetailErr(err, errors.ErrNoCode, "serialization.ReadUint32, deserialize height error!")
+	}
+	ratio, err := serialization.ReadUint32(r)
+	if err != nil {
+		return errors.NewDetailErr(err, errors.ErrNoCode, "serialization.ReadUint32, deserialize ratio error!")
+	}
+	this.Height = height
+	this.Ratio = ratio
+	return nil
+}
+
+// POCPlotDir is a directory holding plot files, usually one per disk. Plot
+// dirs are node local, they are not part of the genesis config.
+type POCPlotDir struct {
+	Path     string `json:"path"`
+	Capacity uint64 `json:"capacity"` // unit 'M'
+	Priority uint32 `json:"priority"` // higher is read first
 }
 
 func (this *POCConfig) Serialize(w io.Writer) error {
@@ -601,6 +710,35 @@ func (this *POCConfig) Serialize(w io.Writer) error {
 	if err := serialization.WriteString(w, this.NonceDir); err != nil {
 		return errors.NewDetailErr(err, errors.ErrNoCode, "serialization.WriteString, serialize NonceDir error!")
 	}
+	if err := serialization.WriteUint32(w, this.TargetBlockTime); err != nil {
+		return errors.NewDetailErr(err, errors.ErrNoCode, "serialization.WriteUint32, serialize target_block_time error!")
+	}
+	if err := serialization.WriteUint64(w, this.InitialBaseTarget); err != nil {
+		return errors.NewDetailErr(err, errors.ErrNoCode, "serialization.WriteUint64, serialize initial_base_target error!")
+	}
+	if err := serialization.WriteString(w, this.ZKVerifyingKey); err != nil {
+		return errors.NewDetailErr(err, errors.ErrNoCode, "serialization.WriteString, serialize zk_verifying_key error!")
+	}
+	if err := serialization.WriteUint32(w, this.ScoopCount); err != nil {
+		return errors.NewDetailErr(err, errors.ErrNoCode, "serialization.WriteUint32, serialize scoop_count error!")
+	}
+	if err := serialization.WriteUint32(w, this.NonceSize); err != nil {
+		return errors.NewDetailErr(err, errors.ErrNoCode, "serialization.WriteUint32, serialize nonce_size error!")
+	}
+	if err := serialization.WriteUint32(w, this.RetargetWindow); err != nil {
+		return errors.NewDetailErr(err, errors.ErrNoCode, "serialization.WriteUint32, serialize retarget_window error!")
+	}
+	if err := serialization.WriteVarUint(w, uint64(len(this.RewardSchedule))); err != nil {
+		return errors.NewDetailErr(err, errors.ErrNoCode, "serialization.WriteVarUint, serialize reward stage length error!")
+	}
+	for _, stage := range this.RewardSchedule {
+		if err := stage.Serialize(w); err != nil {
+			return errors.NewDetailErr(err, errors.ErrNoCode, "serialize reward stage error!")
+		}
+	}
+	if err := serialization.WriteString(w, this.PlotVerification); err != nil {
+		return errors.NewDetailErr(err, errors.ErrNoCode, "serialization.WriteString, serialize plot_verification error!")
+	}
 	return nil
 }
 
@@ -666,6 +804,46 @@ func (this *POCConfig) Deserialize(r io.Reader) error {
 	if err != nil {
 		return errors.NewDetailErr(err, errors.ErrNoCode, "serialization.ReadString, deserialize NonceDir error!")
 	}
+	targetBlockTime, err := serialization.ReadUint32(r)
+	if err != nil {
+		return errors.NewDetailErr(err, errors.ErrNoCode, "serialization.ReadUint32, deserialize targetBlockTime error!")
+	}
+	initialBaseTarget, err := serialization.ReadUint64(r)
+	if err != nil {
+		return errors.NewDetailErr(err, errors.ErrNoCode, "serialization.ReadUint64, deserialize initialBaseTarget error!")
+	}
+	zkVerifyingKey, err := serialization.ReadString(r)
+	if err != nil {
+		return errors.NewDetailErr(err, errors.ErrNoCode, "serialization.ReadString, deserialize zk_verifying_key error!")
+	}
+	scoopCount, err := serialization.ReadUint32(r)
+	if err != nil {
+		return errors.NewDetailErr(err, errors.ErrNoCode, "serialization.ReadUint32, deserialize scoop_count error!")
+	}
+	nonceSize, err := serialization.ReadUint32(r)
+	if err != nil {
+		return errors.NewDetailErr(err, errors.ErrNoCode, "serialization.ReadUint32, deserialize nonce_size error!")
+	}
+	retargetWindow, err := serialization.ReadUint32(r)
+	if err != nil {
+		return errors.NewDetailErr(err, errors.ErrNoCode, "serialization.ReadUint32, deserialize retarget_window error!")
+	}
+	stageLength, err := serialization.ReadVarUint(r, 0)
+	if err != nil {
+		return errors.NewDetailErr(err, errors.ErrNoCode, "serialization.ReadVarUint, deserialize reward stage length error!")
+	}
+	schedule := make([]*POCRewardStage, 0)
+	for i := 0; uint64(i) < stageLength; i++ {
+		stage := new(POCRewardStage)
+		if err := stage.Deserialize(r); err != nil {
+			return errors.NewDetailErr(err, errors.ErrNoCode, "deserialize reward stage error!")
+		}
+		schedule = append(schedule, stage)
+	}
+	plotVerification, err := serialization.ReadString(r)
+	if err != nil {
+		return errors.NewDetailErr(err, errors.ErrNoCode, "serialization.ReadString, deserialize plot_verification error!")
+	}
 	this.N = n
 	this.C = c
 	this.K = k
@@ -679,6 +857,14 @@ func (this *POCConfig) Deserialize(r io.Reader) error {
 	this.Peers = peers
 	this.PocSpace = PocSpace
 	this.NonceDir = NonceDir
+	this.TargetBlockTime = targetBlockTime
+	this.InitialBaseTarget = initialBaseTarget
+	this.ZKVerifyingKey = zkVerifyingKey
+	this.ScoopCount = scoopCount
+	this.NonceSize = nonceSize
+	this.RetargetWindow = retargetWindow
+	this.RewardSchedule = schedule
+	this.PlotVerification = plotVerification
 	return nil
 }
 
@@ -755,8 +941,16 @@ type CommonConfig struct {
 }
 
 type ConsensusConfig struct {
-	EnableConsensus bool
-	MaxTxInBlock    uint
+	EnableConsensus   bool
+	MaxTxInBlock      uint
+	EnablePoolServer  bool
+	PoolServerPort    uint
+	PlotCheckInterval uint
+	PlotRepair        bool
+	ScanWorkers       uint
+	ScanRateLimit     uint
+	ZKProvingKey      string
+	PlotDirs          []*POCPlotDir
 }
 
 type P2PRsvConfig struct {
@@ -780,6 +974,8 @@ type P2PNodeConfig struct {
 	MaxConnInBound            uint
 	MaxConnOutBound           uint
 	MaxConnInBoundForSingleIP uint
+	EnableDiscovery           bool
+	DiscoveryPort             uint
 }
 
 type RpcConfig struct {
@@ -824,8 +1020,11 @@ func NewOntologyConfig() *OntologyConfig {
 			DataDir:        DEFAULT_DATA_DIR,
 		},
 		Consensus: &ConsensusConfig{
-			EnableConsensus: true,
-			MaxTxInBlock:    DEFAULT_MAX_TX_IN_BLOCK,
+			EnableConsensus:   true,
+			MaxTxInBlock:      DEFAULT_MAX_TX_IN_BLOCK,
+			PoolServerPort:    DEFAULT_POOL_SERVER_PORT,
+			PlotCheckInterval: DEFAULT_PLOT_CHECK_INTERVAL,
+			ScanWorkers:       DEFAULT_SCAN_WORKERS,
 		},
 		P2PNode: &P2PNodeConfig{
 			ReservedCfg:               &P2PRsvConfig{},
@@ -843,6 +1042,8 @@ func NewOntologyConfig() *OntologyConfig {
 			MaxConnInBound:            DEFAULT_MAX_CONN_IN_BOUND,
 			MaxConnOutBound:           DEFAULT_MAX_CONN_OUT_BOUND,
 			MaxConnInBoundForSingleIP: DEFAULT_MAX_CONN_IN_BOUND_FOR_SINGLE_IP,
+			EnableDiscovery:           false,
+			DiscoveryPort:             DEFAULT_DISCOVERY_PORT,
 		},
 		Rpc: &RpcConfig{
 			EnableHttpJsonRpc: true,
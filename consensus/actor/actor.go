@@ -64,6 +64,12 @@ func (self *TxPoolActor) VerifyBlock(txs []*types.Transaction, height uint32) er
 	return nil
 }
 
+// SubmitTransaction hands tx to the pool as a locally submitted one, to be
+// verified and broadcast.
+func (self *TxPoolActor) SubmitTransaction(tx *types.Transaction) {
+	self.Pool.Tell(&txpool.TxReq{Tx: tx, Sender: txpool.HttpSender})
+}
+
 type P2PActor struct {
 	P2P *actor.PID
 }
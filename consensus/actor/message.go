@@ -18,11 +18,87 @@
 
 package actor
 
-import "OntologyWithPOC/core/types"
+import (
+	"OntologyWithPOC/consensus/poc/history"
+	"OntologyWithPOC/core/types"
+)
 
 type StartConsensus struct{}
 type StopConsensus struct{}
 
+// mining control, answered with *MiningStatusRsp
+type StartMining struct{}
+type StopMining struct{}
+type PauseMining struct{}
+type SetMiningCapacity struct {
+	PlotDir  string
+	Capacity uint64 // unit 'M'
+}
+type SetRewardAddress struct {
+	Address string
+}
+type GetMiningStatus struct{}
+
+type MiningStatus struct {
+	State         string            `json:"state"`
+	RewardAddress string            `json:"reward_address"`
+	Capacities    map[string]uint64 `json:"capacities"`
+	ExcludedPlots []string          `json:"excluded_plots,omitempty"`
+}
+
+type MiningStatusRsp struct {
+	Status *MiningStatus
+	Error  error
+}
+
+// PoC mining info, answered with *MiningInfoRsp
+type GetMiningInfo struct{}
+
+type MiningInfo struct {
+	Status        *MiningStatus      `json:"status"`
+	PlottedNonces uint64             `json:"plotted_nonces"`
+	Round         *MiningRoundInfo   `json:"round"`
+	History       []*MiningRoundInfo `json:"history"` // sealed rounds, latest last
+}
+
+// MiningRoundInfo is the mining of block BlockNum. BestDeadline is the best
+// deadline the node found, Winner the deadline of the block sealed.
+type MiningRoundInfo struct {
+	BlockNum      uint32        `json:"block_num"`
+	PrevHash      string        `json:"prev_hash"`
+	BaseTarget    uint64        `json:"base_target"`
+	GenSig        string        `json:"gen_sig"`
+	ScannedNonces uint64        `json:"scanned_nonces"`
+	BestDeadline  *DeadlineInfo `json:"best_deadline,omitempty"`
+	Winner        *DeadlineInfo `json:"winner,omitempty"`
+}
+
+type DeadlineInfo struct {
+	BlockNum  uint32 `json:"block_num"`
+	Proposer  uint32 `json:"proposer"` // peer index
+	AccountID string `json:"account_id"`
+	NonceNr   uint64 `json:"nonce_nr"`
+	Deadline  uint64 `json:"deadline"`
+}
+
+type MiningInfoRsp struct {
+	Info  *MiningInfo
+	Error error
+}
+
+// PoC deadline history of the heights Start to End, of the rounds AccountID
+// offered a deadline for unless empty, answered with *DeadlineHistoryRsp
+type GetDeadlineHistory struct {
+	Start     uint32
+	End       uint32
+	AccountID string
+}
+
+type DeadlineHistoryRsp struct {
+	Records []*history.Record
+	Error   error
+}
+
 //internal Message
 type TimeOut struct{}
 type BlockCompleted struct {
@@ -20,24 +20,15 @@ package consensus
 
 import (
 	"OntologyWithPOC/account"
-	"OntologyWithPOC/common/config"
 	"OntologyWithPOC/common/log"
 	"OntologyWithPOC/consensus/dbft"
 	"OntologyWithPOC/consensus/poc"
-	"OntologyWithPOC/consensus/poc/config"
 	"OntologyWithPOC/consensus/solo"
 	"OntologyWithPOC/consensus/vbft"
-	"context"
-	"github.com/fsnotify/fsnotify"
 	"github.com/ontio/ontology-eventbus/actor"
-	"github.com/spf13/viper"
 	"io/ioutil"
-	"math/rand"
-	"os"
-	"strconv"
 	"sync"
 	"syscall"
-	"time"
 	_ "unsafe"
 )
 
@@ -56,27 +47,6 @@ const (
 
 var quitWg sync.WaitGroup
 
-//调用os.MkdirAll递归创建文件夹
-func createFile(filePath string) error {
-	if !isExist(filePath) {
-		err := os.MkdirAll(filePath, os.ModePerm)
-		return err
-	}
-	return nil
-}
-
-// 判断所给路径文件/文件夹是否存在(返回true是存在)
-func isExist(path string) bool {
-	_, err := os.Stat(path) //os.Stat获取文件信息
-	if err != nil {
-		if os.IsExist(err) {
-			return true
-		}
-		return false
-	}
-	return true
-}
-
 func GetAllFileSize(pathname string) uint64 {
 	rd, err := ioutil.ReadDir(pathname)
 	if err != nil {
@@ -143,121 +113,6 @@ func DiskUsage(path string) (disk DiskStatus) {
 	return
 }
 
-type configViper struct {
-	v *viper.Viper
-}
-
-//监听配置文件的修改和变动
-func WatchConfig(c *configViper, account *account.Account) error {
-	if err := LoadConfigFromProperties(c, account); err != nil {
-		return err
-	}
-	ctx, _ := context.WithCancel(context.Background())
-
-	//监听回调函数
-	watch := func(e fsnotify.Event) {
-		log.Infof("=======================================dig_status: %s, wallet_url: %s, mining_use_space: %s",
-			c.v.Get("dig_status"),
-			c.v.Get("wallet_url"),
-			c.v.Get("mining_use_space"),
-		)
-		space, _ := strconv.ParseUint(c.v.Get("mining_use_space").(string), 10, 64)
-		disk := DiskUsage(".")
-		if disk.Free/MB > 2*space {
-			switch c.v.Get("mining_use_space").(type) {
-			case string:
-				if config.DefConfig.Genesis.POC.PocSpace < space {
-					filespace := GetAllFileSize(config.DefConfig.Genesis.POC.NonceDir)
-					dfspace := space * 1024 * 1024
-					if filespace < dfspace && (dfspace-filespace)/262144 != 0 {
-						for i := uint64(0); i < (dfspace-filespace)/262144; i++ {
-							nonceNr := rand.New(rand.NewSource(time.Now().UnixNano())).Uint64()
-							pubkey := pocconfig.PubkeyID(account.PubKey())
-							poc.Callshabal("genNonce256", []byte(strconv.FormatUint(nonceNr, 10)), []byte(pubkey),
-								[]byte(strconv.Itoa(0)), []byte(""), []byte(config.DefConfig.Genesis.POC.NonceDir))
-						}
-					} else {
-						log.Info("There is enough nonce file, the space is more than the default config!!!")
-					}
-				} else {
-					dfspace := space * 1024 * 1024
-					rd, err := ioutil.ReadDir(config.DefConfig.Genesis.POC.NonceDir)
-					if err != nil {
-						panic(err)
-					}
-					for _, fi := range rd {
-						if fi.IsDir() {
-							continue
-						} else {
-							//err = os.Remove(config.DefConfig.Genesis.POC.NonceDir + "/" + fi.Name())
-							err = os.Truncate(config.DefConfig.Genesis.POC.NonceDir+"/"+fi.Name(), 0)
-							if err != nil {
-								log.Error(err)
-								continue
-							}
-							filespace := GetAllFileSize(config.DefConfig.Genesis.POC.NonceDir)
-							if filespace <= dfspace {
-								break
-							}
-						}
-					}
-				}
-			}
-		} else {
-			log.Warn("There is not enough disk space for poc space!!! The disk space must bigger than the config poc space.")
-		}
-		//cancel()
-	}
-	c.v.OnConfigChange(watch)
-
-	c.v.WatchConfig()
-	<-ctx.Done()
-	return nil
-}
-
-func LoadConfigFromProperties(c *configViper, account *account.Account) error {
-	c.v = viper.New()
-
-	//设置配置文件的名字
-	c.v.SetConfigName("mining_config")
-
-	//添加配置文件所在的路径
-	c.v.AddConfigPath("./")
-
-	//设置配置文件类型
-	c.v.SetConfigType("properties")
-
-	if err := c.v.ReadInConfig(); err != nil {
-		log.Error(err)
-		log.Error("The configuration file does not exist, so the front-end configuration information cannot be read")
-	}
-
-	disk := DiskUsage(".")
-	if disk.Free/MB < config.DefConfig.Genesis.POC.PocSpace {
-		config.DefConfig.Genesis.POC.PocSpace = disk.Free / (MB * 3)
-	}
-
-	err := createFile(config.DefConfig.Genesis.POC.NonceDir)
-	if err != nil {
-		panic(err)
-	}
-	filespace := GetAllFileSize(config.DefConfig.Genesis.POC.NonceDir)
-	pocspace := config.DefConfig.Genesis.POC.PocSpace
-	dfspace := pocspace * 1024 * 1024
-	if filespace < dfspace && (dfspace-filespace)/262144 != 0 {
-		for i := uint64(0); i < (dfspace-filespace)/262144; i++ {
-			nonceNr := rand.New(rand.NewSource(time.Now().UnixNano())).Uint64()
-			pubkey := pocconfig.PubkeyID(account.PubKey())
-			poc.Callshabal("genNonce256", []byte(strconv.FormatUint(nonceNr, 10)), []byte(pubkey),
-				[]byte(strconv.Itoa(0)), []byte(""), []byte(config.DefConfig.Genesis.POC.NonceDir))
-		}
-	} else {
-		log.Info("There is enough nonce file, the space is more than the default config!!!")
-	}
-
-	return nil
-}
-
 func NewConsensusService(consensusType string, account *account.Account, txpool *actor.PID, ledger *actor.PID, p2p *actor.PID) (ConsensusService, error) {
 	if consensusType == "" {
 		consensusType = CONSENSUS_DBFT
@@ -272,13 +127,6 @@ func NewConsensusService(consensusType string, account *account.Account, txpool
 	case CONSENSUS_VBFT:
 		consensus, err = vbft.NewVbftServer(account, txpool, p2p)
 	case CONSENSUS_POC:
-		go func() {
-			c := configViper{}
-			err = WatchConfig(&c, account)
-			if err != nil {
-				log.Error(err)
-			}
-		}()
 		consensus, err = poc.NewPocServer(account, txpool, p2p)
 	}
 	log.Infof("ConsensusType:%s", consensusType)
@@ -0,0 +1,105 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"fmt"
+	"math"
+
+	"OntologyWithPOC/common/config"
+	"OntologyWithPOC/consensus/poc/config"
+)
+
+// blockBaseTarget returns the base target recorded in blk, falling back to the
+// initial base target for blocks produced before retargeting was introduced.
+func blockBaseTarget(blk *Block) uint64 {
+	if blk != nil && blk.Info != nil && blk.Info.BaseTarget != 0 {
+		return blk.Info.BaseTarget
+	}
+	return pocconfig.InitialBaseTarget(config.DefConfig.Genesis.POC)
+}
+
+// retarget computes the base target of a block sealed at timestamp from the
+// blocks preceding it. The average base target of history is scaled by the
+// ratio of the actual to the expected time the blocks took, so the deadlines
+// shrink when blocks are slow and grow when blocks are fast. The ratio is
+// clamped to [1/2, 2] to damp the adjustment.
+func retarget(history []*Block, timestamp uint32, targetBlockTime uint32, initial uint64) uint64 {
+	if len(history) == 0 {
+		return initial
+	}
+	// the quotients and remainders are summed apart, a sum of 64 bit base
+	// targets overflows
+	var avg, rem uint64
+	n := uint64(len(history))
+	for _, blk := range history {
+		bt := initial
+		if blk.Info != nil && blk.Info.BaseTarget != 0 {
+			bt = blk.Info.BaseTarget
+		}
+		avg += bt / n
+		rem += bt % n
+	}
+	avg += rem / n
+
+	expected := uint64(targetBlockTime) * uint64(len(history))
+	if expected == 0 {
+		return avg
+	}
+	var actual uint64
+	if first := history[0].Block.Header.Timestamp; timestamp > first {
+		actual = uint64(timestamp - first)
+	}
+	if actual < expected/2 {
+		actual = expected / 2
+	}
+	if actual > expected*2 {
+		actual = expected * 2
+	}
+
+	if avg > math.MaxUint64/actual {
+		return math.MaxUint64
+	}
+	bt := avg * actual / expected
+	if bt == 0 {
+		bt = 1
+	}
+	return bt
+}
+
+// nextBaseTarget returns the base target block blkNum must carry when sealed
+// at timestamp. The genesis block is excluded from the history since its
+// timestamp is unrelated to block production.
+func (self *Server) nextBaseTarget(blkNum uint32, timestamp uint32) (uint64, error) {
+	cfg := config.DefConfig.Genesis.POC
+	window := pocconfig.RetargetWindow(cfg)
+	start := uint32(1)
+	if blkNum > window+1 {
+		start = blkNum - window
+	}
+	history := make([]*Block, 0, window)
+	for n := start; n < blkNum; n++ {
+		blk, _ := self.blockPool.getSealedBlock(n)
+		if blk == nil {
+			return 0, fmt.Errorf("failed to get sealed block %d", n)
+		}
+		history = append(history, blk)
+	}
+	return retarget(history, timestamp, pocconfig.TargetBlockTime(cfg), pocconfig.InitialBaseTarget(cfg)), nil
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"math"
+	"testing"
+
+	"OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/core/types"
+)
+
+func constructHistory(count int, interval uint32, baseTarget uint64) []*Block {
+	history := make([]*Block, 0, count)
+	for i := 0; i < count; i++ {
+		history = append(history, &Block{
+			Block: &types.Block{
+				Header: &types.Header{
+					Height:    uint32(i + 1),
+					Timestamp: 1000 + uint32(i)*interval,
+				},
+			},
+			Info: &pocconfig.PocBlockInfo{BaseTarget: baseTarget},
+		})
+	}
+	return history
+}
+
+func TestRetarget(t *testing.T) {
+	const target = 30
+	history := constructHistory(pocconfig.DefaultRetargetWindow, target, 1000)
+	next := history[0].Block.Header.Timestamp + target*pocconfig.DefaultRetargetWindow
+	if bt := retarget(history, next, target, 500); bt != 1000 {
+		t.Errorf("retarget on schedule: %d, expected 1000", bt)
+	}
+
+	// blocks twice as slow as the target double the base target
+	history = constructHistory(pocconfig.DefaultRetargetWindow, 2*target, 1000)
+	next = history[0].Block.Header.Timestamp + 2*target*pocconfig.DefaultRetargetWindow
+	if bt := retarget(history, next, target, 500); bt != 2000 {
+		t.Errorf("retarget on slow blocks: %d, expected 2000", bt)
+	}
+
+	// the adjustment is clamped to a factor of two
+	history = constructHistory(pocconfig.DefaultRetargetWindow, 1, 1000)
+	next = history[0].Block.Header.Timestamp + pocconfig.DefaultRetargetWindow
+	if bt := retarget(history, next, target, 500); bt != 500 {
+		t.Errorf("retarget on fast blocks: %d, expected 500", bt)
+	}
+	history = constructHistory(pocconfig.DefaultRetargetWindow, 10*target, 1000)
+	next = history[0].Block.Header.Timestamp + 10*target*pocconfig.DefaultRetargetWindow
+	if bt := retarget(history, next, target, 500); bt != 2000 {
+		t.Errorf("retarget on very slow blocks: %d, expected 2000", bt)
+	}
+}
+
+func TestRetargetBounds(t *testing.T) {
+	if bt := retarget(nil, 1000, 30, 500); bt != 500 {
+		t.Errorf("retarget without history: %d, expected 500", bt)
+	}
+
+	// blocks without a base target count as the initial one
+	history := constructHistory(4, 30, 0)
+	if bt := retarget(history, 1000+4*30, 30, 500); bt != 500 {
+		t.Errorf("retarget on legacy blocks: %d, expected 500", bt)
+	}
+
+	history = constructHistory(4, 1, 1)
+	if bt := retarget(history, 1004, 30, 1); bt != 1 {
+		t.Errorf("retarget lower bound: %d, expected 1", bt)
+	}
+	history = constructHistory(4, 60, math.MaxUint64)
+	if bt := retarget(history, 1000+4*60, 30, 1); bt != math.MaxUint64 {
+		t.Errorf("retarget upper bound: %d, expected %d", bt, uint64(math.MaxUint64))
+	}
+}
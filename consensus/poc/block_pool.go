@@ -56,6 +56,8 @@ type CandidateInfo struct {
 
 	// server sealed block for this round
 	SealedBlock *Block
+	// competing sealed blocks of this height, see onForkBlock
+	ForkBlocks []*Block
 
 	// candidate msgs for this round
 	Proposals  []*blockProposalMsg
@@ -71,7 +73,8 @@ type BlockPool struct {
 
 	server          *Server
 	chainStore      *ChainStore
-	candidateBlocks map[uint32]*CandidateInfo // indexed by blockNum
+	candidateBlocks map[uint32]*CandidateInfo   // indexed by blockNum
+	orphanBlocks    map[common.Uint256][]*Block // fork blocks indexed by parent hash
 }
 
 func newBlockPool(server *Server, historyLen uint32, store *ChainStore) (*BlockPool, error) {
@@ -80,6 +83,7 @@ func newBlockPool(server *Server, historyLen uint32, store *ChainStore) (*BlockP
 		HistoryLen:      historyLen,
 		chainStore:      store,
 		candidateBlocks: make(map[uint32]*CandidateInfo),
+		orphanBlocks:    make(map[common.Uint256][]*Block),
 	}
 
 	var blkNum uint32
@@ -106,6 +110,7 @@ func (pool *BlockPool) clean() {
 	defer pool.lock.Unlock()
 
 	pool.candidateBlocks = make(map[uint32]*CandidateInfo)
+	pool.orphanBlocks = make(map[common.Uint256][]*Block)
 }
 
 func (pool *BlockPool) getCandidateInfoLocked(blkNum uint32) *CandidateInfo {
@@ -658,7 +663,7 @@ func (pool *BlockPool) setBlockSealed(block *Block, forEmpty bool, sigdata bool)
 	}
 
 	// add block to chain store
-	if err := pool.chainStore.AddBlock(c.SealedBlock); err != nil {
+	if err := pool.chainStore.AddBlock(c.SealedBlock, true); err != nil {
 		return fmt.Errorf("failed to seal block (%d) to chainstore: %s", blkNum, err)
 	}
 	stateRoot, err := pool.chainStore.getExecMerkleRoot(pool.chainStore.GetChainedBlockNum())
@@ -667,6 +672,10 @@ func (pool *BlockPool) setBlockSealed(block *Block, forEmpty bool, sigdata bool)
 		return nil
 	}
 	if blocksubmitMsg, _ := pool.server.constructBlockSubmitMsg(pool.chainStore.GetChainedBlockNum(), stateRoot); blocksubmitMsg != nil {
+		// our own state root counts for the submission too
+		if h, err := HashMsg(blocksubmitMsg); err == nil {
+			_ = pool.server.msgPool.AddMsg(blocksubmitMsg, h)
+		}
 		pool.server.broadcast(blocksubmitMsg)
 		pool.server.pocActionC <- &PocAction{
 			Type:     SubmitBlock,
@@ -700,6 +709,198 @@ func (pool *BlockPool) getSealedBlock(blockNum uint32) (*Block, common.Uint256)
 	return blk, blk.Block.Hash()
 }
 
+// addForkBlock keeps a sealed block competing with our chain, its parent
+// must be known to the pool, see onForkBlock.
+func (pool *BlockPool) addForkBlock(block *Block) error {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	blkNum := block.getBlockNum()
+	if blkNum <= pool.chainStore.db.GetCurrentBlockHeight() {
+		return fmt.Errorf("fork of submitted block %d", blkNum)
+	}
+	c := pool.getCandidateInfoLocked(blkNum)
+	h := block.Block.Hash()
+	if c.SealedBlock != nil && c.SealedBlock.Block.Hash() == h {
+		return nil
+	}
+	for _, blk := range c.ForkBlocks {
+		if blk.Block.Hash() == h {
+			return nil
+		}
+	}
+	if len(c.ForkBlocks) >= maxForkBlocks {
+		return fmt.Errorf("too many fork blocks of height %d", blkNum)
+	}
+	c.ForkBlocks = append(c.ForkBlocks, block)
+	return nil
+}
+
+// getBlockLocked returns the block of height blkNum and hash h, whether it is
+// sealed in our chain or kept as a fork.
+func (pool *BlockPool) getBlockLocked(blkNum uint32, h common.Uint256) (*Block, bool) {
+	c := pool.candidateBlocks[blkNum]
+	if c == nil {
+		return nil, false
+	}
+	if c.SealedBlock != nil && c.SealedBlock.Block.Hash() == h {
+		return c.SealedBlock, true
+	}
+	for _, blk := range c.ForkBlocks {
+		if blk.Block.Hash() == h {
+			return blk, false
+		}
+	}
+	return nil, false
+}
+
+// getParentBlock returns the parent of block if the pool knows it.
+func (pool *BlockPool) getParentBlock(block *Block) *Block {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	blk, _ := pool.getBlockLocked(block.getBlockNum()-1, block.getPrevBlockHash())
+	return blk
+}
+
+// forkBranch returns the fork blocks from our chain up to tip, in height
+// order. The parent of the first block is sealed in our chain.
+func (pool *BlockPool) forkBranch(tip *Block) ([]*Block, error) {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	height := pool.chainStore.db.GetCurrentBlockHeight()
+	branch := []*Block{tip}
+	for blk := tip; ; {
+		blkNum := blk.getBlockNum()
+		if blkNum <= height {
+			return nil, fmt.Errorf("fork of block %d below submitted block %d", blkNum, height)
+		}
+		prev, sealed := pool.getBlockLocked(blkNum-1, blk.getPrevBlockHash())
+		if prev == nil {
+			return nil, fmt.Errorf("unknown parent of fork block %d", blkNum)
+		}
+		if sealed {
+			break
+		}
+		branch = append([]*Block{prev}, branch...)
+		blk = prev
+	}
+	return branch, nil
+}
+
+// dropBlocks unchains our sealed blocks from blkNum on, keeping them as forks
+// of their heights. The dropped blocks are returned in height order.
+func (pool *BlockPool) dropBlocks(blkNum uint32) ([]*Block, error) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	chained := pool.chainStore.GetChainedBlockNum()
+	dropped := make([]*Block, 0)
+	if blkNum > chained {
+		return dropped, nil
+	}
+	if err := pool.chainStore.dropBlocks(blkNum); err != nil {
+		return nil, err
+	}
+	for n := blkNum; n <= chained+1; n++ {
+		c := pool.candidateBlocks[n]
+		if c == nil {
+			continue
+		}
+		// the round msgs were built on the dropped blocks
+		forks := c.ForkBlocks
+		if c.SealedBlock != nil {
+			dropped = append(dropped, c.SealedBlock)
+			forks = append(forks, c.SealedBlock)
+		}
+		pool.candidateBlocks[n] = &CandidateInfo{
+			ForkBlocks:  forks,
+			Proposals:   make([]*blockProposalMsg, 0),
+			CommitMsgs:  make([]*blockCommitMsg, 0),
+			EndorseSigs: make(map[uint32][]*CandidateEndorseSigInfo),
+		}
+	}
+	return dropped, nil
+}
+
+// chainBlock adds a fork block on top of our chain, re-executing the ledger
+// state on top of its parent. Blocks not committed are not submitted to the
+// ledger until commitBlocks marks them.
+func (pool *BlockPool) chainBlock(block *Block, committed bool) error {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	blkNum := block.getBlockNum()
+	if blkNum != pool.chainStore.GetChainedBlockNum()+1 {
+		return fmt.Errorf("chaining block %d, chained %d", blkNum, pool.chainStore.GetChainedBlockNum())
+	}
+	if err := pool.chainStore.AddBlock(block, committed); err != nil {
+		return fmt.Errorf("failed to add fork block (%d) to chainstore: %s", blkNum, err)
+	}
+
+	c := pool.getCandidateInfoLocked(blkNum)
+	h := block.Block.Hash()
+	forks := make([]*Block, 0, len(c.ForkBlocks))
+	for _, blk := range c.ForkBlocks {
+		if blk.Block.Hash() != h {
+			forks = append(forks, blk)
+		}
+	}
+	c.ForkBlocks = forks
+	c.SealedBlock = block
+	return nil
+}
+
+// commitBlocks marks the chained blocks up to blkNum committed.
+func (pool *BlockPool) commitBlocks(blkNum uint32) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	pool.chainStore.setCommitted(blkNum)
+}
+
+// addOrphanBlock keeps a fork block whose parent is not known yet.
+func (pool *BlockPool) addOrphanBlock(block *Block) error {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	prevHash := block.getPrevBlockHash()
+	h := block.Block.Hash()
+	for _, blk := range pool.orphanBlocks[prevHash] {
+		if blk.Block.Hash() == h {
+			return nil
+		}
+	}
+	n := 0
+	for _, blks := range pool.orphanBlocks {
+		n += len(blks)
+	}
+	if n >= maxOrphanBlocks {
+		return fmt.Errorf("too many orphan blocks")
+	}
+	pool.orphanBlocks[prevHash] = append(pool.orphanBlocks[prevHash], block)
+	return nil
+}
+
+// isOrphanParent reports whether some orphan block is waiting for the block h.
+func (pool *BlockPool) isOrphanParent(h common.Uint256) bool {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+
+	_, present := pool.orphanBlocks[h]
+	return present
+}
+
+// takeOrphanBlocks removes and returns the orphan blocks of parent h.
+func (pool *BlockPool) takeOrphanBlocks(h common.Uint256) []*Block {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	blks := pool.orphanBlocks[h]
+	delete(pool.orphanBlocks, h)
+	return blks
+}
+
 func (pool *BlockPool) findConsensusEmptyProposal(blockNum uint32) (*blockProposalMsg, error) {
 	pool.lock.RLock()
 	defer pool.lock.RUnlock()
@@ -755,6 +956,11 @@ func (pool *BlockPool) onBlockSealed(blockNum uint32) {
 	for _, n := range toFreeCandidates {
 		delete(pool.candidateBlocks, n)
 	}
+	for h, blks := range pool.orphanBlocks {
+		if blks[0].getBlockNum() < blockNum-pool.HistoryLen {
+			delete(pool.orphanBlocks, h)
+		}
+	}
 }
 
 func (pool *BlockPool) getExecMerkleRoot(blkNum uint32) (common.Uint256, error) {
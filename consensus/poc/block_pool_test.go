@@ -74,7 +74,7 @@ func TestAddBlock(t *testing.T) {
 	if err != nil {
 		t.Errorf("buildTestBlock err:%s", err)
 	}
-	err = blockpool.chainStore.AddBlock(blk)
+	err = blockpool.chainStore.AddBlock(blk, true)
 	if err != nil {
 		t.Errorf("AddBlock err:%s", err)
 	}
@@ -88,3 +88,106 @@ func TestAddBlock(t *testing.T) {
 		t.Errorf("submitBlock err:%s", err)
 	}
 }
+
+func TestReorganizeBlocks(t *testing.T) {
+	blockpool, err := buildTestBlockPool(t)
+	if err != nil {
+		t.Fatalf("buildTestBlockPool err:%s", err)
+	}
+	defer cleanTestChainStore()
+
+	lgr := blockpool.chainStore.db
+	extend := func(parent *Block, n int) []*Block {
+		blks := make([]*Block, 0, n)
+		for i := 0; i < n; i++ {
+			blk, err := buildTestBlock(t, parent.Block, lgr)
+			if err != nil {
+				t.Fatalf("buildTestBlock err:%s", err)
+			}
+			blks = append(blks, blk)
+			parent = blk
+		}
+		return blks
+	}
+
+	genesis, _ := blockpool.getSealedBlock(0)
+	ours := extend(genesis, 3)
+	for _, blk := range ours {
+		if err := blockpool.chainBlock(blk, true); err != nil {
+			t.Fatalf("chainBlock %d err:%s", blk.getBlockNum(), err)
+		}
+	}
+	if n := blockpool.chainStore.GetChainedBlockNum(); n != 3 {
+		t.Fatalf("chained %d blocks, expected 3", n)
+	}
+	if lgr.GetCurrentBlockHeight() != 0 {
+		t.Fatalf("blocks within reorg depth submitted")
+	}
+
+	fork := extend(ours[0], 3)
+	for _, blk := range fork {
+		if err := blockpool.addForkBlock(blk); err != nil {
+			t.Fatalf("addForkBlock %d err:%s", blk.getBlockNum(), err)
+		}
+	}
+	branch, err := blockpool.forkBranch(fork[2])
+	if err != nil {
+		t.Fatalf("forkBranch err:%s", err)
+	}
+	if len(branch) != 3 || branch[0] != fork[0] {
+		t.Fatalf("forkBranch returned %d blocks", len(branch))
+	}
+
+	dropped, err := blockpool.dropBlocks(branch[0].getBlockNum())
+	if err != nil {
+		t.Fatalf("dropBlocks err:%s", err)
+	}
+	if len(dropped) != 2 || dropped[0] != ours[1] || dropped[1] != ours[2] {
+		t.Fatalf("dropped %d blocks", len(dropped))
+	}
+	for _, blk := range branch {
+		if err := blockpool.chainBlock(blk, false); err != nil {
+			t.Fatalf("chainBlock %d err:%s", blk.getBlockNum(), err)
+		}
+	}
+	if n := blockpool.chainStore.GetChainedBlockNum(); n != 4 {
+		t.Fatalf("chained %d blocks, expected 4", n)
+	}
+	if _, h := blockpool.getSealedBlock(3); h != fork[1].Block.Hash() {
+		t.Errorf("block 3 not replaced by the fork")
+	}
+	if blockpool.getParentBlock(ours[2]) != ours[1] {
+		t.Errorf("dropped block not kept as fork")
+	}
+	if _, err := blockpool.forkBranch(ours[2]); err != nil {
+		t.Errorf("forkBranch of dropped blocks err:%s", err)
+	}
+
+	// the branch is not submitted before it is committed, the test blocks
+	// are not accepted by the ledger so the submission is read from the
+	// pending blocks
+	submitted := func() uint32 {
+		for n := uint32(0); ; n++ {
+			if blk, present := blockpool.chainStore.pendingBlocks[n+1]; !present || !blk.hasSubmitted {
+				return n
+			}
+		}
+	}
+	tip, _ := blockpool.getSealedBlock(4)
+	for _, blk := range extend(tip, maxReorgDepth) {
+		if err := blockpool.chainBlock(blk, true); err != nil {
+			t.Fatalf("chainBlock %d err:%s", blk.getBlockNum(), err)
+		}
+	}
+	if n := submitted(); n != 1 {
+		t.Fatalf("submitted %d blocks, expected 1 below the uncommitted branch", n)
+	}
+	blockpool.commitBlocks(blockpool.chainStore.GetChainedBlockNum())
+	tip, _ = blockpool.getSealedBlock(blockpool.chainStore.GetChainedBlockNum())
+	if err := blockpool.chainBlock(extend(tip, 1)[0], true); err != nil {
+		t.Fatalf("chainBlock err:%s", err)
+	}
+	if n, chained := submitted(), blockpool.chainStore.GetChainedBlockNum(); n != chained-maxReorgDepth {
+		t.Errorf("submitted %d of %d blocks after the branch is committed", n, chained)
+	}
+}
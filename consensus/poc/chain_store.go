@@ -34,6 +34,9 @@ type PendingBlock struct {
 	block        *Block
 	execResult   *store.ExecuteResult
 	hasSubmitted bool
+	// the committee agreed on the block, or it came with a fork branch which
+	// passed full validation
+	committed bool
 }
 type ChainStore struct {
 	db              *ledger.Ledger
@@ -59,7 +62,7 @@ func OpenBlockStore(db *ledger.Ledger, serverPid *actor.PID) (*ChainStore, error
 	if err != nil {
 		return nil, err
 	}
-	chainstore.pendingBlocks[chainstore.chainedBlockNum] = &PendingBlock{block: block, execResult: &store.ExecuteResult{WriteSet: writeSet, MerkleRoot: merkleRoot}, hasSubmitted: true}
+	chainstore.pendingBlocks[chainstore.chainedBlockNum] = &PendingBlock{block: block, execResult: &store.ExecuteResult{WriteSet: writeSet, MerkleRoot: merkleRoot}, hasSubmitted: true, committed: true}
 	return chainstore, nil
 }
 
@@ -86,12 +89,39 @@ func (self *ChainStore) getExecMerkleRoot(blkNum uint32) (common.Uint256, error)
 }
 
 func (self *ChainStore) getExecWriteSet(blkNum uint32) *overlaydb.MemDB {
+	// the state of a block includes the changes of all the blocks not
+	// submitted to the ledger before it
+	writeSets := make([]*overlaydb.MemDB, 0)
+	for n := self.db.GetCurrentBlockHeight() + 1; n <= blkNum; n++ {
+		blk, present := self.pendingBlocks[n]
+		if !present {
+			break
+		}
+		writeSets = append(writeSets, blk.execResult.WriteSet)
+	}
+	if len(writeSets) > 1 {
+		return overlaydb.MergeWriteSets(writeSets)
+	}
 	if blk, present := self.pendingBlocks[blkNum]; blk != nil && present {
 		return blk.execResult.WriteSet
 	}
 	return nil
 }
 
+// pendingResults returns the execution results of the chained blocks below
+// blkNum which have not been submitted to the ledger, ordered by height.
+func (self *ChainStore) pendingResults(blkNum uint32) ([]store.ExecuteResult, error) {
+	results := make([]store.ExecuteResult, 0)
+	for n := self.db.GetCurrentBlockHeight() + 1; n < blkNum; n++ {
+		blk, present := self.pendingBlocks[n]
+		if !present {
+			return nil, fmt.Errorf("pending block %d not found", n)
+		}
+		results = append(results, *blk.execResult)
+	}
+	return results, nil
+}
+
 func (self *ChainStore) ReloadFromLedger() {
 	height := self.db.GetCurrentBlockHeight()
 	if height > self.chainedBlockNum {
@@ -109,7 +139,30 @@ func (self *ChainStore) ReloadFromLedger() {
 	}
 }
 
-func (self *ChainStore) AddBlock(block *Block) error {
+// dropBlocks removes the chained blocks from blkNum on, which must not have
+// been submitted to the ledger yet, so that a competing branch can be added.
+func (self *ChainStore) dropBlocks(blkNum uint32) error {
+	chained := self.GetChainedBlockNum()
+	if blkNum == 0 || blkNum > chained {
+		return fmt.Errorf("chain store dropping blocks from %d, chained %d", blkNum, chained)
+	}
+	for n := blkNum; n <= chained; n++ {
+		blk, present := self.pendingBlocks[n]
+		if !present || blk.hasSubmitted {
+			return fmt.Errorf("chain store dropping submitted block %d", n)
+		}
+	}
+	for n := blkNum; n <= chained; n++ {
+		delete(self.pendingBlocks, n)
+	}
+	self.chainedBlockNum = blkNum - 1
+	self.ReloadFromLedger()
+	return nil
+}
+
+// AddBlock chains block on top of the chained blocks. Blocks deeper than
+// maxReorgDepth are submitted to the ledger if they are committed.
+func (self *ChainStore) AddBlock(block *Block, committed bool) error {
 	if block == nil {
 		return fmt.Errorf("try add nil block")
 	}
@@ -123,16 +176,23 @@ func (self *ChainStore) AddBlock(block *Block) error {
 		panic("nil block header")
 	}
 	blkNum := self.GetChainedBlockNum() + 1
-	err := self.submitBlock(blkNum - 1)
+	// blocks deeper than maxReorgDepth can no longer be reorganized
+	if blkNum > maxReorgDepth {
+		if err := self.submitCommitted(blkNum - maxReorgDepth); err != nil {
+			log.Errorf("chainstore blkNum:%d, SubmitBlock: %s", blkNum-maxReorgDepth, err)
+		}
+	}
+	pending, err := self.pendingResults(blkNum)
 	if err != nil {
-		log.Errorf("chainstore blkNum:%d, SubmitBlock: %s", blkNum-1, err)
+		log.Errorf("chainstore AddBlock pendingResults: %s", err)
+		return fmt.Errorf("chainstore AddBlock pendingResults: %s", err)
 	}
-	execResult, err := self.db.ExecuteBlock(block.Block)
+	execResult, err := self.db.ExecutePendingBlock(block.Block, pending)
 	if err != nil {
 		log.Errorf("chainstore AddBlock GetBlockExecResult: %s", err)
 		return fmt.Errorf("chainstore AddBlock GetBlockExecResult: %s", err)
 	}
-	self.pendingBlocks[blkNum] = &PendingBlock{block: block, execResult: &execResult, hasSubmitted: false}
+	self.pendingBlocks[blkNum] = &PendingBlock{block: block, execResult: &execResult, hasSubmitted: false, committed: committed}
 
 	if self.pid != nil {
 		self.pid.Tell(
@@ -144,20 +204,55 @@ func (self *ChainStore) AddBlock(block *Block) error {
 	return nil
 }
 
+// setCommitted marks the chained blocks up to blkNum committed.
+func (self *ChainStore) setCommitted(blkNum uint32) {
+	for n := self.db.GetCurrentBlockHeight() + 1; n <= blkNum; n++ {
+		if blk, present := self.pendingBlocks[n]; present {
+			blk.committed = true
+		}
+	}
+}
+
+// submitCommitted submits the chained blocks up to blkNum, stopping below the
+// first one which is not committed.
+func (self *ChainStore) submitCommitted(blkNum uint32) error {
+	n := self.db.GetCurrentBlockHeight()
+	for ; n < blkNum; n++ {
+		if blk, present := self.pendingBlocks[n+1]; !present || !blk.committed {
+			break
+		}
+	}
+	return self.submitBlock(n)
+}
+
+// submitBlock persists the chained blocks up to blkNum to the ledger, in
+// height order as each of them is executed on top of its predecessors.
 func (self *ChainStore) submitBlock(blkNum uint32) error {
 	if blkNum == 0 {
 		return nil
 	}
-	if submitBlk, present := self.pendingBlocks[blkNum]; submitBlk != nil && submitBlk.hasSubmitted == false && present {
-		err := self.db.SubmitBlock(submitBlk.block.Block, *submitBlk.execResult)
-		if err != nil && blkNum > self.GetChainedBlockNum() {
-			return fmt.Errorf("ledger add submitBlk (%d, %d) failed: %s", blkNum, self.GetChainedBlockNum(), err)
+	for n := self.db.GetCurrentBlockHeight() + 1; n <= blkNum; n++ {
+		submitBlk, present := self.pendingBlocks[n]
+		if !present {
+			break
 		}
-		if _, present := self.pendingBlocks[blkNum-1]; present {
-			delete(self.pendingBlocks, blkNum-1)
+		if submitBlk.hasSubmitted {
+			continue
+		}
+		err := self.db.SubmitBlock(submitBlk.block.Block, *submitBlk.execResult)
+		if err != nil && n > self.GetChainedBlockNum() {
+			return fmt.Errorf("ledger add submitBlk (%d, %d) failed: %s", n, self.GetChainedBlockNum(), err)
 		}
 		submitBlk.hasSubmitted = true
 	}
+	// the last submitted block is kept, the state of its successor is
+	// checked against it
+	height := self.db.GetCurrentBlockHeight()
+	for n, blk := range self.pendingBlocks {
+		if n < height && blk.hasSubmitted {
+			delete(self.pendingBlocks, n)
+		}
+	}
 	return nil
 }
 
@@ -56,9 +56,16 @@ type ChainConfig struct {
 // poc consensus payload, stored on each block header
 //
 type PocBlockInfo struct {
-	Proposer           uint32       `json:"leader"`
-	LastConfigBlockNum uint32       `json:"last_config_block_num"`
-	NewChainConfig     *ChainConfig `json:"new_chain_config"`
+	Proposer             uint32       `json:"leader"`
+	LastConfigBlockNum   uint32       `json:"last_config_block_num"`
+	NewChainConfig       *ChainConfig `json:"new_chain_config"`
+	BaseTarget           uint64       `json:"base_target"`
+	GenSig               []byte       `json:"gen_sig"`
+	Deadline             uint64       `json:"deadline"`
+	NonceNr              uint64       `json:"nonce_nr"`
+	CumulativeDifficulty uint64       `json:"cumulative_difficulty"`
+	PlotAccount          string       `json:"plot_account,omitempty"`
+	ZKProof              []byte       `json:"zk_proof,omitempty"`
 }
 
 const (
@@ -0,0 +1,55 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package pocconfig
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"OntologyWithPOC/consensus/poc/shabal"
+)
+
+// CalcDeadline returns the seconds a scoop hashing to target has to wait
+// under baseTarget, the hit is the little endian uint64 of its first 8 bytes.
+func CalcDeadline(target []byte, baseTarget uint64) (uint64, error) {
+	if len(target) < 8 {
+		return 0, fmt.Errorf("invalid target len %d", len(target))
+	}
+	if baseTarget == 0 {
+		return 0, fmt.Errorf("invalid base target")
+	}
+	return binary.LittleEndian.Uint64(target[0:8]) / baseTarget, nil
+}
+
+// NonceDeadline regenerates the nonce nonceNr of accountID and returns the
+// scoop gensig selects in it together with the deadline it yields. It is the
+// one deadline computation of both the consensus and the governance contract.
+func NonceDeadline(accountID string, nonceNr uint64, gensig []byte, baseTarget uint64) (scoopIndex uint32, scoop []byte, deadline uint64, err error) {
+	if len(gensig) != shabal.HashSize {
+		return 0, nil, 0, fmt.Errorf("invalid generation signature len %d", len(gensig))
+	}
+	scoopIndex = shabal.ScoopNum256(gensig)
+	nonce := shabal.GenNonce256(nonceNr, accountID)
+	scoop = nonce[scoopIndex*shabal.ScoopSize : (scoopIndex+1)*shabal.ScoopSize]
+	deadline, err = CalcDeadline(shabal.Target256(gensig, scoop), baseTarget)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+	return scoopIndex, scoop, deadline, nil
+}
@@ -21,7 +21,6 @@ package pocconfig
 import (
 	"encoding/json"
 	"fmt"
-	"hash/fnv"
 	"math"
 	"sort"
 	"strings"
@@ -30,22 +29,70 @@ import (
 	"OntologyWithPOC/common"
 	"OntologyWithPOC/common/config"
 	"OntologyWithPOC/common/log"
+	"OntologyWithPOC/consensus/poc/shabal"
 )
 
-func shuffle_hash(txid common.Uint256, height uint32, id string, idx int) (uint64, error) {
-	data, err := json.Marshal(struct {
-		Txid   common.Uint256 `json:"txid"`
-		Height uint32         `json:"height"`
-		NodeID string         `json:"node_id"`
-		Index  int            `json:"index"`
-	}{txid, height, id, idx})
-	if err != nil {
-		return 0, err
+const (
+	DefaultTargetBlockTime = 30 // seconds
+	// one nonce per account gives a mean best deadline of 2^64/InitialBaseTarget,
+	// so the default starts close to DefaultTargetBlockTime per 1000 nonces
+	DefaultInitialBaseTarget = 614891469123651
+	// number of sealed blocks the base target of a new block is averaged over
+	DefaultRetargetWindow = 24
+)
+
+// TargetBlockTime returns the configured block interval in seconds.
+func TargetBlockTime(cfg *config.POCConfig) uint32 {
+	if cfg == nil || cfg.TargetBlockTime == 0 {
+		return DefaultTargetBlockTime
+	}
+	return cfg.TargetBlockTime
+}
+
+// InitialBaseTarget returns the base target of the genesis block.
+func InitialBaseTarget(cfg *config.POCConfig) uint64 {
+	if cfg == nil || cfg.InitialBaseTarget == 0 {
+		return DefaultInitialBaseTarget
+	}
+	return cfg.InitialBaseTarget
+}
+
+// RetargetWindow returns the number of blocks retargeting averages over.
+func RetargetWindow(cfg *config.POCConfig) uint32 {
+	if cfg == nil || cfg.RetargetWindow == 0 {
+		return DefaultRetargetWindow
 	}
+	return cfg.RetargetWindow
+}
 
-	hash := fnv.New64a()
-	hash.Write(data)
-	return hash.Sum64(), nil
+// PlotVerification returns how deadlines must be proved on the chain, by
+// default either way when a zk verifying key is pinned, by nonce otherwise.
+func PlotVerification(cfg *config.POCConfig) string {
+	if cfg != nil && cfg.PlotVerification != "" {
+		return cfg.PlotVerification
+	}
+	if cfg != nil && cfg.ZKVerifyingKey != "" {
+		return config.POC_PLOT_VERIFY_ANY
+	}
+	return config.POC_PLOT_VERIFY_NONCE
+}
+
+// PlotDirs returns the node local plot directories ordered by read priority,
+// highest first. Without local plot dirs the NonceDir of cfg is used with a
+// PocSpace budget.
+func PlotDirs(local []*config.POCPlotDir, cfg *config.POCConfig) []*config.POCPlotDir {
+	if len(local) == 0 {
+		if cfg == nil || cfg.NonceDir == "" {
+			return nil
+		}
+		return []*config.POCPlotDir{{Path: cfg.NonceDir, Capacity: cfg.PocSpace}}
+	}
+	dirs := make([]*config.POCPlotDir, len(local))
+	copy(dirs, local)
+	sort.SliceStable(dirs, func(i, j int) bool {
+		return dirs[i].Priority > dirs[j].Priority
+	})
+	return dirs
 }
 
 func deepCopy(peersInfo []*config.POCPeerStakeInfo) ([]*config.POCPeerStakeInfo, error) {
@@ -63,17 +110,8 @@ func deepCopy(peersInfo []*config.POCPeerStakeInfo) ([]*config.POCPeerStakeInfo,
 }
 
 func genConsensusPayload(cfg *config.POCConfig, txhash common.Uint256, height uint32) ([]byte, error) {
-	if cfg.C == 0 {
-		return nil, fmt.Errorf("C must larger than zero")
-	}
-	if int(cfg.K) > len(cfg.Peers) {
-		return nil, fmt.Errorf("peer count is less than K")
-	}
-	if cfg.K < 2*cfg.C+1 {
-		return nil, fmt.Errorf("invalid config, K: %d, C: %d", cfg.K, cfg.C)
-	}
-	if cfg.L%cfg.K != 0 || cfg.L < cfg.K*2 {
-		return nil, fmt.Errorf("invalid config, K: %d, L: %d", cfg.K, cfg.L)
+	if len(cfg.Peers) == 0 {
+		return nil, fmt.Errorf("no peer in poc config")
 	}
 	// deep copy to avoid modify global config
 	peers, err := deepCopy(cfg.Peers)
@@ -81,7 +119,7 @@ func genConsensusPayload(cfg *config.POCConfig, txhash common.Uint256, height ui
 		return nil, err
 	}
 
-	chainConfig, err := GenesisChainConfig(cfg, peers, txhash, height)
+	chainConfig, err := GenesisChainConfig(cfg, peers)
 	if err != nil {
 		return nil, err
 	}
@@ -89,87 +127,48 @@ func genConsensusPayload(cfg *config.POCConfig, txhash common.Uint256, height ui
 	// Notice:
 	// take genesis msg as random source,
 	// don't need verify (genesisProposer, vrfValue, vrfProof)
+	genSig := shabal.Sum256(txhash[:])
 
 	pocBlockInfo := &PocBlockInfo{
 		Proposer:           math.MaxUint32,
 		LastConfigBlockNum: math.MaxUint32,
 		NewChainConfig:     chainConfig,
+		BaseTarget:         InitialBaseTarget(cfg),
+		GenSig:             genSig[:],
 	}
 	return json.Marshal(pocBlockInfo)
 }
 
-//GenesisChainConfig return chainconfig
-func GenesisChainConfig(conf *config.POCConfig, peers []*config.POCPeerStakeInfo, txhash common.Uint256, height uint32) (*ChainConfig, error) {
+// GenesisChainConfig returns the chain config of peers. Block production is
+// weighted by plotted space rather than stake, so every peer gets a single
+// slot and the peers only make up the committee until blocks are won.
+func GenesisChainConfig(conf *config.POCConfig, peers []*config.POCPeerStakeInfo) (*ChainConfig, error) {
+	if len(peers) == 0 {
+		return nil, fmt.Errorf("no peer in chain config")
+	}
 	sort.SliceStable(peers, func(i, j int) bool {
-		if peers[i].InitPos > peers[j].InitPos {
-			return true
-		} else if peers[i].InitPos == peers[j].InitPos {
-			return peers[i].PeerPubkey > peers[j].PeerPubkey
-		}
-		return false
+		return peers[i].Index < peers[j].Index
 	})
-	log.Debugf("sorted peers: %v", peers)
-	// get stake sum of top-k peers
-	var sum uint64
-	for i := 0; i < int(conf.K); i++ {
-		sum += peers[i].InitPos
-		log.Debugf("peer: %d, stack: %d", peers[i].Index, peers[i].InitPos)
-	}
-
-	log.Debugf("sum of top K stakes: %d", sum)
-
-	// calculate peer ranks
-	scale := conf.L/conf.K - 1
-	if scale <= 0 {
-		return nil, fmt.Errorf("L is equal or less than K")
-	}
-
-	peerRanks := make([]uint64, 0)
-	for i := 0; i < int(conf.K); i++ {
-		var s uint64 = 1
-		if sum > 0 && peers[i].InitPos > 0 {
-			s = uint64(math.Ceil(float64(peers[i].InitPos) * float64(scale) * float64(conf.K) / float64(sum)))
+	peerCfgs := make([]*PeerConfig, 0, len(peers))
+	posTable := make([]uint32, 0, len(peers))
+	for i, peer := range peers {
+		if i > 0 && peers[i-1].Index == peer.Index {
+			return nil, fmt.Errorf("duplicated peer index %d", peer.Index)
 		}
-		peerRanks = append(peerRanks, s)
-	}
-
-	log.Debugf("peers rank table: %v", peerRanks)
-
-	// calculate pos table
-	chainPeers := make(map[uint32]*PeerConfig, 0)
-	posTable := make([]uint32, 0)
-	for i := 0; i < int(conf.K); i++ {
-		nodeId := peers[i].PeerPubkey
-		chainPeers[peers[i].Index] = &PeerConfig{
-			Index: peers[i].Index,
-			ID:    nodeId,
-		}
-		for j := uint64(0); j < peerRanks[i]; j++ {
-			posTable = append(posTable, peers[i].Index)
-		}
-	}
-	// shuffle
-	for i := len(posTable) - 1; i > 0; i-- {
-		h, err := shuffle_hash(txhash, height, chainPeers[posTable[i]].ID, i)
-		if err != nil {
-			return nil, fmt.Errorf("failed to calculate hash value: %s", err)
-		}
-		j := h % uint64(i)
-		posTable[i], posTable[j] = posTable[j], posTable[i]
-	}
-	log.Debugf("init pos table: %v", posTable)
-
-	// generate chain conf, and save to ChainConfigFile
-	peerCfgs := make([]*PeerConfig, 0)
-	for i := 0; i < int(conf.K); i++ {
-		peerCfgs = append(peerCfgs, chainPeers[peers[i].Index])
+		peerCfgs = append(peerCfgs, &PeerConfig{
+			Index: peer.Index,
+			ID:    peer.PeerPubkey,
+		})
+		posTable = append(posTable, peer.Index)
 	}
+	log.Debugf("chain config peers: %v", posTable)
 
+	n := uint32(len(peerCfgs))
 	chainConfig := &ChainConfig{
 		Version:              1,
 		View:                 1,
-		N:                    conf.K,
-		C:                    conf.C,
+		N:                    n,
+		C:                    (n - 1) / 3,
 		BlockMsgDelay:        time.Duration(conf.BlockMsgDelay) * time.Millisecond,
 		HashMsgDelay:         time.Duration(conf.HashMsgDelay) * time.Millisecond,
 		PeerHandshakeTimeout: time.Duration(conf.PeerHandshakeTimeout) * time.Second,
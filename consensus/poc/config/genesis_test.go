@@ -19,15 +19,17 @@
 package pocconfig
 
 import (
+	"math"
 	"testing"
 
 	"OntologyWithPOC/common"
 	"OntologyWithPOC/common/config"
 	"OntologyWithPOC/common/log"
+	"OntologyWithPOC/consensus/poc/shabal"
 )
 
-func constructConfig() (*config.VBFTConfig, error) {
-	conf := &config.VBFTConfig{
+func constructConfig() (*config.POCConfig, error) {
+	conf := &config.POCConfig{
 		N:                    7,
 		C:                    2,
 		K:                    7,
@@ -37,40 +39,40 @@ func constructConfig() (*config.VBFTConfig, error) {
 		PeerHandshakeTimeout: 10,
 		MaxBlockChangeView:   1000,
 	}
-	var peersinfo []*config.VBFTPeerStakeInfo
-	peer1 := &config.VBFTPeerStakeInfo{
+	var peersinfo []*config.POCPeerStakeInfo
+	peer1 := &config.POCPeerStakeInfo{
 		Index:      1,
 		PeerPubkey: "0253ccfd439b29eca0fe90ca7c6eaa1f98572a054aa2d1d56e72ad96c466107a85",
 		InitPos:    0,
 	}
-	peer2 := &config.VBFTPeerStakeInfo{
+	peer2 := &config.POCPeerStakeInfo{
 		Index:      2,
 		PeerPubkey: "035eb654bad6c6409894b9b42289a43614874c7984bde6b03aaf6fc1d0486d9d45",
 		InitPos:    0,
 	}
 
-	peer3 := &config.VBFTPeerStakeInfo{
+	peer3 := &config.POCPeerStakeInfo{
 		Index:      3,
 		PeerPubkey: "0281d198c0dd3737a9c39191bc2d1af7d65a44261a8a64d6ef74d63f27cfb5ed92",
 		InitPos:    0,
 	}
 
-	peer4 := &config.VBFTPeerStakeInfo{
+	peer4 := &config.POCPeerStakeInfo{
 		Index:      4,
 		PeerPubkey: "023967bba3060bf8ade06d9bad45d02853f6c623e4d4f52d767eb56df4d364a99f",
 		InitPos:    0,
 	}
-	peer5 := &config.VBFTPeerStakeInfo{
+	peer5 := &config.POCPeerStakeInfo{
 		Index:      5,
 		PeerPubkey: "038bfc50b0e3f0e5df6d451069065cbfa7ab5d382a5839cce82e0c963edb026e94",
 		InitPos:    0,
 	}
-	peer6 := &config.VBFTPeerStakeInfo{
+	peer6 := &config.POCPeerStakeInfo{
 		Index:      6,
 		PeerPubkey: "03f1095289e7fddb882f1cb3e158acc1c30d9de606af21c97ba851821e8b6ea535",
 		InitPos:    0,
 	}
-	peer7 := &config.VBFTPeerStakeInfo{
+	peer7 := &config.POCPeerStakeInfo{
 		Index:      8,
 		PeerPubkey: "0215865baab70607f4a2413a7a9ba95ab2c3c0202d5b7731c6824eef48e899fc90",
 		InitPos:    5000,
@@ -102,10 +104,75 @@ func TestGenesisChainConfig(t *testing.T) {
 		t.Errorf("constructConfig failed:%s", err)
 		return
 	}
-	chainconfig, err := GenesisChainConfig(config, config.Peers, common.Uint256{}, 1)
+	chainconfig, err := GenesisChainConfig(config, config.Peers)
 	if err != nil {
 		t.Errorf("TestGenesisChainConfig failed:%s", err)
 		return
 	}
-	t.Logf("TestGenesisChainConfig succ: %v", chainconfig.PosTable)
+	if chainconfig.N != 7 || chainconfig.C != 2 {
+		t.Errorf("TestGenesisChainConfig N %d C %d", chainconfig.N, chainconfig.C)
+	}
+	// stakes do not weight the peers
+	expected := []uint32{1, 2, 3, 4, 5, 6, 8}
+	if len(chainconfig.PosTable) != len(expected) {
+		t.Fatalf("TestGenesisChainConfig pos table %v", chainconfig.PosTable)
+	}
+	for i, idx := range expected {
+		if chainconfig.PosTable[i] != idx || chainconfig.Peers[i].Index != idx {
+			t.Errorf("TestGenesisChainConfig slot %d: %d, peer %d", i, chainconfig.PosTable[i], chainconfig.Peers[i].Index)
+		}
+	}
+
+	chainconfig, err = GenesisChainConfig(config, config.Peers[:1])
+	if err != nil {
+		t.Fatalf("TestGenesisChainConfig single peer failed:%s", err)
+	}
+	if chainconfig.N != 1 || chainconfig.C != 0 {
+		t.Errorf("TestGenesisChainConfig single peer N %d C %d", chainconfig.N, chainconfig.C)
+	}
+
+	if _, err := GenesisChainConfig(config, append(config.Peers[:1:1], config.Peers[0])); err == nil {
+		t.Errorf("TestGenesisChainConfig accepted duplicated peers")
+	}
+}
+
+func TestPlotVerification(t *testing.T) {
+	if mode := PlotVerification(nil); mode != config.POC_PLOT_VERIFY_NONCE {
+		t.Errorf("default mode %s", mode)
+	}
+	cfg := &config.POCConfig{ZKVerifyingKey: "00"}
+	if mode := PlotVerification(cfg); mode != config.POC_PLOT_VERIFY_ANY {
+		t.Errorf("default mode with zk key %s", mode)
+	}
+	cfg.PlotVerification = config.POC_PLOT_VERIFY_ZK
+	if mode := PlotVerification(cfg); mode != config.POC_PLOT_VERIFY_ZK {
+		t.Errorf("configured mode %s", mode)
+	}
+	if window := RetargetWindow(&config.POCConfig{}); window != DefaultRetargetWindow {
+		t.Errorf("default retarget window %d", window)
+	}
+}
+
+func TestPlotFormat(t *testing.T) {
+	if config.POC_SCOOP_COUNT != shabal.ScoopCount || config.POC_NONCE_SIZE != shabal.NonceSize {
+		t.Errorf("genesis plot format %d scoops of %d bytes, shabal %d of %d",
+			config.POC_SCOOP_COUNT, config.POC_NONCE_SIZE, shabal.ScoopCount, shabal.NonceSize)
+	}
+}
+
+func TestTestModeGenesis(t *testing.T) {
+	genesis := config.NewPOCTestModeGenesisConfig(6)
+	cfg := genesis.POC
+	nonces := uint64(cfg.PocSpace) << 20 / shabal.NonceSize
+	mean := math.MaxUint64 / (nonces * cfg.InitialBaseTarget)
+	if mean < 5 || mean > 7 {
+		t.Errorf("test mode mean deadline %d", mean)
+	}
+	cfg.Peers = []*config.POCPeerStakeInfo{{
+		Index:      1,
+		PeerPubkey: "0253ccfd439b29eca0fe90ca7c6eaa1f98572a054aa2d1d56e72ad96c466107a85",
+	}}
+	if _, err := genConsensusPayload(cfg, common.Uint256{}, 0); err != nil {
+		t.Errorf("test mode consensus payload: %s", err)
+	}
 }
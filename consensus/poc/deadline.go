@@ -0,0 +1,179 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"OntologyWithPOC/common"
+	"OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/consensus/poc/shabal"
+	"github.com/ontio/ontology-crypto/keypair"
+)
+
+// tolerated clock difference between the proposer and the receiver, in seconds
+const deadlineDrift = 2
+
+// errForgedDeadline is returned for a deadline its nonce does not yield, which
+// its signer is penalized for.
+var errForgedDeadline = errors.New("deadline not yielded by nonce")
+
+// deadlineProof is the proof-of-capacity behind a deadline: the scoop of
+// nonce NonceNr plotted for AccountID, selected by the previous block. The
+// deadline of a plot with a plot tree, at PlotPath, is proved by ZKProof
+// instead.
+type deadlineProof struct {
+	BlockNum   uint32
+	AccountID  string
+	NonceNr    uint64
+	ScoopIndex uint32
+	Scoop      []byte
+	Deadline   uint64
+	PrevHash   common.Uint256
+	PlotPath   string
+	ZKProof    []byte
+}
+
+// nextGenSig returns the generation signature of a block proposed by pub on
+// top of a block carrying prevGenSig. Nothing the proposer chooses besides its
+// key goes into it, so the challenge of the next round cannot be ground.
+func nextGenSig(prevGenSig []byte, pub keypair.PublicKey) []byte {
+	return shabal.GenSig256(prevGenSig, keypair.SerializePublicKey(pub))
+}
+
+// miningSeed returns the generation signature and base target the scoop
+// selection of the next block uses, taken from the previous block.
+func miningSeed(prevBlk *Block) (gensig []byte, baseTarget uint64, err error) {
+	if prevBlk == nil || prevBlk.Block == nil || prevBlk.Block.Header == nil || prevBlk.Info == nil {
+		return nil, 0, fmt.Errorf("invalid previous block")
+	}
+	if len(prevBlk.Info.GenSig) != shabal.HashSize {
+		return nil, 0, fmt.Errorf("invalid generation signature in block %d", prevBlk.getBlockNum())
+	}
+	return prevBlk.Info.GenSig, blockBaseTarget(prevBlk), nil
+}
+
+// miningRound is the challenge the plots are scanned against for block
+// BlockNum, as set by the previous block PrevHash.
+type miningRound struct {
+	BlockNum   uint32
+	PrevHash   common.Uint256
+	GenSig     []byte
+	BaseTarget uint64
+}
+
+func newMiningRound(blkNum uint32, prevBlk *Block) (*miningRound, error) {
+	gensig, baseTarget, err := miningSeed(prevBlk)
+	if err != nil {
+		return nil, err
+	}
+	return &miningRound{
+		BlockNum:   blkNum,
+		PrevHash:   prevBlk.Block.Hash(),
+		GenSig:     gensig,
+		BaseTarget: baseTarget,
+	}, nil
+}
+
+// nonceDeadline regenerates the nonce nonceNr of accountID and returns the
+// scoop selected on top of prevBlk together with the deadline it yields.
+func nonceDeadline(accountID string, nonceNr uint64, prevBlk *Block) (scoopIndex uint32, scoop []byte, deadline uint64, err error) {
+	gensig, baseTarget, err := miningSeed(prevBlk)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+	return pocconfig.NonceDeadline(accountID, nonceNr, gensig, baseTarget)
+}
+
+// verifyDeadline checks entry is signed by pub, and recomputes the deadline
+// it claims from the previous block, its scoop and the nonce of its account.
+// A signed deadline the nonce does not yield fails with errForgedDeadline.
+func verifyDeadline(entry *deadlineEntry, pub keypair.PublicKey, prevBlk *Block) error {
+	if err := entry.Verify(pub); err != nil {
+		return err
+	}
+	scoopIndex, scoop, deadline, err := nonceDeadline(entry.AccountID, entry.NonceNr, prevBlk)
+	if err != nil {
+		return err
+	}
+	if deadline != entry.Deadline {
+		return errForgedDeadline
+	}
+	if scoopIndex != entry.ScoopIndex {
+		return fmt.Errorf("scoop index mismatch: %d vs %d", entry.ScoopIndex, scoopIndex)
+	}
+	if !bytes.Equal(scoop, entry.Scoop) {
+		return fmt.Errorf("scoop of nonce %d mismatch", entry.NonceNr)
+	}
+	return nil
+}
+
+// deadlineElapsed reports whether deadline seconds have passed at timestamp
+// since the previous block was sealed.
+func deadlineElapsed(prevBlk *Block, deadline uint64, timestamp uint32) bool {
+	return uint64(timestamp) >= uint64(prevBlk.Block.Header.Timestamp)+deadline
+}
+
+// verifyProposalDeadline checks the deadline a proposal claims is the one of
+// the nonce it names of its plot account, and that the deadline had elapsed both at the block
+// timestamp and at now, give or take deadlineDrift.
+func verifyProposalDeadline(blk *Block, pub keypair.PublicKey, prevBlk *Block, now uint32) error {
+	if blk.Info == nil {
+		return fmt.Errorf("no poc info in block %d", blk.getBlockNum())
+	}
+	_, _, deadline, err := nonceDeadline(blockPlotAccount(blk, pub), blk.Info.NonceNr, prevBlk)
+	if err != nil {
+		return err
+	}
+	return verifyDeadlineElapsed(blk, deadline, prevBlk, now)
+}
+
+// verifyDeadlineElapsed checks the deadline of blk is deadline, and had
+// elapsed both at the block timestamp and at now.
+func verifyDeadlineElapsed(blk *Block, deadline uint64, prevBlk *Block, now uint32) error {
+	if deadline != blk.Info.Deadline {
+		return fmt.Errorf("deadline mismatch: %d vs %d", blk.Info.Deadline, deadline)
+	}
+	if !deadlineElapsed(prevBlk, deadline, blk.Block.Header.Timestamp) {
+		return fmt.Errorf("block %d timestamp %d before deadline %d", blk.getBlockNum(), blk.Block.Header.Timestamp, deadline)
+	}
+	if !deadlineElapsed(prevBlk, deadline, now+deadlineDrift) {
+		return fmt.Errorf("deadline %d of block %d not elapsed", deadline, blk.getBlockNum())
+	}
+	return nil
+}
+
+// verifyBlockDeadline checks the deadline of a proposal, proved either by the
+// nonce it names or by a zk proof.
+func (self *Server) verifyBlockDeadline(blk *Block, pub keypair.PublicKey, prevBlk *Block, now uint32) error {
+	zk := blk.Info != nil && len(blk.Info.ZKProof) > 0
+	if err := checkPlotVerification(genesisPlotVerification(), zk); err != nil {
+		return err
+	}
+	if !zk {
+		return verifyProposalDeadline(blk, pub, prevBlk, now)
+	}
+	deadline, err := self.verifyZKProof(blk.getBlockNum(), blockPlotAccount(blk, pub), blk.Info.ZKProof, prevBlk, false)
+	if err != nil {
+		return err
+	}
+	return verifyDeadlineElapsed(blk, deadline, prevBlk, now)
+}
@@ -0,0 +1,299 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"OntologyWithPOC/common/log"
+	"OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/consensus/poc/shabal"
+	"OntologyWithPOC/core/signature"
+)
+
+// deadlines of this many recent blocks are kept and relayed
+const deadlineHistoryLen = 30
+
+// known reports whether a deadline at least as good as entry is already kept
+// for its (block, peer).
+func (self *blockPeersDeadline) known(entry *deadlineEntry) bool {
+	self.locker.Lock()
+	defer self.locker.Unlock()
+
+	peers, present := self.blockpeersdeadline[entry.BlockNum]
+	if !present {
+		return false
+	}
+	peers.locker.Lock()
+	defer peers.locker.Unlock()
+	old, present := peers.entries[entry.PeerIndex]
+	return present && old.Deadline <= entry.Deadline
+}
+
+// addEntry keeps entry if it is the best deadline known for its (block, peer),
+// and reports whether it was kept.
+func (self *blockPeersDeadline) addEntry(entry *deadlineEntry) bool {
+	self.locker.Lock()
+	defer self.locker.Unlock()
+
+	peers, present := self.blockpeersdeadline[entry.BlockNum]
+	if !present {
+		peers = &peersDeadline{
+			peersdeadline: make(map[uint32]uint64),
+			entries:       make(map[uint32]*deadlineEntry),
+		}
+		self.blockpeersdeadline[entry.BlockNum] = peers
+		self.pruneLocked()
+		if _, present := self.blockpeersdeadline[entry.BlockNum]; !present {
+			return false
+		}
+	}
+
+	peers.locker.Lock()
+	defer peers.locker.Unlock()
+	if old, present := peers.entries[entry.PeerIndex]; present && old.Deadline <= entry.Deadline {
+		return false
+	}
+	peers.entries[entry.PeerIndex] = entry
+	peers.peersdeadline[entry.PeerIndex] = entry.Deadline
+	return true
+}
+
+func (self *blockPeersDeadline) getEntry(blkNum uint32, peerIdx uint32) *deadlineEntry {
+	self.locker.Lock()
+	defer self.locker.Unlock()
+
+	peers, present := self.blockpeersdeadline[blkNum]
+	if !present {
+		return nil
+	}
+	peers.locker.Lock()
+	defer peers.locker.Unlock()
+	return peers.entries[peerIdx]
+}
+
+// ranking returns the peers with a verified deadline for blkNum, the
+// earliest deadline first. The leader proposer of the round is taken from it.
+func (self *blockPeersDeadline) ranking(blkNum uint32) []uint32 {
+	if self == nil {
+		return nil
+	}
+	self.locker.Lock()
+	defer self.locker.Unlock()
+
+	peers, present := self.blockpeersdeadline[blkNum]
+	if !present {
+		return nil
+	}
+	peers.locker.Lock()
+	defer peers.locker.Unlock()
+	ranking := make([]uint32, 0, len(peers.peersdeadline))
+	for idx := range peers.peersdeadline {
+		ranking = append(ranking, idx)
+	}
+	sort.Slice(ranking, func(i, j int) bool {
+		di, dj := peers.peersdeadline[ranking[i]], peers.peersdeadline[ranking[j]]
+		if di != dj {
+			return di < dj
+		}
+		return ranking[i] < ranking[j]
+	})
+	return ranking
+}
+
+func (self *blockPeersDeadline) pruneLocked() {
+	if len(self.blockpeersdeadline) <= deadlineHistoryLen {
+		return
+	}
+	keys := make([]int, 0, len(self.blockpeersdeadline))
+	for key := range self.blockpeersdeadline {
+		keys = append(keys, int(key))
+	}
+	sort.Ints(keys)
+	for _, key := range keys[:len(keys)-deadlineHistoryLen] {
+		delete(self.blockpeersdeadline, uint32(key))
+	}
+}
+
+func (self *Server) constructDeadlineEntry(proof *deadlineProof) (*deadlineEntry, error) {
+	entry := &deadlineEntry{
+		BlockNum:   proof.BlockNum,
+		PeerIndex:  self.Index,
+		PeerID:     pocconfig.PubkeyID(self.account.PublicKey),
+		Deadline:   proof.Deadline,
+		AccountID:  proof.AccountID,
+		NonceNr:    proof.NonceNr,
+		ScoopIndex: proof.ScoopIndex,
+		Scoop:      proof.Scoop,
+		PrevHash:   proof.PrevHash,
+	}
+	if len(proof.ZKProof) > 0 {
+		entry.NonceNr, entry.Scoop, entry.ZKProof = 0, nil, proof.ZKProof
+	}
+	hash, err := entry.Hash()
+	if err != nil {
+		return nil, err
+	}
+	entry.Sig, err = signature.Sign(self.account, hash[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign deadline of block %d: %s", proof.BlockNum, err)
+	}
+	return entry, nil
+}
+
+// verifyDeadlineEntry checks entry is signed by a node allowed to mine with
+// its plot account before regenerating its nonce or verifying its zk proof.
+// Entries sent by nodes not admitted yet are limited per sender, as their
+// keys come for free.
+func (self *Server) verifyDeadlineEntry(entry *deadlineEntry, sender string) error {
+	prevBlk, prevHash := self.blockPool.getSealedBlock(entry.BlockNum - 1)
+	if prevBlk == nil {
+		return fmt.Errorf("prev block %d not sealed", entry.BlockNum-1)
+	}
+	if entry.PrevHash != prevHash {
+		return fmt.Errorf("deadline mined on another block %d", entry.BlockNum-1)
+	}
+	pub, err := self.deadlineSigner(entry)
+	if err != nil {
+		return err
+	}
+	if err := checkPlotVerification(genesisPlotVerification(), len(entry.ZKProof) > 0); err != nil {
+		return err
+	}
+	if err := entry.Verify(pub); err != nil {
+		return err
+	}
+	if err := self.verifyPlotBinding(entry.BlockNum, entry.AccountID, pub); err != nil {
+		return err
+	}
+	if sender != "" && !self.memberLimiter.allow(sender, time.Now()) {
+		return fmt.Errorf("too many deadlines from %s", sender)
+	}
+	if len(entry.ZKProof) > 0 {
+		err := self.verifyZKDeadlineEntry(entry, prevBlk, rand.Intn(zkAuditSampling) == 0)
+		if err == errFailedAudit {
+			self.reportFailedAudit(entry, pub)
+		}
+		return err
+	}
+	if err := verifyDeadline(entry, pub, prevBlk); err != nil {
+		if err == errForgedDeadline {
+			self.reportForgedDeadline(entry, pub)
+		}
+		return err
+	}
+	return nil
+}
+
+// verifyZKDeadlineEntry checks entry proves its deadline with its zk proof,
+// auditing its plot tree if audit is set.
+func (self *Server) verifyZKDeadlineEntry(entry *deadlineEntry, prevBlk *Block, audit bool) error {
+	gensig, _, err := miningSeed(prevBlk)
+	if err != nil {
+		return err
+	}
+	if scoopIndex := shabal.ScoopNum256(gensig); entry.ScoopIndex != scoopIndex {
+		return fmt.Errorf("scoop index mismatch: %d vs %d", entry.ScoopIndex, scoopIndex)
+	}
+	deadline, err := self.verifyZKProof(entry.BlockNum, entry.AccountID, entry.ZKProof, prevBlk, audit)
+	if err != nil {
+		return err
+	}
+	if deadline != entry.Deadline {
+		return fmt.Errorf("deadline mismatch: %d vs %d", entry.Deadline, deadline)
+	}
+	return nil
+}
+
+// broadcastDeadlineEntries gossips entries, split into msgs of at most
+// maxDeadlineEntries entries.
+func (self *Server) broadcastDeadlineEntries(blkNum uint32, entries []*deadlineEntry) {
+	for len(entries) > 0 {
+		n := len(entries)
+		if n > maxDeadlineEntries {
+			n = maxDeadlineEntries
+		}
+		self.broadcast(&deadLineMsg{
+			BlockNum: blkNum,
+			Entries:  entries[:n],
+		})
+		entries = entries[n:]
+	}
+}
+
+// onDeadlineMsg keeps the verified entries of msg improving on the deadlines
+// already known, and relays only those. sender is the key of the node not
+// admitted yet which sent msg, empty for peers.
+func (self *Server) onDeadlineMsg(fromPeer uint32, sender string, msg *deadLineMsg) {
+	fresh := make([]*deadlineEntry, 0, len(msg.Entries))
+	for _, entry := range msg.Entries {
+		if self.peersDeadLine.known(entry) {
+			continue
+		}
+		if err := self.verifyDeadlineEntry(entry, sender); err != nil {
+			log.Errorf("server %d failed to verify deadline of peer %d from %d, blk %d: %s",
+				self.Index, entry.PeerIndex, fromPeer, entry.BlockNum, err)
+			continue
+		}
+		if entry.PeerID != "" {
+			if err := self.admitMember(entry.PeerID, entry.PeerIndex, false); err != nil {
+				log.Warnf("server %d, deadline of peer %d, blk %d: %s",
+					self.Index, entry.PeerIndex, entry.BlockNum, err)
+				continue
+			}
+		}
+		if self.peersDeadLine.addEntry(entry) {
+			fresh = append(fresh, entry)
+		}
+	}
+	rounds, groups := groupDeadlineEntries(fresh)
+	for _, blkNum := range rounds {
+		self.broadcastDeadlineEntries(blkNum, groups[blkNum])
+	}
+}
+
+// groupDeadlineEntries splits entries by the block they are mined for, a msg
+// may relay deadlines of several recent rounds. The rounds are returned in
+// height order.
+func groupDeadlineEntries(entries []*deadlineEntry) ([]uint32, map[uint32][]*deadlineEntry) {
+	groups := make(map[uint32][]*deadlineEntry)
+	rounds := make([]uint32, 0)
+	for _, entry := range entries {
+		if _, present := groups[entry.BlockNum]; !present {
+			rounds = append(rounds, entry.BlockNum)
+		}
+		groups[entry.BlockNum] = append(groups[entry.BlockNum], entry)
+	}
+	sort.Slice(rounds, func(i, j int) bool { return rounds[i] < rounds[j] })
+	return rounds, groups
+}
+
+// announceDeadline signs and gossips the node's own deadline proof.
+func (self *Server) announceDeadline(proof *deadlineProof) {
+	entry, err := self.constructDeadlineEntry(proof)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	self.peersDeadLine.addEntry(entry)
+	self.broadcastDeadlineEntries(proof.BlockNum, []*deadlineEntry{entry})
+}
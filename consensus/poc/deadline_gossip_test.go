@@ -0,0 +1,126 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"testing"
+)
+
+func newTestPeersDeadline() *blockPeersDeadline {
+	return &blockPeersDeadline{blockpeersdeadline: make(map[uint32]*peersDeadline)}
+}
+
+func TestAddDeadlineEntry(t *testing.T) {
+	store := newTestPeersDeadline()
+	entry := &deadlineEntry{BlockNum: 2, PeerIndex: 1, Deadline: 100}
+	if store.known(entry) {
+		t.Errorf("entry should be unknown")
+	}
+	if !store.addEntry(entry) {
+		t.Fatalf("first entry should be kept")
+	}
+	if !store.known(entry) || store.addEntry(entry) {
+		t.Errorf("entry should be known once added")
+	}
+
+	worse := &deadlineEntry{BlockNum: 2, PeerIndex: 1, Deadline: 200}
+	if store.addEntry(worse) {
+		t.Errorf("worse deadline should not replace the best one")
+	}
+	better := &deadlineEntry{BlockNum: 2, PeerIndex: 1, Deadline: 50}
+	if !store.addEntry(better) {
+		t.Errorf("better deadline should be kept")
+	}
+	if e := store.getEntry(2, 1); e != better {
+		t.Errorf("best entry: %v", e)
+	}
+	if d := store.blockpeersdeadline[2].peersdeadline[1]; d != 50 {
+		t.Errorf("best deadline: %d", d)
+	}
+
+	other := &deadlineEntry{BlockNum: 2, PeerIndex: 2, Deadline: 300}
+	if !store.addEntry(other) {
+		t.Errorf("deadline of other peer should be kept")
+	}
+}
+
+func TestPruneDeadlineEntries(t *testing.T) {
+	store := newTestPeersDeadline()
+	for blkNum := uint32(1); blkNum <= deadlineHistoryLen+5; blkNum++ {
+		store.addEntry(&deadlineEntry{BlockNum: blkNum, PeerIndex: 1, Deadline: 100})
+	}
+	if len(store.blockpeersdeadline) != deadlineHistoryLen {
+		t.Errorf("kept deadlines of %d blocks", len(store.blockpeersdeadline))
+	}
+	if store.getEntry(5, 1) != nil || store.getEntry(6, 1) == nil {
+		t.Errorf("deadlines of oldest blocks should be pruned")
+	}
+	if store.addEntry(&deadlineEntry{BlockNum: 1, PeerIndex: 1, Deadline: 100}) {
+		t.Errorf("deadline older than history should not be kept")
+	}
+}
+
+func TestDeadlineRanking(t *testing.T) {
+	store := newTestPeersDeadline()
+	store.addEntry(&deadlineEntry{BlockNum: 2, PeerIndex: 3, Deadline: 300})
+	store.addEntry(&deadlineEntry{BlockNum: 2, PeerIndex: 1, Deadline: 100})
+	store.addEntry(&deadlineEntry{BlockNum: 2, PeerIndex: memberIndexBase + 7, Deadline: 50})
+	store.addEntry(&deadlineEntry{BlockNum: 2, PeerIndex: 2, Deadline: 100})
+	store.addEntry(&deadlineEntry{BlockNum: 3, PeerIndex: 4, Deadline: 10})
+
+	ranking := store.ranking(2)
+	expected := []uint32{memberIndexBase + 7, 1, 2, 3}
+	if len(ranking) != len(expected) {
+		t.Fatalf("ranking: %v", ranking)
+	}
+	for i := range expected {
+		if ranking[i] != expected[i] {
+			t.Errorf("ranking: %v, expected %v", ranking, expected)
+			break
+		}
+	}
+	if r := store.ranking(4); len(r) != 0 {
+		t.Errorf("ranking of block without deadlines: %v", r)
+	}
+	var none *blockPeersDeadline
+	if none.ranking(2) != nil {
+		t.Errorf("ranking without deadlines")
+	}
+}
+
+func TestGroupDeadlineEntries(t *testing.T) {
+	entries := []*deadlineEntry{
+		{BlockNum: 5, PeerIndex: 1},
+		{BlockNum: 4, PeerIndex: 2},
+		{BlockNum: 5, PeerIndex: 3},
+	}
+	rounds, groups := groupDeadlineEntries(entries)
+	if len(rounds) != 2 || rounds[0] != 4 || rounds[1] != 5 {
+		t.Fatalf("rounds: %v", rounds)
+	}
+	if g := groups[4]; len(g) != 1 || g[0] != entries[1] {
+		t.Errorf("entries of round 4: %v", g)
+	}
+	if g := groups[5]; len(g) != 2 || g[0] != entries[0] || g[1] != entries[2] {
+		t.Errorf("entries of round 5: %v", g)
+	}
+	if rounds, _ := groupDeadlineEntries(nil); len(rounds) != 0 {
+		t.Errorf("rounds of no entries: %v", rounds)
+	}
+}
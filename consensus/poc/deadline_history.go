@@ -0,0 +1,119 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"errors"
+	"path/filepath"
+
+	"OntologyWithPOC/common"
+	"OntologyWithPOC/common/config"
+	"OntologyWithPOC/common/log"
+	actorTypes "OntologyWithPOC/consensus/actor"
+	"OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/consensus/poc/history"
+)
+
+var errNoHistory = errors.New("deadline history not kept")
+
+func historyPath() string {
+	return filepath.Join(config.DefConfig.Common.DataDir, config.DefConfig.P2PNode.NetworkName, history.DirName)
+}
+
+// roundEntries returns the deadlines kept for blkNum mined on top of prevHash.
+func (self *blockPeersDeadline) roundEntries(blkNum uint32, prevHash common.Uint256) []*deadlineEntry {
+	self.locker.Lock()
+	defer self.locker.Unlock()
+
+	peers, present := self.blockpeersdeadline[blkNum]
+	if !present {
+		return nil
+	}
+	peers.locker.Lock()
+	defer peers.locker.Unlock()
+	entries := make([]*deadlineEntry, 0, len(peers.entries))
+	for _, entry := range peers.entries {
+		if entry.PrevHash == prevHash {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// blockHistory returns the history record of the sealed block blk, nil for a
+// block without poc info.
+func (self *Server) blockHistory(blk *Block) *history.Record {
+	if blk.Info == nil {
+		return nil
+	}
+	hash, prevHash := blk.Block.Hash(), blk.getPrevBlockHash()
+	winner := &history.Deadline{
+		PeerIndex: blk.getProposer(),
+		AccountID: blk.Info.PlotAccount,
+		NonceNr:   blk.Info.NonceNr,
+		Deadline:  blk.Info.Deadline,
+		ZK:        len(blk.Info.ZKProof) > 0,
+	}
+	if pub := self.peerPool.GetPeerPubKey(blk.getProposer()); pub != nil {
+		winner.PeerID = pocconfig.PubkeyID(pub)
+		winner.AccountID = blockPlotAccount(blk, pub)
+	}
+	rec := &history.Record{
+		Height:     blk.getBlockNum(),
+		Hash:       hash.ToHexString(),
+		PrevHash:   prevHash.ToHexString(),
+		Timestamp:  blk.Block.Header.Timestamp,
+		BaseTarget: blk.Info.BaseTarget,
+		Winner:     winner,
+	}
+	for _, entry := range self.peersDeadLine.roundEntries(blk.getBlockNum(), prevHash) {
+		rec.Deadlines = append(rec.Deadlines, &history.Deadline{
+			PeerIndex: entry.PeerIndex,
+			PeerID:    entry.PeerID,
+			AccountID: entry.AccountID,
+			NonceNr:   entry.NonceNr,
+			Deadline:  entry.Deadline,
+			ZK:        len(entry.ZKProof) > 0,
+		})
+	}
+	rec.SortDeadlines()
+	return rec
+}
+
+// recordHistory persists the round of the sealed block blk.
+func (self *Server) recordHistory(blk *Block) {
+	if self.history == nil {
+		return
+	}
+	rec := self.blockHistory(blk)
+	if rec == nil {
+		return
+	}
+	if err := self.history.Put(rec); err != nil {
+		log.Errorf("server %d, record history of block %d: %s", self.Index, rec.Height, err)
+	}
+}
+
+func (self *Server) getDeadlineHistory(req *actorTypes.GetDeadlineHistory) *actorTypes.DeadlineHistoryRsp {
+	if self.history == nil {
+		return &actorTypes.DeadlineHistoryRsp{Error: errNoHistory}
+	}
+	recs, err := self.history.Query(req.Start, req.End, req.AccountID)
+	return &actorTypes.DeadlineHistoryRsp{Records: recs, Error: err}
+}
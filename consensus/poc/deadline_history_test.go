@@ -0,0 +1,81 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"testing"
+
+	"OntologyWithPOC/common"
+	actorTypes "OntologyWithPOC/consensus/actor"
+	"OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/consensus/poc/history"
+	"OntologyWithPOC/core/types"
+)
+
+func TestRecordHistory(t *testing.T) {
+	store, err := history.NewMemStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	server := constructServer()
+	server.peerPool = peerPool()
+	server.history = store
+	server.peersDeadLine = &blockPeersDeadline{blockpeersdeadline: make(map[uint32]*peersDeadline)}
+
+	prevHash := common.Uint256{1}
+	server.peersDeadLine.addEntry(&deadlineEntry{BlockNum: 2, PeerIndex: 1, AccountID: "a", NonceNr: 7, Deadline: 30, PrevHash: prevHash})
+	server.peersDeadLine.addEntry(&deadlineEntry{BlockNum: 2, PeerIndex: 2, AccountID: "b", NonceNr: 8, Deadline: 20, PrevHash: prevHash})
+	// deadline of a round on top of another block
+	server.peersDeadLine.addEntry(&deadlineEntry{BlockNum: 2, PeerIndex: 3, AccountID: "c", Deadline: 10, PrevHash: common.Uint256{2}})
+
+	blk := &Block{
+		Block: &types.Block{Header: &types.Header{Height: 2, PrevBlockHash: prevHash, Timestamp: 1030}},
+		Info:  &pocconfig.PocBlockInfo{Proposer: 1, PlotAccount: "a", NonceNr: 7, Deadline: 30, BaseTarget: 1000},
+	}
+	server.recordHistory(blk)
+
+	rsp := server.getDeadlineHistory(&actorTypes.GetDeadlineHistory{Start: 1, End: 3})
+	if rsp.Error != nil {
+		t.Fatalf("deadline history: %s", rsp.Error)
+	}
+	if len(rsp.Records) != 1 {
+		t.Fatalf("deadline history records: %d", len(rsp.Records))
+	}
+	rec := rsp.Records[0]
+	if rec.Height != 2 || rec.BaseTarget != 1000 || rec.Timestamp != 1030 || rec.PrevHash != prevHash.ToHexString() {
+		t.Errorf("history record mismatch: %v", rec)
+	}
+	if rec.Winner.AccountID != "a" || rec.Winner.Deadline != 30 || rec.Winner.PeerID == "" {
+		t.Errorf("history winner mismatch: %v", rec.Winner)
+	}
+	if len(rec.Deadlines) != 2 || rec.Deadlines[0].AccountID != "b" || rec.Deadlines[1].AccountID != "a" {
+		t.Errorf("history deadlines mismatch: %d", len(rec.Deadlines))
+	}
+
+	rsp = server.getDeadlineHistory(&actorTypes.GetDeadlineHistory{Start: 1, End: 3, AccountID: "c"})
+	if rsp.Error != nil || len(rsp.Records) != 0 {
+		t.Errorf("deadline history of account of other round: %d records, %v", len(rsp.Records), rsp.Error)
+	}
+
+	server.history = nil
+	if rsp := server.getDeadlineHistory(&actorTypes.GetDeadlineHistory{Start: 1, End: 3}); rsp.Error == nil {
+		t.Errorf("deadline history without store should fail")
+	}
+}
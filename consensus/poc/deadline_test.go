@@ -0,0 +1,310 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"OntologyWithPOC/account"
+	"OntologyWithPOC/common"
+	"OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/consensus/poc/plot"
+	"OntologyWithPOC/consensus/poc/shabal"
+	"OntologyWithPOC/core/signature"
+	"OntologyWithPOC/core/types"
+)
+
+func constructPrevBlock() *Block {
+	return &Block{
+		Block: &types.Block{
+			Header: &types.Header{
+				Height:           1,
+				Timestamp:        1000,
+				ConsensusData:    12345,
+				ConsensusPayload: []byte("{}"),
+			},
+		},
+		Info: &pocconfig.PocBlockInfo{
+			Proposer:   1,
+			BaseTarget: 1000 << 32,
+			GenSig:     shabal.GenSig256(nil, []byte("genesis")),
+		},
+	}
+}
+
+func constructMiningRound(t *testing.T, blkNum uint32, prevBlk *Block) *miningRound {
+	round, err := newMiningRound(blkNum, prevBlk)
+	if err != nil {
+		t.Fatalf("mining round: %s", err)
+	}
+	return round
+}
+
+func TestCalcDeadline(t *testing.T) {
+	// the hit is the little endian uint64 of the first 8 bytes
+	deadline, err := pocconfig.CalcDeadline([]byte{0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0xff}, 2)
+	if err != nil {
+		t.Fatalf("CalcDeadline: %s", err)
+	}
+	if deadline != 36028797018964096 {
+		t.Errorf("CalcDeadline: %d, expected 36028797018964096", deadline)
+	}
+	if _, err := pocconfig.CalcDeadline([]byte{0x00, 0x00, 0x01, 0x00}, 2); err == nil {
+		t.Errorf("CalcDeadline should fail on short target")
+	}
+	if _, err := pocconfig.CalcDeadline([]byte{0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01}, 0); err == nil {
+		t.Errorf("CalcDeadline should fail on zero base target")
+	}
+}
+
+func signDeadlineEntry(acc *account.Account, entry *deadlineEntry) *deadlineEntry {
+	hash, _ := entry.Hash()
+	entry.Sig, _ = signature.Sign(acc, hash[:])
+	return entry
+}
+
+func TestDeadlineEntryVerify(t *testing.T) {
+	acc := account.NewAccount("SHA256withECDSA")
+	entry := signDeadlineEntry(acc, &deadlineEntry{
+		BlockNum:   2,
+		PeerIndex:  1,
+		Deadline:   100,
+		AccountID:  pocconfig.PubkeyID(acc.PublicKey),
+		NonceNr:    1,
+		ScoopIndex: 1,
+		Scoop:      make([]byte, shabal.ScoopSize),
+	})
+	if err := entry.Verify(acc.PublicKey); err != nil {
+		t.Errorf("deadline entry verify: %s", err)
+	}
+
+	other := account.NewAccount("SHA256withECDSA")
+	if err := entry.Verify(other.PublicKey); err == nil {
+		t.Errorf("deadline entry of other account should fail")
+	}
+
+	entry.Deadline = 10
+	if err := entry.Verify(acc.PublicKey); err == nil {
+		t.Errorf("deadline entry altered by relay should fail")
+	}
+	entry.Deadline = 100
+
+	entry.Scoop = entry.Scoop[:shabal.ScoopSize-1]
+	if err := entry.Verify(acc.PublicKey); err == nil {
+		t.Errorf("deadline entry with short scoop should fail")
+	}
+}
+
+func TestDeadLineMsgVerify(t *testing.T) {
+	entry := &deadlineEntry{
+		BlockNum:   2,
+		ScoopIndex: 1,
+		Scoop:      make([]byte, shabal.ScoopSize),
+	}
+	msg := &deadLineMsg{BlockNum: 2, Entries: []*deadlineEntry{entry}}
+	if err := msg.Verify(nil); err != nil {
+		t.Errorf("deadline msg verify: %s", err)
+	}
+
+	msg.Entries = nil
+	if err := msg.Verify(nil); err == nil {
+		t.Errorf("empty deadline msg should fail")
+	}
+	for i := 0; i <= maxDeadlineEntries; i++ {
+		msg.Entries = append(msg.Entries, entry)
+	}
+	if err := msg.Verify(nil); err == nil {
+		t.Errorf("oversized deadline msg should fail")
+	}
+}
+
+func TestDeadLineMsgSerialize(t *testing.T) {
+	msg := &deadLineMsg{
+		BlockNum: 2,
+		Entries: []*deadlineEntry{{
+			BlockNum:   2,
+			PeerIndex:  1,
+			Deadline:   100,
+			AccountID:  "0123",
+			NonceNr:    7,
+			ScoopIndex: 1,
+			Scoop:      make([]byte, shabal.ScoopSize),
+			PrevHash:   common.Uint256{1},
+			Sig:        []byte{1, 2, 3},
+		}},
+	}
+	payload, err := SerializePOCMsg(msg)
+	if err != nil {
+		t.Fatalf("serialize deadline msg: %s", err)
+	}
+	m, err := DeserializePOCMsg(payload)
+	if err != nil {
+		t.Fatalf("deserialize deadline msg: %s", err)
+	}
+	dl := m.(*deadLineMsg)
+	if len(dl.Entries) != 1 {
+		t.Fatalf("deadline msg entries: %d", len(dl.Entries))
+	}
+	e, expected := dl.Entries[0], msg.Entries[0]
+	if e.NonceNr != expected.NonceNr || e.ScoopIndex != expected.ScoopIndex || e.AccountID != expected.AccountID ||
+		e.PrevHash != expected.PrevHash || !bytes.Equal(e.Scoop, expected.Scoop) || !bytes.Equal(e.Sig, expected.Sig) {
+		t.Errorf("deadline msg mismatch: %v", e)
+	}
+}
+
+func TestScanPlot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "poc-plot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	acc := account.NewAccount("SHA256withECDSA")
+	accountID := pocconfig.PubkeyID(acc.PublicKey)
+	path, err := plot.Generate(dir, accountID, 100, 3)
+	if err != nil {
+		t.Fatalf("generate plot: %s", err)
+	}
+	prevBlk := constructPrevBlock()
+	round := constructMiningRound(t, 2, prevBlk)
+	proof, err := scanPlot(path, accountID, round)
+	if err != nil {
+		t.Fatalf("scan plot: %s", err)
+	}
+	if proof.NonceNr < 100 || proof.NonceNr >= 103 {
+		t.Errorf("invalid nonce of proof: %d", proof.NonceNr)
+	}
+	if proof.PrevHash != prevBlk.Block.Hash() {
+		t.Errorf("proof not mined on top of prev block")
+	}
+	if _, err := scanPlot(path, "0123", round); err == nil {
+		t.Errorf("scan plot of other account should fail")
+	}
+
+	entry := signDeadlineEntry(acc, &deadlineEntry{
+		BlockNum:   proof.BlockNum,
+		Deadline:   proof.Deadline,
+		AccountID:  proof.AccountID,
+		NonceNr:    proof.NonceNr,
+		ScoopIndex: proof.ScoopIndex,
+		Scoop:      proof.Scoop,
+		PrevHash:   proof.PrevHash,
+	})
+	if err := verifyDeadline(entry, acc.PublicKey, prevBlk); err != nil {
+		t.Errorf("verify deadline: %s", err)
+	}
+
+	entry.Deadline = proof.Deadline + 1
+	signDeadlineEntry(acc, entry)
+	if err := verifyDeadline(entry, acc.PublicKey, prevBlk); err != errForgedDeadline {
+		t.Errorf("verify deadline should fail on forged deadline: %v", err)
+	}
+	entry.Deadline = proof.Deadline
+	entry.NonceNr = 103
+	signDeadlineEntry(acc, entry)
+	if err := verifyDeadline(entry, acc.PublicKey, prevBlk); err == nil {
+		t.Errorf("verify deadline should fail on forged nonce")
+	}
+}
+
+func TestScanPlotDirs(t *testing.T) {
+	acc := account.NewAccount("SHA256withECDSA")
+	accountID := pocconfig.PubkeyID(acc.PublicKey)
+	round := constructMiningRound(t, 2, constructPrevBlock())
+
+	var dirs []string
+	var best *deadlineProof
+	for i := 0; i < 3; i++ {
+		dir, err := ioutil.TempDir("", "poc-plot")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		path, err := plot.Generate(dir, accountID, uint64(100*(i+1)), 2)
+		if err != nil {
+			t.Fatalf("generate plot: %s", err)
+		}
+		proof, err := scanPlot(path, accountID, round)
+		if err != nil {
+			t.Fatalf("scan plot: %s", err)
+		}
+		if best == nil || proof.Deadline < best.Deadline {
+			best = proof
+		}
+		dirs = append(dirs, dir)
+	}
+	// a missing dir is skipped
+	dirs = append(dirs, filepath.Join(dirs[0], "missing"))
+
+	proof := scanPlotDirs(dirs, accountID, round)
+	if proof == nil {
+		t.Fatalf("no proof from plot dirs")
+	}
+	if proof.Deadline != best.Deadline || proof.NonceNr != best.NonceNr {
+		t.Errorf("best proof of dirs: nonce %d deadline %d, expected nonce %d deadline %d",
+			proof.NonceNr, proof.Deadline, best.NonceNr, best.Deadline)
+	}
+	if scanPlotDirs(nil, accountID, round) != nil {
+		t.Errorf("proof without plot dirs")
+	}
+}
+
+func TestVerifyProposalDeadline(t *testing.T) {
+	acc := account.NewAccount("SHA256withECDSA")
+	prevBlk := constructPrevBlock()
+	_, _, deadline, err := nonceDeadline(pocconfig.PubkeyID(acc.PublicKey), 100, prevBlk)
+	if err != nil {
+		t.Fatalf("nonce deadline: %s", err)
+	}
+	elapsed := prevBlk.Block.Header.Timestamp + uint32(deadline)
+
+	blk := &Block{
+		Block: &types.Block{
+			Header: &types.Header{Height: 2, Timestamp: elapsed},
+		},
+		Info: &pocconfig.PocBlockInfo{Deadline: deadline, NonceNr: 100},
+	}
+	if err := verifyProposalDeadline(blk, acc.PublicKey, prevBlk, elapsed); err != nil {
+		t.Errorf("verify proposal deadline: %s", err)
+	}
+	if err := verifyProposalDeadline(blk, acc.PublicKey, prevBlk, elapsed-deadlineDrift-1); err == nil {
+		t.Errorf("proposal before its deadline elapsed should fail")
+	}
+
+	blk.Block.Header.Timestamp = elapsed - 1
+	if err := verifyProposalDeadline(blk, acc.PublicKey, prevBlk, elapsed); err == nil {
+		t.Errorf("proposal timestamped before its deadline should fail")
+	}
+	blk.Block.Header.Timestamp = elapsed
+
+	blk.Info.Deadline = deadline + 1
+	if err := verifyProposalDeadline(blk, acc.PublicKey, prevBlk, elapsed+1); err == nil {
+		t.Errorf("proposal with forged deadline should fail")
+	}
+	blk.Info.Deadline = deadline
+
+	other := account.NewAccount("SHA256withECDSA")
+	if err := verifyProposalDeadline(blk, other.PublicKey, prevBlk, elapsed); err == nil {
+		t.Errorf("proposal with nonce of another account should fail")
+	}
+}
@@ -41,6 +41,7 @@ const (
 	EventPeerHeartbeat
 	EventTxPool
 	EventTxBlockTimeout
+	EventDeadlineElapsed
 	EventMax
 )
 
@@ -347,6 +348,31 @@ func (self *EventTimer) CancelTxBlockTimeout(blockNum uint32) {
 	self.cancelEventTimer(EventTxBlockTimeout, blockNum)
 }
 
+// StartDeadlineTimer fires EventDeadlineElapsed for blockNum after timeout,
+// the time left until the node's deadline for the block expires.
+func (self *EventTimer) StartDeadlineTimer(blockNum uint32, timeout time.Duration) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	timers := self.eventTimers[EventDeadlineElapsed]
+	if t, present := timers[blockNum]; present {
+		t.Stop()
+	}
+	timers[blockNum] = time.AfterFunc(timeout, func() {
+		self.C <- &TimerEvent{
+			evtType:  EventDeadlineElapsed,
+			blockNum: blockNum,
+		}
+	})
+}
+
+func (self *EventTimer) CancelDeadlineTimer(blockNum uint32) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.cancelEventTimer(EventDeadlineElapsed, blockNum)
+}
+
 func (self *EventTimer) startPeerTicker(peerIdx uint32) error {
 	self.lock.Lock()
 	defer self.lock.Unlock()
@@ -0,0 +1,134 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"bytes"
+	"fmt"
+
+	"OntologyWithPOC/account"
+	"OntologyWithPOC/common/config"
+	"OntologyWithPOC/common/log"
+	pocconfig "OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/core/signature"
+	"OntologyWithPOC/core/types"
+	"OntologyWithPOC/core/utils"
+	gover "OntologyWithPOC/smartcontract/service/native/governance"
+	nutils "OntologyWithPOC/smartcontract/service/native/utils"
+	"github.com/ontio/ontology-crypto/keypair"
+)
+
+// gas limit of evidence transactions, covering the invoke code of two headers.
+// Forged deadline and failed audit evidence also pay for the nonces
+// regenerated to verify them.
+const evidenceGasLimit = 200000
+
+// pocEvidenceTransaction builds the transaction submitting evidence against
+// peer pub, paid and signed by acc. The nonce keeps the transactions of one
+// offence identical, so the pool drops resubmissions.
+func pocEvidenceTransaction(acc *account.Account, nonce uint32, evidenceType uint64, pub keypair.PublicKey, messages, sigs [][]byte) (*types.Transaction, error) {
+	if len(messages) != len(sigs) {
+		return nil, fmt.Errorf("%d messages of %d sigs", len(messages), len(sigs))
+	}
+	param := &gover.SubmitPocEvidenceParam{
+		EvidenceType: evidenceType,
+		PeerPubkey:   pocconfig.PubkeyID(pub),
+		Messages:     messages,
+		Sigs:         sigs,
+	}
+	code, err := utils.BuildNativeInvokeCode(nutils.GovernanceContractAddress, 0, gover.SUBMIT_POC_EVIDENCE, []interface{}{param})
+	if err != nil {
+		return nil, err
+	}
+	mutable := utils.NewInvokeTransaction(code)
+	mutable.GasPrice = config.DefConfig.Common.GasPrice
+	mutable.GasLimit = evidenceGasLimit
+	switch evidenceType {
+	case gover.POC_EVIDENCE_FORGED_DEADLINE:
+		mutable.GasLimit += gover.POC_FORGED_DEADLINE_GAS
+	case gover.POC_EVIDENCE_FAILED_AUDIT:
+		mutable.GasLimit += gover.POC_FAILED_AUDIT_GAS
+	}
+	mutable.Nonce = nonce
+	mutable.Payer = acc.Address
+	hash := mutable.Hash()
+	sig, err := signature.Sign(acc, hash[:])
+	if err != nil {
+		return nil, err
+	}
+	mutable.Sigs = []types.Sig{{
+		PubKeys: []keypair.PublicKey{acc.PublicKey},
+		M:       1,
+		SigData: [][]byte{sig},
+	}}
+	return mutable.IntoImmutable()
+}
+
+func (self *Server) submitEvidence(evidenceType uint64, blkNum uint32, pub keypair.PublicKey, messages, sigs [][]byte) {
+	tx, err := pocEvidenceTransaction(self.account, blkNum, evidenceType, pub, messages, sigs)
+	if err != nil {
+		log.Errorf("server %d failed to build evidence of block %d: %s", self.Index, blkNum, err)
+		return
+	}
+	hash := tx.Hash()
+	log.Warnf("server %d submits evidence %d against %s of block %d, tx %s",
+		self.Index, evidenceType, pocconfig.PubkeyID(pub), blkNum, hash.ToHexString())
+	self.poolActor.SubmitTransaction(tx)
+}
+
+// reportDoubleProposal submits the two blocks of proposer if they are
+// different proposals, not the block and empty block of one proposal.
+func (self *Server) reportDoubleProposal(proposer uint32, blk1, blk2 *Block) {
+	header1, header2 := blk1.Block.Header, blk2.Block.Header
+	if len(header1.SigData) == 0 || len(header2.SigData) == 0 {
+		return
+	}
+	if bytes.Equal(header1.ConsensusPayload, header2.ConsensusPayload) {
+		return
+	}
+	pub := self.peerPool.GetPeerPubKey(proposer)
+	if pub == nil {
+		return
+	}
+	self.submitEvidence(gover.POC_EVIDENCE_DOUBLE_PROPOSAL, header1.Height, pub,
+		[][]byte{header1.ToArray(), header2.ToArray()},
+		[][]byte{header1.SigData[0], header2.SigData[0]})
+}
+
+// reportForgedDeadline submits entry, signed by pub with a deadline its nonce
+// does not yield.
+func (self *Server) reportForgedDeadline(entry *deadlineEntry, pub keypair.PublicKey) {
+	data, err := entry.signedData()
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	self.submitEvidence(gover.POC_EVIDENCE_FORGED_DEADLINE, entry.BlockNum, pub, [][]byte{data}, [][]byte{entry.Sig})
+}
+
+// reportFailedAudit submits entry, signed by pub with a zk proof whose plot
+// tree audit fails.
+func (self *Server) reportFailedAudit(entry *deadlineEntry, pub keypair.PublicKey) {
+	data, err := entry.signedData()
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	self.submitEvidence(gover.POC_EVIDENCE_FAILED_AUDIT, entry.BlockNum, pub, [][]byte{data}, [][]byte{entry.Sig})
+}
@@ -0,0 +1,187 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"testing"
+
+	"OntologyWithPOC/account"
+	"OntologyWithPOC/common/config"
+	pocconfig "OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/core/payload"
+	"OntologyWithPOC/core/signature"
+	"OntologyWithPOC/core/types"
+	"OntologyWithPOC/core/utils"
+	"OntologyWithPOC/smartcontract/event"
+	gover "OntologyWithPOC/smartcontract/service/native/governance"
+	"github.com/ontio/ontology-crypto/keypair"
+)
+
+func TestPocEvidenceTransaction(t *testing.T) {
+	acc := account.NewAccount("SHA256withECDSA")
+	offender := account.NewAccount("SHA256withECDSA")
+	tx, err := pocEvidenceTransaction(acc, 10, 1, offender.PublicKey, [][]byte{{1}}, [][]byte{{2}})
+	if err != nil {
+		t.Fatalf("evidence transaction: %s", err)
+	}
+	if tx.Payer != acc.Address {
+		t.Errorf("evidence transaction not paid by submitter")
+	}
+	if len(tx.Sigs) != 1 {
+		t.Fatalf("evidence transaction sigs: %d", len(tx.Sigs))
+	}
+	sig, err := tx.Sigs[0].GetSig()
+	if err != nil {
+		t.Fatalf("evidence transaction sig: %s", err)
+	}
+	hash := tx.Hash()
+	if err := signature.Verify(acc.PublicKey, hash[:], sig.SigData[0]); err != nil {
+		t.Errorf("evidence transaction sig: %s", err)
+	}
+
+	tx2, err := pocEvidenceTransaction(acc, 10, 1, offender.PublicKey, [][]byte{{1}}, [][]byte{{2}})
+	if err != nil {
+		t.Fatalf("evidence transaction: %s", err)
+	}
+	if tx2.Hash() != hash {
+		t.Errorf("evidence transactions of one offence differ")
+	}
+
+	if _, err := pocEvidenceTransaction(acc, 10, 1, offender.PublicKey, [][]byte{{1}}, nil); err == nil {
+		t.Errorf("evidence transaction with missing sig should fail")
+	}
+
+	// evidence regenerating nonces pays for them
+	audit, err := pocEvidenceTransaction(acc, 10, gover.POC_EVIDENCE_FAILED_AUDIT, offender.PublicKey, [][]byte{{1}}, [][]byte{{2}})
+	if err != nil {
+		t.Fatalf("evidence transaction: %s", err)
+	}
+	if audit.GasLimit != evidenceGasLimit+gover.POC_FAILED_AUDIT_GAS {
+		t.Errorf("failed audit evidence gas limit %d", audit.GasLimit)
+	}
+}
+
+func TestDeadlineEntrySignedData(t *testing.T) {
+	acc := account.NewAccount("SHA256withECDSA")
+	entry := signDeadlineEntry(acc, &deadlineEntry{BlockNum: 2, Deadline: 100, NonceNr: 7})
+	data, err := entry.signedData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := hashData(data)
+	if err := signature.Verify(acc.PublicKey, hash[:], entry.Sig); err != nil {
+		t.Errorf("signed data of deadline entry: %s", err)
+	}
+}
+
+// withGasLimit re-signs the invoke code of tx by acc under gasLimit.
+func withGasLimit(t *testing.T, acc *account.Account, tx *types.Transaction, gasLimit uint64) *types.Transaction {
+	mutable := utils.NewInvokeTransaction(tx.Payload.(*payload.InvokeCode).Code)
+	mutable.GasLimit = gasLimit
+	mutable.Nonce = tx.Nonce + 1
+	mutable.Payer = acc.Address
+	hash := mutable.Hash()
+	sig, err := signature.Sign(acc, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	mutable.Sigs = []types.Sig{{PubKeys: []keypair.PublicKey{acc.PublicKey}, M: 1, SigData: [][]byte{sig}}}
+	immutable, err := mutable.IntoImmutable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return immutable
+}
+
+func TestExecuteEvidenceTransactions(t *testing.T) {
+	blockpool, err := buildTestBlockPool(t)
+	if err != nil {
+		t.Fatalf("buildTestBlockPool err:%s", err)
+	}
+	defer cleanTestChainStore()
+
+	gasPrice := config.DefConfig.Common.GasPrice
+	config.DefConfig.Common.GasPrice = 0
+	defer func() { config.DefConfig.Common.GasPrice = gasPrice }()
+
+	acc := account.NewAccount("SHA256withECDSA")
+	offender := account.NewAccount("SHA256withECDSA")
+	lgr := blockpool.chainStore.db
+	genesis, genesisHash := blockpool.getSealedBlock(0)
+	blk, err := buildTestBlock(t, genesis.Block, lgr)
+	if err != nil {
+		t.Fatalf("buildTestBlock err:%s", err)
+	}
+
+	// two proposals of the offender at height 1
+	headers := make([][]byte, 0, 2)
+	sigs := make([][]byte, 0, 2)
+	for _, p := range []string{`{"nonce_nr":1}`, `{"nonce_nr":2}`} {
+		header := &types.Header{Height: 1, PrevBlockHash: genesisHash, ConsensusPayload: []byte(p)}
+		hash := header.Hash()
+		sig, err := signature.Sign(offender, hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		headers = append(headers, header.ToArray())
+		sigs = append(sigs, sig)
+	}
+	doubleProposal, err := pocEvidenceTransaction(acc, 1, gover.POC_EVIDENCE_DOUBLE_PROPOSAL, offender.PublicKey, headers, sigs)
+	if err != nil {
+		t.Fatalf("double proposal evidence: %s", err)
+	}
+
+	// a deadline of the offender at height 1 its nonce does not yield
+	entry := signDeadlineEntry(offender, &deadlineEntry{
+		BlockNum:  1,
+		PrevHash:  genesisHash,
+		AccountID: pocconfig.PubkeyID(offender.PublicKey),
+		NonceNr:   7,
+		Deadline:  12345,
+	})
+	data, err := entry.signedData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := pocEvidenceTransaction(acc, 1, gover.POC_EVIDENCE_FORGED_DEADLINE, offender.PublicKey, [][]byte{data}, [][]byte{entry.Sig})
+	if err != nil {
+		t.Fatalf("forged deadline evidence: %s", err)
+	}
+	// the nonce regenerated to verify it is not paid by the flat gas limit
+	underpaid := withGasLimit(t, acc, forged, evidenceGasLimit)
+
+	blk.Block.Transactions = []*types.Transaction{doubleProposal, underpaid, forged}
+	result, err := lgr.ExecuteBlock(blk.Block)
+	if err != nil {
+		t.Fatalf("ExecuteBlock err:%s", err)
+	}
+	if len(result.Notify) != 3 {
+		t.Fatalf("%d notifies of 3 transactions", len(result.Notify))
+	}
+	if result.Notify[0].State != event.CONTRACT_STATE_SUCCESS {
+		t.Errorf("double proposal evidence failed")
+	}
+	if result.Notify[1].State == event.CONTRACT_STATE_SUCCESS {
+		t.Errorf("forged deadline evidence accepted without paying for the nonce")
+	}
+	// evidence of another type at the same height is not a resubmission
+	if result.Notify[2].State != event.CONTRACT_STATE_SUCCESS {
+		t.Errorf("forged deadline evidence failed")
+	}
+}
@@ -0,0 +1,399 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"time"
+
+	"OntologyWithPOC/common/config"
+	"OntologyWithPOC/common/log"
+	"OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/core/ledger"
+	"github.com/ontio/ontology-crypto/keypair"
+	"github.com/ontio/ontology-crypto/signature"
+)
+
+// blocks deeper than maxReorgDepth below the chain tip are submitted to the
+// ledger even if the committee did not agree on their state yet, a heavier
+// branch can replace at most the blocks above them
+const maxReorgDepth = 6
+
+const (
+	// competing blocks kept for one height
+	maxForkBlocks = 8
+	// fork blocks kept while their parent is fetched from the peers
+	maxOrphanBlocks = maxReorgDepth * maxForkBlocks
+)
+
+// blockDifficulty is the work a block sealed under baseTarget represents, the
+// lower the base target the longer the deadlines and the harder the block.
+func blockDifficulty(baseTarget uint64) uint64 {
+	if baseTarget == 0 {
+		return 1
+	}
+	return math.MaxUint64 / baseTarget
+}
+
+func cumulativeDifficulty(prevBlk *Block, baseTarget uint64) uint64 {
+	var prev uint64
+	if prevBlk.Info != nil {
+		prev = prevBlk.Info.CumulativeDifficulty
+	}
+	difficulty := blockDifficulty(baseTarget)
+	if prev > math.MaxUint64-difficulty {
+		return math.MaxUint64
+	}
+	return prev + difficulty
+}
+
+func verifyCumulativeDifficulty(blk *Block, prevBlk *Block) error {
+	if blk.Info == nil {
+		return fmt.Errorf("no poc info in block %d", blk.getBlockNum())
+	}
+	expected := cumulativeDifficulty(prevBlk, blk.Info.BaseTarget)
+	if blk.Info.CumulativeDifficulty != expected {
+		return fmt.Errorf("cumulative difficulty mismatch: %d vs %d", blk.Info.CumulativeDifficulty, expected)
+	}
+	return nil
+}
+
+// heavierBlock reports whether chain tip a is preferred over b: the higher
+// cumulative difficulty wins, then the earlier deadline, then the lower hash.
+func heavierBlock(a, b *Block) bool {
+	if a.Info.CumulativeDifficulty != b.Info.CumulativeDifficulty {
+		return a.Info.CumulativeDifficulty > b.Info.CumulativeDifficulty
+	}
+	if a.Info.Deadline != b.Info.Deadline {
+		return a.Info.Deadline < b.Info.Deadline
+	}
+	ha, hb := a.Block.Hash(), b.Block.Hash()
+	return bytes.Compare(ha[:], hb[:]) < 0
+}
+
+// onForkBlock handles a sealed block competing with our chain. Fork blocks
+// are kept in the block pool, their unknown parents fetched from the peers,
+// and the heaviest branch replaces our blocks down to the fork point as long
+// as none of them is submitted to the ledger.
+func (self *Server) onForkBlock(block *Block) error {
+	blkNum := block.getBlockNum()
+	if blkNum <= ledger.DefLedger.GetCurrentBlockHeight() {
+		return nil
+	}
+	parent := self.blockPool.getParentBlock(block)
+	if parent == nil {
+		if err := self.blockPool.addOrphanBlock(block); err != nil {
+			return fmt.Errorf("orphan fork block %d: %s", blkNum, err)
+		}
+		// the parent is taken from the block fetch responses
+		self.broadcast(self.constructBlockFetchMsg(blkNum - 1))
+		return nil
+	}
+	tip, err := self.addForkBlocks(block, parent)
+	if err != nil {
+		return fmt.Errorf("invalid fork block %d: %s", blkNum, err)
+	}
+
+	ourTip, _ := self.blockPool.getSealedBlock(self.GetCommittedBlockNo())
+	if ourTip == nil || ourTip.Info == nil || !heavierBlock(tip, ourTip) {
+		return nil
+	}
+	branch, err := self.blockPool.forkBranch(tip)
+	if err != nil {
+		log.Warnf("server %d, heavier fork %d beyond reorg depth: %s", self.Index, tip.getBlockNum(), err)
+		return nil
+	}
+	if err := self.reorganize(branch); err != nil {
+		return fmt.Errorf("failed to reorganize to fork block %d: %s", tip.getBlockNum(), err)
+	}
+	log.Infof("server %d, reorganized blocks %d-%d to proposer %d, cumulative difficulty %d",
+		self.Index, branch[0].getBlockNum(), tip.getBlockNum(), tip.getProposer(), tip.Info.CumulativeDifficulty)
+	return nil
+}
+
+// addForkBlocks keeps block and the orphan blocks descending from it, and
+// returns the heaviest of them.
+func (self *Server) addForkBlocks(block *Block, parent *Block) (*Block, error) {
+	if err := self.verifyForkHeader(block, parent); err != nil {
+		return nil, err
+	}
+	if err := self.blockPool.addForkBlock(block); err != nil {
+		return nil, err
+	}
+	tip := block
+	for _, child := range self.blockPool.takeOrphanBlocks(block.Block.Hash()) {
+		childTip, err := self.addForkBlocks(child, block)
+		if err != nil {
+			log.Warnf("server %d, dropped orphan block %d: %s", self.Index, child.getBlockNum(), err)
+			continue
+		}
+		if heavierBlock(childTip, tip) {
+			tip = childTip
+		}
+	}
+	return tip, nil
+}
+
+// reorganize replaces our blocks above the fork point with branch. Each
+// block of the branch is fully verified on top of the state of its parent,
+// our blocks are chained back if one of them is invalid.
+func (self *Server) reorganize(branch []*Block) error {
+	forkPoint := branch[0].getBlockNum() - 1
+	oldTip := self.GetCommittedBlockNo()
+	dropped, err := self.blockPool.dropBlocks(forkPoint + 1)
+	if err != nil {
+		return err
+	}
+	if err := self.chainForkBlocks(branch); err != nil {
+		if self.GetCommittedBlockNo() > forkPoint {
+			if _, derr := self.blockPool.dropBlocks(forkPoint + 1); derr != nil {
+				log.Errorf("server %d, failed to drop fork blocks: %s", self.Index, derr)
+			}
+		}
+		for _, blk := range dropped {
+			if cerr := self.blockPool.chainBlock(blk, true); cerr != nil {
+				log.Errorf("server %d, failed to restore block %d: %s", self.Index, blk.getBlockNum(), cerr)
+				break
+			}
+		}
+		return err
+	}
+	// the branch passed full validation, its blocks may reach the ledger
+	newTip := self.GetCommittedBlockNo()
+	self.blockPool.commitBlocks(newTip)
+	self.metaLock.Lock()
+	self.currentBlockNum = newTip + 1
+	self.metaLock.Unlock()
+	for _, blk := range branch {
+		self.recordHistory(blk)
+	}
+
+	// the rounds on top of the replaced blocks start over
+	top := oldTip
+	if newTip > top {
+		top = newTip
+	}
+	for blkNum := forkPoint + 1; blkNum <= top+1; blkNum++ {
+		self.timer.onBlockSealed(blkNum)
+		self.resetRoundDeadlines(blkNum)
+	}
+	self.scheduleProposal(newTip + 1)
+	self.notifyNewRound()
+	return nil
+}
+
+func (self *Server) chainForkBlocks(branch []*Block) error {
+	for _, blk := range branch {
+		prevBlk, _ := self.blockPool.getSealedBlock(blk.getBlockNum() - 1)
+		if prevBlk == nil {
+			return fmt.Errorf("no parent of fork block %d", blk.getBlockNum())
+		}
+		if err := self.verifyForkBlock(blk, prevBlk); err != nil {
+			return fmt.Errorf("invalid fork block %d: %s", blk.getBlockNum(), err)
+		}
+		if err := self.blockPool.chainBlock(blk, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resetRoundDeadlines forgets the deadlines scanned for blkNum, they were
+// derived from a generation signature which is no longer in the chain.
+func (self *Server) resetRoundDeadlines(blkNum uint32) {
+	self.deadlineLock.Lock()
+	if self.deadlineProof != nil && self.deadlineProof.BlockNum == blkNum {
+		self.deadlineProof = nil
+	}
+	self.deadlineLock.Unlock()
+
+	self.peersDeadLine.locker.Lock()
+	delete(self.peersDeadLine.blockpeersdeadline, blkNum)
+	self.peersDeadLine.locker.Unlock()
+}
+
+// verifyForkHeader checks what a fork block commits to on top of its parent,
+// without the ledger state of the parent being executed. The base target and
+// the signatures are checked before the block counts towards the cumulative
+// difficulty of its branch.
+func (self *Server) verifyForkHeader(block *Block, prevBlk *Block) error {
+	if block.Info == nil {
+		return fmt.Errorf("no poc info")
+	}
+	if prevBlk.Info == nil {
+		return fmt.Errorf("no poc info in parent")
+	}
+	pk := self.proposerKey(block)
+	if pk == nil {
+		return fmt.Errorf("unknown proposer %d", block.getProposer())
+	}
+	if !bytes.Equal(block.Info.GenSig, nextGenSig(prevBlk.Info.GenSig, pk)) {
+		return fmt.Errorf("invalid generation signature")
+	}
+	timestamp := block.Block.Header.Timestamp
+	if timestamp <= prevBlk.Block.Header.Timestamp {
+		return fmt.Errorf("timestamp %d not after parent %d", timestamp, prevBlk.Block.Header.Timestamp)
+	}
+	baseTarget, err := self.forkBaseTarget(prevBlk, timestamp)
+	if err != nil {
+		return err
+	}
+	if block.Info.BaseTarget != baseTarget {
+		return fmt.Errorf("base target mismatch: %d vs %d", block.Info.BaseTarget, baseTarget)
+	}
+	if err := self.verifyForkSigs(block, pk, prevBlk); err != nil {
+		return err
+	}
+	return verifyCumulativeDifficulty(block, prevBlk)
+}
+
+// forkHistory returns up to count blocks of the branch ending with prevBlk,
+// in height order and without the genesis block. Ancestors no longer kept in
+// the block pool are taken from our chain.
+func (self *Server) forkHistory(prevBlk *Block, count uint32) ([]*Block, error) {
+	history := make([]*Block, 0, count)
+	for blk := prevBlk; blk.getBlockNum() > 0 && uint32(len(history)) < count; {
+		history = append(history, blk)
+		if uint32(len(history)) == count || blk.getBlockNum() == 1 {
+			break
+		}
+		parent := self.blockPool.getParentBlock(blk)
+		if parent == nil {
+			sealed, h := self.blockPool.getSealedBlock(blk.getBlockNum() - 1)
+			if sealed == nil || h != blk.getPrevBlockHash() {
+				return nil, fmt.Errorf("unknown ancestor %d of fork", blk.getBlockNum()-1)
+			}
+			parent = sealed
+		}
+		blk = parent
+	}
+	for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+		history[i], history[j] = history[j], history[i]
+	}
+	return history, nil
+}
+
+// forkBaseTarget mirrors nextBaseTarget on the branch ending with prevBlk.
+func (self *Server) forkBaseTarget(prevBlk *Block, timestamp uint32) (uint64, error) {
+	cfg := config.DefConfig.Genesis.POC
+	history, err := self.forkHistory(prevBlk, pocconfig.RetargetWindow(cfg))
+	if err != nil {
+		return 0, err
+	}
+	return retarget(history, timestamp, pocconfig.TargetBlockTime(cfg), pocconfig.InitialBaseTarget(cfg)), nil
+}
+
+// forkCommittee mirrors recentWinners and calcCommittee on the branch ending
+// with prevBlk, returning the keys of the committee.
+func (self *Server) forkCommittee(prevBlk *Block) ([]keypair.PublicKey, error) {
+	history, err := self.forkHistory(prevBlk, committeeWindow)
+	if err != nil {
+		return nil, err
+	}
+	winners := make([]uint32, 0)
+	keys := make(map[uint32]keypair.PublicKey)
+	for i := len(history) - 1; i >= 0; i-- {
+		proposer := history[i].getProposer()
+		if proposer == math.MaxUint32 || keys[proposer] != nil {
+			continue
+		}
+		pk := self.proposerKey(history[i])
+		if pk == nil {
+			continue
+		}
+		keys[proposer] = pk
+		winners = append(winners, proposer)
+	}
+	committee := make([]keypair.PublicKey, 0, maxCommitteeSize)
+	for _, idx := range calcCommittee(winners, self.config) {
+		pk := keys[idx]
+		if pk == nil {
+			pk = self.peerPool.GetPeerPubKey(idx)
+		}
+		if pk != nil {
+			committee = append(committee, pk)
+		}
+	}
+	return committee, nil
+}
+
+// verifyForkSigs checks a fork block is signed by its proposer and endorsed by
+// more than C members of the committee of its branch, as sealed blocks are.
+func (self *Server) verifyForkSigs(block *Block, pk keypair.PublicKey, prevBlk *Block) error {
+	header := block.Block.Header
+	if len(header.SigData) == 0 || len(header.Bookkeepers) != len(header.SigData) {
+		return fmt.Errorf("%d signatures of %d bookkeepers", len(header.SigData), len(header.Bookkeepers))
+	}
+	if pocconfig.PubkeyID(header.Bookkeepers[0]) != pocconfig.PubkeyID(pk) {
+		return fmt.Errorf("not signed by proposer %d", block.getProposer())
+	}
+	hash := block.Block.Hash()
+	for i, pub := range header.Bookkeepers {
+		sig, err := signature.Deserialize(header.SigData[i])
+		if err != nil {
+			return fmt.Errorf("signature %d: %s", i, err)
+		}
+		if !signature.Verify(pub, hash[:], sig) {
+			return fmt.Errorf("invalid signature %d", i)
+		}
+	}
+
+	committee, err := self.forkCommittee(prevBlk)
+	if err != nil {
+		return err
+	}
+	members := make(map[string]bool)
+	for _, pub := range committee {
+		members[pocconfig.PubkeyID(pub)] = true
+	}
+	endorsers := make(map[string]bool)
+	for _, pub := range header.Bookkeepers[1:] {
+		if id := pocconfig.PubkeyID(pub); members[id] {
+			endorsers[id] = true
+		}
+	}
+	C := uint32(0)
+	if len(committee) > 0 {
+		C = uint32(len(committee)-1) / 3
+	}
+	if uint32(len(endorsers)) <= C {
+		return fmt.Errorf("%d committee endorsements, %d needed", len(endorsers), C+1)
+	}
+	return nil
+}
+
+// verifyForkBlock fully checks a fork block once its parent is chained.
+func (self *Server) verifyForkBlock(block *Block, prevBlk *Block) error {
+	if err := self.verifyForkHeader(block, prevBlk); err != nil {
+		return err
+	}
+	pk := self.proposerKey(block)
+	if err := self.verifyPlotBinding(block.getBlockNum(), blockPlotAccount(block, pk), pk); err != nil {
+		return err
+	}
+	if err := self.verifyPocRewardTransaction(block, pk); err != nil {
+		return err
+	}
+	if err := self.verifyBlockDeadline(block, pk, prevBlk, uint32(time.Now().Unix())); err != nil {
+		return err
+	}
+	return self.admitMember(pocconfig.PubkeyID(pk), block.getProposer(), false)
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"OntologyWithPOC/account"
+	"OntologyWithPOC/common/config"
+	pocconfig "OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/core/signature"
+	"OntologyWithPOC/core/types"
+)
+
+func constructForkBlock(nonce uint64, deadline uint64, difficulty uint64) *Block {
+	return &Block{
+		Block: &types.Block{
+			Header: &types.Header{
+				Height:           2,
+				ConsensusData:    nonce,
+				ConsensusPayload: []byte("{}"),
+			},
+		},
+		Info: &pocconfig.PocBlockInfo{
+			Deadline:             deadline,
+			CumulativeDifficulty: difficulty,
+		},
+	}
+}
+
+func TestBlockDifficulty(t *testing.T) {
+	if d := blockDifficulty(1000); d != math.MaxUint64/1000 {
+		t.Errorf("blockDifficulty: %d, expected %d", d, uint64(math.MaxUint64/1000))
+	}
+	if blockDifficulty(10) <= blockDifficulty(1000) {
+		t.Errorf("lower base target should be harder")
+	}
+	if d := blockDifficulty(0); d != 1 {
+		t.Errorf("blockDifficulty of zero base target: %d", d)
+	}
+	if d := blockDifficulty(math.MaxUint64); d != 1 {
+		t.Errorf("blockDifficulty of max base target: %d", d)
+	}
+}
+
+func TestVerifyCumulativeDifficulty(t *testing.T) {
+	prevBlk := constructPrevBlock()
+	prevBlk.Info.CumulativeDifficulty = 100
+
+	blk := constructForkBlock(1, 10, 100+blockDifficulty(2000))
+	blk.Info.BaseTarget = 2000
+	if err := verifyCumulativeDifficulty(blk, prevBlk); err != nil {
+		t.Errorf("verifyCumulativeDifficulty: %s", err)
+	}
+	blk.Info.CumulativeDifficulty++
+	if err := verifyCumulativeDifficulty(blk, prevBlk); err == nil {
+		t.Errorf("verifyCumulativeDifficulty should fail on forged difficulty")
+	}
+}
+
+func TestHeavierBlock(t *testing.T) {
+	light := constructForkBlock(1, 10, 100)
+	heavy := constructForkBlock(2, 50, 200)
+	if !heavierBlock(heavy, light) || heavierBlock(light, heavy) {
+		t.Errorf("higher cumulative difficulty should win")
+	}
+
+	early := constructForkBlock(3, 5, 100)
+	if !heavierBlock(early, light) || heavierBlock(light, early) {
+		t.Errorf("earlier deadline should win on equal difficulty")
+	}
+
+	a := constructForkBlock(4, 10, 100)
+	ha, hl := a.Block.Hash(), light.Block.Hash()
+	if heavierBlock(a, light) == heavierBlock(light, a) {
+		t.Errorf("hash tie break should order blocks (%x, %x)", ha, hl)
+	}
+}
+
+func signForkBlock(t *testing.T, blk *Block, accs ...*account.Account) {
+	hash := blk.Block.Hash()
+	blk.Block.Header.Bookkeepers = nil
+	blk.Block.Header.SigData = nil
+	for _, acc := range accs {
+		sig, err := signature.Sign(acc, hash[:])
+		if err != nil {
+			t.Fatalf("sign block %d: %s", blk.getBlockNum(), err)
+		}
+		blk.Block.Header.Bookkeepers = append(blk.Block.Header.Bookkeepers, acc.PublicKey)
+		blk.Block.Header.SigData = append(blk.Block.Header.SigData, sig)
+	}
+}
+
+func TestForkBaseTargetRejected(t *testing.T) {
+	blockpool, err := buildTestBlockPool(t)
+	if err != nil {
+		t.Fatalf("buildTestBlockPool err:%s", err)
+	}
+	defer cleanTestChainStore()
+
+	acc := account.NewAccount("SHA256withECDSA")
+	peerpool := constructPeerPool(false)
+	if err := peerpool.addPeer(&pocconfig.PeerConfig{Index: 1, ID: pocconfig.PubkeyID(acc.PublicKey)}); err != nil {
+		t.Fatalf("addPeer err:%s", err)
+	}
+	server := &Server{blockPool: blockpool, peerPool: peerpool, config: &pocconfig.ChainConfig{}}
+
+	lgr := blockpool.chainStore.db
+	genesis, _ := blockpool.getSealedBlock(0)
+	ours, _ := buildTestBlock(t, genesis.Block, lgr)
+	ours.Info = &pocconfig.PocBlockInfo{
+		Proposer:             1,
+		BaseTarget:           pocconfig.InitialBaseTarget(config.DefConfig.Genesis.POC),
+		GenSig:               []byte("genesis gensig"),
+		CumulativeDifficulty: 100,
+	}
+	signForkBlock(t, ours, acc)
+	if err := blockpool.chainBlock(ours, true); err != nil {
+		t.Fatalf("chainBlock err:%s", err)
+	}
+
+	buildFork := func(baseTarget uint64) *Block {
+		blk, _ := buildTestBlock(t, ours.Block, lgr)
+		blk.Info = &pocconfig.PocBlockInfo{
+			Proposer:             1,
+			BaseTarget:           baseTarget,
+			GenSig:               nextGenSig(ours.Info.GenSig, acc.PublicKey),
+			CumulativeDifficulty: cumulativeDifficulty(ours, baseTarget),
+		}
+		signForkBlock(t, blk, acc, acc)
+		return blk
+	}
+
+	good := buildFork(0)
+	expected, err := server.forkBaseTarget(ours, good.Block.Header.Timestamp)
+	if err != nil {
+		t.Fatalf("forkBaseTarget err:%s", err)
+	}
+	good = buildFork(expected)
+	if err := server.verifyForkHeader(good, ours); err != nil {
+		t.Errorf("verifyForkHeader: %s", err)
+	}
+
+	// the lowest base target claims the highest difficulty
+	bad := buildFork(1)
+	if bad.Info.CumulativeDifficulty <= good.Info.CumulativeDifficulty {
+		t.Fatalf("forged base target should claim more difficulty")
+	}
+	if _, err := server.addForkBlocks(bad, ours); err == nil || !strings.Contains(err.Error(), "base target") {
+		t.Errorf("fork block with forged base target accepted: %v", err)
+	}
+	if blk, _ := blockpool.getBlockLocked(bad.getBlockNum(), bad.Block.Hash()); blk != nil {
+		t.Errorf("rejected fork block kept in the pool")
+	}
+
+	unendorsed := buildFork(expected)
+	signForkBlock(t, unendorsed, acc)
+	if err := server.verifyForkHeader(unendorsed, ours); err == nil {
+		t.Errorf("fork block without endorsements accepted")
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package history
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+var csvHeader = []string{"height", "hash", "timestamp", "base_target", "winner",
+	"peer_index", "peer_id", "account_id", "nonce_nr", "deadline", "zk"}
+
+// WriteJSON writes recs as a JSON array.
+func WriteJSON(w io.Writer, recs []*Record) error {
+	if recs == nil {
+		recs = []*Record{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(recs)
+}
+
+// WriteCSV writes a row per deadline of recs, the one of the proposer flagged
+// as winner. A winning deadline the node did not receive gets a row as well.
+func WriteCSV(w io.Writer, recs []*Record) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, rec := range recs {
+		won := false
+		for _, d := range rec.Deadlines {
+			winner := isWinner(rec, d)
+			won = won || winner
+			if err := cw.Write(csvRow(rec, d, winner)); err != nil {
+				return err
+			}
+		}
+		if !won && rec.Winner != nil {
+			if err := cw.Write(csvRow(rec, rec.Winner, true)); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func isWinner(rec *Record, d *Deadline) bool {
+	w := rec.Winner
+	return w != nil && w.PeerIndex == d.PeerIndex && w.AccountID == d.AccountID &&
+		w.NonceNr == d.NonceNr && w.Deadline == d.Deadline
+}
+
+func csvRow(rec *Record, d *Deadline, winner bool) []string {
+	return []string{
+		strconv.FormatUint(uint64(rec.Height), 10),
+		rec.Hash,
+		strconv.FormatUint(uint64(rec.Timestamp), 10),
+		strconv.FormatUint(rec.BaseTarget, 10),
+		strconv.FormatBool(winner),
+		strconv.FormatUint(uint64(d.PeerIndex), 10),
+		d.PeerID,
+		d.AccountID,
+		strconv.FormatUint(d.NonceNr, 10),
+		strconv.FormatUint(d.Deadline, 10),
+		strconv.FormatBool(d.ZK),
+	}
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package history
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+)
+
+func TestWriteCSV(t *testing.T) {
+	missed := testRecord(2, "a")
+	missed.Deadlines = missed.Deadlines[1:]
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, []*Record{testRecord(1, "a", "b"), missed}); err != nil {
+		t.Fatalf("write csv: %s", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv: %s", err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("csv rows: %d", len(rows))
+	}
+	if rows[1][0] != "1" || rows[1][4] != "true" || rows[1][7] != "a" {
+		t.Errorf("winner row: %v", rows[1])
+	}
+	if rows[2][4] != "false" || rows[2][7] != "b" || rows[2][9] != "20" {
+		t.Errorf("deadline row: %v", rows[2])
+	}
+	// the winning deadline not received still gets a row
+	if rows[3][0] != "2" || rows[3][4] != "true" {
+		t.Errorf("missed winner row: %v", rows[3])
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, []*Record{testRecord(1, "a", "b")}); err != nil {
+		t.Fatalf("write json: %s", err)
+	}
+	var recs []*Record
+	if err := json.Unmarshal(buf.Bytes(), &recs); err != nil {
+		t.Fatalf("read json: %s", err)
+	}
+	if len(recs) != 1 || recs[0].Winner.AccountID != "a" || len(recs[0].Deadlines) != 2 {
+		t.Errorf("json records mismatch")
+	}
+
+	buf.Reset()
+	if err := WriteJSON(&buf, nil); err != nil || bytes.TrimSpace(buf.Bytes())[0] != '[' {
+		t.Errorf("json of no records: %s", buf.String())
+	}
+}
@@ -0,0 +1,253 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package history persists the deadlines of the sealed PoC rounds, so that the
+// choice of every proposer can be audited after the fact.
+package history
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	storcomm "OntologyWithPOC/core/store/common"
+	"OntologyWithPOC/core/store/leveldbstore"
+)
+
+// DirName is the directory of the history store in the chain data dir.
+const DirName = "pochistory"
+
+// MaxQueryRange is the most heights a query spans.
+const MaxQueryRange = 1000
+
+const (
+	recordPrefix  byte = 0x01 // key: prefix+height, value: json record
+	accountPrefix byte = 0x02 // key: prefix+len+account id+height, value: empty
+)
+
+// Deadline is a deadline offered for a round.
+type Deadline struct {
+	PeerIndex uint32 `json:"peer_index"`
+	PeerID    string `json:"peer_id,omitempty"`
+	AccountID string `json:"account_id"`
+	NonceNr   uint64 `json:"nonce_nr"`
+	Deadline  uint64 `json:"deadline"`
+	ZK        bool   `json:"zk,omitempty"`
+}
+
+// Record is the round of the block sealed at Height: the verified deadline of
+// its proposer, and the deadlines the node received for the round, the
+// earliest first.
+type Record struct {
+	Height     uint32      `json:"height"`
+	Hash       string      `json:"hash"`
+	PrevHash   string      `json:"prev_hash"`
+	Timestamp  uint32      `json:"timestamp"`
+	BaseTarget uint64      `json:"base_target"`
+	Winner     *Deadline   `json:"winner"`
+	Deadlines  []*Deadline `json:"deadlines"`
+}
+
+// SortDeadlines orders the deadlines of the record the way proposers are
+// ranked: the earliest deadline first, then the lowest peer index.
+func (self *Record) SortDeadlines() {
+	sort.Slice(self.Deadlines, func(i, j int) bool {
+		di, dj := self.Deadlines[i], self.Deadlines[j]
+		if di.Deadline != dj.Deadline {
+			return di.Deadline < dj.Deadline
+		}
+		return di.PeerIndex < dj.PeerIndex
+	})
+}
+
+func (self *Record) accounts() []string {
+	seen := make(map[string]bool)
+	var accounts []string
+	add := func(d *Deadline) {
+		if d != nil && d.AccountID != "" && !seen[d.AccountID] {
+			seen[d.AccountID] = true
+			accounts = append(accounts, d.AccountID)
+		}
+	}
+	add(self.Winner)
+	for _, d := range self.Deadlines {
+		add(d)
+	}
+	return accounts
+}
+
+// Store keeps a record per height in its own leveldb.
+type Store struct {
+	lock sync.Mutex
+	db   *leveldbstore.LevelDBStore
+}
+
+// Open opens the history store at path, creating it if needed.
+func Open(path string) (*Store, error) {
+	db, err := leveldbstore.NewLevelDBStore(path)
+	if err != nil {
+		return nil, fmt.Errorf("open history store %s: %s", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+// NewMemStore returns a history store kept in memory.
+func NewMemStore() (*Store, error) {
+	db, err := leveldbstore.NewMemLevelDBStore()
+	if err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+func (self *Store) Close() error {
+	return self.db.Close()
+}
+
+func recordKey(height uint32) []byte {
+	key := make([]byte, 5)
+	key[0] = recordPrefix
+	binary.BigEndian.PutUint32(key[1:], height)
+	return key
+}
+
+func accountKeyPrefix(accountID string) []byte {
+	key := make([]byte, 0, 2+len(accountID)+4)
+	key = append(key, accountPrefix, byte(len(accountID)))
+	return append(key, accountID...)
+}
+
+func accountKey(accountID string, height uint32) []byte {
+	key := accountKeyPrefix(accountID)
+	var h [4]byte
+	binary.BigEndian.PutUint32(h[:], height)
+	return append(key, h[:]...)
+}
+
+// Put saves rec, replacing the record of its height, which a reorganization
+// sealed another block at.
+func (self *Store) Put(rec *Record) error {
+	if rec.Winner == nil {
+		return fmt.Errorf("record of height %d without winner", rec.Height)
+	}
+	for _, accountID := range rec.accounts() {
+		if len(accountID) > 0xff {
+			return fmt.Errorf("invalid account id %s", accountID)
+		}
+	}
+	value, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	old, err := self.get(rec.Height)
+	if err != nil && err != storcomm.ErrNotFound {
+		return err
+	}
+	self.db.NewBatch()
+	if old != nil {
+		for _, accountID := range old.accounts() {
+			self.db.BatchDelete(accountKey(accountID, old.Height))
+		}
+	}
+	self.db.BatchPut(recordKey(rec.Height), value)
+	for _, accountID := range rec.accounts() {
+		self.db.BatchPut(accountKey(accountID, rec.Height), nil)
+	}
+	return self.db.BatchCommit()
+}
+
+// Get returns the record of height, or storcomm.ErrNotFound.
+func (self *Store) Get(height uint32) (*Record, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.get(height)
+}
+
+func (self *Store) get(height uint32) (*Record, error) {
+	value, err := self.db.Get(recordKey(height))
+	if err != nil {
+		return nil, err
+	}
+	rec := &Record{}
+	if err := json.Unmarshal(value, rec); err != nil {
+		return nil, fmt.Errorf("invalid record of height %d: %s", height, err)
+	}
+	return rec, nil
+}
+
+// Query returns the records of the heights from start to end, both included,
+// limited to the rounds accountID offered a deadline for unless it is empty.
+// The range spans at most MaxQueryRange heights.
+func (self *Store) Query(start, end uint32, accountID string) ([]*Record, error) {
+	if start > end {
+		return nil, fmt.Errorf("invalid height range %d-%d", start, end)
+	}
+	if end-start >= MaxQueryRange {
+		return nil, fmt.Errorf("height range %d-%d over %d heights", start, end, MaxQueryRange)
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	var heights []uint32
+	if accountID == "" {
+		for h := start; ; h++ {
+			heights = append(heights, h)
+			if h == end {
+				break
+			}
+		}
+	} else {
+		prefix := accountKeyPrefix(accountID)
+		iter := self.db.NewIterator(prefix)
+		for iter.Next() {
+			key := iter.Key()
+			if len(key) != len(prefix)+4 {
+				continue
+			}
+			h := binary.BigEndian.Uint32(key[len(prefix):])
+			if h > end {
+				break
+			}
+			if h >= start {
+				heights = append(heights, h)
+			}
+		}
+		iter.Release()
+		if err := iter.Error(); err != nil {
+			return nil, err
+		}
+	}
+
+	recs := make([]*Record, 0, len(heights))
+	for _, h := range heights {
+		rec, err := self.get(h)
+		if err == storcomm.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
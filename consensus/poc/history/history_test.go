@@ -0,0 +1,125 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package history
+
+import (
+	"testing"
+
+	storcomm "OntologyWithPOC/core/store/common"
+)
+
+func testRecord(height uint32, winner string, others ...string) *Record {
+	rec := &Record{
+		Height:     height,
+		Hash:       "hash",
+		BaseTarget: 1000,
+		Winner:     &Deadline{PeerIndex: 1, AccountID: winner, NonceNr: 7, Deadline: 10},
+	}
+	rec.Deadlines = append(rec.Deadlines, &Deadline{PeerIndex: 1, AccountID: winner, NonceNr: 7, Deadline: 10})
+	for i, account := range others {
+		rec.Deadlines = append(rec.Deadlines, &Deadline{PeerIndex: uint32(i + 2), AccountID: account, Deadline: 20})
+	}
+	return rec
+}
+
+func TestStoreQuery(t *testing.T) {
+	store, err := NewMemStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	for h := uint32(1); h <= 10; h++ {
+		if h == 5 {
+			continue
+		}
+		rec := testRecord(h, "a", "b")
+		if h%2 == 0 {
+			rec = testRecord(h, "b", "c")
+		}
+		if err := store.Put(rec); err != nil {
+			t.Fatalf("put record %d: %s", h, err)
+		}
+	}
+	if _, err := store.Get(5); err != storcomm.ErrNotFound {
+		t.Errorf("get missing record: %v", err)
+	}
+	rec, err := store.Get(4)
+	if err != nil {
+		t.Fatalf("get record: %s", err)
+	}
+	if rec.Winner.AccountID != "b" || len(rec.Deadlines) != 2 || rec.BaseTarget != 1000 {
+		t.Errorf("record mismatch: %v", rec)
+	}
+
+	recs, err := store.Query(3, 7, "")
+	if err != nil {
+		t.Fatalf("query: %s", err)
+	}
+	if len(recs) != 4 || recs[0].Height != 3 || recs[3].Height != 7 {
+		t.Errorf("query 3-7: %d records", len(recs))
+	}
+	recs, err = store.Query(1, 10, "c")
+	if err != nil {
+		t.Fatalf("query account: %s", err)
+	}
+	if len(recs) != 5 || recs[0].Height != 2 {
+		t.Errorf("query account c: %d records", len(recs))
+	}
+	recs, err = store.Query(3, 6, "a")
+	if err != nil {
+		t.Fatalf("query account: %s", err)
+	}
+	if len(recs) != 1 || recs[0].Height != 3 {
+		t.Errorf("query account a in 3-6: %d records", len(recs))
+	}
+
+	// a reorganized block replaces the record and its account index
+	if err := store.Put(testRecord(3, "d")); err != nil {
+		t.Fatalf("replace record: %s", err)
+	}
+	if recs, _ := store.Query(3, 3, "a"); len(recs) != 0 {
+		t.Errorf("replaced record still indexed")
+	}
+	if recs, _ := store.Query(3, 3, "d"); len(recs) != 1 {
+		t.Errorf("replacing record not indexed")
+	}
+
+	if _, err := store.Query(5, 4, ""); err == nil {
+		t.Errorf("query of inverted range should fail")
+	}
+	if _, err := store.Query(0, MaxQueryRange, ""); err == nil {
+		t.Errorf("query over max range should fail")
+	}
+	if err := store.Put(&Record{Height: 11}); err == nil {
+		t.Errorf("record without winner should fail")
+	}
+}
+
+func TestSortDeadlines(t *testing.T) {
+	rec := &Record{Deadlines: []*Deadline{
+		{PeerIndex: 3, Deadline: 20},
+		{PeerIndex: 2, Deadline: 10},
+		{PeerIndex: 1, Deadline: 20},
+	}}
+	rec.SortDeadlines()
+	if rec.Deadlines[0].PeerIndex != 2 || rec.Deadlines[1].PeerIndex != 1 || rec.Deadlines[2].PeerIndex != 3 {
+		t.Errorf("sorted deadlines: %d %d %d", rec.Deadlines[0].PeerIndex, rec.Deadlines[1].PeerIndex, rec.Deadlines[2].PeerIndex)
+	}
+}
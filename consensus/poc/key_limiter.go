@@ -0,0 +1,84 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"sync"
+	"time"
+)
+
+// keyLimiter keeps a token bucket per key, refilling at rate tokens a second
+// up to burst. New keys are refused while maxKeys keys are still refilling.
+type keyLimiter struct {
+	lock    sync.Mutex
+	rate    float64
+	burst   float64
+	maxKeys int
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newKeyLimiter(rate, burst float64, maxKeys int) *keyLimiter {
+	return &keyLimiter{
+		rate:    rate,
+		burst:   burst,
+		maxKeys: maxKeys,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow takes a token of the bucket of key.
+func (self *keyLimiter) allow(key string, now time.Time) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	bucket, present := self.buckets[key]
+	if !present {
+		if len(self.buckets) >= self.maxKeys {
+			self.prune(now)
+			if len(self.buckets) >= self.maxKeys {
+				return false
+			}
+		}
+		bucket = &tokenBucket{tokens: self.burst, last: now}
+		self.buckets[key] = bucket
+	}
+	bucket.tokens += now.Sub(bucket.last).Seconds() * self.rate
+	if bucket.tokens > self.burst {
+		bucket.tokens = self.burst
+	}
+	bucket.last = now
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// prune forgets the keys whose bucket has refilled.
+func (self *keyLimiter) prune(now time.Time) {
+	for key, bucket := range self.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*self.rate >= self.burst {
+			delete(self.buckets, key)
+		}
+	}
+}
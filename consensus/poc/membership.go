@@ -0,0 +1,302 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"OntologyWithPOC/common/log"
+	"OntologyWithPOC/consensus/poc/config"
+	"github.com/ontio/ontology-crypto/keypair"
+)
+
+const (
+	// participants outside the chain config peers take the index derived from
+	// their key, above the range of the config peer indexes
+	memberIndexBase = 1 << 31
+	maxMembers      = 4096
+	// a key whose first member index is held by an active member takes the
+	// next one, up to memberProbes indexes
+	memberProbes = 8
+	// members without a verified deadline or block for this long are evicted
+	memberIdleTimeout = 30 * time.Minute
+	// deadlines of a node not admitted yet verified per second, each one
+	// regenerates a nonce, with bursts of up to memberDeadlineBurst
+	memberDeadlineRate  = 1
+	memberDeadlineBurst = 4
+	// max nodes not admitted yet whose deadline rate is tracked
+	maxPendingMembers = 256
+
+	// endorsers and committers are drawn from the winners of this many recent blocks
+	committeeWindow  = 64
+	maxCommitteeSize = 7
+	// committees of fewer recent winners are topped up with the chain config
+	// peers, so a single repeated winner does not commit blocks alone
+	minCommitteeSize = 4
+	// proposers ranked next to the leader, stepping in when it fails to propose
+	backupProposers = 3
+)
+
+func memberIndex(peerID string) uint32 {
+	return memberIndexAt(peerID, 0)
+}
+
+func memberIndexAt(peerID string, probe uint32) uint32 {
+	data := []byte(peerID)
+	if probe > 0 {
+		data = append(data, byte(probe))
+	}
+	hash := hashData(data)
+	idx := uint32(memberIndexBase) | binary.BigEndian.Uint32(hash[:4])
+	if idx == math.MaxUint32 {
+		idx--
+	}
+	return idx
+}
+
+func isMemberIndex(idx uint32) bool {
+	return idx >= memberIndexBase && idx != math.MaxUint32
+}
+
+// isMemberIndexOf returns whether idx is one of the member indexes of peerID.
+func isMemberIndexOf(peerID string, idx uint32) bool {
+	if !isMemberIndex(idx) {
+		return false
+	}
+	for probe := uint32(0); probe < memberProbes; probe++ {
+		if memberIndexAt(peerID, probe) == idx {
+			return true
+		}
+	}
+	return false
+}
+
+// participantIndex returns the index peerID takes part in consensus with:
+// its chain config one, or the one derived from its key.
+func (self *Server) participantIndex(peerID string) uint32 {
+	if idx, present := self.peerPool.GetPeerIndex(peerID); present {
+		return idx
+	}
+	return memberIndex(peerID)
+}
+
+// soleChainPeer returns whether the server is the only peer of its chain
+// config, as in the test mode.
+func (self *Server) soleChainPeer() bool {
+	self.metaLock.RLock()
+	defer self.metaLock.RUnlock()
+	return self.config != nil && len(self.config.Peers) == 1 && self.config.Peers[0].Index == self.Index
+}
+
+// deadlineSigner returns the key of the node which signed entry, so deadlines
+// of nodes never seen before are verified as well as the ones of known peers.
+func (self *Server) deadlineSigner(entry *deadlineEntry) (keypair.PublicKey, error) {
+	if entry.PeerID == "" {
+		if pub := self.peerPool.GetPeerPubKey(entry.PeerIndex); pub != nil {
+			return pub, nil
+		}
+		return nil, fmt.Errorf("unknown deadline signer %d", entry.PeerIndex)
+	}
+	pub, err := pocconfig.Pubkey(entry.PeerID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid deadline signer %s: %s", entry.PeerID, err)
+	}
+	if idx, present := self.peerPool.GetPeerIndex(entry.PeerID); present && !isMemberIndex(idx) {
+		if idx != entry.PeerIndex {
+			return nil, fmt.Errorf("deadline signer %s has index %d, not %d", entry.PeerID, idx, entry.PeerIndex)
+		}
+		return pub, nil
+	}
+	if !isMemberIndexOf(entry.PeerID, entry.PeerIndex) {
+		return nil, fmt.Errorf("deadline signer %s has no member index %d", entry.PeerID, entry.PeerIndex)
+	}
+	if known := self.peerPool.GetPeerPubKey(entry.PeerIndex); known != nil && pocconfig.PubkeyID(known) != entry.PeerID {
+		if entry.PeerIndex == self.Index {
+			// another key landed on our index, move to the next one
+			atomic.StoreUint32(&self.indexContested, 1)
+		}
+		if !isMemberIndex(entry.PeerIndex) || entry.PeerIndex == self.Index {
+			return nil, fmt.Errorf("deadline signer index %d taken", entry.PeerIndex)
+		}
+	}
+	return pub, nil
+}
+
+// admitMember binds the key of a verified deadline or block to the member
+// index it claims. Only nodes proving their plots this way take part as members.
+func (self *Server) admitMember(peerID string, idx uint32, force bool) error {
+	if !isMemberIndex(idx) {
+		return nil
+	}
+	added, err := self.peerPool.bindMember(peerID, idx, force)
+	if err != nil {
+		return err
+	}
+	if added {
+		log.Infof("server %d, admitted member %d: %s", self.Index, idx, peerID)
+	}
+	return nil
+}
+
+// proposerKey returns the key of the proposer of blk, taken from the
+// bookkeeper of the block for members not admitted yet.
+func (self *Server) proposerKey(blk *Block) keypair.PublicKey {
+	proposer := blk.getProposer()
+	if !isMemberIndex(proposer) || len(blk.Block.Header.Bookkeepers) == 0 {
+		return self.peerPool.GetPeerPubKey(proposer)
+	}
+	pub := blk.Block.Header.Bookkeepers[0]
+	peerID := pocconfig.PubkeyID(pub)
+	if !isMemberIndexOf(peerID, proposer) {
+		return nil
+	}
+	if idx, present := self.peerPool.GetPeerIndex(peerID); present && !isMemberIndex(idx) {
+		return nil
+	}
+	return pub
+}
+
+// evictIdleMembers drops the members inactive for memberIdleTimeout, with
+// their msg processors.
+func (self *Server) evictIdleMembers() {
+	for _, idx := range self.peerPool.evictMembers(memberIdleTimeout) {
+		log.Infof("server %d, evicted idle member %d", self.Index, idx)
+		self.stopPeerProcessor(idx)
+	}
+}
+
+// relocateIndex moves the server to its next member index once another key
+// claimed its current one, as long as it is not on the committee.
+func (self *Server) relocateIndex() {
+	if !isMemberIndex(self.Index) || atomic.LoadUint32(&self.indexContested) == 0 {
+		return
+	}
+	self.metaLock.RLock()
+	cfg := self.currentParticipantConfig
+	self.metaLock.RUnlock()
+	if cfg != nil {
+		for _, idx := range cfg.Committers {
+			if idx == self.Index {
+				return
+			}
+		}
+	}
+	peerID := pocconfig.PubkeyID(self.account.PublicKey)
+	for probe := uint32(0); probe+1 < memberProbes; probe++ {
+		if memberIndexAt(peerID, probe) != self.Index {
+			continue
+		}
+		next := memberIndexAt(peerID, probe+1)
+		if _, err := self.peerPool.bindMember(peerID, next, false); err != nil {
+			log.Warnf("server %d, failed to move to member index %d: %s", self.Index, next, err)
+			return
+		}
+		log.Warnf("server %d, member index taken by another key, moved to %d", self.Index, next)
+		self.Index = next
+		break
+	}
+	atomic.StoreUint32(&self.indexContested, 0)
+}
+
+// recentWinners returns the distinct proposers of the committeeWindow blocks
+// before blkNum, the latest first.
+func (self *Server) recentWinners(blkNum uint32) []uint32 {
+	winners := make([]uint32, 0)
+	seen := make(map[uint32]bool)
+	for n := blkNum; n > 1 && blkNum-n < committeeWindow; n-- {
+		blk, _ := self.blockPool.getSealedBlock(n - 1)
+		if blk == nil {
+			break
+		}
+		proposer := blk.getProposer()
+		if proposer == math.MaxUint32 || seen[proposer] {
+			continue
+		}
+		seen[proposer] = true
+		winners = append(winners, proposer)
+	}
+	return winners
+}
+
+// calcCommittee returns the endorsers and committers of a round: the recent
+// winners up to maxCommitteeSize, topped up with the chain config peers while
+// fewer than minCommitteeSize.
+func calcCommittee(winners []uint32, chain *pocconfig.ChainConfig) []uint32 {
+	committee := make([]uint32, 0, maxCommitteeSize)
+	seen := make(map[uint32]bool)
+	for _, idx := range winners {
+		if len(committee) == maxCommitteeSize {
+			break
+		}
+		committee = append(committee, idx)
+		seen[idx] = true
+	}
+	if len(committee) >= minCommitteeSize || chain == nil {
+		return committee
+	}
+	peers := make([]uint32, 0, len(chain.Peers))
+	for _, p := range chain.Peers {
+		if !seen[p.Index] {
+			peers = append(peers, p.Index)
+		}
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i] < peers[j] })
+	for _, idx := range peers {
+		if len(committee) == minCommitteeSize {
+			break
+		}
+		committee = append(committee, idx)
+	}
+	return committee
+}
+
+// quorum returns the faults C tolerated by the committee of the round and its
+// size N, which take the place of the chain config ones.
+func (cfg *BlockParticipantConfig) quorum() (uint32, uint32) {
+	n := uint32(len(cfg.Committers))
+	if n == 0 {
+		return 0, 1
+	}
+	return (n - 1) / 3, n
+}
+
+func (self *Server) quorum() (uint32, uint32) {
+	self.metaLock.RLock()
+	defer self.metaLock.RUnlock()
+
+	if self.currentParticipantConfig == nil {
+		return self.config.C, self.config.N
+	}
+	return self.currentParticipantConfig.quorum()
+}
+
+func (self *Server) committeeC() uint32 {
+	C, _ := self.quorum()
+	return C
+}
+
+func (self *Server) committeeN() uint32 {
+	_, N := self.quorum()
+	return N
+}
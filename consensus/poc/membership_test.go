@@ -0,0 +1,372 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"OntologyWithPOC/account"
+	"OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/core/ledger"
+	"OntologyWithPOC/core/types"
+	p2pmsg "OntologyWithPOC/p2pserver/message/types"
+	"github.com/ontio/ontology-crypto/keypair"
+)
+
+func TestMemberIndex(t *testing.T) {
+	acc := account.NewAccount("SHA256withECDSA")
+	id := pocconfig.PubkeyID(acc.PublicKey)
+	idx := memberIndex(id)
+	if idx != memberIndex(id) {
+		t.Errorf("member index not deterministic")
+	}
+	if !isMemberIndex(idx) {
+		t.Errorf("member index %d out of member range", idx)
+	}
+	if isMemberIndex(1) || isMemberIndex(math.MaxUint32) {
+		t.Errorf("config peer index taken as member index")
+	}
+}
+
+func TestAddMember(t *testing.T) {
+	pool := NewPeerPool(0, nil)
+	acc := account.NewAccount("SHA256withECDSA")
+	id := pocconfig.PubkeyID(acc.PublicKey)
+
+	idx, added, err := pool.addMember(id)
+	if err != nil || !added {
+		t.Fatalf("add member: %v, %s", added, err)
+	}
+	if idx != memberIndex(id) {
+		t.Errorf("member index %d, expected %d", idx, memberIndex(id))
+	}
+	if again, added, err := pool.addMember(id); err != nil || added || again != idx {
+		t.Errorf("member added twice: %d, %v, %v", again, added, err)
+	}
+	if pk := pool.GetPeerPubKey(idx); pk == nil || pocconfig.PubkeyID(pk) != id {
+		t.Errorf("member pubkey not registered")
+	}
+	if _, _, err := pool.addMember("0123"); err == nil {
+		t.Errorf("member with invalid key should fail")
+	}
+
+	// config peers keep their index
+	peer := account.NewAccount("SHA256withECDSA")
+	peerID := pocconfig.PubkeyID(peer.PublicKey)
+	if err := pool.addPeer(&pocconfig.PeerConfig{Index: 2, ID: peerID}); err != nil {
+		t.Fatalf("add peer: %s", err)
+	}
+	if idx, added, err := pool.addMember(peerID); err != nil || added || idx != 2 {
+		t.Errorf("config peer added as member: %d, %v, %v", idx, added, err)
+	}
+
+	for i := uint32(0); len(pool.members) < maxMembers; i++ {
+		pool.members[memberIndexBase+i] = time.Now()
+	}
+	other := account.NewAccount("SHA256withECDSA")
+	otherID := pocconfig.PubkeyID(other.PublicKey)
+	if _, _, err := pool.addMember(otherID); err == nil {
+		t.Errorf("members beyond maxMembers should fail")
+	}
+	// idle members make room for new ones
+	pool.members[memberIndexBase] = time.Now().Add(-memberIdleTimeout)
+	if _, added, err := pool.addMember(otherID); err != nil || !added {
+		t.Errorf("member replacing an idle one: %v, %v", added, err)
+	}
+	if _, present := pool.members[memberIndexBase]; present {
+		t.Errorf("idle member not evicted")
+	}
+}
+
+func TestMemberIndexCollision(t *testing.T) {
+	pool := NewPeerPool(0, nil)
+	victim := pocconfig.PubkeyID(account.NewAccount("SHA256withECDSA").PublicKey)
+	squatter := pocconfig.PubkeyID(account.NewAccount("SHA256withECDSA").PublicKey)
+
+	// a key landing on the first index of another one holds it
+	idx := memberIndex(victim)
+	pool.configs[idx] = &pocconfig.PeerConfig{Index: idx, ID: squatter}
+	pool.IDMap[squatter] = idx
+	pool.peers[idx] = &Peer{Index: idx}
+	pool.members[idx] = time.Now()
+
+	moved, added, err := pool.addMember(victim)
+	if err != nil || !added {
+		t.Fatalf("member on a taken index: %v, %s", added, err)
+	}
+	if moved != memberIndexAt(victim, 1) || !isMemberIndexOf(victim, moved) {
+		t.Errorf("member took index %d, expected its next one", moved)
+	}
+	if _, err := pool.bindMember(victim, idx, false); err == nil {
+		t.Errorf("binding the index of an active member should fail")
+	}
+	if _, err := pool.bindMember(victim, memberIndex(squatter), false); err == nil {
+		t.Errorf("binding an index of another key should fail")
+	}
+
+	// the chain decides for the victim
+	if added, err := pool.bindMember(victim, idx, true); err != nil || !added {
+		t.Fatalf("forced member binding: %v, %s", added, err)
+	}
+	if got, _ := pool.GetPeerIndex(victim); got != idx {
+		t.Errorf("member index %d, expected %d", got, idx)
+	}
+	if _, present := pool.GetPeerIndex(squatter); present {
+		t.Errorf("displaced member still bound")
+	}
+	if _, present := pool.peers[moved]; present {
+		t.Errorf("former index of the member still held")
+	}
+}
+
+func TestEvictMembers(t *testing.T) {
+	pool := NewPeerPool(0, nil)
+	peer := account.NewAccount("SHA256withECDSA")
+	if err := pool.addPeer(&pocconfig.PeerConfig{Index: 1, ID: pocconfig.PubkeyID(peer.PublicKey)}); err != nil {
+		t.Fatalf("add peer: %s", err)
+	}
+	idle, _, _ := pool.addMember(pocconfig.PubkeyID(account.NewAccount("SHA256withECDSA").PublicKey))
+	active, _, _ := pool.addMember(pocconfig.PubkeyID(account.NewAccount("SHA256withECDSA").PublicKey))
+	pool.members[idle] = time.Now().Add(-2 * memberIdleTimeout)
+	pool.members[active] = time.Now().Add(-2 * memberIdleTimeout)
+	pool.touchMember(active)
+
+	evicted := pool.evictMembers(memberIdleTimeout)
+	if len(evicted) != 1 || evicted[0] != idle {
+		t.Errorf("evicted %v, expected %d", evicted, idle)
+	}
+	if pool.GetPeerPubKey(idle) != nil || pool.GetPeerPubKey(active) == nil || pool.GetPeerPubKey(1) == nil {
+		t.Errorf("eviction removed the wrong peers")
+	}
+	// evicted members are no longer tracked
+	pool.peerConnected(idle)
+	pool.peerHeartbeat(idle, nil)
+}
+
+func TestDeadlineSigner(t *testing.T) {
+	server := constructServer()
+	server.peerPool = NewPeerPool(0, server)
+	acc := account.NewAccount("SHA256withECDSA")
+	id := pocconfig.PubkeyID(acc.PublicKey)
+
+	// a node never seen before signs under its member index
+	entry := &deadlineEntry{BlockNum: 2, PeerIndex: memberIndex(id), PeerID: id}
+	pub, err := server.deadlineSigner(entry)
+	if err != nil {
+		t.Fatalf("deadline signer: %s", err)
+	}
+	if pocconfig.PubkeyID(pub) != id {
+		t.Errorf("deadline signer key mismatch")
+	}
+
+	entry.PeerIndex = 1
+	if _, err := server.deadlineSigner(entry); err == nil {
+		t.Errorf("deadline signer with another index should fail")
+	}
+
+	peer := account.NewAccount("SHA256withECDSA")
+	peerID := pocconfig.PubkeyID(peer.PublicKey)
+	if err := server.peerPool.addPeer(&pocconfig.PeerConfig{Index: 1, ID: peerID}); err != nil {
+		t.Fatalf("add peer: %s", err)
+	}
+	if _, err := server.deadlineSigner(&deadlineEntry{PeerIndex: 1, PeerID: peerID}); err != nil {
+		t.Errorf("deadline signer of config peer: %s", err)
+	}
+	if _, err := server.deadlineSigner(&deadlineEntry{PeerIndex: 1}); err != nil {
+		t.Errorf("deadline signer of config peer without id: %s", err)
+	}
+	if _, err := server.deadlineSigner(&deadlineEntry{PeerIndex: 1, PeerID: id}); err == nil {
+		t.Errorf("deadline signer claiming the index of a config peer should fail")
+	}
+	if _, err := server.deadlineSigner(&deadlineEntry{PeerIndex: 5}); err == nil {
+		t.Errorf("unknown deadline signer without id should fail")
+	}
+}
+
+func TestCalcCommittee(t *testing.T) {
+	chain := &pocconfig.ChainConfig{
+		Peers: []*pocconfig.PeerConfig{{Index: 3}, {Index: 1}, {Index: 2}},
+	}
+	committee := calcCommittee(nil, chain)
+	if len(committee) != 3 || committee[0] != 1 || committee[2] != 3 {
+		t.Errorf("committee before any winner: %v", committee)
+	}
+
+	winners := []uint32{memberIndexBase + 1, memberIndexBase + 5, memberIndexBase + 7, 2}
+	committee = calcCommittee(winners, chain)
+	if len(committee) != len(winners) || committee[0] != winners[0] {
+		t.Errorf("committee of winners: %v", committee)
+	}
+
+	// a single repeated winner is topped up with the chain config peers
+	committee = calcCommittee([]uint32{memberIndexBase + 1, 2}, chain)
+	if len(committee) != minCommitteeSize || committee[1] != 2 || committee[2] != 1 || committee[3] != 3 {
+		t.Errorf("committee of few winners: %v", committee)
+	}
+	committee = calcCommittee([]uint32{memberIndexBase + 1}, nil)
+	if len(committee) != 1 {
+		t.Errorf("committee of a winner without chain config: %v", committee)
+	}
+
+	winners = nil
+	for i := uint32(0); i < maxCommitteeSize+5; i++ {
+		winners = append(winners, memberIndexBase+i)
+	}
+	committee = calcCommittee(winners, chain)
+	if len(committee) != maxCommitteeSize || committee[maxCommitteeSize-1] != winners[maxCommitteeSize-1] {
+		t.Errorf("committee capped: %v", committee)
+	}
+
+	// the chain config does not bound the participants
+	proposers, endorsers, committers := calcParticipantPeers(chain, winners[:minCommitteeSize], []uint32{memberIndexBase + 9, 1, 2, 3, 4})
+	if len(proposers) != 5 || len(endorsers) != 4 || len(committers) != 4 {
+		t.Errorf("participants: %v, %v, %v", proposers, endorsers, committers)
+	}
+}
+
+func TestCommitteeQuorum(t *testing.T) {
+	for _, c := range []struct {
+		size int
+		C, N uint32
+	}{{0, 0, 1}, {1, 0, 1}, {3, 0, 3}, {4, 1, 4}, {7, 2, 7}} {
+		cfg := &BlockParticipantConfig{Committers: make([]uint32, c.size)}
+		if C, N := cfg.quorum(); C != c.C || N != c.N {
+			t.Errorf("quorum of %d: C %d N %d, expected C %d N %d", c.size, C, N, c.C, c.N)
+		}
+	}
+}
+
+func TestProposerRanking(t *testing.T) {
+	server := constructServer()
+	server.peerPool = NewPeerPool(0, server)
+	server.peersDeadLine = newTestPeersDeadline()
+	acc := account.NewAccount("SHA256withECDSA")
+	member, _, err := server.peerPool.addMember(pocconfig.PubkeyID(acc.PublicKey))
+	if err != nil {
+		t.Fatalf("add member: %s", err)
+	}
+	server.peersDeadLine.addEntry(&deadlineEntry{BlockNum: 3, PeerIndex: 2, Deadline: 100})
+	server.peersDeadLine.addEntry(&deadlineEntry{BlockNum: 3, PeerIndex: member, Deadline: 40})
+
+	if rank := server.getProposerRank(3, member); rank != 0 {
+		t.Errorf("member with earliest deadline ranked %d", rank)
+	}
+	if !server.is2ndProposer(3, 2) {
+		t.Errorf("peer with later deadline should be 2nd proposer")
+	}
+	if rank := server.getProposerRank(3, 5); rank != 2 {
+		t.Errorf("peer without deadline ranked %d", rank)
+	}
+	server.peerPool.peerConnected(member)
+	if !server.isProposer(3, member) || server.isProposer(3, 2) {
+		t.Errorf("member with earliest deadline should be the proposer")
+	}
+}
+
+func TestUnknownNodeNotAdmitted(t *testing.T) {
+	server := constructServer()
+	server.peerPool = NewPeerPool(0, server)
+	server.msgRecvC = make(map[uint32]chan *p2pMsgPayload)
+	server.memberMsgC = make(chan *p2pmsg.ConsensusPayload, 1)
+	acc := account.NewAccount("SHA256withECDSA")
+
+	server.NewConsensusPayload(&p2pmsg.ConsensusPayload{Owner: acc.PublicKey})
+	if _, present := server.peerPool.GetPeerIndex(pocconfig.PubkeyID(acc.PublicKey)); present {
+		t.Errorf("node admitted before any verified deadline")
+	}
+	if len(server.msgRecvC) != 0 {
+		t.Errorf("processor started for an unknown node")
+	}
+	if len(server.memberMsgC) != 1 {
+		t.Errorf("msg of unknown node not queued for deadline verification")
+	}
+	// msgs beyond the queue are dropped rather than blocking
+	server.NewConsensusPayload(&p2pmsg.ConsensusPayload{Owner: acc.PublicKey})
+
+	idx := memberIndex(pocconfig.PubkeyID(acc.PublicKey))
+	if err := server.admitMember(pocconfig.PubkeyID(acc.PublicKey), idx, false); err != nil {
+		t.Fatalf("admit member: %s", err)
+	}
+	if pk := server.proposerKey(&Block{Block: &types.Block{Header: &types.Header{Bookkeepers: []keypair.PublicKey{acc.PublicKey}}},
+		Info: &pocconfig.PocBlockInfo{Proposer: idx}}); pk == nil {
+		t.Errorf("proposer key of admitted member not found")
+	}
+}
+
+func TestMemberDeadlineRateLimit(t *testing.T) {
+	blockpool, err := buildTestBlockPool(t)
+	if err != nil {
+		t.Fatalf("buildTestBlockPool err:%s", err)
+	}
+	defer cleanTestChainStore()
+
+	server := constructServer()
+	server.blockPool = blockpool
+	server.peerPool = NewPeerPool(0, server)
+	// a bucket refilling slower than nonces are regenerated
+	burst := 2
+	server.memberLimiter = newKeyLimiter(0.001, float64(burst), maxPendingMembers)
+	defLedger := ledger.DefLedger
+	ledger.DefLedger = blockpool.chainStore.db
+	defer func() { ledger.DefLedger = defLedger }()
+	genesis, genesisHash := blockpool.getSealedBlock(0)
+	acc := account.NewAccount("SHA256withECDSA")
+	peerID := pocconfig.PubkeyID(acc.PublicKey)
+	newEntry := func(nonce uint64) *deadlineEntry {
+		scoopIndex, scoop, deadline, err := nonceDeadline(peerID, nonce, genesis)
+		if err != nil {
+			t.Fatalf("nonceDeadline err:%s", err)
+		}
+		return signDeadlineEntry(acc, &deadlineEntry{
+			BlockNum:   1,
+			PeerIndex:  memberIndex(peerID),
+			PeerID:     peerID,
+			Deadline:   deadline,
+			AccountID:  peerID,
+			NonceNr:    nonce,
+			ScoopIndex: scoopIndex,
+			Scoop:      scoop,
+			PrevHash:   genesisHash,
+		})
+	}
+
+	// badly signed entries are rejected before taking tokens of the sender
+	unsigned := newEntry(0)
+	unsigned.Sig = nil
+	for i := 0; i <= burst; i++ {
+		if err := server.verifyDeadlineEntry(unsigned, peerID); err == nil || strings.Contains(err.Error(), "too many") {
+			t.Fatalf("unsigned entry: %v", err)
+		}
+	}
+	for i := 0; i < burst; i++ {
+		if err := server.verifyDeadlineEntry(newEntry(uint64(i)), peerID); err != nil {
+			t.Fatalf("entry %d of burst: %s", i, err)
+		}
+	}
+	if err := server.verifyDeadlineEntry(newEntry(uint64(burst)), peerID); err == nil || !strings.Contains(err.Error(), "too many") {
+		t.Errorf("entry beyond burst: %v", err)
+	}
+	// deadlines relayed by peers are not limited
+	if err := server.verifyDeadlineEntry(newEntry(uint64(burst)), ""); err != nil {
+		t.Errorf("entry of peer: %s", err)
+	}
+}
@@ -0,0 +1,421 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"OntologyWithPOC/account"
+	"OntologyWithPOC/common"
+	"OntologyWithPOC/common/config"
+	"OntologyWithPOC/common/log"
+	actorTypes "OntologyWithPOC/consensus/actor"
+	"OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/consensus/poc/plot"
+	"OntologyWithPOC/consensus/poc/shabal"
+)
+
+const (
+	MiningRunning = "running"
+	MiningPaused  = "paused"
+	MiningStopped = "stopped"
+)
+
+// file in the data dir of the node the mining state is persisted to
+const miningStateFile = "mining.json"
+
+// capacities are configured in MB
+const mb = 1024 * 1024
+
+// MiningController drives the local miner: scanning the plots for deadlines
+// and plotting the plot dirs up to their capacity. Its state is persisted,
+// except pausing which only lasts until the node restarts.
+type MiningController struct {
+	lock    sync.RWMutex
+	path    string
+	account *account.Account
+	status  *actorTypes.MiningStatus
+
+	fitC  chan struct{}
+	stopC chan struct{} // closed to interrupt plotting when not running
+	quitC chan struct{}
+}
+
+func newMiningController(dataDir string, acc *account.Account) (*MiningController, error) {
+	ctl := &MiningController{
+		path:    filepath.Join(dataDir, miningStateFile),
+		account: acc,
+		status:  &actorTypes.MiningStatus{State: MiningRunning},
+		fitC:    make(chan struct{}, 1),
+		stopC:   make(chan struct{}),
+		quitC:   make(chan struct{}),
+	}
+	data, err := ioutil.ReadFile(ctl.path)
+	if err == nil {
+		if err := json.Unmarshal(data, ctl.status); err != nil {
+			return nil, fmt.Errorf("invalid mining state %s: %s", ctl.path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read mining state %s: %s", ctl.path, err)
+	}
+	if ctl.status.State == MiningPaused {
+		ctl.status.State = MiningRunning
+	}
+	if ctl.status.Capacities == nil {
+		ctl.status.Capacities = make(map[string]uint64)
+	}
+	if ctl.status.State != MiningRunning {
+		close(ctl.stopC)
+	}
+	return ctl, nil
+}
+
+// Mining reports whether deadlines are to be scanned and proposed.
+func (self *MiningController) Mining() bool {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	return self.status.State == MiningRunning
+}
+
+func (self *MiningController) Status() *actorTypes.MiningStatus {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	return self.statusLocked()
+}
+
+func (self *MiningController) statusLocked() *actorTypes.MiningStatus {
+	status := *self.status
+	status.Capacities = make(map[string]uint64)
+	status.ExcludedPlots = nil
+	for _, dir := range self.plotDirsLocked() {
+		status.Capacities[dir.Path] = dir.Capacity
+		excluded, _ := plot.ListExcluded(dir.Path)
+		status.ExcludedPlots = append(status.ExcludedPlots, excluded...)
+	}
+	return &status
+}
+
+func (self *MiningController) Start() (*actorTypes.MiningStatus, error) {
+	return self.setState(MiningRunning)
+}
+
+func (self *MiningController) Stop() (*actorTypes.MiningStatus, error) {
+	return self.setState(MiningStopped)
+}
+
+func (self *MiningController) Pause() (*actorTypes.MiningStatus, error) {
+	return self.setState(MiningPaused)
+}
+
+func (self *MiningController) setState(state string) (*actorTypes.MiningStatus, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	prev := self.status.State
+	if prev == state {
+		return self.statusLocked(), nil
+	}
+	if state == MiningPaused && prev == MiningStopped {
+		return nil, fmt.Errorf("mining is stopped")
+	}
+	self.status.State = state
+	if err := self.saveLocked(); err != nil {
+		self.status.State = prev
+		return nil, err
+	}
+	if state == MiningRunning {
+		self.stopC = make(chan struct{})
+		self.wake()
+	} else if prev == MiningRunning {
+		close(self.stopC)
+	}
+	log.Infof("mining %s", state)
+	return self.statusLocked(), nil
+}
+
+// SetCapacity changes the capacity in MB of the plot dir, which is grown or
+// shrunk accordingly in the background.
+func (self *MiningController) SetCapacity(dir string, capacity uint64) (*actorTypes.MiningStatus, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	found := false
+	for _, d := range pocconfig.PlotDirs(config.DefConfig.Consensus.PlotDirs, config.DefConfig.Genesis.POC) {
+		if d.Path == dir {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("unknown plot dir %s", dir)
+	}
+	prev, present := self.status.Capacities[dir]
+	self.status.Capacities[dir] = capacity
+	if err := self.saveLocked(); err != nil {
+		if present {
+			self.status.Capacities[dir] = prev
+		} else {
+			delete(self.status.Capacities, dir)
+		}
+		return nil, err
+	}
+	self.wake()
+	return self.statusLocked(), nil
+}
+
+// SetRewardAddress records address as the reward address the node's plot
+// account was last bound to, see Server.bindRewardAddress.
+func (self *MiningController) SetRewardAddress(address string) (*actorTypes.MiningStatus, error) {
+	if _, err := common.AddressFromBase58(address); err != nil {
+		return nil, fmt.Errorf("invalid reward address %s: %s", address, err)
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	prev := self.status.RewardAddress
+	self.status.RewardAddress = address
+	if err := self.saveLocked(); err != nil {
+		self.status.RewardAddress = prev
+		return nil, err
+	}
+	return self.statusLocked(), nil
+}
+
+// PlotDirs returns the configured plot dirs with the capacities set at runtime.
+func (self *MiningController) PlotDirs() []*config.POCPlotDir {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	return self.plotDirsLocked()
+}
+
+func (self *MiningController) plotDirsLocked() []*config.POCPlotDir {
+	var dirs []*config.POCPlotDir
+	for _, dir := range pocconfig.PlotDirs(config.DefConfig.Consensus.PlotDirs, config.DefConfig.Genesis.POC) {
+		d := *dir
+		if capacity, present := self.status.Capacities[d.Path]; present {
+			d.Capacity = capacity
+		}
+		dirs = append(dirs, &d)
+	}
+	return dirs
+}
+
+func (self *MiningController) saveLocked() error {
+	data, err := json.Marshal(self.status)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(self.path), 0755); err != nil {
+		return fmt.Errorf("create data dir: %s", err)
+	}
+	tmp := self.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("write mining state: %s", err)
+	}
+	return os.Rename(tmp, self.path)
+}
+
+func (self *MiningController) wake() {
+	select {
+	case self.fitC <- struct{}{}:
+	default:
+	}
+}
+
+// run plots the plot dirs up to their capacity whenever mining is running and
+// the capacity changed, plotting is interrupted when mining stops.
+func (self *MiningController) run() {
+	self.wake()
+	for {
+		select {
+		case <-self.fitC:
+			self.lock.RLock()
+			running := self.status.State == MiningRunning
+			stopC := self.stopC
+			dirs := self.plotDirsLocked()
+			self.lock.RUnlock()
+			if running {
+				fitPlotDirs(self.account, dirs, stopC)
+			}
+		case <-self.quitC:
+			return
+		}
+	}
+}
+
+func (self *MiningController) close() {
+	close(self.quitC)
+}
+
+// fitPlotDirs brings every plot dir to its own capacity, the dirs are
+// independent disks so they are handled concurrently.
+func fitPlotDirs(acc *account.Account, dirs []*config.POCPlotDir, stopC <-chan struct{}) {
+	var wg sync.WaitGroup
+	for _, dir := range dirs {
+		wg.Add(1)
+		go func(dir *config.POCPlotDir) {
+			defer wg.Done()
+			if err := fitPlotDir(acc, dir.Path, dir.Capacity, stopC); err != nil && err != plot.ErrStopped {
+				log.Errorf("plot dir %s: %s", dir.Path, err)
+			}
+		}(dir)
+	}
+	wg.Wait()
+}
+
+// fitPlotDir grows or shrinks the plots of dir to capacity MB. Growing first
+// resumes the interrupted plots of the account, then plots a new file.
+// Shrinking removes whole complete plots, newest first. Plots are never
+// truncated: a scan holding one open keeps reading it after it is unlinked.
+func fitPlotDir(acc *account.Account, dir string, capacity uint64, stopC <-chan struct{}) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	accountID := pocconfig.PubkeyID(acc.PubKey())
+	incomplete, err := plot.Incomplete(dir, accountID)
+	if err != nil {
+		return err
+	}
+	for _, path := range incomplete {
+		if err := fillPlot(path, stopC); err != nil {
+			return err
+		}
+	}
+
+	plots, err := plot.List(dir)
+	if err != nil {
+		return err
+	}
+	used := uint64(0)
+	sizes := make(map[string]uint64)
+	modTimes := make(map[string]time.Time)
+	// excluded plots keep their space until repaired or removed by the operator
+	excluded, err := plot.ListExcluded(dir)
+	if err != nil {
+		return err
+	}
+	for _, path := range excluded {
+		if fi, err := os.Stat(path); err == nil {
+			used += uint64(fi.Size())
+		}
+	}
+	for _, path := range plots {
+		fi, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		sizes[path] = uint64(fi.Size())
+		modTimes[path] = fi.ModTime()
+		used += uint64(fi.Size())
+	}
+
+	budget := capacity * mb
+	// a new plot takes its header on top of its nonces
+	if used+plot.HeaderSize < budget {
+		nonceCount := (budget - used - plot.HeaderSize) / shabal.NonceSize
+		if nonceCount == 0 {
+			return nil
+		}
+		free, err := plot.FreeSpace(dir)
+		if err != nil {
+			return err
+		}
+		if free < nonceCount*shabal.NonceSize {
+			return fmt.Errorf("not enough disk space for %d MB of plots", (budget-used)/mb)
+		}
+		startNonce := rand.New(rand.NewSource(time.Now().UnixNano())).Uint64() % (math.MaxUint64 - nonceCount)
+		p, err := plot.Create(dir, &plot.Header{
+			Layout:     plot.LayoutNonce,
+			AccountID:  accountID,
+			StartNonce: startNonce,
+			NonceCount: nonceCount,
+		})
+		if err != nil {
+			return err
+		}
+		defer p.Close()
+		log.Infof("plotting %d nonces into %s", nonceCount, p.Path)
+		return p.FillUntil(1, nil, stopC)
+	}
+
+	sort.Slice(plots, func(i, j int) bool {
+		return modTimes[plots[i]].After(modTimes[plots[j]])
+	})
+	for _, path := range plots {
+		if used <= budget {
+			break
+		}
+		if err := os.Remove(path); err != nil {
+			log.Error(err)
+			continue
+		}
+		log.Infof("plot %s removed to fit %d MB", path, capacity)
+		used -= sizes[path]
+	}
+	return nil
+}
+
+func fillPlot(path string, stopC <-chan struct{}) error {
+	p, err := plot.OpenForWrite(path)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	log.Infof("resume plotting %s", path)
+	return p.FillUntil(1, nil, stopC)
+}
+
+func (self *Server) handleMiningControl(msg interface{}) *actorTypes.MiningStatusRsp {
+	var status *actorTypes.MiningStatus
+	var err error
+	switch m := msg.(type) {
+	case *actorTypes.StartMining:
+		status, err = self.miner.Start()
+	case *actorTypes.StopMining:
+		status, err = self.miner.Stop()
+	case *actorTypes.PauseMining:
+		status, err = self.miner.Pause()
+	case *actorTypes.SetMiningCapacity:
+		status, err = self.miner.SetCapacity(m.PlotDir, m.Capacity)
+	case *actorTypes.SetRewardAddress:
+		if err = self.bindRewardAddress(m.Address); err == nil {
+			status, err = self.miner.SetRewardAddress(m.Address)
+		}
+	case *actorTypes.GetMiningStatus:
+		status = self.miner.Status()
+	default:
+		err = fmt.Errorf("unknown mining control %T", msg)
+	}
+	if err == nil && !self.miner.Mining() {
+		// no more proposals from the deadline scanned so far
+		self.deadlineLock.Lock()
+		self.deadlineProof = nil
+		self.deadlineLock.Unlock()
+	}
+	return &actorTypes.MiningStatusRsp{Status: status, Error: err}
+}
@@ -0,0 +1,198 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"encoding/hex"
+	"sync"
+
+	actorTypes "OntologyWithPOC/consensus/actor"
+	"OntologyWithPOC/consensus/poc/plot"
+	"OntologyWithPOC/events"
+	"OntologyWithPOC/events/message"
+)
+
+// number of sealed rounds kept in the mining history
+const miningHistoryLen = 100
+
+// miningInfo collects what the node mined in the recent rounds, for the
+// mining info endpoints.
+type miningInfo struct {
+	lock    sync.RWMutex
+	round   *actorTypes.MiningRoundInfo
+	history []*actorTypes.MiningRoundInfo
+}
+
+func newMiningInfo() *miningInfo {
+	return &miningInfo{}
+}
+
+func copyRoundInfo(round *actorTypes.MiningRoundInfo) *actorTypes.MiningRoundInfo {
+	if round == nil {
+		return nil
+	}
+	r := *round
+	return &r
+}
+
+// startRound makes round the current one, and returns its info.
+func (self *miningInfo) startRound(round *miningRound) *actorTypes.MiningRoundInfo {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.round = &actorTypes.MiningRoundInfo{
+		BlockNum:   round.BlockNum,
+		PrevHash:   round.PrevHash.ToHexString(),
+		BaseTarget: round.BaseTarget,
+		GenSig:     hex.EncodeToString(round.GenSig),
+	}
+	return copyRoundInfo(self.round)
+}
+
+func (self *miningInfo) currentLocked(blkNum uint32, prevHash string) *actorTypes.MiningRoundInfo {
+	if self.round == nil || self.round.BlockNum != blkNum || self.round.PrevHash != prevHash {
+		return nil
+	}
+	return self.round
+}
+
+func (self *miningInfo) addScanned(round *miningRound, nonces uint64) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if cur := self.currentLocked(round.BlockNum, round.PrevHash.ToHexString()); cur != nil {
+		cur.ScannedNonces += nonces
+	}
+}
+
+// offerDeadline records the best deadline of the node in the current round.
+func (self *miningInfo) offerDeadline(proof *deadlineProof, proposer uint32) *actorTypes.DeadlineInfo {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	cur := self.currentLocked(proof.BlockNum, proof.PrevHash.ToHexString())
+	if cur == nil {
+		return nil
+	}
+	if cur.BestDeadline != nil && cur.BestDeadline.Deadline <= proof.Deadline {
+		return nil
+	}
+	cur.BestDeadline = &actorTypes.DeadlineInfo{
+		BlockNum:  proof.BlockNum,
+		Proposer:  proposer,
+		AccountID: proof.AccountID,
+		NonceNr:   proof.NonceNr,
+		Deadline:  proof.Deadline,
+	}
+	d := *cur.BestDeadline
+	return &d
+}
+
+// sealRound records the winner of the round of blk in the history.
+func (self *miningInfo) sealRound(blk *Block) {
+	if blk.Info == nil {
+		return
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	prevHash := blk.getPrevBlockHash()
+	round := copyRoundInfo(self.currentLocked(blk.getBlockNum(), prevHash.ToHexString()))
+	if round == nil {
+		// not mined by the node
+		round = &actorTypes.MiningRoundInfo{
+			BlockNum: blk.getBlockNum(),
+			PrevHash: prevHash.ToHexString(),
+		}
+	}
+	round.BaseTarget = blk.Info.BaseTarget
+	round.Winner = &actorTypes.DeadlineInfo{
+		BlockNum:  blk.getBlockNum(),
+		Proposer:  blk.getProposer(),
+		AccountID: blk.Info.PlotAccount,
+		NonceNr:   blk.Info.NonceNr,
+		Deadline:  blk.Info.Deadline,
+	}
+	self.history = append(self.history, round)
+	if len(self.history) > miningHistoryLen {
+		self.history = self.history[len(self.history)-miningHistoryLen:]
+	}
+}
+
+func (self *miningInfo) get() (*actorTypes.MiningRoundInfo, []*actorTypes.MiningRoundInfo) {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	history := make([]*actorTypes.MiningRoundInfo, 0, len(self.history))
+	for _, round := range self.history {
+		history = append(history, copyRoundInfo(round))
+	}
+	return copyRoundInfo(self.round), history
+}
+
+// plottedNonces returns the number of nonces plotted in the plots of dirs.
+func plottedNonces(dirs []string) uint64 {
+	var nonces uint64
+	for _, dir := range dirs {
+		plots, err := plot.List(dir)
+		if err != nil {
+			continue
+		}
+		for _, path := range plots {
+			p, err := plot.Open(path)
+			if err != nil {
+				continue
+			}
+			if n, err := p.NoncesWritten(); err == nil {
+				nonces += n
+			}
+			p.Close()
+		}
+	}
+	return nonces
+}
+
+func publishMiningEvent(topic string, msg interface{}) {
+	if events.DefActorPublisher != nil {
+		events.DefActorPublisher.Publish(topic, msg)
+	}
+}
+
+func (self *Server) getMiningInfo() *actorTypes.MiningInfoRsp {
+	var dirs []string
+	for _, dir := range self.miner.PlotDirs() {
+		dirs = append(dirs, dir.Path)
+	}
+	round, history := self.miningInfo.get()
+	return &actorTypes.MiningInfoRsp{
+		Info: &actorTypes.MiningInfo{
+			Status:        self.miner.Status(),
+			PlottedNonces: plottedNonces(dirs),
+			Round:         round,
+			History:       history,
+		},
+	}
+}
+
+// onMiningRound publishes the start of the mining of round.
+func (self *Server) onMiningRound(round *miningRound) {
+	publishMiningEvent(message.TOPIC_POC_MINING_ROUND, self.miningInfo.startRound(round))
+}
+
+// onBestDeadline publishes the proof improving the best deadline of the node.
+func (self *Server) onBestDeadline(proof *deadlineProof) {
+	if d := self.miningInfo.offerDeadline(proof, self.Index); d != nil {
+		publishMiningEvent(message.TOPIC_POC_BEST_DEADLINE, d)
+	}
+}
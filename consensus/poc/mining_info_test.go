@@ -0,0 +1,82 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"testing"
+
+	"OntologyWithPOC/common"
+	"OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/core/types"
+)
+
+func TestMiningInfo(t *testing.T) {
+	info := newMiningInfo()
+	round := constructMiningRound(t, 2, constructPrevBlock())
+	if r := info.startRound(round); r.BlockNum != 2 || r.BaseTarget != round.BaseTarget {
+		t.Errorf("round info: %v", r)
+	}
+	info.addScanned(round, 10)
+	info.addScanned(round, 5)
+	proof := &deadlineProof{BlockNum: 2, PrevHash: round.PrevHash, AccountID: "0123", NonceNr: 7, Deadline: 100}
+	if d := info.offerDeadline(proof, 1); d == nil || d.Deadline != 100 || d.Proposer != 1 {
+		t.Errorf("best deadline: %v", d)
+	}
+	worse := *proof
+	worse.Deadline = 200
+	if d := info.offerDeadline(&worse, 1); d != nil {
+		t.Errorf("worse deadline recorded: %v", d)
+	}
+	stale := *proof
+	stale.Deadline = 50
+	stale.PrevHash = common.Uint256{1}
+	if d := info.offerDeadline(&stale, 1); d != nil {
+		t.Errorf("deadline of other round recorded: %v", d)
+	}
+
+	cur, history := info.get()
+	if cur.ScannedNonces != 15 || cur.BestDeadline.Deadline != 100 || len(history) != 0 {
+		t.Errorf("mining info: %v, %d rounds", cur, len(history))
+	}
+
+	blk := &Block{
+		Block: &types.Block{Header: &types.Header{Height: 2, PrevBlockHash: round.PrevHash}},
+		Info:  &pocconfig.PocBlockInfo{Proposer: 3, Deadline: 80, BaseTarget: 1200},
+	}
+	info.sealRound(blk)
+	_, history = info.get()
+	if len(history) != 1 {
+		t.Fatalf("%d rounds in history", len(history))
+	}
+	if h := history[0]; h.ScannedNonces != 15 || h.BestDeadline.Deadline != 100 || h.Winner.Proposer != 3 || h.BaseTarget != 1200 {
+		t.Errorf("sealed round: %v", h)
+	}
+
+	for i := 0; i < miningHistoryLen; i++ {
+		blk.Block.Header.Height = uint32(3 + i)
+		info.sealRound(blk)
+	}
+	_, history = info.get()
+	if len(history) != miningHistoryLen || history[0].BlockNum != 3 {
+		t.Errorf("history of %d rounds from %d", len(history), history[0].BlockNum)
+	}
+	if history[0].ScannedNonces != 0 {
+		t.Errorf("round not mined has scanned nonces")
+	}
+}
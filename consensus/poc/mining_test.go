@@ -0,0 +1,175 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"OntologyWithPOC/account"
+	"OntologyWithPOC/common/config"
+	"OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/consensus/poc/plot"
+)
+
+func withPlotDirs(dirs ...string) func() {
+	local := config.DefConfig.Consensus.PlotDirs
+	var plotDirs []*config.POCPlotDir
+	for _, dir := range dirs {
+		plotDirs = append(plotDirs, &config.POCPlotDir{Path: dir})
+	}
+	config.DefConfig.Consensus.PlotDirs = plotDirs
+	return func() {
+		config.DefConfig.Consensus.PlotDirs = local
+	}
+}
+
+func TestMiningControllerState(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "poc-mining")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+	plotDir := filepath.Join(dataDir, "plots")
+	defer withPlotDirs(plotDir)()
+
+	acc := account.NewAccount("SHA256withECDSA")
+	ctl, err := newMiningController(dataDir, acc)
+	if err != nil {
+		t.Fatalf("new mining controller: %s", err)
+	}
+	if !ctl.Mining() {
+		t.Errorf("mining should run by default")
+	}
+
+	if _, err := ctl.SetCapacity(plotDir, 10); err != nil {
+		t.Errorf("set capacity: %s", err)
+	}
+	if _, err := ctl.SetCapacity(filepath.Join(dataDir, "other"), 10); err == nil {
+		t.Errorf("capacity of unknown plot dir should fail")
+	}
+	if _, err := ctl.SetRewardAddress("invalid"); err == nil {
+		t.Errorf("invalid reward address should fail")
+	}
+	address := acc.Address.ToBase58()
+	if _, err := ctl.SetRewardAddress(address); err != nil {
+		t.Errorf("set reward address: %s", err)
+	}
+
+	status, err := ctl.Pause()
+	if err != nil || status.State != MiningPaused || ctl.Mining() {
+		t.Fatalf("pause mining: %v, %v", status, err)
+	}
+
+	// pausing does not survive restarts
+	ctl, err = newMiningController(dataDir, acc)
+	if err != nil {
+		t.Fatalf("reload mining controller: %s", err)
+	}
+	status = ctl.Status()
+	if status.State != MiningRunning || status.RewardAddress != address || status.Capacities[plotDir] != 10 {
+		t.Errorf("reloaded mining status: %v", status)
+	}
+
+	if _, err := ctl.Stop(); err != nil {
+		t.Fatalf("stop mining: %s", err)
+	}
+	if _, err := ctl.Pause(); err == nil {
+		t.Errorf("pausing stopped mining should fail")
+	}
+	ctl, err = newMiningController(dataDir, acc)
+	if err != nil {
+		t.Fatalf("reload mining controller: %s", err)
+	}
+	if ctl.Mining() {
+		t.Errorf("stopped mining should stay stopped")
+	}
+	if status, err := ctl.Start(); err != nil || status.State != MiningRunning {
+		t.Errorf("start mining: %v, %v", status, err)
+	}
+}
+
+func TestFitPlotDirShrink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "poc-plot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	acc := account.NewAccount("SHA256withECDSA")
+	accountID := pocconfig.PubkeyID(acc.PublicKey)
+	for _, start := range []uint64{100, 200} {
+		if _, err := plot.Generate(dir, accountID, start, 1); err != nil {
+			t.Fatalf("generate plot: %s", err)
+		}
+	}
+	if err := fitPlotDir(acc, dir, 0, nil); err != nil {
+		t.Fatalf("fit plot dir: %s", err)
+	}
+	plots, err := plot.List(dir)
+	if err != nil || len(plots) != 0 {
+		t.Errorf("plots left: %v, %v", plots, err)
+	}
+}
+
+func TestFitPlotDirExcluded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "poc-plot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	acc := account.NewAccount("SHA256withECDSA")
+	path, err := plot.Generate(dir, pocconfig.PubkeyID(acc.PublicKey), 100, 4)
+	if err != nil {
+		t.Fatalf("generate plot: %s", err)
+	}
+	if _, err := plot.Exclude(path); err != nil {
+		t.Fatalf("exclude plot: %s", err)
+	}
+	// the space of the excluded plot fills the capacity
+	if err := fitPlotDir(acc, dir, 1, nil); err != nil {
+		t.Fatalf("fit plot dir: %s", err)
+	}
+	plots, err := plot.List(dir)
+	if err != nil || len(plots) != 0 {
+		t.Errorf("plotted over excluded plot: %v, %v", plots, err)
+	}
+}
+
+func TestFitPlotDirStopped(t *testing.T) {
+	dir, err := ioutil.TempDir("", "poc-plot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	acc := account.NewAccount("SHA256withECDSA")
+	stopC := make(chan struct{})
+	close(stopC)
+	if err := fitPlotDir(acc, dir, 1, stopC); err != plot.ErrStopped {
+		t.Fatalf("fit plot dir: %v", err)
+	}
+	incomplete, err := plot.Incomplete(dir, pocconfig.PubkeyID(acc.PublicKey))
+	if err != nil || len(incomplete) != 1 {
+		t.Errorf("stopped plotting should leave one plot to resume: %v, %v", incomplete, err)
+	}
+}
@@ -200,7 +200,21 @@ func (self *Server) constructBlock(blkNum uint32, prevBlkHash common.Uint256, tx
 	}
 
 	txRoot := common.ComputeMerkleRoot(txHash)
-	blockRoot := ledger.DefLedger.GetBlockRootWithNewTxRoots(lastBlock.Block.Header.Height, []common.Uint256{lastBlock.Block.Header.TransactionsRoot, txRoot})
+	// the block root covers the blocks not submitted to the ledger yet
+	startHeight := ledger.DefLedger.GetCurrentBlockHeight()
+	if startHeight >= blkNum {
+		return nil, fmt.Errorf("constructBlock blknum:%d, ledger height:%d", blkNum, startHeight)
+	}
+	txRoots := make([]common.Uint256, 0, blkNum-startHeight+1)
+	for n := startHeight; n < blkNum; n++ {
+		blk, _ := self.blockPool.getSealedBlock(n)
+		if blk == nil {
+			return nil, fmt.Errorf("constructBlock getblock failed blknum:%d", n)
+		}
+		txRoots = append(txRoots, blk.Block.Header.TransactionsRoot)
+	}
+	txRoots = append(txRoots, txRoot)
+	blockRoot := ledger.DefLedger.GetBlockRootWithNewTxRoots(startHeight, txRoots)
 
 	blkHeader := &types.Header{
 		PrevBlockHash:    prevBlkHash,
@@ -227,7 +241,7 @@ func (self *Server) constructBlock(blkNum uint32, prevBlkHash common.Uint256, tx
 	return blk, nil
 }
 
-func (self *Server) constructProposalMsg(blkNum uint32, sysTxs, userTxs []*types.Transaction, chainconfig *pocconfig.ChainConfig) (*blockProposalMsg, error) {
+func (self *Server) constructProposalMsg(blkNum uint32, sysTxs, userTxs []*types.Transaction, chainconfig *pocconfig.ChainConfig, proof *deadlineProof) (*blockProposalMsg, error) {
 	prevBlk, prevBlkHash := self.blockPool.getSealedBlock(blkNum - 1)
 	if prevBlk == nil {
 		return nil, fmt.Errorf("failed to get prevBlock (%d)", blkNum-1)
@@ -244,10 +258,26 @@ func (self *Server) constructProposalMsg(blkNum uint32, sysTxs, userTxs []*types
 	if chainconfig != nil {
 		lastConfigBlkNum = blkNum
 	}
+	baseTarget, err := self.nextBaseTarget(blkNum, blocktimestamp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate base target: %s", err)
+	}
 	pocBlkInfo := &pocconfig.PocBlockInfo{}
 	pocBlkInfo.LastConfigBlockNum = lastConfigBlkNum
 	pocBlkInfo.NewChainConfig = chainconfig
 	pocBlkInfo.Proposer = self.Index
+	pocBlkInfo.BaseTarget = baseTarget
+	pocBlkInfo.CumulativeDifficulty = cumulativeDifficulty(prevBlk, baseTarget)
+	pocBlkInfo.GenSig = nextGenSig(prevBlk.Info.GenSig, self.account.PublicKey)
+	pocBlkInfo.Deadline = proof.Deadline
+	if len(proof.ZKProof) > 0 {
+		pocBlkInfo.ZKProof = proof.ZKProof
+	} else {
+		pocBlkInfo.NonceNr = proof.NonceNr
+	}
+	if proof.AccountID != pocconfig.PubkeyID(self.account.PublicKey) {
+		pocBlkInfo.PlotAccount = proof.AccountID
+	}
 
 	consensusPayload, err := json.Marshal(pocBlkInfo)
 	if err != nil {
@@ -22,6 +22,7 @@ import (
 	"OntologyWithPOC/common"
 	"OntologyWithPOC/common/serialization"
 	pocconfig "OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/consensus/poc/shabal"
 	"bytes"
 	"encoding/json"
 	"errors"
@@ -57,11 +58,94 @@ type ConsensusMsg interface {
 	Serialize() ([]byte, error)
 }
 
+// upper bound of the entries carried by one deadline msg
+const maxDeadlineEntries = 64
+
+// deadlineEntry is the deadline a peer found for a block, signed by the peer
+// itself so that it can be relayed by others without being altered. PeerID is
+// the key of the peer, which may be outside the chain config peers. A deadline
+// proved by ZKProof carries neither its nonce nor its scoop.
+type deadlineEntry struct {
+	BlockNum   uint32 `json:"block_num"`
+	PeerIndex  uint32 `json:"peer_index"`
+	PeerID     string `json:"peer_id"`
+	Deadline   uint64 `json:"deadline"`
+	AccountID  string `json:"account_id"`
+	NonceNr    uint64 `json:"nonce_nr"`
+	ScoopIndex uint32 `json:"scoop_index"`
+	Scoop      []byte `json:"scoop"`
+	ZKProof    []byte `json:"zk_proof,omitempty"`
+	// hash of the block the deadline was mined on top of
+	PrevHash common.Uint256 `json:"prev_hash"`
+	Sig      []byte         `json:"sig"`
+}
+
+// signedData returns the entry as signed by its owner, without the signature.
+func (entry *deadlineEntry) signedData() ([]byte, error) {
+	unsigned := *entry
+	unsigned.Sig = nil
+	data, err := json.Marshal(&unsigned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal deadline entry: %s", err)
+	}
+	return data, nil
+}
+
+// Hash returns the hash signed by the owner of the entry.
+func (entry *deadlineEntry) Hash() (common.Uint256, error) {
+	data, err := entry.signedData()
+	if err != nil {
+		return common.Uint256{}, err
+	}
+	return hashData(data), nil
+}
+
+func (entry *deadlineEntry) verifyFormat() error {
+	if len(entry.ZKProof) > 0 {
+		if len(entry.ZKProof) > maxZKProofSize {
+			return fmt.Errorf("zk proof too large: %d", len(entry.ZKProof))
+		}
+		if len(entry.Scoop) != 0 {
+			return fmt.Errorf("scoop of zk proved deadline")
+		}
+	} else if len(entry.Scoop) != shabal.ScoopSize {
+		return fmt.Errorf("invalid scoop len %d", len(entry.Scoop))
+	}
+	if entry.ScoopIndex >= shabal.ScoopCount {
+		return fmt.Errorf("invalid scoop index %d", entry.ScoopIndex)
+	}
+	return nil
+}
+
+// Verify checks the entry is signed by pub, the key of peer PeerIndex. Whether
+// the peer may claim the plot account of the entry is up to verifyPlotBinding.
+func (entry *deadlineEntry) Verify(pub keypair.PublicKey) error {
+	if err := entry.verifyFormat(); err != nil {
+		return err
+	}
+	if pub == nil {
+		return fmt.Errorf("no pubkey of peer %d", entry.PeerIndex)
+	}
+	hash, err := entry.Hash()
+	if err != nil {
+		return err
+	}
+	sig, err := signature.Deserialize(entry.Sig)
+	if err != nil {
+		return fmt.Errorf("deserialize deadline sig: %s", err)
+	}
+	if !signature.Verify(pub, hash[:], sig) {
+		return fmt.Errorf("failed to verify deadline sig of peer %d", entry.PeerIndex)
+	}
+	return nil
+}
+
+// deadLineMsg gossips deadline entries, the sender's own one and the ones it
+// relays for other peers. Entries are signed by their owners, their
+// signatures are checked against the owners' keys when they are received.
 type deadLineMsg struct {
-	BlockNum      uint32                       `json:"block_num"`
-	PeerIndex     uint32                       `json:"peer_index"`
-	PeerDeadLine  uint64                       `json:"peer_deadline"`
-	PeersDeadLine map[uint32]map[uint32]uint64 `json:"block_peers_deadline"`
+	BlockNum uint32           `json:"block_num"`
+	Entries  []*deadlineEntry `json:"entries"`
 }
 
 func (msg *deadLineMsg) Type() MsgType {
@@ -69,6 +153,17 @@ func (msg *deadLineMsg) Type() MsgType {
 }
 
 func (msg *deadLineMsg) Verify(pub keypair.PublicKey) error {
+	if len(msg.Entries) == 0 || len(msg.Entries) > maxDeadlineEntries {
+		return fmt.Errorf("invalid deadline entry count %d", len(msg.Entries))
+	}
+	for _, entry := range msg.Entries {
+		if entry == nil {
+			return fmt.Errorf("nil deadline entry")
+		}
+		if err := entry.verifyFormat(); err != nil {
+			return fmt.Errorf("deadline entry of peer %d: %s", entry.PeerIndex, err)
+		}
+	}
 	return nil
 }
 
@@ -82,6 +177,9 @@ func (msg *deadLineMsg) Serialize() ([]byte, error) {
 
 type blockProposalMsg struct {
 	Block *Block `json:"block"`
+
+	// generation signature of the parent block, known once it is sealed
+	prevGenSig []byte
 }
 
 func (msg *blockProposalMsg) Type() MsgType {
@@ -120,6 +218,25 @@ func (msg *blockProposalMsg) Verify(pub keypair.PublicKey) error {
 		}
 	}
 
+	if msg.prevGenSig != nil {
+		return msg.verifyGenSig(pub)
+	}
+
+	return nil
+}
+
+// verifyGenSig checks the generation signature of the proposal is derived from
+// the one of its parent and the proposer key.
+func (msg *blockProposalMsg) verifyGenSig(pub keypair.PublicKey) error {
+	if msg.Block.Info == nil {
+		return errors.New("no poc info in block")
+	}
+	if pub == nil {
+		return fmt.Errorf("no proposer key of block %d", msg.GetBlockNum())
+	}
+	if !bytes.Equal(msg.Block.Info.GenSig, nextGenSig(msg.prevGenSig, pub)) {
+		return fmt.Errorf("invalid generation signature of block %d", msg.GetBlockNum())
+	}
 	return nil
 }
 
@@ -30,12 +30,15 @@ import (
 	"OntologyWithPOC/core/types"
 )
 
+var testPrevGenSig = make([]byte, 32)
+
 func constructProposalMsgTest(acc *account.Account) *blockProposalMsg {
 	txRoot := common.ComputeMerkleRoot(nil)
 	pocBlkInfo := &pocconfig.PocBlockInfo{
 		Proposer:           1,
 		LastConfigBlockNum: 12,
 		NewChainConfig:     nil,
+		GenSig:             nextGenSig(testPrevGenSig, acc.PublicKey),
 	}
 	consensusPayload, err := json.Marshal(pocBlkInfo)
 	if err != nil {
@@ -87,6 +90,24 @@ func TestBlockProposalMsgVerify(t *testing.T) {
 	t.Log("TestBlockProposalMsgVerify Verify succ\n")
 }
 
+func TestBlockProposalMsgVerifyGenSig(t *testing.T) {
+	acc := account.NewAccount("SHA256withECDSA")
+	msg := constructProposalMsgTest(acc)
+	msg.prevGenSig = testPrevGenSig
+	if err := msg.Verify(acc.PublicKey); err != nil {
+		t.Errorf("blockProposalMsg Verify with gensig failed: %v", err)
+	}
+
+	other := account.NewAccount("SHA256withECDSA")
+	if err := msg.verifyGenSig(other.PublicKey); err == nil {
+		t.Errorf("gensig of another proposer should fail")
+	}
+	msg.prevGenSig = msg.Block.Info.GenSig
+	if err := msg.Verify(acc.PublicKey); err == nil {
+		t.Errorf("gensig on another parent should fail")
+	}
+}
+
 func constructEndorseMsg(acc *account.Account, proposal *blockProposalMsg, blkHash common.Uint256) (*blockEndorseMsg, error) {
 	sig, _ := signature.Sign(acc, blkHash[:])
 	msg := &blockEndorseMsg{
@@ -108,7 +108,6 @@ func (self *Syncer) stop() {
 }
 
 func (self *Syncer) run() {
-	self.server.quitWg.Add(1)
 	defer self.server.quitWg.Done()
 
 	for {
@@ -244,17 +243,22 @@ func (self *Syncer) blockConsensusDone(blks BlockFromPeers) *Block {
 	for _, blk := range blks {
 		proposers[blk.getProposer()] += 1
 	}
+	// among the blocks agreed on by enough peers, follow the heaviest chain
+	var best *Block
 	for proposerId, cnt := range proposers {
-		if cnt > int(self.server.config.C) {
+		if cnt > int(self.server.committeeC()) {
 			// find the block
 			for _, blk := range blks {
 				if blk.getProposer() == proposerId {
-					return blk
+					if best == nil || heavierBlock(blk, best) {
+						best = blk
+					}
+					break
 				}
 			}
 		}
 	}
-	return nil
+	return best
 }
 
 func (self *Syncer) getCurrentTargetBlockNum() uint32 {
@@ -272,7 +276,7 @@ func (self *Syncer) blockCheckMerkleRoot(blks BlockFromPeers) *Block {
 		merkleRoot[blk.getPrevBlockMerkleRoot()] += 1
 	}
 	for merklerootvalue, cnt := range merkleRoot {
-		if cnt > int(self.server.config.C) {
+		if cnt > int(self.server.committeeC()) {
 			// find the block
 			for _, blk := range blks {
 				if blk.getPrevBlockMerkleRoot() == merklerootvalue {
@@ -27,7 +27,6 @@ import (
 	"bytes"
 	"fmt"
 	"math"
-	"sort"
 )
 
 func (self *Server) GetCurrentBlockNo() uint32 {
@@ -65,8 +64,8 @@ func (self *Server) isPeerActive(peerIdx uint32, blockNum uint32) bool {
 }
 
 //
-// the first proposer as leader-proposer,
-// all other proposer as 2nd-proposer
+// the proposer with the earliest verified deadline as leader-proposer,
+// the next backupProposers ones as 2nd-proposer
 // before propose-timeout, only proposal from leader-proposer is accepted
 //
 func (self *Server) isProposer(blockNum uint32, peerIdx uint32) bool {
@@ -78,20 +77,19 @@ func (self *Server) isProposer(blockNum uint32, peerIdx uint32) bool {
 			return false
 		}
 		// the first active proposer
-		for _, id := range self.currentParticipantConfig.Proposers {
+		for _, id := range self.peersDeadLine.ranking(blockNum) {
 			if self.isPeerAlive(id, blockNum) {
 				return peerIdx == id
 			}
 		}
 	}
 
-	// TODO: proposer check for non-current block
 	return false
 }
 
 func (self *Server) is2ndProposer(blockNum uint32, peerIdx uint32) bool {
 	rank := self.getProposerRank(blockNum, peerIdx)
-	return rank > 0 && rank <= int(self.config.C)
+	return rank > 0 && rank <= backupProposers
 }
 
 func (self *Server) getProposerRank(blockNum uint32, peerIdx uint32) int {
@@ -108,13 +106,14 @@ func (self *Server) isEndorser(blockNum uint32, peerIdx uint32) bool {
 	// the first 2C+1 active endorsers
 	var activeN uint32
 	{
+		C, _ := self.currentParticipantConfig.quorum()
 		for _, id := range self.currentParticipantConfig.Endorsers {
 			if id == peerIdx {
 				return true
 			}
 			if self.isPeerActive(id, blockNum) {
 				activeN++
-				if activeN > self.config.C*2 {
+				if activeN > C*2 {
 					break
 				}
 			}
@@ -131,13 +130,14 @@ func (self *Server) isCommitter(blockNum uint32, peerIdx uint32) bool {
 	// the first 2C+1 active committers
 	var activeN uint32
 	{
+		C, _ := self.currentParticipantConfig.quorum()
 		for _, id := range self.currentParticipantConfig.Committers {
 			if id == peerIdx {
 				return true
 			}
 			if self.isPeerActive(id, blockNum) {
 				activeN++
-				if activeN > self.config.C*2 {
+				if activeN > C*2 {
 					break
 				}
 			}
@@ -148,16 +148,13 @@ func (self *Server) isCommitter(blockNum uint32, peerIdx uint32) bool {
 }
 
 func (self *Server) getProposerRankLocked(blockNum uint32, peerIdx uint32) int {
-	if blockNum == self.currentParticipantConfig.BlockNum {
-		for rank, id := range self.currentParticipantConfig.Proposers {
-			if id == peerIdx {
-				return rank
-			}
+	ranking := self.peersDeadLine.ranking(blockNum)
+	for rank, id := range ranking {
+		if id == peerIdx {
+			return rank
 		}
-	} else {
-		log.Errorf("todo: get proposer config for non-current blocknum:%d, current.BlockNum%d,peerIdx:%d", blockNum, self.currentParticipantConfig.BlockNum, peerIdx)
 	}
-	return len(self.currentParticipantConfig.Proposers)
+	return len(ranking)
 }
 
 func (self *Server) getHighestRankProposal(blockNum uint32, proposals []*blockProposalMsg) *blockProposalMsg {
@@ -191,7 +188,7 @@ func (self *Server) getHighestRankProposal(blockNum uint32, proposals []*blockPr
 //
 //  call this method with metaLock locked
 //
-func (self *Server) buildParticipantConfig(blkNum uint32, block *Block, chainCfg *pocconfig.ChainConfig) (*BlockParticipantConfig, error) {
+func (self *Server) buildParticipantConfig(blkNum uint32, winners []uint32, chainCfg *pocconfig.ChainConfig) (*BlockParticipantConfig, error) {
 
 	if blkNum == 0 {
 		return nil, fmt.Errorf("not participant config for genesis block")
@@ -202,156 +199,19 @@ func (self *Server) buildParticipantConfig(blkNum uint32, block *Block, chainCfg
 		ChainConfig: chainCfg,
 	}
 
-	cfg.Proposers, cfg.Endorsers, cfg.Committers = calcParticipantPeers(chainCfg, self.peersDeadLine, self.peersDeadLineBackUp)
+	cfg.Proposers, cfg.Endorsers, cfg.Committers = calcParticipantPeers(chainCfg, winners, self.peersDeadLine.ranking(blkNum))
 	log.Infof("server %d, blkNum: %d, state: %d, participants config: %v, %v, %v", self.Index, blkNum,
 		self.getState(), cfg.Proposers, cfg.Endorsers, cfg.Committers)
 
 	return cfg, nil
 }
 
-func calcParticipant(seed []byte, dposTable []uint32, k uint32) uint32 {
-	var v1, v2 uint32
-	bIdx := k / 8
-	bits1 := k % 8
-	bits2 := 8 + bits1 // L - 8 + bits1
-	if k >= 512 {
-		return math.MaxUint32
-	}
-	// Note: take 16bits random variable from vrf, if len(dposTable) is not power of 2,
-	// this algorithm will break the fairness of vrf. to be fixed
-	v1 = uint32(seed[bIdx]) >> bits1
-	if bIdx+1 < uint32(len(seed)) {
-		v2 = uint32(seed[bIdx+1])
-	} else {
-		v2 = uint32(seed[0])
-	}
-
-	v2 = v2 & ((1 << bits2) - 1)
-	v := (v2 << (8 - bits1)) + v1
-	v = v % uint32(len(dposTable))
-	return dposTable[v]
-}
-
-func calcParticipantPeers(chain *pocconfig.ChainConfig, blockPeersDeadline *blockPeersDeadline, peersDeadLineBackUp *blockPeersDeadline) ([]uint32, []uint32, []uint32) {
-	peers := make([]uint32, 0)
-	peerMap := make(map[uint32]bool)
-	c := int(chain.C)
-	blockPeersDeadline.locker.Lock()
-	defer blockPeersDeadline.locker.Unlock()
-	peersDeadLineBackUp.locker.Lock()
-	defer peersDeadLineBackUp.locker.Unlock()
-	if len(blockPeersDeadline.blockpeersdeadline) < 3 {
-		vacated := "1c9810aa9822e511d5804a9c4db9dd08497c31087b0daafa34d768a3253441fa20515e2f30f81741102af0ca3cefc4818fef16adb825fbaa8cad78647f3afb590e"
-		// 1. select peers as many as possible
-		for i := 0; i < len(chain.PosTable); i++ {
-			peerId := calcParticipant([]byte(vacated), chain.PosTable, uint32(i))
-			if peerId == math.MaxUint32 {
-				break
-			}
-			if _, present := peerMap[peerId]; !present {
-				peers = append(peers, peerId)
-				peerMap[peerId] = true
-				if len(peerMap) > (c+1)+((2*c+1)*2) || len(peerMap) == int(chain.N) {
-					break
-				}
-			}
-		}
-
-		if len(peerMap) <= c*3 {
-			for _, peer := range chain.Peers {
-				if _, present := peerMap[peer.Index]; !present {
-					peers = append(peers, peer.Index)
-					peerMap[peer.Index] = true
-				}
-				if len(peerMap) > c*3 {
-					break
-				}
-			}
-		}
-	} else {
-		type sDeadline struct {
-			index    uint32
-			deadline uint64
-		}
-
-		var keys []int
-		for k := range blockPeersDeadline.blockpeersdeadline {
-			keys = append(keys, int(k))
-		}
-		sort.Ints(keys)
-
-		var lstsDeadline []sDeadline
-
-		for peerIndex, deadline := range blockPeersDeadline.blockpeersdeadline[uint32(keys[len(keys)-3])].peersdeadline {
-			lstsDeadline = append(lstsDeadline, sDeadline{peerIndex, deadline})
-		}
-		sort.Slice(lstsDeadline, func(i, j int) bool {
-			//return lstsDeadline[i].deadline > lstsDeadline[j].deadline  // 降序
-			return lstsDeadline[i].deadline < lstsDeadline[j].deadline // 升序
-		})
-		for i := 0; i < len(lstsDeadline); i++ {
-			peers = append(peers, lstsDeadline[i].index)
-		}
-
-		if len(peers) < 3 && len(peersDeadLineBackUp.blockpeersdeadline) > 3 {
-			keys = []int{}
-			for k := range peersDeadLineBackUp.blockpeersdeadline {
-				keys = append(keys, int(k))
-			}
-			sort.Ints(keys)
-
-			var lstsDeadline []sDeadline
-
-			for peerIndex, deadline := range peersDeadLineBackUp.blockpeersdeadline[uint32(keys[len(keys)-3])].peersdeadline {
-				lstsDeadline = append(lstsDeadline, sDeadline{peerIndex, deadline})
-			}
-			sort.Slice(lstsDeadline, func(i, j int) bool {
-				//return lstsDeadline[i].deadline > lstsDeadline[j].deadline  // 降序
-				return lstsDeadline[i].deadline < lstsDeadline[j].deadline // 升序
-			})
-			for i := 0; i < len(lstsDeadline); i++ {
-				peers = append(peers, lstsDeadline[i].index)
-			}
-		}
-	}
-
-	log.Info("========================calcParticipantPeers: ", peers)
-	// [p0, p1, p2, .... p_c+1, ...    .. p_m, ....      pn]
-	//  <-- proposer  --><--- endorser --><-- committer -->
-	nCommitter := 2*c + 1
-	propsers := peers[0 : c+1]
-	n1 := (len(peers) - len(propsers)) / 2
-	endorsers0 := peers[c+1 : c+1+n1]
-	committers := peers[c+1+n1:]
-
-	// copy endorser0 to endorser
-	endorsers := make([]uint32, 0)
-	endorsers = append(endorsers, endorsers0...)
-	if len(endorsers) < nCommitter {
-		// not enough endorser, get more from committer/proposer
-		// 1. add last empty proposer
-		endorsers = append(endorsers, propsers[c])
-		// 2. add committers if not enough
-		for i := len(committers) - 1; i >= 0 && len(endorsers) < nCommitter; i-- {
-			endorsers = append(endorsers, committers[i])
-		}
-		// 3. add proposers if not enough
-		for i := c - 1; i > 0 && len(endorsers) < nCommitter; i-- {
-			endorsers = append(endorsers, propsers[i])
-		}
-	}
-	if len(committers) < nCommitter {
-		// not enough committer, get more from endorser/proposer
-		// 1. add proposers if not enough
-		for i := 1; i < len(propsers) && len(committers) < nCommitter; i++ {
-			committers = append(committers, propsers[i])
-		}
-		// 2. add init-endorsers if not enough
-		for i := len(endorsers0) - 1; i >= 0 && len(committers) < nCommitter; i-- {
-			committers = append(committers, endorsers0[i])
-		}
-	}
-	return propsers, endorsers, committers
+// calcParticipantPeers returns the proposers of a round, ranked by their
+// verified deadlines as known when the round starts, and its endorsers and
+// committers, the committee drawn from the recent winners.
+func calcParticipantPeers(chain *pocconfig.ChainConfig, winners []uint32, ranking []uint32) ([]uint32, []uint32, []uint32) {
+	committee := calcCommittee(winners, chain)
+	return ranking, committee, committee
 }
 
 //
@@ -426,7 +286,7 @@ func (self *Server) heartbeat() {
 }
 
 func (self *Server) receiveFromPeer(peerIdx uint32) (uint32, []byte, error) {
-	if C, present := self.msgRecvC[peerIdx]; present {
+	if C, present := self.peerRecvC(peerIdx); present {
 		select {
 		case payload := <-C:
 			if payload != nil {
@@ -47,6 +47,7 @@ type PeerPool struct {
 
 	peers                  map[uint32]*Peer
 	peerConnectionWaitings map[uint32]chan struct{}
+	members                map[uint32]time.Time // peers outside the chain config, to their last activity
 }
 
 func NewPeerPool(maxSize int, server *Server) *PeerPool {
@@ -58,6 +59,7 @@ func NewPeerPool(maxSize int, server *Server) *PeerPool {
 		P2pMap:                 make(map[uint32]uint64),
 		peers:                  make(map[uint32]*Peer),
 		peerConnectionWaitings: make(map[uint32]chan struct{}),
+		members:                make(map[uint32]time.Time),
 	}
 }
 
@@ -69,6 +71,7 @@ func (pool *PeerPool) clean() {
 	pool.IDMap = make(map[string]uint32)
 	pool.P2pMap = make(map[uint32]uint64)
 	pool.peers = make(map[uint32]*Peer)
+	pool.members = make(map[uint32]time.Time)
 }
 
 // FIXME: should rename to isPeerConnected
@@ -102,6 +105,144 @@ func (pool *PeerPool) addPeer(config *pocconfig.PeerConfig) error {
 	return nil
 }
 
+// addMember adds a participant outside the chain config peers under the first
+// of its member indexes not held by an active member, and reports whether it
+// was not known yet.
+func (pool *PeerPool) addMember(peerID string) (uint32, bool, error) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	if idx, present := pool.IDMap[peerID]; present {
+		pool.touchMemberLocked(idx)
+		return idx, false, nil
+	}
+	for probe := uint32(0); probe < memberProbes; probe++ {
+		idx := memberIndexAt(peerID, probe)
+		if err := pool.bindMemberLocked(peerID, idx, false); err == nil {
+			return idx, true, nil
+		} else if _, taken := pool.peers[idx]; !taken {
+			return 0, false, err
+		}
+	}
+	return 0, false, fmt.Errorf("member indexes of %s taken", peerID)
+}
+
+// bindMember binds peerID to idx, one of its member indexes, as claimed by a
+// verified deadline or block of peerID. An idle member holding idx is evicted,
+// an active one only if force is set, when the chain decided for peerID.
+func (pool *PeerPool) bindMember(peerID string, idx uint32, force bool) (bool, error) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	if known, present := pool.IDMap[peerID]; present && known == idx {
+		pool.touchMemberLocked(idx)
+		return false, nil
+	}
+	if err := pool.bindMemberLocked(peerID, idx, force); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (pool *PeerPool) bindMemberLocked(peerID string, idx uint32, force bool) error {
+	if !isMemberIndexOf(peerID, idx) {
+		return fmt.Errorf("index %d is not a member index of %s", idx, peerID)
+	}
+	peerPK, err := pocconfig.Pubkey(peerID)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal member pubkey: %s", err)
+	}
+	if known, present := pool.IDMap[peerID]; present && !isMemberIndex(known) {
+		return fmt.Errorf("%s is the chain config peer %d", peerID, known)
+	}
+	if _, present := pool.peers[idx]; present {
+		last, member := pool.members[idx]
+		if !member {
+			return fmt.Errorf("member index %d held by a chain config peer", idx)
+		}
+		if !force && time.Since(last) < memberIdleTimeout {
+			return fmt.Errorf("member index %d held by %s", idx, pool.configs[idx].ID)
+		}
+		pool.removeMemberLocked(idx)
+	}
+	if known, present := pool.IDMap[peerID]; present {
+		// the member moved to another of its indexes
+		pool.removeMemberLocked(known)
+	}
+	if len(pool.members) >= maxMembers && !pool.evictStalestLocked() {
+		return fmt.Errorf("too many members: %d", len(pool.members))
+	}
+	pool.configs[idx] = &pocconfig.PeerConfig{Index: idx, ID: peerID}
+	pool.IDMap[peerID] = idx
+	pool.peers[idx] = &Peer{
+		Index:          idx,
+		PubKey:         peerPK,
+		LastUpdateTime: time.Unix(0, 0),
+		connected:      false,
+	}
+	pool.members[idx] = time.Now()
+	return nil
+}
+
+func (pool *PeerPool) removeMemberLocked(idx uint32) {
+	if cfg, present := pool.configs[idx]; present {
+		delete(pool.IDMap, cfg.ID)
+	}
+	delete(pool.configs, idx)
+	delete(pool.peers, idx)
+	delete(pool.P2pMap, idx)
+	delete(pool.members, idx)
+	if C, present := pool.peerConnectionWaitings[idx]; present {
+		delete(pool.peerConnectionWaitings, idx)
+		close(C)
+	}
+}
+
+// evictStalestLocked evicts the member idle for the longest time, if any is
+// idle, to make room for a new one.
+func (pool *PeerPool) evictStalestLocked() bool {
+	stalest, oldest := uint32(0), time.Now().Add(-memberIdleTimeout)
+	for idx, last := range pool.members {
+		if last.Before(oldest) {
+			stalest, oldest = idx, last
+		}
+	}
+	if stalest == 0 {
+		return false
+	}
+	pool.removeMemberLocked(stalest)
+	return true
+}
+
+func (pool *PeerPool) touchMemberLocked(idx uint32) {
+	if _, present := pool.members[idx]; present {
+		pool.members[idx] = time.Now()
+	}
+}
+
+// touchMember records the activity of the member idx, keeping it from eviction.
+func (pool *PeerPool) touchMember(idx uint32) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	pool.touchMemberLocked(idx)
+}
+
+// evictMembers removes the members inactive for idle and returns their indexes.
+func (pool *PeerPool) evictMembers(idle time.Duration) []uint32 {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	evicted := make([]uint32, 0)
+	for idx, last := range pool.members {
+		if time.Since(last) >= idle {
+			pool.removeMemberLocked(idx)
+			evicted = append(evicted, idx)
+		}
+	}
+	return evicted
+}
+
 func (pool *PeerPool) getActivePeerCount() int {
 	pool.lock.RLock()
 	defer pool.lock.RUnlock()
@@ -138,6 +279,10 @@ func (pool *PeerPool) peerConnected(peerIdx uint32) {
 	pool.lock.Lock()
 	defer pool.lock.Unlock()
 
+	if _, present := pool.peers[peerIdx]; !present {
+		// evicted member
+		return
+	}
 	// new peer, rather than modify
 	pool.peers[peerIdx] = &Peer{
 		Index:          peerIdx,
@@ -155,6 +300,10 @@ func (pool *PeerPool) peerDisconnected(peerIdx uint32) {
 	pool.lock.Lock()
 	defer pool.lock.Unlock()
 
+	if _, present := pool.peers[peerIdx]; !present {
+		// evicted member
+		return
+	}
 	var lastUpdateTime time.Time
 	if p, present := pool.peers[peerIdx]; present {
 		lastUpdateTime = p.LastUpdateTime
@@ -172,6 +321,10 @@ func (pool *PeerPool) peerHandshake(peerIdx uint32, msg *peerHandshakeMsg) {
 	pool.lock.Lock()
 	defer pool.lock.Unlock()
 
+	if _, present := pool.peers[peerIdx]; !present {
+		// evicted member
+		return
+	}
 	pool.peers[peerIdx] = &Peer{
 		Index:          peerIdx,
 		PubKey:         pool.peers[peerIdx].PubKey,
@@ -186,6 +339,10 @@ func (pool *PeerPool) peerHeartbeat(peerIdx uint32, msg *peerHeartbeatMsg) {
 	pool.lock.Lock()
 	defer pool.lock.Unlock()
 
+	if _, present := pool.peers[peerIdx]; !present {
+		// evicted member
+		return
+	}
 	if C, present := pool.peerConnectionWaitings[peerIdx]; present {
 		// wake up peer connection waitings
 		delete(pool.peerConnectionWaitings, peerIdx)
@@ -0,0 +1,180 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package plot
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"OntologyWithPOC/consensus/poc/shabal"
+)
+
+// ExcludedExt is appended to the file name of a plot which failed
+// verification, List skips it so that it is no more scanned for deadlines.
+const ExcludedExt = ".bad"
+
+// NonceRange is the range [From, To) of nonce indexes of a plot.
+type NonceRange struct {
+	From uint64
+	To   uint64
+}
+
+func (r NonceRange) String() string {
+	return fmt.Sprintf("[%d, %d)", r.From, r.To)
+}
+
+// CheckReport is the result of verifying a plot.
+type CheckReport struct {
+	Path    string
+	Written uint64       // complete nonces in the file
+	Checked uint64       // nonces regenerated and compared
+	Bad     []NonceRange // corrupted nonces, sorted
+}
+
+func (r *CheckReport) Corrupted() bool {
+	return len(r.Bad) > 0
+}
+
+// BadNonces returns the number of corrupted nonces found.
+func (r *CheckReport) BadNonces() uint64 {
+	n := uint64(0)
+	for _, bad := range r.Bad {
+		n += bad.To - bad.From
+	}
+	return n
+}
+
+// verifyNonce regenerates the nonce at index and compares one of its scoops
+// with the plot. A scoop which cannot be read is corrupted as well.
+func (p *Plot) verifyNonce(index uint64, scoop uint32) bool {
+	read, err := p.ReadScoop(index, scoop)
+	if err != nil {
+		return false
+	}
+	nonce := shabal.GenNonce256(p.StartNonce+index, p.AccountID)
+	return bytes.Equal(read, nonce[scoop*shabal.ScoopSize:(scoop+1)*shabal.ScoopSize])
+}
+
+// Check verifies samples nonces of the plot picked by rnd, or every written
+// nonce if samples is 0, comparing a random scoop of each to the regenerated
+// nonce. The first and last written nonces, where interrupted writes land,
+// are always checked. Around a corrupted nonce the neighbours are checked
+// until good ones are met, so the report has the whole corrupted ranges.
+func (p *Plot) Check(samples uint64, rnd *rand.Rand) (*CheckReport, error) {
+	written, err := p.NoncesWritten()
+	if err != nil {
+		return nil, err
+	}
+	report := &CheckReport{Path: p.Path, Written: written}
+	if written == 0 {
+		return report, nil
+	}
+
+	checked := make(map[uint64]bool)
+	bad := make(map[uint64]bool)
+	check := func(index uint64) bool {
+		good, present := checked[index]
+		if !present {
+			good = p.verifyNonce(index, uint32(rnd.Intn(shabal.ScoopCount)))
+			checked[index] = good
+			if !good {
+				bad[index] = true
+			}
+		}
+		return good
+	}
+
+	var indexes []uint64
+	if samples == 0 || samples >= written {
+		for i := uint64(0); i < written; i++ {
+			indexes = append(indexes, i)
+		}
+	} else {
+		indexes = append(indexes, 0, written-1)
+		for i := uint64(0); i < samples; i++ {
+			indexes = append(indexes, uint64(rnd.Int63n(int64(written))))
+		}
+	}
+	for _, index := range indexes {
+		if check(index) {
+			continue
+		}
+		// extend to the corrupted neighbours
+		for i := index; i > 0; i-- {
+			if check(i - 1) {
+				break
+			}
+		}
+		for i := index + 1; i < written; i++ {
+			if check(i) {
+				break
+			}
+		}
+	}
+
+	report.Checked = uint64(len(checked))
+	for i := uint64(0); i < written; i++ {
+		if !bad[i] {
+			continue
+		}
+		if n := len(report.Bad); n > 0 && report.Bad[n-1].To == i {
+			report.Bad[n-1].To = i + 1
+		} else {
+			report.Bad = append(report.Bad, NonceRange{From: i, To: i + 1})
+		}
+	}
+	return report, nil
+}
+
+// Repair regenerates the nonces of ranges, the plot must be opened for write.
+func (p *Plot) Repair(ranges []NonceRange) error {
+	for _, r := range ranges {
+		for i := r.From; i < r.To; i++ {
+			if err := p.WriteNonce(i, shabal.GenNonce256(p.StartNonce+i, p.AccountID)); err != nil {
+				return err
+			}
+		}
+	}
+	return p.file.Sync()
+}
+
+// Exclude renames the plot file at path so that it is no more listed, and
+// returns its new path.
+func Exclude(path string) (string, error) {
+	excluded := path + ExcludedExt
+	return excluded, os.Rename(path, excluded)
+}
+
+// Include renames back an excluded plot file, and returns its new path.
+func Include(path string) (string, error) {
+	if !strings.HasSuffix(path, FileExt+ExcludedExt) {
+		return "", fmt.Errorf("%s is not an excluded plot", path)
+	}
+	included := strings.TrimSuffix(path, ExcludedExt)
+	return included, os.Rename(path, included)
+}
+
+// ListExcluded returns the excluded plot files in dir.
+func ListExcluded(dir string) ([]string, error) {
+	return filepath.Glob(filepath.Join(dir, "*"+FileExt+ExcludedExt))
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package plot
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"testing"
+
+	"OntologyWithPOC/consensus/poc/shabal"
+)
+
+func corruptNonces(t *testing.T, path string, from, to uint64) {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zeros := make([]byte, (to-from)*shabal.NonceSize)
+	if _, err := f.WriteAt(zeros, HeaderSize+int64(from)*shabal.NonceSize); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckAndRepair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "poc-plot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path, err := Generate(dir, testAccountID, 1000, 4)
+	if err != nil {
+		t.Fatalf("generate plot: %s", err)
+	}
+	rnd := rand.New(rand.NewSource(1))
+	p, err := OpenForWrite(path)
+	if err != nil {
+		t.Fatalf("open plot: %s", err)
+	}
+	defer p.Close()
+	report, err := p.Check(0, rnd)
+	if err != nil {
+		t.Fatalf("check plot: %s", err)
+	}
+	if report.Corrupted() || report.Checked != 4 || report.Written != 4 {
+		t.Errorf("check of sound plot: %v", report)
+	}
+
+	corruptNonces(t, path, 1, 2)
+	corruptNonces(t, path, 3, 4)
+	report, err = p.Check(0, rnd)
+	if err != nil {
+		t.Fatalf("check plot: %s", err)
+	}
+	if len(report.Bad) != 2 || report.Bad[0] != (NonceRange{1, 2}) || report.Bad[1] != (NonceRange{3, 4}) {
+		t.Errorf("corrupted ranges: %v", report.Bad)
+	}
+	if report.BadNonces() != 2 {
+		t.Errorf("corrupted nonces: %d", report.BadNonces())
+	}
+
+	// the last nonce is always sampled
+	report, err = p.Check(1, rnd)
+	if err != nil {
+		t.Fatalf("check plot: %s", err)
+	}
+	if !report.Corrupted() || report.Bad[len(report.Bad)-1] != (NonceRange{3, 4}) {
+		t.Errorf("sampled check missed the last nonce: %v", report.Bad)
+	}
+
+	if err := p.Repair([]NonceRange{{1, 2}, {3, 4}}); err != nil {
+		t.Fatalf("repair plot: %s", err)
+	}
+	report, err = p.Check(0, rnd)
+	if err != nil {
+		t.Fatalf("check plot: %s", err)
+	}
+	if report.Corrupted() {
+		t.Errorf("repaired plot still corrupted: %v", report.Bad)
+	}
+}
+
+func TestExclude(t *testing.T) {
+	dir, err := ioutil.TempDir("", "poc-plot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path, err := Generate(dir, testAccountID, 1000, 1)
+	if err != nil {
+		t.Fatalf("generate plot: %s", err)
+	}
+	excluded, err := Exclude(path)
+	if err != nil {
+		t.Fatalf("exclude plot: %s", err)
+	}
+	if plots, _ := List(dir); len(plots) != 0 {
+		t.Errorf("excluded plot listed: %v", plots)
+	}
+	if plots, _ := ListExcluded(dir); len(plots) != 1 || plots[0] != excluded {
+		t.Errorf("list excluded plots: %v", plots)
+	}
+
+	if _, err := Include(path); err == nil {
+		t.Errorf("include of a plot not excluded should fail")
+	}
+	included, err := Include(excluded)
+	if err != nil {
+		t.Fatalf("include plot: %s", err)
+	}
+	if included != path {
+		t.Errorf("included plot path %s, expected %s", included, path)
+	}
+	if plots, _ := List(dir); len(plots) != 1 {
+		t.Errorf("included plot not listed: %v", plots)
+	}
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package plot
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"OntologyWithPOC/consensus/poc/shabal"
+)
+
+// ConvertExt is appended to the file name of a plot being converted to
+// another layout, the conversion replaces the plot once complete.
+const ConvertExt = ".converting"
+
+// nonces converted at once, 16 MB
+const convertChunk = 64
+
+// The nonces converted so far are kept in a trailer after the nonces of the
+// file being converted, so that an interrupted conversion can be resumed.
+const trailerSize = 8
+
+func (p *Plot) readTrailer() (uint64, error) {
+	fi, err := p.file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if fi.Size() < p.Size()+trailerSize {
+		return 0, nil
+	}
+	buf := make([]byte, trailerSize)
+	if _, err := p.file.ReadAt(buf, p.Size()); err != nil {
+		return 0, err
+	}
+	converted := binary.LittleEndian.Uint64(buf)
+	if converted > p.NonceCount {
+		return 0, fmt.Errorf("invalid conversion progress %d of %d nonces", converted, p.NonceCount)
+	}
+	return converted, nil
+}
+
+func (p *Plot) writeTrailer(converted uint64) error {
+	buf := make([]byte, trailerSize)
+	binary.LittleEndian.PutUint64(buf, converted)
+	if err := p.file.Sync(); err != nil {
+		return err
+	}
+	_, err := p.file.WriteAt(buf, p.Size())
+	return err
+}
+
+// Convert rewrites the complete plot at path in layout. The nonces are
+// streamed chunk by chunk into a file next to the plot, which replaces it once
+// complete. A conversion stopped by closing stopC, or interrupted, resumes
+// from its last chunk. progress, if not nil, is called with the number of
+// nonces converted.
+func Convert(path string, layout Layout, progress func(converted uint64), stopC <-chan struct{}) error {
+	src, err := Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if src.Layout == layout {
+		return nil
+	}
+	written, err := src.NoncesWritten()
+	if err != nil {
+		return err
+	}
+	if written < src.NonceCount {
+		return fmt.Errorf("plot %s is incomplete: %d of %d nonces", path, written, src.NonceCount)
+	}
+
+	dst, err := openConversion(path, &src.Header, layout)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	converted, err := dst.readTrailer()
+	if err != nil {
+		return err
+	}
+	for converted < src.NonceCount {
+		select {
+		case <-stopC:
+			return ErrStopped
+		default:
+		}
+		to := converted + convertChunk
+		if to > src.NonceCount {
+			to = src.NonceCount
+		}
+		buf, err := src.readNonces(converted, to)
+		if err != nil {
+			return err
+		}
+		if err := dst.writeNonces(converted, buf); err != nil {
+			return err
+		}
+		if err := dst.writeTrailer(to); err != nil {
+			return err
+		}
+		converted = to
+		if progress != nil {
+			progress(converted)
+		}
+	}
+
+	if err := dst.file.Truncate(dst.Size()); err != nil {
+		return err
+	}
+	if err := dst.file.Sync(); err != nil {
+		return err
+	}
+	// a scan holding the plot open keeps reading the replaced file
+	return os.Rename(dst.Path, path)
+}
+
+// openConversion opens the file converting the plot at path of h to layout,
+// creating it if the conversion is not resumed.
+func openConversion(path string, h *Header, layout Layout) (*Plot, error) {
+	convPath := path + ConvertExt
+	if dst, err := OpenForWrite(convPath); err == nil {
+		if dst.Layout == layout && dst.AccountID == h.AccountID &&
+			dst.StartNonce == h.StartNonce && dst.NonceCount == h.NonceCount {
+			return dst, nil
+		}
+		dst.Close()
+		if err := os.Remove(convPath); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		// a conversion interrupted before its header was written
+		if err := os.Remove(convPath); err != nil {
+			return nil, err
+		}
+	}
+
+	hdr := *h
+	hdr.Version = HeaderVersion
+	hdr.Layout = layout
+	buf, err := hdr.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	free, err := FreeSpace(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	size := HeaderSize + hdr.NonceCount*shabal.NonceSize
+	if free < size {
+		return nil, fmt.Errorf("not enough disk space to convert %s: %d MB needed", path, size/(1024*1024))
+	}
+	f, err := os.OpenFile(convPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &Plot{Header: hdr, Path: convPath, file: f}, nil
+}
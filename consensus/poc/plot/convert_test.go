@@ -0,0 +1,147 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package plot
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "poc-plot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path, err := Generate(dir, testAccountID, 1000, 3)
+	if err != nil {
+		t.Fatalf("generate plot: %s", err)
+	}
+	original, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := Open(path)
+	if err != nil {
+		t.Fatalf("open plot: %s", err)
+	}
+	scoops, err := src.ReadScoops(7)
+	if err != nil {
+		t.Fatalf("read scoops: %s", err)
+	}
+
+	// a conversion interrupted after its first nonce
+	dst, err := openConversion(path, &src.Header, LayoutScoop)
+	if err != nil {
+		t.Fatalf("open conversion: %s", err)
+	}
+	buf, err := src.readNonces(0, 1)
+	if err != nil {
+		t.Fatalf("read nonces: %s", err)
+	}
+	if err := dst.writeNonces(0, buf); err != nil {
+		t.Fatalf("write nonces: %s", err)
+	}
+	if err := dst.writeTrailer(1); err != nil {
+		t.Fatalf("write trailer: %s", err)
+	}
+	dst.Close()
+	src.Close()
+
+	stopC := make(chan struct{})
+	close(stopC)
+	if err := Convert(path, LayoutScoop, nil, stopC); err != ErrStopped {
+		t.Errorf("stopped conversion: %v", err)
+	}
+
+	var progress []uint64
+	if err := Convert(path, LayoutScoop, func(n uint64) { progress = append(progress, n) }, nil); err != nil {
+		t.Fatalf("convert plot: %s", err)
+	}
+	if len(progress) != 1 || progress[0] != 3 {
+		t.Errorf("conversion not resumed: %v", progress)
+	}
+	if _, err := os.Stat(path + ConvertExt); !os.IsNotExist(err) {
+		t.Errorf("conversion file left: %v", err)
+	}
+
+	p, err := Open(path)
+	if err != nil {
+		t.Fatalf("open converted plot: %s", err)
+	}
+	if p.Layout != LayoutScoop {
+		t.Errorf("converted plot layout: %s", p.Layout)
+	}
+	if n, err := p.NoncesWritten(); err != nil || n != 3 {
+		t.Errorf("nonces of converted plot: %d, %v", n, err)
+	}
+	read, err := p.ReadScoops(7)
+	if err != nil || !bytes.Equal(read, scoops) {
+		t.Errorf("scoops of converted plot mismatch: %v", err)
+	}
+	report, err := p.Check(0, rand.New(rand.NewSource(1)))
+	if err != nil || report.Corrupted() {
+		t.Errorf("check of converted plot: %v, %v", report, err)
+	}
+	p.Close()
+
+	if err := Convert(path, LayoutNonce, nil, nil); err != nil {
+		t.Fatalf("convert plot back: %s", err)
+	}
+	converted, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(converted, original) {
+		t.Errorf("plot converted back differs from the original")
+	}
+}
+
+func TestConvertIncomplete(t *testing.T) {
+	dir, err := ioutil.TempDir("", "poc-plot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p, err := Create(dir, &Header{Layout: LayoutNonce, AccountID: testAccountID, StartNonce: 1, NonceCount: 2})
+	if err != nil {
+		t.Fatalf("create plot: %s", err)
+	}
+	p.Close()
+	if err := Convert(p.Path, LayoutScoop, nil, nil); err == nil {
+		t.Errorf("conversion of incomplete plot should fail")
+	}
+}
+
+func TestParseLayout(t *testing.T) {
+	for _, l := range []Layout{LayoutNonce, LayoutScoop} {
+		parsed, err := ParseLayout(l.String())
+		if err != nil || parsed != l {
+			t.Errorf("parse layout %s: %s, %v", l, parsed, err)
+		}
+	}
+	if _, err := ParseLayout("random"); err == nil {
+		t.Errorf("parse of unknown layout should fail")
+	}
+}
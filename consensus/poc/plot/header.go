@@ -0,0 +1,154 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package plot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+
+	"OntologyWithPOC/common"
+)
+
+const (
+	HeaderSize     = 256
+	HeaderVersion  = 1
+	MaxAccountSize = 128
+	FileExt        = ".plot"
+)
+
+var headerMagic = []byte("POCPLOT\x00")
+
+// Layout is the order the scoops of a plot are stored in.
+type Layout uint8
+
+const (
+	// LayoutNonce stores whole nonces one after another, as generated.
+	LayoutNonce Layout = iota
+	// LayoutScoop stores the same scoop of all nonces one after another, so
+	// that the scoops scanned for a block are read sequentially.
+	LayoutScoop
+)
+
+func (l Layout) String() string {
+	switch l {
+	case LayoutNonce:
+		return "nonce"
+	case LayoutScoop:
+		return "scoop"
+	default:
+		return fmt.Sprintf("layout(%d)", uint8(l))
+	}
+}
+
+// ParseLayout returns the layout named s.
+func ParseLayout(s string) (Layout, error) {
+	for _, l := range []Layout{LayoutNonce, LayoutScoop} {
+		if l.String() == s {
+			return l, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown plot layout %s", s)
+}
+
+// Header is stored at the beginning of every plot file, the nonces
+// [StartNonce, StartNonce+NonceCount) of AccountID follow it.
+type Header struct {
+	Version    uint16
+	Layout     Layout
+	AccountID  string
+	StartNonce uint64
+	NonceCount uint64
+}
+
+// FileName returns the canonical name of the plot file of the header.
+func (h *Header) FileName() string {
+	return fmt.Sprintf("%s_%d_%d%s", h.AccountID, h.StartNonce, h.NonceCount, FileExt)
+}
+
+func (h *Header) Serialize() ([]byte, error) {
+	if len(h.AccountID) == 0 || len(h.AccountID) > MaxAccountSize {
+		return nil, fmt.Errorf("invalid account id len %d", len(h.AccountID))
+	}
+	if h.Layout != LayoutNonce && h.Layout != LayoutScoop {
+		return nil, fmt.Errorf("unsupported plot layout %d", h.Layout)
+	}
+	if h.StartNonce+h.NonceCount < h.StartNonce {
+		return nil, fmt.Errorf("nonce range overflow: %d+%d", h.StartNonce, h.NonceCount)
+	}
+	sink := common.NewZeroCopySink(make([]byte, 0, HeaderSize))
+	sink.WriteBytes(headerMagic)
+	sink.WriteUint16(h.Version)
+	sink.WriteUint8(uint8(h.Layout))
+	sink.WriteUint64(h.StartNonce)
+	sink.WriteUint64(h.NonceCount)
+	sink.WriteString(h.AccountID)
+	sink.WriteBytes(make([]byte, HeaderSize-4-sink.Size()))
+	sink.WriteUint32(crc32.ChecksumIEEE(sink.Bytes()))
+	return sink.Bytes(), nil
+}
+
+func (h *Header) Deserialize(data []byte) error {
+	if len(data) < HeaderSize {
+		return fmt.Errorf("plot header too short: %d", len(data))
+	}
+	data = data[:HeaderSize]
+	if !bytes.Equal(data[:len(headerMagic)], headerMagic) {
+		return fmt.Errorf("not a plot file")
+	}
+	if crc32.ChecksumIEEE(data[:HeaderSize-4]) != binary.LittleEndian.Uint32(data[HeaderSize-4:]) {
+		return fmt.Errorf("plot header checksum mismatch")
+	}
+
+	source := common.NewZeroCopySource(data[len(headerMagic):])
+	version, eof := source.NextUint16()
+	if eof {
+		return fmt.Errorf("read version: %s", common.ErrIrregularData)
+	}
+	layout, eof := source.NextUint8()
+	if eof {
+		return fmt.Errorf("read layout: %s", common.ErrIrregularData)
+	}
+	startNonce, eof := source.NextUint64()
+	if eof {
+		return fmt.Errorf("read start nonce: %s", common.ErrIrregularData)
+	}
+	nonceCount, eof := source.NextUint64()
+	if eof {
+		return fmt.Errorf("read nonce count: %s", common.ErrIrregularData)
+	}
+	accountID, _, irregular, eof := source.NextString()
+	if irregular || eof || len(accountID) == 0 || len(accountID) > MaxAccountSize {
+		return fmt.Errorf("read account id: %s", common.ErrIrregularData)
+	}
+	if version != HeaderVersion {
+		return fmt.Errorf("unsupported plot version %d", version)
+	}
+	if Layout(layout) != LayoutNonce && Layout(layout) != LayoutScoop {
+		return fmt.Errorf("unsupported plot layout %d", layout)
+	}
+
+	h.Version = version
+	h.Layout = Layout(layout)
+	h.StartNonce = startNonce
+	h.NonceCount = nonceCount
+	h.AccountID = accountID
+	return nil
+}
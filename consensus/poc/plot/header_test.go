@@ -0,0 +1,67 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package plot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHeaderSerialize(t *testing.T) {
+	h := &Header{
+		Version:    HeaderVersion,
+		Layout:     LayoutNonce,
+		AccountID:  "035110e9",
+		StartNonce: 100,
+		NonceCount: 8,
+	}
+	buf, err := h.Serialize()
+	if err != nil {
+		t.Fatalf("serialize header: %s", err)
+	}
+	if len(buf) != HeaderSize {
+		t.Fatalf("header size %d", len(buf))
+	}
+	h2 := &Header{}
+	if err := h2.Deserialize(buf); err != nil {
+		t.Fatalf("deserialize header: %s", err)
+	}
+	if *h2 != *h {
+		t.Errorf("header mismatch: %v vs %v", h2, h)
+	}
+
+	buf[20] ^= 0x01
+	if err := h2.Deserialize(buf); err == nil {
+		t.Errorf("deserialize should fail on corrupted header")
+	}
+}
+
+func TestHeaderInvalid(t *testing.T) {
+	h := &Header{Version: HeaderVersion, AccountID: strings.Repeat("0", MaxAccountSize+1)}
+	if _, err := h.Serialize(); err == nil {
+		t.Errorf("serialize should fail on long account id")
+	}
+	h = &Header{Version: HeaderVersion, AccountID: "035110e9", StartNonce: ^uint64(0), NonceCount: 2}
+	if _, err := h.Serialize(); err == nil {
+		t.Errorf("serialize should fail on nonce overflow")
+	}
+	if err := h.Deserialize(make([]byte, HeaderSize)); err == nil {
+		t.Errorf("deserialize should fail without magic")
+	}
+}
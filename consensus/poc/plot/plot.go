@@ -0,0 +1,378 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package plot implements the PoC plot files: a header followed by the
+// nonces of one account, and the scoop reader used for deadline scanning.
+package plot
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"OntologyWithPOC/consensus/poc/shabal"
+)
+
+// nonces written between two syncs of the plot file
+const syncInterval = 64
+
+type Plot struct {
+	Header
+	Path string
+	file *os.File
+}
+
+// Create creates the plot file of h in dir, only the header is written.
+func Create(dir string, h *Header) (*Plot, error) {
+	h.Version = HeaderVersion
+	buf, err := h.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, h.FileName())
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &Plot{Header: *h, Path: path, file: f}, nil
+}
+
+// Open opens a plot file for reading.
+func Open(path string) (*Plot, error) {
+	return openFile(path, os.O_RDONLY)
+}
+
+// OpenForWrite opens a plot file to resume plotting.
+func OpenForWrite(path string) (*Plot, error) {
+	return openFile(path, os.O_RDWR)
+}
+
+func openFile(path string, flag int) (*Plot, error) {
+	f, err := os.OpenFile(path, flag, 0600)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, HeaderSize)
+	if _, err := io.ReadFull(f, buf); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("read plot header of %s: %s", path, err)
+	}
+	p := &Plot{Path: path, file: f}
+	if err := p.Header.Deserialize(buf); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("invalid plot %s: %s", path, err)
+	}
+	return p, nil
+}
+
+func (p *Plot) Close() error {
+	return p.file.Close()
+}
+
+func (p *Plot) nonceOffset(index uint64) int64 {
+	return HeaderSize + int64(index)*shabal.NonceSize
+}
+
+// scoopOffset returns the offset of scoop of the nonce at index, as laid
+// out by the plot.
+func (p *Plot) scoopOffset(index uint64, scoop uint32) int64 {
+	if p.Layout == LayoutScoop {
+		return HeaderSize + (int64(scoop)*int64(p.NonceCount)+int64(index))*shabal.ScoopSize
+	}
+	return p.nonceOffset(index) + int64(scoop)*shabal.ScoopSize
+}
+
+// Size returns the expected file size of the complete plot.
+func (p *Plot) Size() int64 {
+	return p.nonceOffset(p.NonceCount)
+}
+
+// NoncesWritten returns the number of complete nonces in the file. Scoop
+// ordered plots are only ever written whole.
+func (p *Plot) NoncesWritten() (uint64, error) {
+	fi, err := p.file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if p.Layout == LayoutScoop {
+		if fi.Size() < p.Size() {
+			return 0, nil
+		}
+		return p.NonceCount, nil
+	}
+	n := uint64(fi.Size()-HeaderSize) / shabal.NonceSize
+	if n > p.NonceCount {
+		n = p.NonceCount
+	}
+	return n, nil
+}
+
+// WriteNonce writes the nonce at index of the plot.
+func (p *Plot) WriteNonce(index uint64, nonce []byte) error {
+	if index >= p.NonceCount {
+		return fmt.Errorf("nonce index %d out of range %d", index, p.NonceCount)
+	}
+	if len(nonce) != shabal.NonceSize {
+		return fmt.Errorf("invalid nonce len %d", len(nonce))
+	}
+	return p.writeNonces(index, nonce)
+}
+
+// readNonces reads the nonces [from, to) whole, one after another.
+func (p *Plot) readNonces(from, to uint64) ([]byte, error) {
+	buf := make([]byte, (to-from)*shabal.NonceSize)
+	if p.Layout != LayoutScoop {
+		_, err := p.file.ReadAt(buf, p.nonceOffset(from))
+		return buf, err
+	}
+	scoops := make([]byte, (to-from)*shabal.ScoopSize)
+	for scoop := uint32(0); scoop < shabal.ScoopCount; scoop++ {
+		if _, err := p.file.ReadAt(scoops, p.scoopOffset(from, scoop)); err != nil {
+			return nil, err
+		}
+		for i := uint64(0); i < to-from; i++ {
+			copy(buf[i*shabal.NonceSize+uint64(scoop)*shabal.ScoopSize:], scoops[i*shabal.ScoopSize:(i+1)*shabal.ScoopSize])
+		}
+	}
+	return buf, nil
+}
+
+// writeNonces writes whole nonces from index from on, as laid out by the plot.
+func (p *Plot) writeNonces(from uint64, buf []byte) error {
+	if p.Layout != LayoutScoop {
+		_, err := p.file.WriteAt(buf, p.nonceOffset(from))
+		return err
+	}
+	n := uint64(len(buf)) / shabal.NonceSize
+	scoops := make([]byte, n*shabal.ScoopSize)
+	for scoop := uint32(0); scoop < shabal.ScoopCount; scoop++ {
+		for i := uint64(0); i < n; i++ {
+			copy(scoops[i*shabal.ScoopSize:(i+1)*shabal.ScoopSize], buf[i*shabal.NonceSize+uint64(scoop)*shabal.ScoopSize:])
+		}
+		if _, err := p.file.WriteAt(scoops, p.scoopOffset(from, scoop)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadScoop reads scoop of the nonce at index.
+func (p *Plot) ReadScoop(index uint64, scoop uint32) ([]byte, error) {
+	if index >= p.NonceCount {
+		return nil, fmt.Errorf("nonce index %d out of range %d", index, p.NonceCount)
+	}
+	if scoop >= shabal.ScoopCount {
+		return nil, fmt.Errorf("invalid scoop %d", scoop)
+	}
+	buf := make([]byte, shabal.ScoopSize)
+	if _, err := p.file.ReadAt(buf, p.scoopOffset(index, scoop)); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// ReadScoops reads scoop of every complete nonce, the scoop of the nonce at
+// index i is at buf[i*ScoopSize:(i+1)*ScoopSize].
+func (p *Plot) ReadScoops(scoop uint32) ([]byte, error) {
+	n, err := p.NoncesWritten()
+	if err != nil {
+		return nil, err
+	}
+	return p.ReadScoopRange(scoop, 0, n)
+}
+
+// ReadScoopRange reads scoop of the nonces [from, to). Scoop ordered plots
+// are read at once, nonce ordered ones with one read per nonce.
+func (p *Plot) ReadScoopRange(scoop uint32, from, to uint64) ([]byte, error) {
+	if scoop >= shabal.ScoopCount {
+		return nil, fmt.Errorf("invalid scoop %d", scoop)
+	}
+	if from > to || to > p.NonceCount {
+		return nil, fmt.Errorf("nonce range [%d, %d) out of range %d", from, to, p.NonceCount)
+	}
+	buf := make([]byte, (to-from)*shabal.ScoopSize)
+	if p.Layout == LayoutScoop {
+		if _, err := p.file.ReadAt(buf, p.scoopOffset(from, scoop)); err != nil {
+			return nil, err
+		}
+		return buf, nil
+	}
+	for i := uint64(0); i < to-from; i++ {
+		off := p.scoopOffset(from+i, scoop)
+		if _, err := p.file.ReadAt(buf[i*shabal.ScoopSize:(i+1)*shabal.ScoopSize], off); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+// ErrStopped is returned by FillUntil when plotting was stopped before the
+// plot was complete.
+var ErrStopped = errors.New("plotting stopped")
+
+// Fill generates the nonces missing from the plot with workers goroutines.
+// Nonces are written in order so that an interrupted plot can be resumed
+// from the number of complete nonces in the file. progress, if not nil, is
+// called with the number of nonces written.
+func (p *Plot) Fill(workers int, progress func(written uint64)) error {
+	return p.FillUntil(workers, progress, nil)
+}
+
+// FillUntil is Fill, stopping with ErrStopped once stopC is closed. The plot
+// can be resumed later on.
+func (p *Plot) FillUntil(workers int, progress func(written uint64), stopC <-chan struct{}) error {
+	if p.Layout != LayoutNonce {
+		return fmt.Errorf("%s ordered plots are only written by Convert", p.Layout)
+	}
+	from, err := p.NoncesWritten()
+	if err != nil {
+		return err
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	type result struct {
+		index uint64
+		nonce []byte
+	}
+	indexC := make(chan uint64)
+	resultC := make(chan *result, workers)
+	quitC := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexC {
+				resultC <- &result{index, shabal.GenNonce256(p.StartNonce+index, p.AccountID)}
+			}
+		}()
+	}
+	go func() {
+		defer close(indexC)
+		for index := from; index < p.NonceCount; index++ {
+			select {
+			case indexC <- index:
+			case <-quitC:
+				return
+			case <-stopC:
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(resultC)
+	}()
+
+	pending := make(map[uint64][]byte)
+	next := from
+	for r := range resultC {
+		if err != nil {
+			continue
+		}
+		pending[r.index] = r.nonce
+		for nonce, present := pending[next]; present; nonce, present = pending[next] {
+			delete(pending, next)
+			if err = p.WriteNonce(next, nonce); err != nil {
+				close(quitC)
+				break
+			}
+			next++
+			if next%syncInterval == 0 {
+				if err = p.file.Sync(); err != nil {
+					close(quitC)
+					break
+				}
+			}
+			if progress != nil {
+				progress(next)
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if err = p.file.Sync(); err != nil {
+		return err
+	}
+	if next < p.NonceCount {
+		return ErrStopped
+	}
+	return nil
+}
+
+// Generate plots nonceCount nonces of accountID from startNonce into dir.
+func Generate(dir string, accountID string, startNonce uint64, nonceCount uint64) (string, error) {
+	p, err := Create(dir, &Header{
+		Layout:     LayoutNonce,
+		AccountID:  accountID,
+		StartNonce: startNonce,
+		NonceCount: nonceCount,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer p.Close()
+	return p.Path, p.Fill(1, nil)
+}
+
+// Incomplete returns the plot files of accountID in dir which have not been
+// completely plotted.
+func Incomplete(dir string, accountID string) ([]string, error) {
+	plots, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, path := range plots {
+		p, err := Open(path)
+		if err != nil {
+			continue
+		}
+		n, err := p.NoncesWritten()
+		p.Close()
+		if err == nil && p.AccountID == accountID && p.Layout == LayoutNonce && n < p.NonceCount {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// List returns the plot files in dir.
+func List(dir string) ([]string, error) {
+	return filepath.Glob(filepath.Join(dir, "*"+FileExt))
+}
+
+// FreeSpace returns the bytes available to plots on the disk holding dir.
+func FreeSpace(dir string) (uint64, error) {
+	fs := syscall.Statfs_t{}
+	if err := syscall.Statfs(dir, &fs); err != nil {
+		return 0, err
+	}
+	return fs.Bavail * uint64(fs.Bsize), nil
+}
@@ -0,0 +1,207 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package plot
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"OntologyWithPOC/consensus/poc/shabal"
+)
+
+const testAccountID = "035110e9"
+
+func TestGenerate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "poc-plot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path, err := Generate(dir, testAccountID, 1000, 2)
+	if err != nil {
+		t.Fatalf("generate plot: %s", err)
+	}
+	if filepath.Base(path) != "035110e9_1000_2.plot" {
+		t.Errorf("plot file name: %s", path)
+	}
+	plots, err := List(dir)
+	if err != nil || len(plots) != 1 || plots[0] != path {
+		t.Errorf("list plots: %v, %v", plots, err)
+	}
+
+	p, err := Open(path)
+	if err != nil {
+		t.Fatalf("open plot: %s", err)
+	}
+	defer p.Close()
+	if p.AccountID != testAccountID || p.StartNonce != 1000 || p.NonceCount != 2 {
+		t.Errorf("plot header: %v", p.Header)
+	}
+	fi, _ := os.Stat(path)
+	if fi.Size() != p.Size() {
+		t.Errorf("plot size %d, expected %d", fi.Size(), p.Size())
+	}
+
+	nonce := shabal.GenNonce256(1001, testAccountID)
+	scoop, err := p.ReadScoop(1, 7)
+	if err != nil {
+		t.Fatalf("read scoop: %s", err)
+	}
+	if !bytes.Equal(scoop, nonce[7*shabal.ScoopSize:8*shabal.ScoopSize]) {
+		t.Errorf("scoop mismatch")
+	}
+	scoops, err := p.ReadScoops(7)
+	if err != nil {
+		t.Fatalf("read scoops: %s", err)
+	}
+	if len(scoops) != 2*shabal.ScoopSize || !bytes.Equal(scoops[shabal.ScoopSize:], scoop) {
+		t.Errorf("scoops mismatch")
+	}
+	if _, err := p.ReadScoop(2, 7); err == nil {
+		t.Errorf("read scoop should fail out of range")
+	}
+	scoops, err = p.ReadScoopRange(7, 1, 2)
+	if err != nil {
+		t.Fatalf("read scoop range: %s", err)
+	}
+	if !bytes.Equal(scoops, scoop) {
+		t.Errorf("scoop range mismatch")
+	}
+	if _, err := p.ReadScoopRange(7, 1, 3); err == nil {
+		t.Errorf("read scoop range should fail out of range")
+	}
+}
+
+func TestPartialPlot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "poc-plot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p, err := Create(dir, &Header{AccountID: testAccountID, StartNonce: 5, NonceCount: 3})
+	if err != nil {
+		t.Fatalf("create plot: %s", err)
+	}
+	nonce := shabal.GenNonce256(5, testAccountID)
+	if err := p.WriteNonce(0, nonce); err != nil {
+		t.Fatalf("write nonce: %s", err)
+	}
+	p.Close()
+
+	p, err = Open(p.Path)
+	if err != nil {
+		t.Fatalf("open plot: %s", err)
+	}
+	defer p.Close()
+	n, err := p.NoncesWritten()
+	if err != nil || n != 1 {
+		t.Errorf("nonces written: %d, %v", n, err)
+	}
+	scoops, err := p.ReadScoops(0)
+	if err != nil {
+		t.Fatalf("read scoops: %s", err)
+	}
+	if !bytes.Equal(scoops, nonce[:shabal.ScoopSize]) {
+		t.Errorf("scoops of partial plot mismatch")
+	}
+
+	if _, err := Create(dir, &Header{AccountID: testAccountID, StartNonce: 5, NonceCount: 3}); err == nil {
+		t.Errorf("create should fail on existing plot")
+	}
+}
+
+func TestFillResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "poc-plot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p, err := Create(dir, &Header{AccountID: testAccountID, StartNonce: 7, NonceCount: 5})
+	if err != nil {
+		t.Fatalf("create plot: %s", err)
+	}
+	if err := p.WriteNonce(0, shabal.GenNonce256(7, testAccountID)); err != nil {
+		t.Fatalf("write nonce: %s", err)
+	}
+	p.Close()
+	// interrupted in the middle of the second nonce
+	if err := os.Truncate(p.Path, HeaderSize+shabal.NonceSize+100); err != nil {
+		t.Fatal(err)
+	}
+
+	incomplete, err := Incomplete(dir, testAccountID)
+	if err != nil || len(incomplete) != 1 || incomplete[0] != p.Path {
+		t.Fatalf("incomplete plots: %v, %v", incomplete, err)
+	}
+
+	p, err = OpenForWrite(p.Path)
+	if err != nil {
+		t.Fatalf("open plot: %s", err)
+	}
+	defer p.Close()
+	var written []uint64
+	if err := p.Fill(3, func(n uint64) { written = append(written, n) }); err != nil {
+		t.Fatalf("fill plot: %s", err)
+	}
+	if len(written) != 4 || written[len(written)-1] != 5 {
+		t.Errorf("progress: %v", written)
+	}
+	for i := uint64(0); i < 5; i++ {
+		nonce := shabal.GenNonce256(7+i, testAccountID)
+		scoop, err := p.ReadScoop(i, shabal.ScoopCount-1)
+		if err != nil {
+			t.Fatalf("read scoop: %s", err)
+		}
+		if !bytes.Equal(scoop, nonce[shabal.NonceSize-shabal.ScoopSize:]) {
+			t.Errorf("nonce %d mismatch", i)
+		}
+	}
+	if incomplete, _ := Incomplete(dir, testAccountID); len(incomplete) != 0 {
+		t.Errorf("plot should be complete: %v", incomplete)
+	}
+}
+
+func TestFillUntilStopped(t *testing.T) {
+	dir, err := ioutil.TempDir("", "poc-plot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p, err := Create(dir, &Header{AccountID: testAccountID, StartNonce: 7, NonceCount: 32})
+	if err != nil {
+		t.Fatalf("create plot: %s", err)
+	}
+	defer p.Close()
+	stopC := make(chan struct{})
+	close(stopC)
+	if err := p.FillUntil(1, nil, stopC); err != ErrStopped {
+		t.Fatalf("fill stopped plot: %v", err)
+	}
+	n, err := p.NoncesWritten()
+	if err != nil || n >= p.NonceCount {
+		t.Errorf("nonces written by stopped plot: %d, %v", n, err)
+	}
+}
@@ -0,0 +1,189 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"OntologyWithPOC/account"
+	"OntologyWithPOC/common"
+	"OntologyWithPOC/common/config"
+	"OntologyWithPOC/common/log"
+	"OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/core/ledger"
+	"OntologyWithPOC/core/signature"
+	scommon "OntologyWithPOC/core/store/common"
+	"OntologyWithPOC/core/store/overlaydb"
+	"OntologyWithPOC/core/types"
+	"OntologyWithPOC/core/utils"
+	gov "OntologyWithPOC/smartcontract/service/native/governance"
+	"OntologyWithPOC/smartcontract/service/native/plot_binding"
+	nutils "OntologyWithPOC/smartcontract/service/native/utils"
+	"OntologyWithPOC/smartcontract/service/neovm"
+	"github.com/ontio/ontology-crypto/keypair"
+)
+
+// blockPlotAccount returns the plot account of the nonce a block proposed by
+// pub names: the proposer's own account unless the block says otherwise.
+func blockPlotAccount(blk *Block, pub keypair.PublicKey) string {
+	if blk.Info != nil && blk.Info.PlotAccount != "" {
+		return blk.Info.PlotAccount
+	}
+	return pocconfig.PubkeyID(pub)
+}
+
+// plotRewardAddress returns the address the rewards of plot account
+// accountID go to: the one bound in the plot binding contract, or the address
+// of the plot account itself if it has none.
+func plotRewardAddress(memdb *overlaydb.MemDB, accountID string) (common.Address, error) {
+	pub, err := pocconfig.Pubkey(accountID)
+	if err != nil {
+		return common.ADDRESS_EMPTY, fmt.Errorf("invalid plot account %s: %s", accountID, err)
+	}
+	value, err := GetStorageValue(memdb, ledger.DefLedger, nutils.PlotBindingContractAddress,
+		plot_binding.RewardAddressKey(keypair.SerializePublicKey(pub)))
+	if err == scommon.ErrNotFound {
+		return types.AddressFromPubKey(pub), nil
+	}
+	if err != nil {
+		return common.ADDRESS_EMPTY, fmt.Errorf("get reward address of plot account %s: %s", accountID, err)
+	}
+	return common.AddressParseFromBytes(value)
+}
+
+// bindRewardAddressTransaction builds the plot binding transaction sending
+// the rewards of the plot account of acc to rewardAddr, paid and signed by
+// acc.
+func bindRewardAddressTransaction(acc *account.Account, nonce uint32, rewardAddr common.Address) (*types.Transaction, error) {
+	param := &plot_binding.BindRewardAddressParam{
+		PlotPubkey:    keypair.SerializePublicKey(acc.PublicKey),
+		RewardAddress: rewardAddr,
+	}
+	code, err := utils.BuildNativeInvokeCode(nutils.PlotBindingContractAddress, 0, plot_binding.BIND_REWARD_ADDRESS, []interface{}{param})
+	if err != nil {
+		return nil, err
+	}
+	mutable := utils.NewInvokeTransaction(code)
+	mutable.GasPrice = config.DefConfig.Common.GasPrice
+	mutable.GasLimit = neovm.MIN_TRANSACTION_GAS
+	mutable.Nonce = nonce
+	mutable.Payer = acc.Address
+	hash := mutable.Hash()
+	sig, err := signature.Sign(acc, hash[:])
+	if err != nil {
+		return nil, err
+	}
+	mutable.Sigs = []types.Sig{{
+		PubKeys: []keypair.PublicKey{acc.PublicKey},
+		M:       1,
+		SigData: [][]byte{sig},
+	}}
+	return mutable.IntoImmutable()
+}
+
+// bindRewardAddress submits the binding of the rewards of the node's own plot
+// account to address. The rewards follow once the transaction is executed.
+func (self *Server) bindRewardAddress(address string) error {
+	rewardAddr, err := common.AddressFromBase58(address)
+	if err != nil {
+		return fmt.Errorf("invalid reward address %s: %s", address, err)
+	}
+	tx, err := bindRewardAddressTransaction(self.account, uint32(time.Now().Unix()), rewardAddr)
+	if err != nil {
+		return fmt.Errorf("failed to build reward address binding: %s", err)
+	}
+	hash := tx.Hash()
+	log.Infof("server %d binds its plot rewards to %s, tx %s", self.Index, address, hash.ToHexString())
+	self.poolActor.SubmitTransaction(tx)
+	return nil
+}
+
+// plotRoot returns the plot tree root registered by plot account accountID,
+// nil if it has none.
+func plotRoot(memdb *overlaydb.MemDB, accountID string) (*plot_binding.PlotRoot, error) {
+	pub, err := pocconfig.Pubkey(accountID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid plot account %s: %s", accountID, err)
+	}
+	value, err := GetStorageValue(memdb, ledger.DefLedger, nutils.PlotBindingContractAddress,
+		plot_binding.PlotRootKey(keypair.SerializePublicKey(pub)))
+	if err == scommon.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get plot root of plot account %s: %s", accountID, err)
+	}
+	root := new(plot_binding.PlotRoot)
+	if err := root.Deserialize(bytes.NewReader(value)); err != nil {
+		return nil, fmt.Errorf("invalid plot root of plot account %s: %s", accountID, err)
+	}
+	return root, nil
+}
+
+// verifyPlotAccount checks pub may claim deadlines of plot account accountID:
+// either the plot is its own, or the plot account bound its rewards, at
+// rewardAddr, to the address of pub.
+func verifyPlotAccount(accountID string, pub keypair.PublicKey, rewardAddr common.Address) error {
+	if accountID == pocconfig.PubkeyID(pub) {
+		return nil
+	}
+	addr := types.AddressFromPubKey(pub)
+	if rewardAddr != addr {
+		return fmt.Errorf("plot account %s not bound to %s", accountID, addr.ToBase58())
+	}
+	return nil
+}
+
+// isBlackListed reports whether the governance contract black listed pub,
+// as done for the nodes convicted by poc evidence.
+func isBlackListed(memdb *overlaydb.MemDB, pub keypair.PublicKey) (bool, error) {
+	key := append([]byte(gov.BLACK_LIST), keypair.SerializePublicKey(pub)...)
+	_, err := GetStorageValue(memdb, ledger.DefLedger, nutils.GovernanceContractAddress, key)
+	if err == scommon.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("get black list of %s: %s", pocconfig.PubkeyID(pub), err)
+	}
+	return true, nil
+}
+
+// verifyPlotBinding checks pub may claim deadlines of plot account accountID
+// for block blkNum, against the bindings and the black list as of the
+// previous block.
+func (self *Server) verifyPlotBinding(blkNum uint32, accountID string, pub keypair.PublicKey) error {
+	writeSet := self.blockPool.getExecWriteSet(blkNum - 1)
+	blackListed, err := isBlackListed(writeSet, pub)
+	if err != nil {
+		return err
+	}
+	if blackListed {
+		return fmt.Errorf("%s is black listed", pocconfig.PubkeyID(pub))
+	}
+	if accountID == pocconfig.PubkeyID(pub) {
+		return nil
+	}
+	rewardAddr, err := plotRewardAddress(writeSet, accountID)
+	if err != nil {
+		return err
+	}
+	return verifyPlotAccount(accountID, pub, rewardAddr)
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"bytes"
+	"testing"
+
+	"OntologyWithPOC/account"
+	"OntologyWithPOC/common"
+	"OntologyWithPOC/common/config"
+	"OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/core/payload"
+	"OntologyWithPOC/core/signature"
+	"OntologyWithPOC/core/states"
+	scommon "OntologyWithPOC/core/store/common"
+	"OntologyWithPOC/core/store/overlaydb"
+	"OntologyWithPOC/core/types"
+	"OntologyWithPOC/smartcontract/event"
+	gov "OntologyWithPOC/smartcontract/service/native/governance"
+	nutils "OntologyWithPOC/smartcontract/service/native/utils"
+	"github.com/ontio/ontology-crypto/keypair"
+)
+
+func TestBlockPlotAccount(t *testing.T) {
+	acc := account.NewAccount("SHA256withECDSA")
+	plotAcc := account.NewAccount("SHA256withECDSA")
+	blk := &Block{Info: &pocconfig.PocBlockInfo{}}
+	if id := blockPlotAccount(blk, acc.PublicKey); id != pocconfig.PubkeyID(acc.PublicKey) {
+		t.Errorf("plot account of block %s, expected proposer's own", id)
+	}
+	blk.Info.PlotAccount = pocconfig.PubkeyID(plotAcc.PublicKey)
+	if id := blockPlotAccount(blk, acc.PublicKey); id != blk.Info.PlotAccount {
+		t.Errorf("plot account of block %s, expected %s", id, blk.Info.PlotAccount)
+	}
+}
+
+func TestVerifyPlotAccount(t *testing.T) {
+	acc := account.NewAccount("SHA256withECDSA")
+	plotAcc := account.NewAccount("SHA256withECDSA")
+	plotID := pocconfig.PubkeyID(plotAcc.PublicKey)
+
+	if err := verifyPlotAccount(pocconfig.PubkeyID(acc.PublicKey), acc.PublicKey, common.ADDRESS_EMPTY); err != nil {
+		t.Errorf("own plot account: %s", err)
+	}
+	if err := verifyPlotAccount(plotID, acc.PublicKey, types.AddressFromPubKey(acc.PublicKey)); err != nil {
+		t.Errorf("plot account bound to peer: %s", err)
+	}
+	if err := verifyPlotAccount(plotID, acc.PublicKey, types.AddressFromPubKey(plotAcc.PublicKey)); err == nil {
+		t.Errorf("unbound plot account of other peer should fail")
+	}
+	other := account.NewAccount("SHA256withECDSA")
+	if err := verifyPlotAccount(plotID, acc.PublicKey, other.Address); err == nil {
+		t.Errorf("plot account bound to other address should fail")
+	}
+}
+
+func TestIsBlackListed(t *testing.T) {
+	acc := account.NewAccount("SHA256withECDSA")
+	key := append([]byte{byte(scommon.ST_STORAGE)}, nutils.GovernanceContractAddress[:]...)
+	key = append(key, []byte(gov.BLACK_LIST)...)
+	key = append(key, keypair.SerializePublicKey(acc.PublicKey)...)
+
+	memdb := overlaydb.NewMemDB(0, 0)
+	memdb.Delete(key)
+	if black, err := isBlackListed(memdb, acc.PublicKey); err != nil || black {
+		t.Errorf("node black listed without black list item: %v", err)
+	}
+	memdb.Put(key, states.GenRawStorageItem([]byte{1}))
+	if black, err := isBlackListed(memdb, acc.PublicKey); err != nil || !black {
+		t.Errorf("black listed node not found: %v", err)
+	}
+}
+
+func TestBindRewardAddressTransaction(t *testing.T) {
+	acc := account.NewAccount("SHA256withECDSA")
+	rewardAddr := account.NewAccount("SHA256withECDSA").Address
+	tx, err := bindRewardAddressTransaction(acc, 10, rewardAddr)
+	if err != nil {
+		t.Fatalf("bind reward address transaction: %s", err)
+	}
+	if tx.Payer != acc.Address || len(tx.Sigs) != 1 {
+		t.Fatalf("bind reward address transaction not paid and signed by the plot account")
+	}
+	sig, err := tx.Sigs[0].GetSig()
+	if err != nil {
+		t.Fatalf("bind reward address transaction sig: %s", err)
+	}
+	hash := tx.Hash()
+	if err := signature.Verify(acc.PublicKey, hash[:], sig.SigData[0]); err != nil {
+		t.Errorf("bind reward address transaction sig: %s", err)
+	}
+
+	invoke, ok := tx.Payload.(*payload.InvokeCode)
+	if !ok || !bytes.Contains(invoke.Code, keypair.SerializePublicKey(acc.PublicKey)) || !bytes.Contains(invoke.Code, rewardAddr[:]) {
+		t.Errorf("bind reward address transaction does not bind %s", rewardAddr.ToBase58())
+	}
+}
+
+func TestExecuteBindRewardAddressTransaction(t *testing.T) {
+	blockpool, err := buildTestBlockPool(t)
+	if err != nil {
+		t.Fatalf("buildTestBlockPool err:%s", err)
+	}
+	defer cleanTestChainStore()
+
+	gasPrice := config.DefConfig.Common.GasPrice
+	config.DefConfig.Common.GasPrice = 0
+	defer func() { config.DefConfig.Common.GasPrice = gasPrice }()
+
+	acc := account.NewAccount("SHA256withECDSA")
+	rewardAddr := account.NewAccount("SHA256withECDSA").Address
+	tx, err := bindRewardAddressTransaction(acc, 10, rewardAddr)
+	if err != nil {
+		t.Fatalf("bind reward address transaction: %s", err)
+	}
+	lgr := blockpool.chainStore.db
+	genesis, _ := blockpool.getSealedBlock(0)
+	blk, err := buildTestBlock(t, genesis.Block, lgr)
+	if err != nil {
+		t.Fatalf("buildTestBlock err:%s", err)
+	}
+	blk.Block.Transactions = []*types.Transaction{tx}
+	result, err := lgr.ExecuteBlock(blk.Block)
+	if err != nil {
+		t.Fatalf("ExecuteBlock err:%s", err)
+	}
+	if len(result.Notify) != 1 || result.Notify[0].State != event.CONTRACT_STATE_SUCCESS {
+		t.Fatalf("bind reward address transaction failed to execute")
+	}
+	addr, err := plotRewardAddress(result.WriteSet, pocconfig.PubkeyID(acc.PublicKey))
+	if err != nil || addr != rewardAddr {
+		t.Errorf("reward address %s, error %v", addr.ToBase58(), err)
+	}
+}
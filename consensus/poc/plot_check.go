@@ -0,0 +1,118 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"OntologyWithPOC/common/log"
+	"OntologyWithPOC/consensus/poc/plot"
+)
+
+// nonces of every plot verified by a background check
+const plotCheckSamples = 16
+
+// checkPlot verifies the complete plot at path. A corrupted plot is repaired
+// if repair is set and the repair holds, excluded from scanning otherwise.
+// Incomplete plots are left to the plotter.
+func checkPlot(path string, samples uint64, repair bool, rnd *rand.Rand) (*plot.CheckReport, error) {
+	open := plot.Open
+	if repair {
+		open = plot.OpenForWrite
+	}
+	p, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	written, err := p.NoncesWritten()
+	if err != nil || written < p.NonceCount {
+		p.Close()
+		return nil, err
+	}
+	report, err := p.Check(samples, rnd)
+	if err != nil || !report.Corrupted() {
+		p.Close()
+		return report, err
+	}
+	log.Errorf("plot %s corrupted, %d nonces in %v", path, report.BadNonces(), report.Bad)
+
+	if repair {
+		if err := p.Repair(report.Bad); err != nil {
+			log.Errorf("repair plot %s: %s", path, err)
+		} else if report, err = p.Check(samples, rnd); err == nil && !report.Corrupted() {
+			p.Close()
+			log.Infof("plot %s repaired", path)
+			return report, nil
+		}
+	}
+	p.Close()
+	excluded, err := plot.Exclude(path)
+	if err != nil {
+		return report, err
+	}
+	log.Errorf("plot %s excluded from mining as %s", path, excluded)
+	return report, nil
+}
+
+// checkPlotDirs verifies the plots of dirs, the dirs are independent disks so
+// they are handled concurrently.
+func checkPlotDirs(dirs []string, samples uint64, repair bool) {
+	var wg sync.WaitGroup
+	for _, dir := range dirs {
+		wg.Add(1)
+		go func(dir string) {
+			defer wg.Done()
+			plots, err := plot.List(dir)
+			if err != nil {
+				log.Errorf("list plots of %s: %s", dir, err)
+				return
+			}
+			rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+			for _, path := range plots {
+				if _, err := checkPlot(path, samples, repair, rnd); err != nil {
+					log.Errorf("check plot %s: %s", path, err)
+				}
+			}
+		}(dir)
+	}
+	wg.Wait()
+}
+
+// runCheck verifies the plot dirs every interval while mining is running.
+func (self *MiningController) runCheck(interval time.Duration, repair bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if !self.Mining() {
+				continue
+			}
+			var dirs []string
+			for _, dir := range self.PlotDirs() {
+				dirs = append(dirs, dir.Path)
+			}
+			checkPlotDirs(dirs, plotCheckSamples, repair)
+		case <-self.quitC:
+			return
+		}
+	}
+}
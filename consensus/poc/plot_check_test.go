@@ -0,0 +1,90 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"testing"
+
+	"OntologyWithPOC/consensus/poc/plot"
+	"OntologyWithPOC/consensus/poc/shabal"
+)
+
+func corruptPlot(t *testing.T, path string, index uint64) {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteAt(make([]byte, shabal.NonceSize), plot.HeaderSize+int64(index)*shabal.NonceSize); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckPlot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "poc-plot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rnd := rand.New(rand.NewSource(1))
+
+	path, err := plot.Generate(dir, "0123", 100, 2)
+	if err != nil {
+		t.Fatalf("generate plot: %s", err)
+	}
+	corruptPlot(t, path, 1)
+	report, err := checkPlot(path, plotCheckSamples, true, rnd)
+	if err != nil {
+		t.Fatalf("check plot: %s", err)
+	}
+	if report == nil || report.Corrupted() {
+		t.Errorf("corrupted plot not repaired: %v", report)
+	}
+	if plots, _ := plot.List(dir); len(plots) != 1 {
+		t.Errorf("repaired plot not listed: %v", plots)
+	}
+
+	corruptPlot(t, path, 1)
+	report, err = checkPlot(path, plotCheckSamples, false, rnd)
+	if err != nil {
+		t.Fatalf("check plot: %s", err)
+	}
+	if report == nil || !report.Corrupted() {
+		t.Errorf("corrupted plot not reported: %v", report)
+	}
+	if plots, _ := plot.List(dir); len(plots) != 0 {
+		t.Errorf("corrupted plot not excluded: %v", plots)
+	}
+	if plots, _ := plot.ListExcluded(dir); len(plots) != 1 {
+		t.Errorf("excluded plots: %v", plots)
+	}
+
+	// incomplete plots are left to the plotter
+	p, err := plot.Create(dir, &plot.Header{Layout: plot.LayoutNonce, AccountID: "0123", StartNonce: 200, NonceCount: 2})
+	if err != nil {
+		t.Fatalf("create plot: %s", err)
+	}
+	p.Close()
+	if report, err := checkPlot(p.Path, plotCheckSamples, false, rnd); report != nil || err != nil {
+		t.Errorf("check of incomplete plot: %v, %v", report, err)
+	}
+}
@@ -0,0 +1,373 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"OntologyWithPOC/common/log"
+	"OntologyWithPOC/consensus/poc/shabal"
+)
+
+const (
+	PoolMiningInfoPath = "/mininginfo"
+	PoolSubmitPath     = "/submitnonce"
+
+	// interval pool miners poll the mining info at
+	poolPollInterval = 3 * time.Second
+	poolHttpTimeout  = 10 * time.Second
+	// max size of a submission body
+	poolMaxBodySize = 4096
+	// submissions a client may make per second, every one regenerates a
+	// nonce, with bursts of up to poolSubmitBurst
+	poolSubmitRate  = 2
+	poolSubmitBurst = 10
+	// max clients whose submission rate is tracked
+	poolMaxClients = 1024
+)
+
+// PoolMiningInfo is the round a pool is mining.
+type PoolMiningInfo struct {
+	Height     uint32 `json:"height"`
+	GenSig     string `json:"generation_signature"`
+	BaseTarget uint64 `json:"base_target"`
+}
+
+// PoolSubmission is a nonce a miner submits for a round of the pool.
+type PoolSubmission struct {
+	Height    uint32 `json:"height"`
+	AccountID string `json:"account_id"`
+	NonceNr   uint64 `json:"nonce"`
+}
+
+// PoolSubmitResult is the deadline the pool verified for a submission.
+type PoolSubmitResult struct {
+	Deadline uint64 `json:"deadline"`
+	Error    string `json:"error,omitempty"`
+}
+
+// poolChain is what a pool server needs of the consensus node it feeds.
+type poolChain interface {
+	// poolRound returns the round being mined and the block it is mined on.
+	poolRound() (*miningRound, *Block, error)
+	// verifyPoolAccount checks the pool may claim the deadlines of plot
+	// account accountID for block blkNum.
+	verifyPoolAccount(blkNum uint32, accountID string) error
+	offerDeadline(proof *deadlineProof) bool
+}
+
+// poolServer publishes the mining info of the node to pool miners, verifies
+// the nonces they submit and offers the best deadline of every round to the
+// node, which gossips and proposes with it like its own.
+type poolServer struct {
+	chain    poolChain
+	lock     sync.Mutex
+	best     *deadlineProof
+	bestSig  []byte
+	limiter  *keyLimiter
+	listener net.Listener
+	server   *http.Server
+}
+
+func newPoolServer(chain poolChain) *poolServer {
+	self := &poolServer{
+		chain:   chain,
+		limiter: newKeyLimiter(poolSubmitRate, poolSubmitBurst, poolMaxClients),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(PoolMiningInfoPath, self.handleMiningInfo)
+	mux.HandleFunc(PoolSubmitPath, self.handleSubmit)
+	self.server = &http.Server{
+		Handler:      mux,
+		ReadTimeout:  poolHttpTimeout,
+		WriteTimeout: poolHttpTimeout,
+	}
+	return self
+}
+
+func (self *poolServer) start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("pool server listen on %s: %s", addr, err)
+	}
+	self.listener = listener
+	go func() {
+		if err := self.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Errorf("pool server: %s", err)
+		}
+	}()
+	log.Infof("pool server listening on %s", listener.Addr())
+	return nil
+}
+
+func (self *poolServer) addr() string {
+	return self.listener.Addr().String()
+}
+
+func (self *poolServer) stop() {
+	if err := self.server.Close(); err != nil {
+		log.Errorf("close pool server: %s", err)
+	}
+}
+
+func (self *poolServer) miningInfo() (*PoolMiningInfo, error) {
+	round, _, err := self.chain.poolRound()
+	if err != nil {
+		return nil, err
+	}
+	return &PoolMiningInfo{
+		Height:     round.BlockNum,
+		GenSig:     hex.EncodeToString(round.GenSig),
+		BaseTarget: round.BaseTarget,
+	}, nil
+}
+
+// submit regenerates the submitted nonce and offers its deadline to the node
+// if it is the best of the round so far.
+func (self *poolServer) submit(sub *PoolSubmission) (uint64, error) {
+	round, prevBlk, err := self.chain.poolRound()
+	if err != nil {
+		return 0, err
+	}
+	if sub.Height != round.BlockNum {
+		return 0, fmt.Errorf("submission for height %d, mining %d", sub.Height, round.BlockNum)
+	}
+	if err := checkPlotVerification(genesisPlotVerification(), false); err != nil {
+		return 0, err
+	}
+	if err := self.chain.verifyPoolAccount(round.BlockNum, sub.AccountID); err != nil {
+		return 0, err
+	}
+	scoopIndex, scoop, deadline, err := nonceDeadline(sub.AccountID, sub.NonceNr, prevBlk)
+	if err != nil {
+		return 0, err
+	}
+	proof := &deadlineProof{
+		BlockNum:   round.BlockNum,
+		AccountID:  sub.AccountID,
+		NonceNr:    sub.NonceNr,
+		ScoopIndex: scoopIndex,
+		Scoop:      scoop,
+		Deadline:   deadline,
+		PrevHash:   round.PrevHash,
+	}
+
+	self.lock.Lock()
+	better := self.best == nil || self.best.BlockNum != proof.BlockNum ||
+		!bytes.Equal(self.bestSig, round.GenSig) || proof.Deadline < self.best.Deadline
+	if better {
+		self.best = proof
+		self.bestSig = round.GenSig
+	}
+	self.lock.Unlock()
+
+	if better {
+		log.Infof("pool deadline %d of block %d from account %s", deadline, proof.BlockNum, sub.AccountID)
+		self.chain.offerDeadline(proof)
+	}
+	return deadline, nil
+}
+
+func (self *poolServer) handleMiningInfo(w http.ResponseWriter, r *http.Request) {
+	info, err := self.miningInfo()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	writePoolResponse(w, http.StatusOK, info)
+}
+
+func (self *poolServer) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "submissions must be posted", http.StatusMethodNotAllowed)
+		return
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !self.limiter.allow(host, time.Now()) {
+		writePoolResponse(w, http.StatusTooManyRequests, &PoolSubmitResult{Error: "too many submissions"})
+		return
+	}
+	sub := &PoolSubmission{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, poolMaxBodySize)).Decode(sub); err != nil {
+		writePoolResponse(w, http.StatusBadRequest, &PoolSubmitResult{Error: fmt.Sprintf("invalid submission: %s", err)})
+		return
+	}
+	deadline, err := self.submit(sub)
+	if err != nil {
+		writePoolResponse(w, http.StatusBadRequest, &PoolSubmitResult{Error: err.Error()})
+		return
+	}
+	writePoolResponse(w, http.StatusOK, &PoolSubmitResult{Deadline: deadline})
+}
+
+func writePoolResponse(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("write pool response: %s", err)
+	}
+}
+
+// PoolMiner scans the local plots of an account against the round of a pool
+// and submits the best deadline of every round to it. The plot account must
+// have bound its rewards to the address of the pool node.
+type PoolMiner struct {
+	url       string
+	accountID string
+	dirs      []string
+	client    *http.Client
+	last      *PoolMiningInfo
+	quitC     chan struct{}
+	quitOnce  sync.Once
+}
+
+func NewPoolMiner(url string, accountID string, dirs []string) *PoolMiner {
+	return &PoolMiner{
+		url:       strings.TrimRight(url, "/"),
+		accountID: accountID,
+		dirs:      dirs,
+		client:    &http.Client{Timeout: poolHttpTimeout},
+		quitC:     make(chan struct{}),
+	}
+}
+
+// Run mines until Stop is called.
+func (self *PoolMiner) Run() {
+	ticker := time.NewTicker(poolPollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := self.mineRound(); err != nil {
+			log.Errorf("pool miner: %s", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-self.quitC:
+			return
+		}
+	}
+}
+
+func (self *PoolMiner) Stop() {
+	self.quitOnce.Do(func() { close(self.quitC) })
+}
+
+// mineRound scans the plots if the pool moved on to a new round, and submits
+// the best deadline found. It returns nil if the round was mined already.
+func (self *PoolMiner) mineRound() (*PoolSubmitResult, error) {
+	info, err := self.getMiningInfo()
+	if err != nil {
+		return nil, err
+	}
+	if self.last != nil && *self.last == *info {
+		return nil, nil
+	}
+	gensig, err := hex.DecodeString(info.GenSig)
+	if err != nil || len(gensig) != shabal.HashSize {
+		return nil, fmt.Errorf("invalid generation signature %s of height %d", info.GenSig, info.Height)
+	}
+	round := &miningRound{
+		BlockNum:   info.Height,
+		GenSig:     gensig,
+		BaseTarget: info.BaseTarget,
+	}
+	best := scanPlotDirs(self.dirs, self.accountID, round)
+	self.last = info
+	if best == nil {
+		return nil, fmt.Errorf("no deadline found for height %d", info.Height)
+	}
+	result, err := self.submit(&PoolSubmission{
+		Height:    best.BlockNum,
+		AccountID: best.AccountID,
+		NonceNr:   best.NonceNr,
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("pool accepted deadline %d of nonce %d for height %d", result.Deadline, best.NonceNr, best.BlockNum)
+	return result, nil
+}
+
+func (self *PoolMiner) getMiningInfo() (*PoolMiningInfo, error) {
+	resp, err := self.client.Get(self.url + PoolMiningInfoPath)
+	if err != nil {
+		return nil, fmt.Errorf("get mining info: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("get mining info: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	info := &PoolMiningInfo{}
+	if err := json.NewDecoder(resp.Body).Decode(info); err != nil {
+		return nil, fmt.Errorf("decode mining info: %s", err)
+	}
+	return info, nil
+}
+
+func (self *PoolMiner) submit(sub *PoolSubmission) (*PoolSubmitResult, error) {
+	data, err := json.Marshal(sub)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := self.client.Post(self.url+PoolSubmitPath, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("submit nonce: %s", err)
+	}
+	defer resp.Body.Close()
+	result := &PoolSubmitResult{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return nil, fmt.Errorf("submit nonce: %s, %s", resp.Status, err)
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("submit nonce: %s", result.Error)
+	}
+	return result, nil
+}
+
+func (self *Server) poolRound() (*miningRound, *Block, error) {
+	if !self.miner.Mining() {
+		return nil, nil, fmt.Errorf("mining %s", self.miner.Status().State)
+	}
+	blkNum := self.GetCurrentBlockNo()
+	prevBlk, _ := self.blockPool.getSealedBlock(blkNum - 1)
+	if prevBlk == nil {
+		return nil, nil, fmt.Errorf("prev block %d not sealed", blkNum-1)
+	}
+	round, err := newMiningRound(blkNum, prevBlk)
+	if err != nil {
+		return nil, nil, err
+	}
+	return round, prevBlk, nil
+}
+
+func (self *Server) verifyPoolAccount(blkNum uint32, accountID string) error {
+	return self.verifyPlotBinding(blkNum, accountID, self.account.PublicKey)
+}
@@ -0,0 +1,201 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"OntologyWithPOC/account"
+	"OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/consensus/poc/plot"
+)
+
+// testPoolChain is a chain mining round 2, which accepts the plot accounts
+// listed in accounts and records the deadlines offered to it.
+type testPoolChain struct {
+	lock     sync.Mutex
+	prevBlk  *Block
+	accounts map[string]bool
+	offered  []*deadlineProof
+}
+
+func (self *testPoolChain) poolRound() (*miningRound, *Block, error) {
+	round, err := newMiningRound(2, self.prevBlk)
+	return round, self.prevBlk, err
+}
+
+func (self *testPoolChain) verifyPoolAccount(blkNum uint32, accountID string) error {
+	if !self.accounts[accountID] {
+		return fmt.Errorf("plot account %s not bound to pool", accountID)
+	}
+	return nil
+}
+
+func (self *testPoolChain) offerDeadline(proof *deadlineProof) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.offered = append(self.offered, proof)
+	return true
+}
+
+func TestPoolMining(t *testing.T) {
+	chain := &testPoolChain{
+		prevBlk:  constructPrevBlock(),
+		accounts: make(map[string]bool),
+	}
+	pool := newPoolServer(chain)
+	if err := pool.start("127.0.0.1:0"); err != nil {
+		t.Fatalf("start pool: %s", err)
+	}
+	defer pool.stop()
+	url := "http://" + pool.addr()
+
+	var miners []*PoolMiner
+	var best uint64
+	for i := 0; i < 3; i++ {
+		dir, err := ioutil.TempDir("", "poc-pool")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		acc := account.NewAccount("SHA256withECDSA")
+		accountID := pocconfig.PubkeyID(acc.PublicKey)
+		if _, err := plot.Generate(dir, accountID, uint64(100*(i+1)), 3); err != nil {
+			t.Fatalf("generate plot: %s", err)
+		}
+		chain.accounts[accountID] = true
+		miners = append(miners, NewPoolMiner(url, accountID, []string{dir}))
+	}
+
+	for i, miner := range miners {
+		result, err := miner.mineRound()
+		if err != nil {
+			t.Fatalf("miner %d: %s", i, err)
+		}
+		if result == nil {
+			t.Fatalf("miner %d submitted nothing", i)
+		}
+		if i == 0 || result.Deadline < best {
+			best = result.Deadline
+		}
+		// the round is mined once
+		if result, err := miner.mineRound(); result != nil || err != nil {
+			t.Errorf("miner %d mined round again: %v, %v", i, result, err)
+		}
+	}
+
+	if len(chain.offered) == 0 {
+		t.Fatalf("no deadline offered to the chain")
+	}
+	for i := 1; i < len(chain.offered); i++ {
+		if chain.offered[i].Deadline >= chain.offered[i-1].Deadline {
+			t.Errorf("offered deadline %d not better than %d", chain.offered[i].Deadline, chain.offered[i-1].Deadline)
+		}
+	}
+	offered := chain.offered[len(chain.offered)-1]
+	if offered.Deadline != best || offered.BlockNum != 2 {
+		t.Errorf("offered deadline %d of block %d, expected %d of block 2", offered.Deadline, offered.BlockNum, best)
+	}
+	_, scoop, deadline, err := nonceDeadline(offered.AccountID, offered.NonceNr, chain.prevBlk)
+	if err != nil || deadline != offered.Deadline || !bytes.Equal(scoop, offered.Scoop) {
+		t.Errorf("offered deadline %d of nonce %d not regenerated: %d, %v", offered.Deadline, offered.NonceNr, deadline, err)
+	}
+}
+
+func TestPoolSubmitRejected(t *testing.T) {
+	acc := account.NewAccount("SHA256withECDSA")
+	accountID := pocconfig.PubkeyID(acc.PublicKey)
+	chain := &testPoolChain{
+		prevBlk:  constructPrevBlock(),
+		accounts: map[string]bool{accountID: true},
+	}
+	pool := newPoolServer(chain)
+	if err := pool.start("127.0.0.1:0"); err != nil {
+		t.Fatalf("start pool: %s", err)
+	}
+	defer pool.stop()
+	miner := NewPoolMiner("http://"+pool.addr()+"/", accountID, nil)
+
+	info, err := miner.getMiningInfo()
+	if err != nil {
+		t.Fatalf("get mining info: %s", err)
+	}
+	if info.Height != 2 || info.BaseTarget != 1000<<32 {
+		t.Errorf("mining info: %v", info)
+	}
+
+	if _, err := miner.submit(&PoolSubmission{Height: 3, AccountID: accountID, NonceNr: 1}); err == nil {
+		t.Errorf("submission for another height should fail")
+	}
+	other := account.NewAccount("SHA256withECDSA")
+	if _, err := miner.submit(&PoolSubmission{Height: 2, AccountID: pocconfig.PubkeyID(other.PublicKey), NonceNr: 1}); err == nil {
+		t.Errorf("submission of unbound account should fail")
+	}
+	result, err := miner.submit(&PoolSubmission{Height: 2, AccountID: accountID, NonceNr: 1})
+	if err != nil {
+		t.Fatalf("submit: %s", err)
+	}
+	_, _, deadline, _ := nonceDeadline(accountID, 1, chain.prevBlk)
+	if result.Deadline != deadline {
+		t.Errorf("submitted deadline %d, expected %d", result.Deadline, deadline)
+	}
+	if len(chain.offered) != 1 {
+		t.Errorf("%d deadlines offered, expected 1", len(chain.offered))
+	}
+}
+
+func TestPoolSubmitRateLimit(t *testing.T) {
+	pool := newPoolServer(&testPoolChain{})
+	now := time.Now()
+	for i := 0; i < poolSubmitBurst; i++ {
+		if !pool.limiter.allow("10.0.0.1", now) {
+			t.Fatalf("submission %d of burst refused", i)
+		}
+	}
+	if pool.limiter.allow("10.0.0.1", now) {
+		t.Errorf("submission beyond burst allowed")
+	}
+	if !pool.limiter.allow("10.0.0.2", now) {
+		t.Errorf("submission of another client refused")
+	}
+	if !pool.limiter.allow("10.0.0.1", now.Add(time.Second/poolSubmitRate)) {
+		t.Errorf("submission after refill refused")
+	}
+
+	// new clients are refused while the tracked ones are refilling
+	for i := len(pool.limiter.buckets); i < poolMaxClients; i++ {
+		pool.limiter.allow(strconv.Itoa(i), now)
+	}
+	if pool.limiter.allow("10.0.0.3", now) {
+		t.Errorf("client beyond limit allowed")
+	}
+	if !pool.limiter.allow("10.0.0.3", now.Add(time.Minute)) {
+		t.Errorf("client refused after idle clients pruned")
+	}
+	if len(pool.limiter.buckets) != 1 {
+		t.Errorf("%d clients tracked after prune, expected 1", len(pool.limiter.buckets))
+	}
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"fmt"
+	"math"
+
+	"OntologyWithPOC/common"
+	"OntologyWithPOC/core/types"
+	"OntologyWithPOC/core/utils"
+	gover "OntologyWithPOC/smartcontract/service/native/governance"
+	nutils "OntologyWithPOC/smartcontract/service/native/utils"
+	"github.com/ontio/ontology-crypto/keypair"
+)
+
+// pocRewardTransaction builds the system transaction which pays the reward of
+// block blkNum to rewardAddr, the reward address of its winning plot, leaving
+// the pool share to the proposer pub if the plot is not its own.
+func pocRewardTransaction(blkNum uint32, rewardAddr common.Address, pub keypair.PublicKey) (*types.Transaction, error) {
+	param := &gover.DistributePocRewardParam{
+		PlotRewardAddress: rewardAddr,
+		ProposerAddress:   types.AddressFromPubKey(pub),
+	}
+	// invoked with the param struct, as the native contract reads it
+	code, err := utils.BuildNativeInvokeCode(nutils.GovernanceContractAddress, 0, gover.DISTRIBUTE_POC_REWARD, []interface{}{param})
+	if err != nil {
+		return nil, err
+	}
+	mutable := utils.NewInvokeTransaction(code)
+	mutable.GasLimit = math.MaxUint64
+	mutable.Nonce = blkNum
+	return mutable.IntoImmutable()
+}
+
+// createPocRewardTransaction builds the reward transaction of block blkNum
+// proposed by pub with a deadline of plot account accountID, paying the
+// reward address the plot account has bound as of the previous block.
+func (self *Server) createPocRewardTransaction(blkNum uint32, accountID string, pub keypair.PublicKey) (*types.Transaction, error) {
+	rewardAddr, err := plotRewardAddress(self.blockPool.getExecWriteSet(blkNum-1), accountID)
+	if err != nil {
+		return nil, err
+	}
+	return pocRewardTransaction(blkNum, rewardAddr, pub)
+}
+
+// verifyPocRewardTransaction checks the first transaction of blk is its
+// reward transaction.
+func (self *Server) verifyPocRewardTransaction(blk *Block, pub keypair.PublicKey) error {
+	txs := blk.Block.Transactions
+	if len(txs) == 0 {
+		return fmt.Errorf("no reward transaction in block %d", blk.getBlockNum())
+	}
+	tx, err := self.createPocRewardTransaction(blk.getBlockNum(), blockPlotAccount(blk, pub), pub)
+	if err != nil {
+		return err
+	}
+	if hash := txs[0].Hash(); hash != tx.Hash() {
+		return fmt.Errorf("invalid reward transaction %s in block %d", hash.ToHexString(), blk.getBlockNum())
+	}
+	return nil
+}
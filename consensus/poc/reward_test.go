@@ -0,0 +1,92 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"bytes"
+	"testing"
+
+	"OntologyWithPOC/account"
+	"OntologyWithPOC/common"
+	"OntologyWithPOC/core/payload"
+	"OntologyWithPOC/core/types"
+	"OntologyWithPOC/smartcontract/event"
+	gover "OntologyWithPOC/smartcontract/service/native/governance"
+)
+
+func TestPocRewardTransaction(t *testing.T) {
+	proposer := account.NewAccount("SHA256withECDSA")
+	reward := account.NewAccount("SHA256withECDSA")
+
+	tx, err := pocRewardTransaction(2, reward.Address, proposer.PublicKey)
+	if err != nil {
+		t.Fatalf("reward transaction: %s", err)
+	}
+	if tx.Payer != common.ADDRESS_EMPTY || len(tx.Sigs) != 0 || tx.GasPrice != 0 {
+		t.Errorf("reward transaction is not a system transaction")
+	}
+	// every node builds the same transaction for the block
+	tx2, _ := pocRewardTransaction(2, reward.Address, proposer.PublicKey)
+	if tx.Hash() != tx2.Hash() {
+		t.Errorf("reward transaction not deterministic")
+	}
+	for _, other := range []struct {
+		blkNum uint32
+		reward *account.Account
+	}{{3, reward}, {2, proposer}} {
+		tx3, _ := pocRewardTransaction(other.blkNum, other.reward.Address, proposer.PublicKey)
+		if tx.Hash() == tx3.Hash() {
+			t.Errorf("reward transaction of block %d to %s same as block 2", other.blkNum, other.reward.Address.ToBase58())
+		}
+	}
+
+	code := tx.Payload.(*payload.InvokeCode).Code
+	if !bytes.Contains(code, reward.Address[:]) || !bytes.Contains(code, proposer.Address[:]) ||
+		!bytes.Contains(code, []byte(gover.DISTRIBUTE_POC_REWARD)) {
+		t.Errorf("reward transaction does not invoke %s with reward and proposer address", gover.DISTRIBUTE_POC_REWARD)
+	}
+}
+
+func TestExecutePocRewardTransaction(t *testing.T) {
+	blockpool, err := buildTestBlockPool(t)
+	if err != nil {
+		t.Fatalf("buildTestBlockPool err:%s", err)
+	}
+	defer cleanTestChainStore()
+
+	lgr := blockpool.chainStore.db
+	genesis, _ := blockpool.getSealedBlock(0)
+	blk, err := buildTestBlock(t, genesis.Block, lgr)
+	if err != nil {
+		t.Fatalf("buildTestBlock err:%s", err)
+	}
+	proposer := account.NewAccount("SHA256withECDSA")
+	tx, err := pocRewardTransaction(1, proposer.Address, proposer.PublicKey)
+	if err != nil {
+		t.Fatalf("reward transaction: %s", err)
+	}
+	blk.Block.Transactions = []*types.Transaction{tx}
+	result, err := lgr.ExecuteBlock(blk.Block)
+	if err != nil {
+		t.Fatalf("ExecuteBlock err:%s", err)
+	}
+	if len(result.Notify) != 1 || result.Notify[0].State != event.CONTRACT_STATE_SUCCESS {
+		t.Errorf("reward transaction failed to execute")
+	}
+}
@@ -0,0 +1,271 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"OntologyWithPOC/common/log"
+	"OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/consensus/poc/plot"
+	"OntologyWithPOC/consensus/poc/shabal"
+	"OntologyWithPOC/consensus/poc/zkproof"
+)
+
+// number of nonces whose scoops are read at once, a scan checks for
+// cancellation between chunks
+const scanChunk = 1024
+
+// errScanCancelled is returned by a scan aborted as its round is outdated.
+var errScanCancelled = errors.New("scan cancelled")
+
+func scanCancelled(cancelC <-chan struct{}) bool {
+	select {
+	case <-cancelC:
+		return true
+	default:
+		return false
+	}
+}
+
+// rateLimiter is a token bucket limiting reads to rate bytes per second, with
+// bursts of up to one second of reading. A nil rateLimiter does not limit.
+type rateLimiter struct {
+	lock  sync.Mutex
+	rate  uint64
+	avail float64
+	last  time.Time
+}
+
+func newRateLimiter(rate uint64) *rateLimiter {
+	if rate == 0 {
+		return nil
+	}
+	return &rateLimiter{
+		rate: rate,
+		last: time.Now(),
+	}
+}
+
+// wait blocks until n bytes may be read, or fails with errScanCancelled once
+// cancelC is closed.
+func (self *rateLimiter) wait(n uint64, cancelC <-chan struct{}) error {
+	if self == nil {
+		return nil
+	}
+	self.lock.Lock()
+	now := time.Now()
+	self.avail += now.Sub(self.last).Seconds() * float64(self.rate)
+	if self.avail > float64(self.rate) {
+		self.avail = float64(self.rate)
+	}
+	self.last = now
+	self.avail -= float64(n)
+	var delay time.Duration
+	if self.avail < 0 {
+		delay = time.Duration(-self.avail / float64(self.rate) * float64(time.Second))
+	}
+	self.lock.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-cancelC:
+		return errScanCancelled
+	}
+}
+
+// plotScanner scans the plot directories for the best deadline of a round.
+// Every plot is read once, by one of workers readers of its directory, and
+// the scoops read from each directory are limited to rate bytes per second,
+// 0 meaning unlimited. read, if not nil, is told the number of nonces read as
+// the scan goes. With zk set, the deadlines of the plots having a plot tree of
+// zk are proved with zk proofs, and with zkOnly the deadlines of the other
+// plots are left out.
+type plotScanner struct {
+	workers int
+	rate    uint64
+	read    func(round *miningRound, nonces uint64)
+	zk      *zkproof.Params
+	zkOnly  bool
+}
+
+func newPlotScanner(workers uint, rate uint64) *plotScanner {
+	if workers == 0 {
+		workers = 1
+	}
+	return &plotScanner{
+		workers: int(workers),
+		rate:    rate,
+	}
+}
+
+// scan returns the best deadline of the plots of accountID in dirs, nil if
+// cancelC is closed before the scan completes. offer, if not nil, is called
+// with every proof improving the best deadline found so far, as soon as it is
+// found. On equal deadlines the proof of the directory listed first, then of
+// the plot listed first, wins.
+func (self *plotScanner) scan(dirs []string, accountID string, round *miningRound, cancelC <-chan struct{}, offer func(*deadlineProof)) *deadlineProof {
+	var lock sync.Mutex
+	var offered *deadlineProof
+	improve := func(proof *deadlineProof) {
+		lock.Lock()
+		defer lock.Unlock()
+		if scanCancelled(cancelC) || (offered != nil && proof.Deadline >= offered.Deadline) {
+			return
+		}
+		offered = proof
+		if offer != nil {
+			offer(proof)
+		}
+	}
+
+	proofs := make([][]*deadlineProof, len(dirs))
+	var wg sync.WaitGroup
+	for i, dir := range dirs {
+		plots, err := plot.List(dir)
+		if err != nil {
+			log.Errorf("scan plot dir %s: %s", dir, err)
+			continue
+		}
+		proofs[i] = make([]*deadlineProof, len(plots))
+		queue := make(chan int, len(plots))
+		for j := range plots {
+			queue <- j
+		}
+		close(queue)
+		limiter := newRateLimiter(self.rate)
+		for w := 0; w < self.workers; w++ {
+			wg.Add(1)
+			go func(dirProofs []*deadlineProof, plots []string) {
+				defer wg.Done()
+				for j := range queue {
+					proof, err := scanPlotFile(plots[j], accountID, round, limiter, cancelC, self.read, self.zk)
+					if err == errScanCancelled {
+						return
+					}
+					if err != nil {
+						log.Errorf("scan plot %s: %s", plots[j], err)
+						continue
+					}
+					if proof != nil && (!self.zkOnly || proof.PlotPath != "") {
+						dirProofs[j] = proof
+						improve(proof)
+					}
+				}
+			}(proofs[i], plots)
+		}
+	}
+	wg.Wait()
+	if scanCancelled(cancelC) {
+		return nil
+	}
+
+	var best *deadlineProof
+	for _, dirProofs := range proofs {
+		for _, proof := range dirProofs {
+			if proof != nil && (best == nil || proof.Deadline < best.Deadline) {
+				best = proof
+			}
+		}
+	}
+	return best
+}
+
+// scanPlot reads the scoop selected by round from every nonce of the plot and
+// returns the best deadline found.
+func scanPlot(path string, accountID string, round *miningRound) (*deadlineProof, error) {
+	return scanPlotFile(path, accountID, round, nil, nil, nil, nil)
+}
+
+func scanPlotFile(path string, accountID string, round *miningRound, limiter *rateLimiter, cancelC <-chan struct{},
+	read func(*miningRound, uint64), zk *zkproof.Params) (*deadlineProof, error) {
+	p, err := plot.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Close()
+	if p.AccountID != accountID {
+		return nil, fmt.Errorf("plot %s belongs to account %s", path, p.AccountID)
+	}
+	n, err := p.NoncesWritten()
+	if err != nil {
+		return nil, err
+	}
+
+	scoopIndex := shabal.ScoopNum256(round.GenSig)
+	zkPath := ""
+	if zk != nil && zkPlotTree(path, *zk, n) {
+		zkPath = path
+	}
+	var best *deadlineProof
+	for from := uint64(0); from < n; from += scanChunk {
+		if scanCancelled(cancelC) {
+			return nil, errScanCancelled
+		}
+		to := from + scanChunk
+		if to > n {
+			to = n
+		}
+		if err := limiter.wait((to-from)*shabal.ScoopSize, cancelC); err != nil {
+			return nil, err
+		}
+		scoops, err := p.ReadScoopRange(scoopIndex, from, to)
+		if err != nil {
+			return nil, err
+		}
+		if read != nil {
+			read(round, to-from)
+		}
+		for i := uint64(0); i < to-from; i++ {
+			scoop := scoops[i*shabal.ScoopSize : (i+1)*shabal.ScoopSize]
+			deadline, err := pocconfig.CalcDeadline(shabal.Target256(round.GenSig, scoop), round.BaseTarget)
+			if err != nil {
+				return nil, err
+			}
+			if best == nil || deadline < best.Deadline {
+				best = &deadlineProof{
+					BlockNum:   round.BlockNum,
+					AccountID:  accountID,
+					NonceNr:    p.StartNonce + from + i,
+					ScoopIndex: scoopIndex,
+					Scoop:      scoop,
+					Deadline:   deadline,
+					PrevHash:   round.PrevHash,
+					PlotPath:   zkPath,
+				}
+			}
+		}
+	}
+	return best, nil
+}
+
+// scanPlotDirs scans dirs concurrently with one unlimited reader per
+// directory, as they usually sit on different disks.
+func scanPlotDirs(dirs []string, accountID string, round *miningRound) *deadlineProof {
+	return newPlotScanner(1, 0).scan(dirs, accountID, round, nil, nil)
+}
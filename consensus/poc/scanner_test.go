@@ -0,0 +1,120 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"OntologyWithPOC/account"
+	"OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/consensus/poc/plot"
+)
+
+func TestRateLimiter(t *testing.T) {
+	var unlimited *rateLimiter
+	if err := unlimited.wait(1<<30, nil); err != nil {
+		t.Errorf("unlimited wait: %s", err)
+	}
+
+	limiter := newRateLimiter(10000)
+	start := time.Now()
+	if err := limiter.wait(1000, nil); err != nil {
+		t.Fatalf("wait: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("wait of 1000 bytes at 10000 B/s took %v", elapsed)
+	}
+
+	cancelC := make(chan struct{})
+	close(cancelC)
+	if err := limiter.wait(1<<20, cancelC); err != errScanCancelled {
+		t.Errorf("cancelled wait: %v", err)
+	}
+}
+
+func TestPlotScanner(t *testing.T) {
+	acc := account.NewAccount("SHA256withECDSA")
+	accountID := pocconfig.PubkeyID(acc.PublicKey)
+	round := constructMiningRound(t, 2, constructPrevBlock())
+
+	var dirs []string
+	var best *deadlineProof
+	for i := 0; i < 2; i++ {
+		dir, err := ioutil.TempDir("", "poc-plot")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		for j := 0; j < 2; j++ {
+			path, err := plot.Generate(dir, accountID, uint64(100*(2*i+j+1)), 2)
+			if err != nil {
+				t.Fatalf("generate plot: %s", err)
+			}
+			proof, err := scanPlot(path, accountID, round)
+			if err != nil {
+				t.Fatalf("scan plot: %s", err)
+			}
+			if best == nil || proof.Deadline < best.Deadline {
+				best = proof
+			}
+		}
+		dirs = append(dirs, dir)
+	}
+
+	var offers []*deadlineProof
+	var read uint64
+	scanner := newPlotScanner(2, 1<<20)
+	scanner.read = func(r *miningRound, nonces uint64) {
+		atomic.AddUint64(&read, nonces)
+	}
+	proof := scanner.scan(dirs, accountID, round, make(chan struct{}), func(proof *deadlineProof) {
+		offers = append(offers, proof)
+	})
+	if proof == nil {
+		t.Fatalf("no proof from scanner")
+	}
+	if proof.Deadline != best.Deadline || proof.NonceNr != best.NonceNr {
+		t.Errorf("best proof: nonce %d deadline %d, expected nonce %d deadline %d",
+			proof.NonceNr, proof.Deadline, best.NonceNr, best.Deadline)
+	}
+	if read != 8 {
+		t.Errorf("%d nonces read, expected 8", read)
+	}
+	if len(offers) == 0 || offers[len(offers)-1].Deadline != best.Deadline {
+		t.Fatalf("best deadline not offered")
+	}
+	for i := 1; i < len(offers); i++ {
+		if offers[i].Deadline >= offers[i-1].Deadline {
+			t.Errorf("offered deadline %d does not improve %d", offers[i].Deadline, offers[i-1].Deadline)
+		}
+	}
+
+	cancelC := make(chan struct{})
+	close(cancelC)
+	offers = nil
+	if proof := newPlotScanner(2, 0).scan(dirs, accountID, round, cancelC, func(proof *deadlineProof) {
+		offers = append(offers, proof)
+	}); proof != nil || len(offers) != 0 {
+		t.Errorf("cancelled scan should offer nothing")
+	}
+}
@@ -22,14 +22,8 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
-	"io/ioutil"
 	"math"
-	"math/rand"
-	"os"
 	"reflect"
-	"sort"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
 
@@ -39,6 +33,8 @@ import (
 	"OntologyWithPOC/common/log"
 	actorTypes "OntologyWithPOC/consensus/actor"
 	"OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/consensus/poc/history"
+	"OntologyWithPOC/consensus/poc/zkproof"
 	"OntologyWithPOC/core/ledger"
 	"OntologyWithPOC/core/payload"
 	"OntologyWithPOC/core/types"
@@ -64,6 +60,7 @@ const (
 	FastForward // for syncer catch up
 	ReBroadcast
 	SubmitBlock
+	ForkBlock // for fork blocks fetched from peers
 )
 
 const (
@@ -77,6 +74,7 @@ type PocAction struct {
 	BlockNum uint32
 	Proposal *blockProposalMsg
 	forEmpty bool
+	block    *Block
 }
 
 type BlockParticipantConfig struct {
@@ -95,6 +93,7 @@ type p2pMsgPayload struct {
 type peersDeadline struct {
 	locker        sync.Mutex
 	peersdeadline map[uint32]uint64
+	entries       map[uint32]*deadlineEntry
 }
 
 type blockPeersDeadline struct {
@@ -135,19 +134,31 @@ type Server struct {
 	stateMgr   *StateMgr
 	timer      *EventTimer
 
-	msgRecvC   map[uint32]chan *p2pMsgPayload
-	msgC       chan ConsensusMsg
-	pocActionC chan *PocAction
-	msgSendC   chan *SendMsgEvent
-	sub        *events.ActorSubscriber
-	quitC      chan struct{}
-	quit       bool
-	quitWg     sync.WaitGroup
+	msgRecvLock sync.RWMutex
+	msgRecvC    map[uint32]chan *p2pMsgPayload
+	msgC        chan ConsensusMsg
+	pocActionC  chan *PocAction
+	msgSendC    chan *SendMsgEvent
+	sub         *events.ActorSubscriber
+	quitC       chan struct{}
+	quit        bool
+	quitWg      sync.WaitGroup
 
 	/// add by zhourz
-	deadline            uint64
-	peersDeadLine       *blockPeersDeadline
-	peersDeadLineBackUp *blockPeersDeadline
+	deadline       uint64
+	deadlineLock   sync.RWMutex
+	deadlineProof  *deadlineProof
+	peersDeadLine  *blockPeersDeadline
+	miner          *MiningController
+	pool           *poolServer
+	newRoundC      chan struct{}
+	miningInfo     *miningInfo
+	zkVerifier     *zkproof.VerifyingKey
+	zkProver       *zkproof.ProvingKey
+	history        *history.Store
+	memberMsgC     chan *p2pmsg.ConsensusPayload // payloads of nodes not admitted yet
+	memberLimiter  *keyLimiter                   // deadlines verified per node not admitted yet
+	indexContested uint32                        // another key claimed our member index
 }
 
 func NewPocServer(account *account.Account, txpool, p2p *actor.PID) (*Server, error) {
@@ -158,6 +169,8 @@ func NewPocServer(account *account.Account, txpool, p2p *actor.PID) (*Server, er
 		p2p:                &actorTypes.P2PActor{P2P: p2p},
 		ledger:             ledger.DefLedger,
 		incrValidator:      increment.NewIncrementValidator(20),
+		newRoundC:          make(chan struct{}, 1),
+		miningInfo:         newMiningInfo(),
 	}
 	service.stateMgr = newStateMgr(service)
 
@@ -194,6 +207,13 @@ func (self *Server) Receive(context actor.Context) {
 		log.Info("poc actor start consensus")
 	case *actorTypes.StopConsensus:
 		self.stop()
+	case *actorTypes.StartMining, *actorTypes.StopMining, *actorTypes.PauseMining,
+		*actorTypes.SetMiningCapacity, *actorTypes.SetRewardAddress, *actorTypes.GetMiningStatus:
+		context.Respond(self.handleMiningControl(msg))
+	case *actorTypes.GetMiningInfo:
+		context.Respond(self.getMiningInfo())
+	case *actorTypes.GetDeadlineHistory:
+		context.Respond(self.getDeadlineHistory(msg))
 	case *message.SaveBlockCompleteMsg:
 		log.Infof("poc actor SaveBlockCompleteMsg receives block complete event. block height=%d, numtx=%d",
 			msg.Block.Header.Height, len(msg.Block.Transactions))
@@ -263,8 +283,8 @@ func (self *Server) CheckSubmitBlock(blkNum uint32, stateRoot common.Uint256) bo
 			}
 		}
 	}
-	m := self.config.N - (self.config.N-1)/3
-	if stateRootCnt < uint32(m) {
+	m := self.committeeN() - (self.committeeN()-1)/3
+	if stateRootCnt < m {
 		return false
 	}
 	return true
@@ -274,9 +294,18 @@ func (self *Server) NewConsensusPayload(payload *p2pmsg.ConsensusPayload) {
 	peerID := pocconfig.PubkeyID(payload.Owner)
 	peerIdx, present := self.peerPool.GetPeerIndex(peerID)
 	if !present {
-		log.Debugf("invalid consensus node: %s", peerID)
+		// nodes outside the chain config are admitted as members once one of
+		// their deadlines is verified, only deadlines are taken from them before
+		select {
+		case self.memberMsgC <- payload:
+		default:
+			log.Debugf("dropped consensus msg of unknown node %s", peerID)
+		}
 		return
 	}
+	if isMemberIndex(peerIdx) {
+		self.startPeerProcessor(peerIdx, payload.Owner)
+	}
 	if self.peerPool.isNewPeer(peerIdx) {
 		self.peerPool.peerConnected(peerIdx)
 	}
@@ -285,15 +314,102 @@ func (self *Server) NewConsensusPayload(payload *p2pmsg.ConsensusPayload) {
 		self.peerPool.addP2pId(peerIdx, payload.PeerId)
 	}
 
-	if C, present := self.msgRecvC[peerIdx]; present {
-		C <- &p2pMsgPayload{
-			fromPeer: peerIdx,
-			payload:  payload,
+	if err := self.deliverPeerMsg(peerIdx, &p2pMsgPayload{
+		fromPeer: peerIdx,
+		payload:  payload,
+	}); err != nil {
+		log.Errorf("consensus msg of node %s: %s", peerID, err)
+	}
+}
+
+// memberMsgLoop verifies the deadlines sent by nodes not admitted yet, so they
+// join as members once one of their deadlines is verified.
+func (self *Server) memberMsgLoop() {
+	defer self.quitWg.Done()
+
+	for {
+		select {
+		case payload := <-self.memberMsgC:
+			msg, err := DeserializePOCMsg(payload.Data)
+			if err != nil {
+				continue
+			}
+			dl, ok := msg.(*deadLineMsg)
+			if !ok || dl.Verify(payload.Owner) != nil {
+				continue
+			}
+			self.onDeadlineMsg(math.MaxUint32, pocconfig.PubkeyID(payload.Owner), dl)
+		case <-self.quitC:
+			return
 		}
-	} else {
-		log.Errorf("consensus msg without receiver: %d node: %s", peerIdx, peerID)
+	}
+}
+
+// startPeerProcessor starts the msg processor of peerIdx unless it runs already.
+func (self *Server) startPeerProcessor(peerIdx uint32, pk keypair.PublicKey) {
+	self.msgRecvLock.Lock()
+	if _, present := self.msgRecvC[peerIdx]; present {
+		self.msgRecvLock.Unlock()
 		return
 	}
+	self.msgRecvC[peerIdx] = make(chan *p2pMsgPayload, 1024)
+	self.msgRecvLock.Unlock()
+
+	go func() {
+		if err := self.run(pk); err != nil {
+			log.Errorf("server %d, processor on peer %d failed: %s",
+				self.Index, peerIdx, err)
+		}
+	}()
+}
+
+// stopPeerProcessor makes the msg processor of peerIdx quit.
+func (self *Server) stopPeerProcessor(peerIdx uint32) {
+	self.msgRecvLock.RLock()
+	defer self.msgRecvLock.RUnlock()
+
+	if C, present := self.msgRecvC[peerIdx]; present {
+		select {
+		case C <- nil:
+		default:
+			log.Warnf("server %d, failed to stop processor on peer %d", self.Index, peerIdx)
+		}
+	}
+}
+
+func (self *Server) deliverPeerMsg(peerIdx uint32, payload *p2pMsgPayload) error {
+	self.msgRecvLock.RLock()
+	defer self.msgRecvLock.RUnlock()
+
+	C, present := self.msgRecvC[peerIdx]
+	if !present {
+		return fmt.Errorf("no receiver for peer %d", peerIdx)
+	}
+	// the processor may be quitting, never block while holding the lock
+	select {
+	case C <- payload:
+		return nil
+	default:
+		return fmt.Errorf("receiver of peer %d full", peerIdx)
+	}
+}
+
+func (self *Server) peerRecvC(peerIdx uint32) (chan *p2pMsgPayload, bool) {
+	self.msgRecvLock.RLock()
+	defer self.msgRecvLock.RUnlock()
+
+	C, present := self.msgRecvC[peerIdx]
+	return C, present
+}
+
+func (self *Server) closePeerRecvC(peerIdx uint32) {
+	self.msgRecvLock.Lock()
+	defer self.msgRecvLock.Unlock()
+
+	if C, present := self.msgRecvC[peerIdx]; present {
+		close(C)
+		delete(self.msgRecvC, peerIdx)
+	}
 }
 
 func (self *Server) LoadChainConfig(blkNum uint32) error {
@@ -350,7 +466,8 @@ func (self *Server) LoadChainConfig(blkNum uint32) error {
 	if block == nil {
 		return fmt.Errorf("failed to get sealed block (%d)", self.GetCommittedBlockNo())
 	}
-	currentParticipantConfig, err := self.buildParticipantConfig(self.GetCurrentBlockNo(), block, self.config)
+	winners := self.recentWinners(self.GetCurrentBlockNo())
+	currentParticipantConfig, err := self.buildParticipantConfig(self.GetCurrentBlockNo(), winners, self.config)
 	if err != nil {
 		return fmt.Errorf("failed to build participant config: %s", err)
 	}
@@ -404,31 +521,23 @@ func (self *Server) updateChainConfig() error {
 				log.Errorf("Pubkey failed: %v", err)
 				return fmt.Errorf("Pubkey failed: %v", err)
 			}
-			peerIdx := p.Index
-			if _, present := self.msgRecvC[peerIdx]; !present {
-				self.msgRecvC[peerIdx] = make(chan *p2pMsgPayload, 1024)
-			}
-			go func() {
-				if err := self.run(publickey); err != nil {
-					log.Errorf("server %d, processor on peer %d failed: %s",
-						self.Index, peerIdx, err)
-				}
-			}()
+			self.startPeerProcessor(p.Index, publickey)
 			log.Infof("updateChainConfig add peer index:%v,id:%v", p.ID, p.Index)
 		}
 	}
 	for index, peerPubKey := range self.peerPool.GetAllPubKeys() {
 		_, present := peermap[index]
-		if !present {
+		// members are not listed in the chain config
+		if !present && !isMemberIndex(index) {
 			if index == self.Index {
 				self.Index = math.MaxUint32
 				log.Infof("updateChainConfig remove index :%d", index)
 			} else {
-				if C, present := self.msgRecvC[index]; present {
+				if _, present := self.peerRecvC(index); present {
 					pubkey := pocconfig.PubkeyID(peerPubKey)
 					self.peerPool.RemovePeerIndex(pubkey)
 					log.Infof("updateChainConfig remove consensus:index:%d,id:%v", index, pubkey)
-					C <- nil
+					self.stopPeerProcessor(index)
 				}
 			}
 		}
@@ -462,67 +571,71 @@ func (self *Server) initialize() error {
 	self.syncer = newSyncer(self)
 
 	self.msgRecvC = make(map[uint32]chan *p2pMsgPayload)
+	self.memberMsgC = make(chan *p2pmsg.ConsensusPayload, CAP_MESSAGE_CHANNEL)
+	self.memberLimiter = newKeyLimiter(memberDeadlineRate, memberDeadlineBurst, maxPendingMembers)
 	self.msgC = make(chan ConsensusMsg, CAP_MESSAGE_CHANNEL)
 	self.pocActionC = make(chan *PocAction, CAP_ACTION_CHANNEL)
 	self.msgSendC = make(chan *SendMsgEvent, CAP_MSG_SEND_CHANNEL)
+	self.quitC = make(chan struct{})
+
+	self.miner, err = newMiningController(config.DefConfig.Common.DataDir, self.account)
+	if err != nil {
+		return fmt.Errorf("init mining controller: %s", err)
+	}
+	if self.zkVerifier, err = genesisVerifyingKey(); err != nil {
+		return fmt.Errorf("load zk verifying key: %s", err)
+	}
+	if path := config.DefConfig.Consensus.ZKProvingKey; path != "" {
+		if self.zkProver, err = loadProvingKey(path, self.zkVerifier); err != nil {
+			return fmt.Errorf("load zk proving key: %s", err)
+		}
+		log.Infof("zk proving key %s loaded", path)
+	}
+	if mode := genesisPlotVerification(); mode == config.POC_PLOT_VERIFY_ZK && self.zkProver == nil {
+		log.Warnf("plot verification is %s, deadlines cannot be proved without a zk proving key", mode)
+	}
+	if self.history, err = history.Open(historyPath()); err != nil {
+		return err
+	}
+	go self.miner.run()
+	if interval := config.DefConfig.Consensus.PlotCheckInterval; interval > 0 {
+		go self.miner.runCheck(time.Duration(interval)*time.Minute, config.DefConfig.Consensus.PlotRepair)
+	}
+	if config.DefConfig.Consensus.EnablePoolServer {
+		self.pool = newPoolServer(self)
+		if err := self.pool.start(fmt.Sprintf(":%d", config.DefConfig.Consensus.PoolServerPort)); err != nil {
+			return err
+		}
+	}
 
 	/// add by zhourz
 	self.peersDeadLine = &blockPeersDeadline{blockpeersdeadline: make(map[uint32]*peersDeadline)}
-	self.peersDeadLineBackUp = &blockPeersDeadline{blockpeersdeadline: make(map[uint32]*peersDeadline)}
+	self.quitWg.Add(1)
 	go self.calDeadLine()
 	ticker := time.NewTicker(time.Second * 30)
+	self.quitWg.Add(1)
 	go func() {
-		self.quitWg.Add(1)
 		defer self.quitWg.Done()
+		defer ticker.Stop()
 
 		for {
 			select {
 			case <-ticker.C:
-				log.Infof("========================%d broadcast deadline %d", self.Index, self.deadline)
-				self.peersDeadLine.locker.Lock()
-				if _, ok := self.peersDeadLine.blockpeersdeadline[self.GetCurrentBlockNo()]; ok {
-					self.peersDeadLine.blockpeersdeadline[self.GetCurrentBlockNo()].locker.Lock()
-					self.peersDeadLine.blockpeersdeadline[self.GetCurrentBlockNo()].peersdeadline[self.Index] = self.deadline
-					self.peersDeadLine.blockpeersdeadline[self.GetCurrentBlockNo()].locker.Unlock()
-				} else {
-					self.peersDeadLine.blockpeersdeadline[self.GetCurrentBlockNo()] = &peersDeadline{
-						peersdeadline: make(map[uint32]uint64),
-					}
-					self.peersDeadLine.blockpeersdeadline[self.GetCurrentBlockNo()].peersdeadline = map[uint32]uint64{self.Index: self.deadline}
-				}
-				if len(self.peersDeadLine.blockpeersdeadline) > 30 {
-					var keys []int
-					for key, _ := range self.peersDeadLine.blockpeersdeadline {
-						keys = append(keys, int(key))
-					}
-					sort.Ints(keys)
-					for _, key := range keys[:(len(keys) - 30)] {
-						delete(self.peersDeadLine.blockpeersdeadline, uint32(key))
-					}
-				} else {
-				}
-
-				aa := make(map[uint32]uint64)
-				aaa := make(map[uint32]map[uint32]uint64)
-				for blockade, value := range self.peersDeadLine.blockpeersdeadline {
-					for index, dl := range value.peersdeadline {
-						aa[index] = dl
-					}
-					aaa[blockade] = aa
+				// re-announce our deadline for peers which missed it
+				blkNum := self.GetCurrentBlockNo()
+				if !self.miner.Mining() {
+					continue
 				}
-				msg := &deadLineMsg{
-					BlockNum:      self.GetCurrentBlockNo(),
-					PeerIndex:     self.Index,
-					PeerDeadLine:  self.deadline,
-					PeersDeadLine: aaa,
+				if entry := self.peersDeadLine.getEntry(blkNum, self.Index); entry != nil {
+					log.Infof("server %d, broadcast deadline %d of block %d", self.Index, entry.Deadline, blkNum)
+					self.broadcastDeadlineEntries(blkNum, []*deadlineEntry{entry})
 				}
-				self.broadcast(msg)
-				self.peersDeadLine.locker.Unlock()
+			case <-self.quitC:
+				return
 			}
 		}
 	}()
 
-	self.quitC = make(chan struct{})
 	if err := self.LoadChainConfig(store.GetChainedBlockNum()); err != nil {
 		log.Errorf("failed to load config: %s", err)
 		return fmt.Errorf("failed to load config: %s", err)
@@ -543,18 +656,27 @@ func (self *Server) initialize() error {
 	index, present := self.peerPool.GetPeerIndex(id)
 	if present {
 		self.Index = index
+	} else if len(self.miner.PlotDirs()) > 0 || config.DefConfig.Consensus.EnablePoolServer {
+		// miners outside the chain config take part as members
+		index, _, err := self.peerPool.addMember(id)
+		if err != nil {
+			return fmt.Errorf("failed to add self as member: %s", err)
+		}
+		self.Index = index
 	} else {
 		self.Index = math.MaxUint32
 	}
 	self.sub.Subscribe(message.TOPIC_SAVE_BLOCK_COMPLETE)
+	self.quitWg.Add(6)
 	go self.syncer.run()
 	go self.stateMgr.run()
 	go self.msgSendLoop()
 	go self.timerLoop()
 	go self.actionLoop()
+	go self.memberMsgLoop()
 
+	self.quitWg.Add(1)
 	go func() {
-		self.quitWg.Add(1)
 		defer self.quitWg.Done()
 
 		for {
@@ -586,17 +708,7 @@ func (self *Server) start() error {
 	// start peers msg handlers
 	for _, p := range self.config.Peers {
 		peerIdx := p.Index
-		pk := self.peerPool.GetPeerPubKey(peerIdx)
-		if _, present := self.msgRecvC[peerIdx]; !present {
-			self.msgRecvC[peerIdx] = make(chan *p2pMsgPayload, 1024)
-		}
-
-		go func() {
-			if err := self.run(pk); err != nil {
-				log.Errorf("server %d, processor on peer %d failed: %s",
-					self.Index, peerIdx, err)
-			}
-		}()
+		self.startPeerProcessor(peerIdx, self.peerPool.GetPeerPubKey(peerIdx))
 	}
 
 	return nil
@@ -617,6 +729,13 @@ func (self *Server) stop() {
 	self.blockPool.clean()
 	self.chainStore.close()
 	self.peerPool.clean()
+	self.miner.close()
+	if self.pool != nil {
+		self.pool.stop()
+	}
+	if self.history != nil {
+		self.history.Close()
+	}
 }
 
 //
@@ -638,8 +757,7 @@ func (self *Server) run(peerPubKey keypair.PublicKey) error {
 	defer func() {
 		// TODO: handle peer disconnection here
 		log.Warnf("server %d: disconnected with peer %d", self.Index, peerIdx)
-		close(self.msgRecvC[peerIdx])
-		delete(self.msgRecvC, peerIdx)
+		self.closePeerRecvC(peerIdx)
 
 		self.peerPool.peerDisconnected(peerIdx)
 		self.stateMgr.StateEventC <- &StateEvent{
@@ -664,53 +782,6 @@ func (self *Server) run(peerPubKey keypair.PublicKey) error {
 			if err != nil {
 				log.Errorf("server %d failed to deserialize poc msg (len %d): %s", self.Index, len(msgData), err)
 			} else {
-				/// add by zhourz
-				if msg.Type() == DeadLineMessage {
-					if dl := msg.(*deadLineMsg); dl != nil {
-						self.peersDeadLine.locker.Lock()
-						self.peersDeadLineBackUp.locker.Lock()
-						if _, ok := self.peersDeadLine.blockpeersdeadline[msg.GetBlockNum()]; ok {
-							self.peersDeadLine.blockpeersdeadline[msg.GetBlockNum()].locker.Lock()
-							self.peersDeadLine.blockpeersdeadline[msg.GetBlockNum()].peersdeadline[dl.PeerIndex] = dl.PeerDeadLine
-							self.peersDeadLine.blockpeersdeadline[msg.GetBlockNum()].locker.Unlock()
-						} else {
-							self.peersDeadLine.blockpeersdeadline[msg.GetBlockNum()] = &peersDeadline{
-								peersdeadline: make(map[uint32]uint64),
-							}
-							self.peersDeadLine.blockpeersdeadline[msg.GetBlockNum()].peersdeadline = map[uint32]uint64{dl.PeerIndex: dl.PeerDeadLine}
-						}
-
-						for blockNum, val := range dl.PeersDeadLine {
-							if _, ok := self.peersDeadLineBackUp.blockpeersdeadline[blockNum]; ok {
-								if len(self.peersDeadLineBackUp.blockpeersdeadline[blockNum].peersdeadline) < len(val) {
-									self.peersDeadLineBackUp.blockpeersdeadline[blockNum].peersdeadline = val
-								} else {
-								}
-							} else {
-								self.peersDeadLineBackUp.blockpeersdeadline[blockNum] = &peersDeadline{
-									peersdeadline: make(map[uint32]uint64),
-								}
-								self.peersDeadLineBackUp.blockpeersdeadline[blockNum].peersdeadline = val
-							}
-						}
-						if len(self.peersDeadLineBackUp.blockpeersdeadline) > 30 {
-							var keys []int
-							for key, _ := range self.peersDeadLineBackUp.blockpeersdeadline {
-								keys = append(keys, int(key))
-							}
-							sort.Ints(keys)
-							for _, key := range keys[:(len(keys) - 30)] {
-								delete(self.peersDeadLineBackUp.blockpeersdeadline, uint32(key))
-							}
-						} else {
-						}
-						self.peersDeadLineBackUp.locker.Unlock()
-						self.peersDeadLine.locker.Unlock()
-					} else {
-					}
-				} else {
-				}
-
 				pk := self.peerPool.GetPeerPubKey(fromPeer)
 				if pk == nil {
 					log.Errorf("server %d failed to get peer %d pubkey", self.Index, fromPeer)
@@ -720,7 +791,12 @@ func (self *Server) run(peerPubKey keypair.PublicKey) error {
 				if msg.Type() == BlockProposalMessage {
 					if proposal := msg.(*blockProposalMsg); proposal != nil {
 						fromPeer = proposal.Block.getProposer()
-						pk = self.peerPool.GetPeerPubKey(proposal.Block.getProposer())
+						pk = self.proposerKey(proposal.Block)
+						if proposal.GetBlockNum() <= self.GetCurrentBlockNo() {
+							if prevBlk, _ := self.blockPool.getSealedBlock(proposal.GetBlockNum() - 1); prevBlk != nil {
+								proposal.prevGenSig = prevBlk.Info.GenSig
+							}
+						}
 					}
 				}
 
@@ -730,6 +806,11 @@ func (self *Server) run(peerPubKey keypair.PublicKey) error {
 					continue
 				}
 
+				if dl, ok := msg.(*deadLineMsg); ok {
+					self.onDeadlineMsg(fromPeer, "", dl)
+					continue
+				}
+
 				if msg.Type() < 4 {
 					log.Infof("server %d received consensus msg, blk %d, type: %d from %d",
 						self.Index, msg.GetBlockNum(), msg.Type(), fromPeer)
@@ -757,8 +838,14 @@ func (self *Server) updateParticipantConfig() error {
 	if block.Info.NewChainConfig != nil {
 		chainconfig = block.Info.NewChainConfig
 	}
+	winners := self.recentWinners(blkNum)
+	// members of the committee stay, idle ones make room for new members
+	for _, idx := range append(winners, self.Index) {
+		self.peerPool.touchMember(idx)
+	}
+	self.evictIdleMembers()
 	self.metaLock.Lock()
-	cfg, err := self.buildParticipantConfig(blkNum, block, chainconfig)
+	cfg, err := self.buildParticipantConfig(blkNum, winners, chainconfig)
 	if err == nil {
 		self.currentParticipantConfig = cfg
 	}
@@ -772,6 +859,7 @@ func (self *Server) updateParticipantConfig() error {
 
 func (self *Server) startNewRound() error {
 	blkNum := self.GetCurrentBlockNo()
+	self.relocateIndex()
 
 	if err := self.updateParticipantConfig(); err != nil {
 		log.Errorf("startNewRound error:%s", err)
@@ -822,14 +910,14 @@ func (self *Server) startNewRound() error {
 			}
 		}
 	}
-	if _, _, done := self.blockPool.commitDone(blkNum, self.config.C, self.config.N); done && len(commits) > 0 {
+	if _, _, done := self.blockPool.commitDone(blkNum, self.committeeC(), self.committeeN()); done && len(commits) > 0 {
 		// resend commit msg to msg-processor to restart commit-done processing
 		// Note: commitDone will set Done flag in block-pool, so removed Done flag checking
 		// in commit msg processing.
 		self.blockPool.setCommitDone(blkNum)
 		self.processConsensusMsg(commits[0])
 		return nil
-	} else if _, _, done := self.blockPool.endorseDone(blkNum, self.config.C); done && len(endorses) > 0 {
+	} else if _, _, done := self.blockPool.endorseDone(blkNum, self.committeeC()); done && len(endorses) > 0 {
 		// resend endorse msg to msg-processor to restart endorse-done processing
 		self.processConsensusMsg(endorses[0])
 		return nil
@@ -849,27 +937,115 @@ func (self *Server) startNewRound() error {
 }
 
 func (self *Server) startNewProposal(blkNum uint32) {
-	// make proposal
-	if self.isProposer(blkNum, self.Index) {
-		log.Infof("server %d, proposer for block %d", self.Index, blkNum)
-		// FIXME: possible deadlock on channel
-		self.pocActionC <- &PocAction{
-			Type:     MakeProposal,
-			BlockNum: blkNum,
-			forEmpty: false,
-		}
-	} else if self.is2ndProposer(blkNum, self.Index) {
-		log.Infof("server %d, 2nd proposer for block %d", self.Index, blkNum)
-		if err := self.timer.StartProposalBackoffTimer(blkNum); err != nil {
-			log.Errorf("server %d, startproposalbackofftimer for block %d err:%s", self.Index, blkNum, err)
+	// proposals are made when deadlines expire, see EventDeadlineElapsed
+	self.scheduleProposal(blkNum)
+}
+
+func (self *Server) getDeadlineProof(blkNum uint32) *deadlineProof {
+	self.deadlineLock.RLock()
+	defer self.deadlineLock.RUnlock()
+
+	if self.deadlineProof == nil || self.deadlineProof.BlockNum != blkNum {
+		return nil
+	}
+	return self.deadlineProof
+}
+
+// offerDeadline makes proof the deadline the node proposes with if it beats
+// the one it has for the block, either scanned locally or submitted to its
+// pool, then announces it.
+func (self *Server) offerDeadline(proof *deadlineProof) bool {
+	// drop proofs of a round outdated while they were scanned
+	if _, prevHash := self.blockPool.getSealedBlock(proof.BlockNum - 1); proof.PrevHash != prevHash {
+		return false
+	}
+	self.deadlineLock.Lock()
+	if cur := self.deadlineProof; cur != nil && cur.BlockNum == proof.BlockNum && cur.Deadline <= proof.Deadline {
+		self.deadlineLock.Unlock()
+		return false
+	}
+	self.deadlineProof = proof
+	self.deadline = proof.Deadline
+	self.deadlineLock.Unlock()
+
+	self.onBestDeadline(proof)
+	self.announceDeadline(proof)
+	self.scheduleProposal(proof.BlockNum)
+	return true
+}
+
+// roundDeadline returns the earliest deadline known for blkNum, either the
+// node's own one or one verified from its peers.
+func (self *Server) roundDeadline(blkNum uint32) (uint64, bool) {
+	deadline, found := uint64(0), false
+	if proof := self.getDeadlineProof(blkNum); proof != nil {
+		deadline, found = proof.Deadline, true
+	}
+
+	self.peersDeadLine.locker.Lock()
+	defer self.peersDeadLine.locker.Unlock()
+	if peers, present := self.peersDeadLine.blockpeersdeadline[blkNum]; present {
+		peers.locker.Lock()
+		for _, d := range peers.peersdeadline {
+			if !found || d < deadline {
+				deadline, found = d, true
+			}
 		}
+		peers.locker.Unlock()
 	}
+	return deadline, found
+}
+
+func (self *Server) startDeadlineTimer(blkNum uint32, prevBlk *Block, deadline uint64) {
+	wait := time.Until(time.Unix(int64(prevBlk.Block.Header.Timestamp)+int64(deadline), 0))
+	if wait < 0 {
+		wait = 0
+	}
+	log.Infof("server %d, deadline %d of block %d expires in %v", self.Index, deadline, blkNum, wait)
+	self.timer.StartDeadlineTimer(blkNum, wait)
+}
 
-	// TODO: if new round block proposal has received, go endorsing/committing directly
+// scheduleProposal arms the deadline timer of blkNum to fire when the earliest
+// deadline known for the round expires, relative to the previous block. Nodes
+// knowing no deadline at all fall back to starting the round right away.
+func (self *Server) scheduleProposal(blkNum uint32) {
+	prevBlk, _ := self.blockPool.getSealedBlock(blkNum - 1)
+	if prevBlk == nil {
+		log.Errorf("server %d, failed to get prev block %d to schedule proposal", self.Index, blkNum-1)
+		return
+	}
+	deadline, _ := self.roundDeadline(blkNum)
+	self.startDeadlineTimer(blkNum, prevBlk, deadline)
+}
 
+func (self *Server) handleDeadlineElapsed(evt *TimerEvent) error {
+	blkNum := evt.blockNum
+	if blkNum != self.GetCurrentBlockNo() || self.blockPool.endorsedForBlock(blkNum) {
+		return nil
+	}
+	prevBlk, _ := self.blockPool.getSealedBlock(blkNum - 1)
+	if prevBlk == nil {
+		return fmt.Errorf("failed to get prev block %d", blkNum-1)
+	}
+	if proof := self.getDeadlineProof(blkNum); proof != nil {
+		if deadlineElapsed(prevBlk, proof.Deadline, uint32(time.Now().Unix())) {
+			log.Infof("server %d, deadline %d of block %d elapsed, proposing", self.Index, proof.Deadline, blkNum)
+			self.pocActionC <- &PocAction{
+				Type:     MakeProposal,
+				BlockNum: blkNum,
+				forEmpty: false,
+			}
+		} else {
+			// an earlier deadline of a peer expired, ours is still pending
+			self.startDeadlineTimer(blkNum, prevBlk, proof.Deadline)
+		}
+	}
+
+	// wait for the proposal of the earliest deadline, then endorse/commit as usual
 	if err := self.timer.StartProposalTimer(blkNum); err != nil {
-		log.Errorf("server %d, startnewproposal for block %d err:%s", self.Index, blkNum, err)
+		return fmt.Errorf("failed to start proposal timer for block %d: %s", blkNum, err)
 	}
+	return nil
 }
 
 // verify consensus messsage, then send msg to processMsgEvent
@@ -1119,6 +1295,16 @@ func (self *Server) onConsensusMsg(peerIdx uint32, msg ConsensusMsg, msgHash com
 		}
 
 	case BlockFetchRespMessage:
+		if pMsg, ok := msg.(*BlockFetchRespMsg); ok && pMsg.BlockData != nil &&
+			self.blockPool.isOrphanParent(pMsg.BlockData.Block.Hash()) {
+			// parent of a fork block, see onForkBlock
+			self.pocActionC <- &PocAction{
+				Type:     ForkBlock,
+				BlockNum: pMsg.BlockNumber,
+				block:    pMsg.BlockData,
+			}
+			return
+		}
 		self.syncer.syncMsgC <- &SyncMsg{
 			fromPeer: peerIdx,
 			msg:      msg,
@@ -1215,6 +1401,13 @@ func (self *Server) processProposalMsg(msg *blockProposalMsg) {
 		log.Errorf("BlockPrposalMessage check MerkleRoot blocknum:%d,msg MerkleRoot:%s,self MerkleRoot:%s", msg.GetBlockNum(), msgMerkleRoot.ToHexString(), merkleRoot.ToHexString())
 		return
 	}
+	proposerPk := self.proposerKey(msg.Block)
+	msg.prevGenSig = blk.Info.GenSig
+	if err := msg.verifyGenSig(proposerPk); err != nil {
+		log.Errorf("BlockPrposalMessage check GenSig blocknum:%d, err:%s", msgBlkNum, err)
+		self.msgPool.DropMsg(msg)
+		return
+	}
 	cfg := pocconfig.ChainConfig{}
 	if blk.getNewChainConfig() != nil {
 		cfg = *blk.getNewChainConfig()
@@ -1233,8 +1426,45 @@ func (self *Server) processProposalMsg(msg *blockProposalMsg) {
 		self.msgPool.DropMsg(msg)
 		return
 	}
+	baseTarget, err := self.nextBaseTarget(msgBlkNum, currentBlockTimestamp)
+	if err != nil {
+		log.Errorf("BlockPrposalMessage check blocknum:%d, base target err:%s", msgBlkNum, err)
+		return
+	}
+	if msg.Block.Info.BaseTarget != baseTarget {
+		log.Errorf("BlockPrposalMessage check blocknum:%d,msg baseTarget:%d,self baseTarget:%d", msgBlkNum, msg.Block.Info.BaseTarget, baseTarget)
+		self.msgPool.DropMsg(msg)
+		return
+	}
+	if err := verifyCumulativeDifficulty(msg.Block, blk); err != nil {
+		log.Errorf("BlockPrposalMessage check blocknum:%d, err:%s", msgBlkNum, err)
+		self.msgPool.DropMsg(msg)
+		return
+	}
+	if err := self.verifyPlotBinding(msgBlkNum, blockPlotAccount(msg.Block, proposerPk), proposerPk); err != nil {
+		log.Errorf("BlockPrposalMessage check plot account blocknum:%d, err:%s", msgBlkNum, err)
+		self.msgPool.DropMsg(msg)
+		return
+	}
+	if err := self.verifyBlockDeadline(msg.Block, proposerPk, blk, uint32(time.Now().Unix())); err != nil {
+		log.Errorf("BlockPrposalMessage check deadline blocknum:%d, err:%s", msgBlkNum, err)
+		self.msgPool.DropMsg(msg)
+		return
+	}
+	if err := self.admitMember(pocconfig.PubkeyID(proposerPk), msg.Block.getProposer(), false); err != nil {
+		log.Errorf("BlockPrposalMessage check proposer blocknum:%d, err:%s", msgBlkNum, err)
+		self.msgPool.DropMsg(msg)
+		return
+	}
+
+	if err := self.verifyPocRewardTransaction(msg.Block, proposerPk); err != nil {
+		log.Errorf("BlockPrposalMessage check reward blocknum:%d, err:%s", msgBlkNum, err)
+		self.msgPool.DropMsg(msg)
+		return
+	}
 
-	txs := msg.Block.Block.Transactions
+	// the reward transaction is verified above
+	txs := msg.Block.Block.Transactions[1:]
 	if len(txs) > 0 && self.nonSystxs(txs, msgBlkNum) {
 		height := uint32(msgBlkNum) - 1
 		start, end := self.incrValidator.BlockRange()
@@ -1298,7 +1528,12 @@ func (self *Server) processMsgEvent() error {
 				// add proposal to block-pool
 				if err := self.blockPool.newBlockProposal(pMsg); err != nil {
 					if err == errDupProposal {
-						// TODO: faulty proposer detected
+						for _, p := range self.blockPool.getBlockProposals(msgBlkNum) {
+							if p.Block.getProposer() == pMsg.Block.getProposer() {
+								self.reportDoubleProposal(pMsg.Block.getProposer(), p.Block, pMsg.Block)
+								break
+							}
+						}
 					}
 					log.Errorf("failed to add block proposal (%d): %s", msgBlkNum, err)
 					return nil
@@ -1365,7 +1600,7 @@ func (self *Server) processMsgEvent() error {
 					//                      start WaitEndorsementTimer
 
 					// TODO: should only count endorsements from endorsers
-					if proposer, forEmpty, done := self.blockPool.endorseDone(msgBlkNum, self.config.C); done {
+					if proposer, forEmpty, done := self.blockPool.endorseDone(msgBlkNum, self.committeeC()); done {
 						// stop endorse timer
 						self.timer.CancelEndorseMsgTimer(msgBlkNum)
 						// stop empty endorse timer
@@ -1386,7 +1621,7 @@ func (self *Server) processMsgEvent() error {
 				} else {
 					// makeEndorsementTimeout handles non-endorser endorsements
 				}
-				if self.blockPool.endorseFailed(msgBlkNum, self.config.C) {
+				if self.blockPool.endorseFailed(msgBlkNum, self.committeeC()) {
 					// endorse failed, start empty endorsing
 					self.timer.C <- &TimerEvent{
 						evtType:  EventEndorseBlockTimeout,
@@ -1417,7 +1652,7 @@ func (self *Server) processMsgEvent() error {
 				log.Infof("server %d received commit from %d, for proposer %d, block %d, empty: %t",
 					self.Index, pMsg.Committer, pMsg.BlockProposer, msgBlkNum, pMsg.CommitForEmpty)
 
-				if proposer, forEmpty, done := self.blockPool.commitDone(msgBlkNum, self.config.C, self.config.N); done {
+				if proposer, forEmpty, done := self.blockPool.commitDone(msgBlkNum, self.committeeC(), self.committeeN()); done {
 					self.blockPool.setCommitDone(msgBlkNum)
 					proposal := self.findBlockProposal(msgBlkNum, proposer, forEmpty)
 					if proposal == nil {
@@ -1459,7 +1694,6 @@ func (self *Server) processMsgEvent() error {
 }
 
 func (self *Server) actionLoop() {
-	self.quitWg.Add(1)
 	defer self.quitWg.Done()
 
 	for {
@@ -1517,8 +1751,8 @@ func (self *Server) actionLoop() {
 				// 2. if commit consensused, seal the proposal
 				for {
 					blkNum := self.GetCurrentBlockNo()
-					C := int(self.config.C)
-					N := int(self.config.N)
+					C := int(self.committeeC())
+					N := int(self.committeeN())
 
 					if err := self.updateParticipantConfig(); err != nil {
 						log.Errorf("server %d update config failed in forwarding: %s", self.Index, err)
@@ -1623,7 +1857,7 @@ func (self *Server) actionLoop() {
 				}
 				if self.isEndorser(blkNum, self.Index) {
 					rebroadcasted := false
-					endorseFailed := self.blockPool.endorseFailed(blkNum, self.config.C)
+					endorseFailed := self.blockPool.endorseFailed(blkNum, self.committeeC())
 					eMsgs := self.msgPool.GetEndorsementsMsgs(blkNum)
 					for _, msg := range eMsgs {
 						e := msg.(*blockEndorseMsg)
@@ -1665,7 +1899,7 @@ func (self *Server) actionLoop() {
 						}
 					}
 					if !committed {
-						if proposer, forEmpty, done := self.blockPool.endorseDone(blkNum, self.config.C); done {
+						if proposer, forEmpty, done := self.blockPool.endorseDone(blkNum, self.committeeC()); done {
 							proposal := self.findBlockProposal(blkNum, proposer, forEmpty)
 
 							// consensus ok, make endorsement
@@ -1680,7 +1914,7 @@ func (self *Server) actionLoop() {
 								log.Errorf("server %d failed to commit block %d on rebroadcasting: %s",
 									self.Index, blkNum, err)
 							}
-						} else if self.blockPool.endorseFailed(blkNum, self.config.C) {
+						} else if self.blockPool.endorseFailed(blkNum, self.committeeC()) {
 							// endorse failed, start empty endorsing
 							self.timer.C <- &TimerEvent{
 								evtType:  EventEndorseBlockTimeout,
@@ -1704,6 +1938,10 @@ func (self *Server) actionLoop() {
 						log.Errorf("SubmitBlock err:%s", err)
 					}
 				}
+			case ForkBlock:
+				if err := self.onForkBlock(action.block); err != nil {
+					log.Warnf("server %d, fork block %d: %s", self.Index, action.BlockNum, err)
+				}
 			}
 		case <-self.quitC:
 			log.Infof("server %d actionLoop quit", self.Index)
@@ -1713,7 +1951,6 @@ func (self *Server) actionLoop() {
 }
 
 func (self *Server) timerLoop() {
-	self.quitWg.Add(1)
 	defer self.quitWg.Done()
 
 	for {
@@ -1794,7 +2031,7 @@ func (self *Server) processTimerEvent(evt *TimerEvent) error {
 		if !isReady(self.getState()) {
 			return nil
 		}
-		if proposer, forEmpty, done := self.blockPool.endorseDone(evt.blockNum, self.config.C); done {
+		if proposer, forEmpty, done := self.blockPool.endorseDone(evt.blockNum, self.committeeC()); done {
 			proposal := self.findBlockProposal(evt.blockNum, proposer, forEmpty)
 
 			// consensus ok, make endorsement
@@ -1852,7 +2089,7 @@ func (self *Server) processTimerEvent(evt *TimerEvent) error {
 		if !isReady(self.getState()) {
 			return nil
 		}
-		if proposer, forEmpty, done := self.blockPool.endorseDone(evt.blockNum, self.config.C); done {
+		if proposer, forEmpty, done := self.blockPool.endorseDone(evt.blockNum, self.committeeC()); done {
 			proposal := self.findBlockProposal(evt.blockNum, proposer, forEmpty)
 
 			// consensus ok, make endorsement
@@ -1897,7 +2134,7 @@ func (self *Server) processTimerEvent(evt *TimerEvent) error {
 			return nil
 		}
 		if !self.blockPool.isCommitHadDone(evt.blockNum) {
-			if proposer, forEmpty, done := self.blockPool.commitDone(evt.blockNum, self.config.C, self.config.N); done {
+			if proposer, forEmpty, done := self.blockPool.commitDone(evt.blockNum, self.committeeC(), self.committeeN()); done {
 				self.blockPool.setCommitDone(evt.blockNum)
 				proposal := self.findBlockProposal(evt.blockNum, proposer, forEmpty)
 				if proposal == nil {
@@ -1943,6 +2180,9 @@ func (self *Server) processTimerEvent(evt *TimerEvent) error {
 		self.timer.stopTxTicker(evt.blockNum)
 		self.timer.CancelTxBlockTimeout(evt.blockNum)
 		self.startNewProposal(evt.blockNum)
+
+	case EventDeadlineElapsed:
+		return self.handleDeadlineElapsed(evt)
 	}
 	return nil
 }
@@ -1979,7 +2219,8 @@ func (self *Server) processHeartbeatMsg(peerIdx uint32, msg *peerHeartbeatMsg) {
 
 func (self *Server) endorseBlock(proposal *blockProposalMsg, forEmpty bool) error {
 	// for each round, one node can only endorse one block, or empty block
-	if proposal.Block.getProposer() == self.Index {
+	// a sole chain peer has to endorse its own proposals
+	if proposal.Block.getProposer() == self.Index && !self.soleChainPeer() {
 		return nil
 	}
 	blkNum := proposal.GetBlockNum()
@@ -1992,7 +2233,7 @@ func (self *Server) endorseBlock(proposal *blockProposalMsg, forEmpty bool) erro
 	}
 
 	if !forEmpty {
-		if self.blockPool.endorseFailed(blkNum, self.config.C) {
+		if self.blockPool.endorseFailed(blkNum, self.committeeC()) {
 			forEmpty = true
 			log.Errorf("server %d, endorsing %d, changed from true to false", self.Index, blkNum)
 		}
@@ -2039,7 +2280,7 @@ func (self *Server) endorseBlock(proposal *blockProposalMsg, forEmpty bool) erro
 
 func (self *Server) commitBlock(proposal *blockProposalMsg, forEmpty bool) error {
 	// for each round, we can only commit one block
-	if proposal.Block.getProposer() == self.Index {
+	if proposal.Block.getProposer() == self.Index && !self.soleChainPeer() {
 		return nil
 	}
 	blkNum := proposal.GetBlockNum()
@@ -2122,9 +2363,14 @@ func (self *Server) fastForwardBlock(block *Block) error {
 		return fmt.Errorf("server %d: invalid fastforward, current state: %d", self.Index, self.getState())
 	}
 	if self.GetCurrentBlockNo() > block.getBlockNum() {
-		return nil
+		// a competing block of a height we already sealed
+		return self.onForkBlock(block)
 	}
 	if self.GetCurrentBlockNo() == block.getBlockNum() {
+		if _, prevHash := self.blockPool.getSealedBlock(block.getBlockNum() - 1); prevHash != block.getPrevBlockHash() {
+			// the block extends a competing branch
+			return self.onForkBlock(block)
+		}
 		// block from peer syncer, there should only one candidate block
 		flag := false
 		if len(block.Block.Header.SigData) == 0 {
@@ -2151,6 +2397,12 @@ func (self *Server) sealBlock(block *Block, empty bool, sigdata bool) error {
 	if err := self.blockPool.setBlockSealed(block, empty, sigdata); err != nil {
 		return fmt.Errorf("failed to seal proposal: %s", err)
 	}
+	if pk := self.proposerKey(block); pk != nil {
+		// the chain decided for the proposer, it takes its index on every node
+		if err := self.admitMember(pocconfig.PubkeyID(pk), block.getProposer(), true); err != nil {
+			log.Warnf("server %d, proposer of sealed block %d: %s", self.Index, sealedBlkNum, err)
+		}
+	}
 
 	// TODO: also persistent the block endorsers and committer msgs
 
@@ -2173,9 +2425,20 @@ func (self *Server) sealBlock(block *Block, empty bool, sigdata bool) error {
 		}
 		self.metaLock.Unlock()
 	}
+	self.miningInfo.sealRound(block)
+	self.recordHistory(block)
+	self.notifyNewRound()
 	return nil
 }
 
+// notifyNewRound wakes up the deadline scanner to start the next round.
+func (self *Server) notifyNewRound() {
+	select {
+	case self.newRoundC <- struct{}{}:
+	default:
+	}
+}
+
 /// add by zhourz
 //字节数(大端)组转成int(无符号的)
 func (self *Server) bytesToIntU(b []byte) (int, error) {
@@ -2209,110 +2472,89 @@ func (self *Server) bytesToIntU(b []byte) (int, error) {
 	}
 }
 
+// calDeadLine scans the plots for every new round, as soon as its previous
+// block is sealed. The scan of an outdated round is cancelled, and the best
+// deadline is offered each time the scan improves it.
 func (self *Server) calDeadLine() {
-	self.quitWg.Add(1)
 	defer self.quitWg.Done()
 
-	ticker := time.NewTicker(time.Second * 3)
+	accountID := pocconfig.PubkeyID(self.account.PubKey())
+	cfg := config.DefConfig.Consensus
+	scanner := newPlotScanner(cfg.ScanWorkers, uint64(cfg.ScanRateLimit)<<20)
+	scanner.read = self.miningInfo.addScanned
+	mode := genesisPlotVerification()
+	if self.zkProver != nil && mode != config.POC_PLOT_VERIFY_NONCE {
+		scanner.zk = &self.zkProver.Params
+	}
+	scanner.zkOnly = mode == config.POC_PLOT_VERIFY_ZK
+	var scanned common.Uint256
+	var cancelC chan struct{}
+	cancel := func() {
+		if cancelC != nil {
+			close(cancelC)
+			cancelC = nil
+		}
+	}
+	defer cancel()
+
+	// the ticker catches the tip changes not going through sealBlock
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
 	for {
 		select {
+		case <-self.newRoundC:
 		case <-ticker.C:
-			rd, err := ioutil.ReadDir(config.DefConfig.Genesis.POC.NonceDir)
-			if err != nil {
-				log.Error(err)
-			}
-			blk, err := self.chainStore.getBlock(ledger.DefLedger.GetCurrentBlockHeight())
-			if err != nil {
-				log.Error(err)
-			}
-			var rdl []os.FileInfo
-			if len(rd) > 0 {
-				for i := 0; i < 20; i++ {
-					rdl = append(rdl, rd[rand.Intn(len(rd))])
-				}
-			}
-
-			var deadlines []int
-			for i := 0; i < len(rdl); i++ {
-				fi := rdl[i]
-
-				if fi.IsDir() {
-					log.Info("[%s]\n", config.DefConfig.Genesis.POC.NonceDir+"\\"+fi.Name())
-					//GetAllFile(pathname + fi.Name() + "\\")
-					continue
-				} else if fi.Name() == ".DS_Store" {
-					continue
-				} else {
-					if strings.Index(fi.Name(), "target") != -1 {
-						err := os.Remove(config.DefConfig.Genesis.POC.NonceDir + "/" + fi.Name())
-						if err != nil {
-							log.Error(err)
-						}
-						continue
-					}
-					if err := func() error {
-						nonceFile, err := os.OpenFile(config.DefConfig.Genesis.POC.NonceDir+"/"+fi.Name(), os.O_RDONLY, 0600)
-						if err != nil {
-							return err
-						}
-						defer nonceFile.Close()
-						nonceByte, err := ioutil.ReadAll(nonceFile)
-						if err != nil {
-							return err
-						}
-						if len(nonceByte) == 0 {
-							err := os.Remove(config.DefConfig.Genesis.POC.NonceDir + "/" + fi.Name())
-							if err != nil {
-								return err
-							}
-						}
-						Callshabal("genHash_Target256", []byte(strconv.FormatUint(blk.Block.Header.ConsensusData, 10)),
-							[]byte(strconv.FormatUint(uint64(blk.Info.Proposer), 10)), []byte(fi.Name()),
-							[]byte(config.DefConfig.Genesis.POC.NonceDir), []byte(fi.Name()))
-						fileObj, err := os.Open(config.DefConfig.Genesis.POC.NonceDir + "/target" + fi.Name())
-						if err != nil {
-							return err
-						}
-						defer fileObj.Close()
-						target, err := ioutil.ReadAll(fileObj)
-						if err != nil {
-							return err
-						}
-						target0Int, err := self.bytesToIntU(target[0:4])
-						if err != nil {
-							return err
-						}
-						baseTargetInt, err := self.bytesToIntU(blk.Block.Header.ConsensusPayload[0:1])
-						if err != nil {
-							return err
-						}
-
-						if target0Int != 0 {
-							deadlines = append(deadlines, target0Int/baseTargetInt)
-						}
-						err = os.Remove(config.DefConfig.Genesis.POC.NonceDir + "/target" + fi.Name())
-						if err != nil {
-							return err
-						}
-						return nil
-					}(); err != nil {
-						log.Error(err)
-						continue
+		case <-self.quitC:
+			return
+		}
+		blkNum := self.GetCurrentBlockNo()
+		prevBlk, prevHash := self.blockPool.getSealedBlock(blkNum - 1)
+		if prevBlk == nil {
+			log.Errorf("server %d failed to get prev block %d", self.Index, blkNum-1)
+			continue
+		}
+		if !self.miner.Mining() {
+			// rescan once mining is resumed
+			cancel()
+			scanned = common.Uint256{}
+			continue
+		}
+		// rescan whenever the chain tip changes, including reorganizations
+		if prevHash == scanned {
+			continue
+		}
+		round, err := newMiningRound(blkNum, prevBlk)
+		if err != nil {
+			log.Errorf("server %d failed to get mining round %d: %s", self.Index, blkNum, err)
+			continue
+		}
+		var dirs []string
+		for _, dir := range self.miner.PlotDirs() {
+			dirs = append(dirs, dir.Path)
+		}
+		cancel()
+		cancelC = make(chan struct{})
+		scanned = prevHash
+		self.onMiningRound(round)
+		go func(cancelC chan struct{}) {
+			start := time.Now()
+			best := scanner.scan(dirs, accountID, round, cancelC, func(proof *deadlineProof) {
+				if proof.PlotPath != "" {
+					if err := proveDeadline(self.zkProver, proof, round); err != nil {
+						log.Errorf("server %d failed to prove deadline %d of block %d: %s", self.Index, proof.Deadline, round.BlockNum, err)
+						return
 					}
 				}
+				self.offerDeadline(proof)
+			})
+			if best != nil {
+				log.Infof("server %d, scanned block %d in %v, best deadline %d", self.Index, round.BlockNum, time.Since(start), best.Deadline)
 			}
-
-			sort.Sort(sort.IntSlice(deadlines))
-			if len(deadlines) > 0 {
-				self.deadline = uint64(deadlines[0])
-			} else {
-			}
-		}
+		}(cancelC)
 	}
 }
 
 func (self *Server) msgSendLoop() {
-	self.quitWg.Add(1)
 	defer self.quitWg.Done()
 
 	for {
@@ -2411,10 +2653,28 @@ func (self *Server) makeProposal(blkNum uint32, forEmpty bool) error {
 			self.Index, blkNum, self.GetCurrentBlockNo())
 	}
 
+	proof := self.getDeadlineProof(blkNum)
+	if proof == nil {
+		return fmt.Errorf("server %d has no deadline for block %d", self.Index, blkNum)
+	}
+	prevBlk, _ := self.blockPool.getSealedBlock(blkNum - 1)
+	if prevBlk == nil {
+		return fmt.Errorf("failed to get prev block %d", blkNum-1)
+	}
+	if !deadlineElapsed(prevBlk, proof.Deadline, uint32(time.Now().Unix())) {
+		return fmt.Errorf("server %d deadline %d of block %d not elapsed", self.Index, proof.Deadline, blkNum)
+	}
+
 	validHeight := self.validHeight(blkNum)
 	sysTxs := make([]*types.Transaction, 0)
 	userTxs := make([]*types.Transaction, 0)
 
+	rewardTx, err := self.createPocRewardTransaction(blkNum, proof.AccountID, self.account.PublicKey)
+	if err != nil {
+		return fmt.Errorf("construct reward transaction error: %v", err)
+	}
+	sysTxs = append(sysTxs, rewardTx)
+
 	//check need update chainconfig
 	cfg := &pocconfig.ChainConfig{}
 	cfg = nil
@@ -2446,7 +2706,7 @@ func (self *Server) makeProposal(blkNum uint32, forEmpty bool) error {
 			}
 		}
 	}
-	proposal, err := self.constructProposalMsg(blkNum, sysTxs, userTxs, cfg)
+	proposal, err := self.constructProposalMsg(blkNum, sysTxs, userTxs, cfg, proof)
 	if err != nil {
 		return fmt.Errorf("failed to construct proposal: %s", err)
 	}
@@ -2615,7 +2875,7 @@ func (self *Server) catchConsensus(blkNum uint32) error {
 		proposals[p.Block.getProposer()] = p
 	}
 
-	C := int(self.config.C)
+	C := int(self.committeeC())
 	eMsgs := self.msgPool.GetEndorsementsMsgs(blkNum)
 	var proposal *blockProposalMsg
 	endorseDone := false
@@ -2710,7 +2970,7 @@ func (self *Server) verifyPrevBlockHash(blkNum uint32, proposal *blockProposalMs
 func (self *Server) hasBlockConsensused() bool {
 	blkNum := self.GetCurrentBlockNo()
 
-	C := int(self.config.C)
+	C := int(self.committeeC())
 	cMsgs := self.msgPool.GetCommitMsgs(blkNum)
 	emptyCnt := 0
 	proposers := make(map[uint32]int)
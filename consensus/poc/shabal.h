@@ -1,6 +0,0 @@
-void shabal256(char *buf1, char *buf2);
-void shabal512(char *buf1, char *buf2);
-void genNonce256(char *buf1, char *buf2, char *buf3);
-void genNonce512(char *buf1, char *buf2, char *buf3);
-void genHash_Target256(char *buf1, char *buf2, char *buf3, char *buf4, char *buf5);
-void genHash_Target512(char *buf1, char *buf2, char *buf3, char *buf4, char *buf5);
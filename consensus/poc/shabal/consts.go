@@ -0,0 +1,59 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package shabal
+
+var aInit256 = [12]uint32{
+	0x52F84552, 0xE54B7999, 0x2D8EE3EC, 0xB9645191,
+	0xE0078B86, 0xBB7C44C9, 0xD2B5C1CA, 0xB0D2EB8C,
+	0x14CE5A45, 0x22AF50DC, 0xEFFDBC6B, 0xEB21B74A,
+}
+
+var bInit256 = [16]uint32{
+	0xB555C6EE, 0x3E710596, 0xA72A652F, 0x9301515F,
+	0xDA28C1FA, 0x696FD868, 0x9CB6BF72, 0x0AFE4002,
+	0xA6E03615, 0x5138C1D4, 0xBE216306, 0xB38B8890,
+	0x3EA8B96B, 0x3299ACE4, 0x30924DD4, 0x55CB34A5,
+}
+
+var cInit256 = [16]uint32{
+	0xB405F031, 0xC4233EBA, 0xB3733979, 0xC0DD9D55,
+	0xC51C28AE, 0xA327B8E1, 0x56C56167, 0xED614433,
+	0x88B59D60, 0x60E2CEBA, 0x758B4B8B, 0x83E82A7F,
+	0xBC968828, 0xE6E00BF7, 0xBA839E55, 0x9B491C60,
+}
+
+var aInit512 = [12]uint32{
+	0x20728DFD, 0x46C0BD53, 0xE782B699, 0x55304632,
+	0x71B4EF90, 0x0EA9E82C, 0xDBB930F1, 0xFAD06B8B,
+	0xBE0CAE40, 0x8BD14410, 0x76D2ADAC, 0x28ACAB7F,
+}
+
+var bInit512 = [16]uint32{
+	0xC1099CB7, 0x07B385F3, 0xE7442C26, 0xCC8AD640,
+	0xEB6F56C7, 0x1EA81AA9, 0x73B9D314, 0x1DE85D08,
+	0x48910A5A, 0x893B22DB, 0xC5A0DF44, 0xBBC4324E,
+	0x72D2F240, 0x75941D99, 0x6D8BDE82, 0xA1A7502B,
+}
+
+var cInit512 = [16]uint32{
+	0xD9BF68D1, 0x58BAD750, 0x56028CB2, 0x8134F359,
+	0xB5D469D8, 0x941A8CC2, 0x418B2A6E, 0x04052780,
+	0x7F07D787, 0x5194358F, 0x3C60D665, 0xBE97D79A,
+	0x950C3434, 0xAED9A06D, 0x2537DC8D, 0x7CDB5969,
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package shabal
+
+import (
+	"encoding/binary"
+	"strconv"
+)
+
+const (
+	HashSize   = Size256
+	NonceSize  = 262144
+	ScoopSize  = 64
+	ScoopCount = NonceSize / ScoopSize
+	HashCount  = NonceSize / HashSize
+
+	// hashes are chained over a window of at most hashCap bytes
+	hashCap = 4096
+)
+
+// GenNonce256 generates the nonce nonceNr plotted for accountID.
+func GenNonce256(nonceNr uint64, accountID string) []byte {
+	seed := []byte(accountID + strconv.FormatUint(nonceNr, 10))
+
+	// gen holds the hashes in generation order, each hash is computed over
+	// the seed prefixed with the previous hashes (latest first) until the
+	// input reaches hashCap, then over the latest hashCap/HashSize hashes.
+	gen := make([]byte, NonceSize)
+	chain := seed
+	n := 0
+	for ; n < HashCount && len(chain)+HashSize < hashCap; n++ {
+		sum := Sum256(chain)
+		copy(gen[n*HashSize:], sum[:])
+		chain = append(sum[:], chain...)
+	}
+	for ; n < HashCount; n++ {
+		start := n - hashCap/HashSize
+		if start < 0 {
+			start = 0
+		}
+		sum := Sum256(gen[start*HashSize : n*HashSize])
+		copy(gen[n*HashSize:], sum[:])
+	}
+
+	h := New256()
+	for i := HashCount - 1; i >= 0; i-- {
+		h.Write(gen[i*HashSize : (i+1)*HashSize])
+	}
+	h.Write(seed)
+	final := h.Sum(nil)
+
+	nonce := gen
+	for i := range nonce {
+		b := nonce[i] ^ final[i%HashSize]
+		if b >= 'A' && b <= 'Z' {
+			b += 'a' - 'A'
+		}
+		nonce[i] = b
+	}
+	return nonce
+}
+
+// GenSig256 returns the generation signature of a block from the previous
+// block's generation signature and the block's generator.
+func GenSig256(prevGenSig []byte, generator []byte) []byte {
+	h := New256()
+	h.Write(prevGenSig)
+	h.Write(generator)
+	return h.Sum(nil)
+}
+
+// ScoopNum256 returns the index of the scoop selected by gensig, the last 4
+// bytes of its hash modulo ScoopCount. Unlike libshabal every scoop can be
+// selected, see the package doc.
+func ScoopNum256(gensig []byte) uint32 {
+	sum := Sum256(gensig)
+	return binary.BigEndian.Uint32(sum[HashSize-4:]) % ScoopCount
+}
+
+// Target256 hashes a scoop with the generation signature.
+func Target256(gensig []byte, scoop []byte) []byte {
+	h := New256()
+	h.Write(scoop)
+	h.Write(gensig)
+	return h.Sum(nil)
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package shabal
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+const testAccountID = "03a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f90"
+
+// the nonce, gensig and target vectors are outputs of the Rust libshabal
+// genNonce256 and genHash_Target256 this package replaces, at heights 7 and
+// 100 where libshabal selects scoops 0 and 1
+
+func TestGenNonce256(t *testing.T) {
+	vectors := []struct {
+		nonceNr   uint64
+		accountID string
+		sum       string
+		head      string
+		tail      string
+	}{
+		{
+			12345, testAccountID,
+			"3cbb391b31f6139319e8f8bde4e55340df3257db878fb088a2e850f02dec1032",
+			"a37bd226f71ee3c3be996e6d19f2cbc9966a6a332e936f7504e63e11c80f35d8a03c158c8c7a63fde46265153cbcfd23aa07a03a699339a160a1ed90ae79300e",
+			"c39f0bf7e71cb5a9c5652b5ca76d081207200dc92cbaa5fd27a481b9deeadcf53c1c17a3c594198f84f905b98705fb0a638e0d04b6340a80de6a9a5e2a6f1170",
+		},
+		{
+			0, "035110e9",
+			"719f0decf28b170e5de8da47e5a180afb3f544e6bb2d83679ac60c8696780108",
+			"fc6b199920f1b65f7cdad6f82006c5901ec3eee78b8cbd1264602fd68d9c015feb6d3afb98d476aee6b6de3a71b79be8af18b06404d27a8ad68cc0f2ecbf1217",
+			"04e1c779181af3317178e23fe7abf5ae9d31e78c65c85c019c769995226fc1a19408f3b5d4f9e1ff689a72cdc2f7c43a85dedaf49fe13d73cb2bb6b6b8e2cb76",
+		},
+	}
+	for _, v := range vectors {
+		nonce := GenNonce256(v.nonceNr, v.accountID)
+		if len(nonce) != NonceSize {
+			t.Fatalf("nonce %d: len %d", v.nonceNr, len(nonce))
+		}
+		sum := Sum256(nonce)
+		if hex.EncodeToString(sum[:]) != v.sum {
+			t.Errorf("nonce %d: sum %x, expected %s", v.nonceNr, sum, v.sum)
+		}
+		if hex.EncodeToString(nonce[:ScoopSize]) != v.head {
+			t.Errorf("nonce %d: first scoop %x", v.nonceNr, nonce[:ScoopSize])
+		}
+		if hex.EncodeToString(nonce[NonceSize-ScoopSize:]) != v.tail {
+			t.Errorf("nonce %d: last scoop %x", v.nonceNr, nonce[NonceSize-ScoopSize:])
+		}
+	}
+}
+
+func TestGenSig256(t *testing.T) {
+	gensig := GenSig256([]byte("ab5c35fd"), []byte("1496cf14"))
+	if hex.EncodeToString(gensig) != "e1f29a673ca0a6ebd88822ffd3a986726ed4f45a5124a86854409f6d899cdbc0" {
+		t.Errorf("gensig: %x", gensig)
+	}
+}
+
+// ScoopNum256 diverges from libshabal, its vectors are from this package
+func TestScoopNum256(t *testing.T) {
+	gensig := GenSig256([]byte("ab5c35fd"), []byte("1496cf14"))
+	for _, expected := range []uint32{1315, 2461, 3950, 3006} {
+		if n := ScoopNum256(gensig); n != expected {
+			t.Errorf("scoop num of gensig %x: %d, expected %d", gensig, n, expected)
+		}
+		gensig = GenSig256(gensig, []byte("gen"))
+	}
+}
+
+func TestTarget256(t *testing.T) {
+	gensig := GenSig256([]byte("ab5c35fd"), []byte("1496cf14"))
+	nonce := GenNonce256(12345, testAccountID)
+	vectors := map[int]string{
+		0: "29deeb655e54b43c2eca078ab2c438747088873de687b24cd608b48e82cf1f1a",
+		1: "1992afd33a07aa658a3c1d119d0db265d4fd45cdc41417276be91a9dca2d84be",
+	}
+	for idx, expected := range vectors {
+		target := Target256(gensig, nonce[idx*ScoopSize:(idx+1)*ScoopSize])
+		if hex.EncodeToString(target) != expected {
+			t.Errorf("target of scoop %d: %x, expected %s", idx, target, expected)
+		}
+	}
+}
+
+func BenchmarkGenNonce256(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		GenNonce256(uint64(i), testAccountID)
+	}
+}
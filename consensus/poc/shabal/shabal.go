@@ -0,0 +1,211 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package shabal implements the Shabal-256 and Shabal-512 hash algorithms and
+// the PoC nonce and target hashing built on them.
+//
+// GenNonce256, GenSig256 and Target256 produce the same bytes as the
+// genNonce256 and genHash_Target256 functions of the Rust libshabal this
+// package replaces. ScoopNum256 intentionally diverges: libshabal picked the
+// scoop as the byte sum of shabal256(height || gensig) divided by 4096, which
+// only ever selects scoop 0 or 1. Plotted nonces are compatible, deadlines
+// computed by libshabal are not.
+package shabal
+
+import (
+	"encoding/binary"
+	"hash"
+)
+
+const (
+	BlockSize = 64
+	Size256   = 32
+	Size512   = 64
+)
+
+type digest struct {
+	a     [12]uint32
+	b     [16]uint32
+	c     [16]uint32
+	wlow  uint32
+	whigh uint32
+	x     [BlockSize]byte
+	nx    int
+	size  int
+}
+
+// New256 returns a new hash.Hash computing the Shabal-256 checksum.
+func New256() hash.Hash {
+	d := &digest{size: Size256}
+	d.Reset()
+	return d
+}
+
+// New512 returns a new hash.Hash computing the Shabal-512 checksum.
+func New512() hash.Hash {
+	d := &digest{size: Size512}
+	d.Reset()
+	return d
+}
+
+// Sum256 returns the Shabal-256 checksum of the data.
+func Sum256(data []byte) [Size256]byte {
+	d := digest{size: Size256}
+	d.Reset()
+	d.Write(data)
+	var sum [Size256]byte
+	d.checkSum(sum[:0])
+	return sum
+}
+
+// Sum512 returns the Shabal-512 checksum of the data.
+func Sum512(data []byte) [Size512]byte {
+	d := digest{size: Size512}
+	d.Reset()
+	d.Write(data)
+	var sum [Size512]byte
+	d.checkSum(sum[:0])
+	return sum
+}
+
+func (d *digest) Reset() {
+	if d.size == Size512 {
+		d.a, d.b, d.c = aInit512, bInit512, cInit512
+	} else {
+		d.a, d.b, d.c = aInit256, bInit256, cInit256
+	}
+	d.wlow, d.whigh = 1, 0
+	d.nx = 0
+}
+
+func (d *digest) Size() int {
+	return d.size
+}
+
+func (d *digest) BlockSize() int {
+	return BlockSize
+}
+
+func (d *digest) Write(p []byte) (int, error) {
+	nn := len(p)
+	if d.nx > 0 {
+		n := copy(d.x[d.nx:], p)
+		d.nx += n
+		if d.nx == BlockSize {
+			d.compress(d.x[:])
+			d.nx = 0
+		}
+		p = p[n:]
+	}
+	for len(p) >= BlockSize {
+		d.compress(p[:BlockSize])
+		p = p[BlockSize:]
+	}
+	if len(p) > 0 {
+		d.nx = copy(d.x[:], p)
+	}
+	return nn, nil
+}
+
+func (d *digest) Sum(in []byte) []byte {
+	// make a copy of d so that caller can keep writing and summing
+	d0 := *d
+	return d0.checkSum(in)
+}
+
+func (d *digest) checkSum(in []byte) []byte {
+	var m [16]uint32
+	d.x[d.nx] = 0x80
+	for i := d.nx + 1; i < BlockSize; i++ {
+		d.x[i] = 0
+	}
+	readM(&m, d.x[:])
+	d.addM(&m)
+	d.xorW()
+	d.perm(&m)
+	for i := 0; i < 3; i++ {
+		d.b, d.c = d.c, d.b
+		d.xorW()
+		d.perm(&m)
+	}
+
+	var out [Size512]byte
+	for i, v := range d.b {
+		binary.LittleEndian.PutUint32(out[4*i:], v)
+	}
+	return append(in, out[Size512-d.size:]...)
+}
+
+func readM(m *[16]uint32, p []byte) {
+	for i := range m {
+		m[i] = binary.LittleEndian.Uint32(p[4*i:])
+	}
+}
+
+func (d *digest) compress(p []byte) {
+	var m [16]uint32
+	readM(&m, p)
+	d.addM(&m)
+	d.xorW()
+	d.perm(&m)
+	d.subM(&m)
+	d.b, d.c = d.c, d.b
+	d.wlow++
+	if d.wlow == 0 {
+		d.whigh++
+	}
+}
+
+func (d *digest) addM(m *[16]uint32) {
+	for i := range d.b {
+		d.b[i] += m[i]
+	}
+}
+
+func (d *digest) subM(m *[16]uint32) {
+	for i := range d.c {
+		d.c[i] -= m[i]
+	}
+}
+
+func (d *digest) xorW() {
+	d.a[0] ^= d.wlow
+	d.a[1] ^= d.whigh
+}
+
+func (d *digest) perm(m *[16]uint32) {
+	for i := range d.b {
+		d.b[i] = d.b[i]<<17 | d.b[i]>>15
+	}
+	for j := 0; j < 3; j++ {
+		for i := 0; i < 16; i++ {
+			xa0 := (16*j + i) % 12
+			xa1 := (16*j + i + 11) % 12
+			xb0, xb1, xb2, xb3 := i, (i+13)%16, (i+9)%16, (i+6)%16
+			xc := d.c[(24-i)%16]
+
+			a1 := d.a[xa1]
+			d.a[xa0] = (d.a[xa0]^(a1<<15|a1>>17)*5^xc)*3 ^ d.b[xb1] ^ (d.b[xb2] &^ d.b[xb3]) ^ m[i]
+			b0 := d.b[xb0]
+			d.b[xb0] = ^((b0<<1 | b0>>31) ^ d.a[xa0])
+		}
+	}
+	for i := range d.a {
+		d.a[i] += d.c[(i+11)%16] + d.c[(i+15)%16] + d.c[(i+3)%16]
+	}
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package shabal
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestSum256(t *testing.T) {
+	vectors := []struct {
+		in  string
+		out string
+	}{
+		{"", "aec750d11feee9f16271922fbaf5a9be142f62019ef8d720f858940070889014"},
+		{"helloworld", "d945dee21ffca23ac232763aa9cac6c15805f144db9d6c97395437e01c8595a8"},
+		{strings.Repeat("a", 64), "e9aa7e28984472bc3fb56596f5e8845680fcaf563d01518ed887c757d048c51c"},
+		{strings.Repeat("abcdefghij", 20), "4d3ab141deefa504a2ac36710f7f792c126f0985d6b433443b22b392a16d2007"},
+	}
+	for _, v := range vectors {
+		sum := Sum256([]byte(v.in))
+		if hex.EncodeToString(sum[:]) != v.out {
+			t.Errorf("Sum256(%q): %x, expected %s", v.in, sum, v.out)
+		}
+	}
+}
+
+func TestSum512(t *testing.T) {
+	vectors := []struct {
+		in  string
+		out string
+	}{
+		{"helloworld", "9ddc513fdce7718ad81d377334fc018e0ed17a9128db5dd99be207ad6891602fc2c33773667e0a43918b66977284ae1b8849aecf2b3aebaa16cc5a6001f52e79"},
+		{strings.Repeat("abcdefghij", 20), "1f5f02fae2ef3af2039a6843478bb3fa09927c81be1a6ca72bc5a22bea3ac8bfad3fc36d4c8ef59a3e193efc38fc8da7cbd1418f7f95897db0f4ec63c8e766e0"},
+	}
+	for _, v := range vectors {
+		sum := Sum512([]byte(v.in))
+		if hex.EncodeToString(sum[:]) != v.out {
+			t.Errorf("Sum512(%q): %x, expected %s", v.in, sum, v.out)
+		}
+	}
+}
+
+func TestHashWrite(t *testing.T) {
+	data := []byte(strings.Repeat("abcdefghij", 20))
+	expected := Sum256(data)
+
+	h := New256()
+	for i := 0; i < len(data); i += 7 {
+		end := i + 7
+		if end > len(data) {
+			end = len(data)
+		}
+		h.Write(data[i:end])
+	}
+	if sum := h.Sum(nil); hex.EncodeToString(sum) != hex.EncodeToString(expected[:]) {
+		t.Errorf("chunked write: %x, expected %x", sum, expected)
+	}
+	// Sum must not change the state
+	if sum := h.Sum(nil); hex.EncodeToString(sum) != hex.EncodeToString(expected[:]) {
+		t.Errorf("second sum: %x, expected %x", sum, expected)
+	}
+
+	h.Reset()
+	h.Write([]byte("helloworld"))
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != "d945dee21ffca23ac232763aa9cac6c15805f144db9d6c97395437e01c8595a8" {
+		t.Errorf("after reset: %s", sum)
+	}
+}
@@ -0,0 +1,439 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"OntologyWithPOC/common"
+	"OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/consensus/poc/shabal"
+	"OntologyWithPOC/core/types"
+)
+
+// timestamp of the simulated genesis block
+const simGenesisTimestamp = 1500000000
+
+// SimConfig configures a PoC network simulation. Every node owns a synthetic
+// plot of Nonces[i] nonces, whose scoops are derived from a hash instead of
+// being plotted so large capacities stay cheap to simulate.
+type SimConfig struct {
+	Nonces            []uint64
+	Blocks            uint32 // height of the chain to produce
+	TargetBlockTime   uint32 // seconds
+	InitialBaseTarget uint64
+	RetargetWindow    uint32        // blocks, 0 for the default
+	Latency           time.Duration // one way delay of every message
+	Jitter            time.Duration // random extra delay of up to Jitter
+	Seed              int64
+}
+
+// SimReport is the outcome of a simulation, seen from the chain of node 0.
+type SimReport struct {
+	Height        uint32
+	BlockTimes    []uint32  // seconds between consecutive blocks
+	BaseTargets   []uint64  // base target of every block
+	ProposerShare []float64 // share of the blocks proposed by each node
+	CapacityShare []float64 // share of the nonces owned by each node
+	Produced      int       // blocks proposed, including the orphaned ones
+	Orphaned      int       // proposed blocks not in the chain
+	Reorgs        int       // chain tips replaced by a heavier fork
+	Rejected      int       // received blocks failing verification
+	Splits        int       // nodes ending on another chain than node 0
+}
+
+// ForkRate returns the share of the proposed blocks which got orphaned.
+func (self *SimReport) ForkRate() float64 {
+	if self.Produced == 0 {
+		return 0
+	}
+	return float64(self.Orphaned) / float64(self.Produced)
+}
+
+// MeanBlockTime returns the average block interval in seconds.
+func (self *SimReport) MeanBlockTime() float64 {
+	if len(self.BlockTimes) == 0 {
+		return 0
+	}
+	var sum uint64
+	for _, t := range self.BlockTimes {
+		sum += uint64(t)
+	}
+	return float64(sum) / float64(len(self.BlockTimes))
+}
+
+// BlockTimePercentile returns the block interval p percent of the blocks
+// took at most.
+func (self *SimReport) BlockTimePercentile(p float64) uint32 {
+	if len(self.BlockTimes) == 0 {
+		return 0
+	}
+	times := append([]uint32(nil), self.BlockTimes...)
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	i := int(math.Ceil(p/100*float64(len(times)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(times) {
+		i = len(times) - 1
+	}
+	return times[i]
+}
+
+type simEvent struct {
+	at   time.Duration
+	seq  uint64
+	fire func()
+}
+
+type simQueue []*simEvent
+
+func (q simQueue) Len() int { return len(q) }
+func (q simQueue) Less(i, j int) bool {
+	if q[i].at != q[j].at {
+		return q[i].at < q[j].at
+	}
+	return q[i].seq < q[j].seq
+}
+func (q simQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *simQueue) Push(x interface{}) { *q = append(*q, x.(*simEvent)) }
+func (q *simQueue) Pop() interface{} {
+	old := *q
+	evt := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return evt
+}
+
+// simulator runs the nodes on a virtual clock, delivering the blocks they
+// propose through an in-memory transport.
+type simulator struct {
+	cfg     *SimConfig
+	rnd     *rand.Rand
+	now     time.Duration
+	seq     uint64
+	queue   simQueue
+	nodes   []*simNode
+	stopped bool
+	report  *SimReport
+}
+
+// simNode follows the proposer rules of Server: it scans its plot once the
+// previous block is known, proposes when its deadline expires, and follows
+// the heaviest chain within maxReorgDepth of its tip.
+type simNode struct {
+	sim       *simulator
+	index     uint32
+	accountID string
+	nonces    uint64
+	blocks    map[common.Uint256]*Block
+	orphans   map[common.Uint256][]*Block
+	tip       *Block
+}
+
+// Simulate runs a PoC network of len(cfg.Nonces) nodes until one of them
+// reaches cfg.Blocks, and reports on the chain they built. Runs of the same
+// config are deterministic.
+func Simulate(cfg *SimConfig) (*SimReport, error) {
+	if len(cfg.Nonces) == 0 {
+		return nil, fmt.Errorf("no simulated node")
+	}
+	if cfg.Blocks == 0 || cfg.TargetBlockTime == 0 || cfg.InitialBaseTarget == 0 {
+		return nil, fmt.Errorf("invalid simulation config")
+	}
+	sim := &simulator{
+		cfg:    cfg,
+		rnd:    rand.New(rand.NewSource(cfg.Seed)),
+		report: &SimReport{},
+	}
+	genesis, err := sim.genesis()
+	if err != nil {
+		return nil, err
+	}
+	var total uint64
+	for i, nonces := range cfg.Nonces {
+		total += nonces
+		sim.nodes = append(sim.nodes, &simNode{
+			sim:       sim,
+			index:     uint32(i),
+			accountID: fmt.Sprintf("sim-node-%d", i),
+			nonces:    nonces,
+			blocks:    map[common.Uint256]*Block{genesis.Block.Hash(): genesis},
+			orphans:   make(map[common.Uint256][]*Block),
+		})
+	}
+	for _, node := range sim.nodes {
+		node.setTip(genesis)
+	}
+	for sim.queue.Len() > 0 {
+		evt := heap.Pop(&sim.queue).(*simEvent)
+		sim.now = evt.at
+		evt.fire()
+	}
+
+	report := sim.report
+	chain := sim.nodes[0].chain()
+	report.Height = chain[len(chain)-1].getBlockNum()
+	report.ProposerShare = make([]float64, len(cfg.Nonces))
+	report.CapacityShare = make([]float64, len(cfg.Nonces))
+	proposed := make([]int, len(cfg.Nonces))
+	for i := 1; i < len(chain); i++ {
+		blk := chain[i]
+		report.BlockTimes = append(report.BlockTimes, blk.Block.Header.Timestamp-chain[i-1].Block.Header.Timestamp)
+		report.BaseTargets = append(report.BaseTargets, blk.Info.BaseTarget)
+		proposed[blk.getProposer()]++
+	}
+	for i, nonces := range cfg.Nonces {
+		if len(chain) > 1 {
+			report.ProposerShare[i] = float64(proposed[i]) / float64(len(chain)-1)
+		}
+		if total > 0 {
+			report.CapacityShare[i] = float64(nonces) / float64(total)
+		}
+	}
+	report.Orphaned = report.Produced - (len(chain) - 1)
+	tipHash := sim.nodes[0].tip.Block.Hash()
+	for _, node := range sim.nodes[1:] {
+		if node.tip.Block.Hash() != tipHash {
+			report.Splits++
+		}
+	}
+	return report, nil
+}
+
+func (self *simulator) genesis() (*Block, error) {
+	return newSimBlock(&types.Header{Timestamp: simGenesisTimestamp}, &pocconfig.PocBlockInfo{
+		BaseTarget: self.cfg.InitialBaseTarget,
+		GenSig:     shabal.GenSig256(nil, []byte("genesis")),
+	})
+}
+
+func (self *simulator) schedule(at time.Duration, fire func()) {
+	self.seq++
+	heap.Push(&self.queue, &simEvent{at: at, seq: self.seq, fire: fire})
+}
+
+// broadcast delivers blk from node from to all other nodes.
+func (self *simulator) broadcast(from uint32, blk *Block) {
+	for _, node := range self.nodes {
+		if node.index == from {
+			continue
+		}
+		delay := self.cfg.Latency
+		if self.cfg.Jitter > 0 {
+			delay += time.Duration(self.rnd.Int63n(int64(self.cfg.Jitter)))
+		}
+		node := node
+		self.schedule(self.now+delay, func() { node.receive(blk) })
+	}
+}
+
+// timestamp returns the block timestamp at virtual time at.
+func (self *simulator) timestamp(at time.Duration) uint32 {
+	return simGenesisTimestamp + uint32(at/time.Second)
+}
+
+func newSimBlock(header *types.Header, info *pocconfig.PocBlockInfo) (*Block, error) {
+	payload, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	header.ConsensusPayload = payload
+	return &Block{
+		Block: &types.Block{Header: header},
+		Info:  info,
+	}, nil
+}
+
+// simScoop returns the scoop of a synthetic nonce.
+func simScoop(accountID string, nonceNr uint64, scoopIndex uint32) []byte {
+	buf := make([]byte, 12, 12+len(accountID))
+	binary.BigEndian.PutUint64(buf, nonceNr)
+	binary.BigEndian.PutUint32(buf[8:], scoopIndex)
+	sum := shabal.Sum512(append(buf, accountID...))
+	return sum[:]
+}
+
+func simDeadline(accountID string, nonceNr uint64, round *miningRound) (uint64, error) {
+	scoop := simScoop(accountID, nonceNr, shabal.ScoopNum256(round.GenSig))
+	return pocconfig.CalcDeadline(shabal.Target256(round.GenSig, scoop), round.BaseTarget)
+}
+
+func (self *simNode) parent(blk *Block) *Block {
+	return self.blocks[blk.getPrevBlockHash()]
+}
+
+// chain returns the blocks from the genesis block to the tip.
+func (self *simNode) chain() []*Block {
+	var chain []*Block
+	for blk := self.tip; blk != nil; blk = self.parent(blk) {
+		chain = append(chain, blk)
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
+
+// setTip makes blk the tip and schedules the proposal of the next block at
+// the expiry of the best deadline of the plot.
+func (self *simNode) setTip(blk *Block) {
+	self.tip = blk
+	if blk.getBlockNum() >= self.sim.cfg.Blocks {
+		self.sim.stopped = true
+	}
+	if self.sim.stopped || self.nonces == 0 {
+		return
+	}
+	round, err := newMiningRound(blk.getBlockNum()+1, blk)
+	if err != nil {
+		return
+	}
+	var best *deadlineProof
+	for i := uint64(0); i < self.nonces; i++ {
+		deadline, err := simDeadline(self.accountID, i, round)
+		if err != nil {
+			return
+		}
+		if best == nil || deadline < best.Deadline {
+			best = &deadlineProof{BlockNum: round.BlockNum, NonceNr: i, Deadline: deadline, PrevHash: round.PrevHash}
+		}
+	}
+	at := time.Duration(uint64(blk.Block.Header.Timestamp)+best.Deadline-simGenesisTimestamp) * time.Second
+	if at < self.sim.now {
+		at = self.sim.now
+	}
+	self.sim.schedule(at, func() { self.propose(blk, best) })
+}
+
+// baseTarget mirrors Server.nextBaseTarget on the chain ending with prevBlk.
+func (self *simNode) baseTarget(prevBlk *Block, timestamp uint32) uint64 {
+	window := int(self.sim.cfg.RetargetWindow)
+	if window == 0 {
+		window = pocconfig.DefaultRetargetWindow
+	}
+	history := make([]*Block, 0, window)
+	for blk := prevBlk; blk != nil && blk.getBlockNum() > 0 && len(history) < window; blk = self.parent(blk) {
+		history = append([]*Block{blk}, history...)
+	}
+	return retarget(history, timestamp, self.sim.cfg.TargetBlockTime, self.sim.cfg.InitialBaseTarget)
+}
+
+func (self *simNode) propose(prevBlk *Block, proof *deadlineProof) {
+	if self.sim.stopped || self.tip != prevBlk {
+		return
+	}
+	timestamp := self.sim.timestamp(self.sim.now)
+	baseTarget := self.baseTarget(prevBlk, timestamp)
+	blk, err := newSimBlock(&types.Header{
+		PrevBlockHash: proof.PrevHash,
+		Height:        proof.BlockNum,
+		Timestamp:     timestamp,
+		ConsensusData: uint64(self.index),
+	}, &pocconfig.PocBlockInfo{
+		Proposer:             self.index,
+		BaseTarget:           baseTarget,
+		GenSig:               shabal.GenSig256(prevBlk.Info.GenSig, []byte(self.accountID)),
+		Deadline:             proof.Deadline,
+		NonceNr:              proof.NonceNr,
+		CumulativeDifficulty: cumulativeDifficulty(prevBlk, baseTarget),
+		PlotAccount:          self.accountID,
+	})
+	if err != nil {
+		return
+	}
+	self.sim.report.Produced++
+	self.sim.broadcast(self.index, blk)
+	self.receive(blk)
+}
+
+func (self *simNode) verify(blk *Block, prevBlk *Block) error {
+	round, err := newMiningRound(blk.getBlockNum(), prevBlk)
+	if err != nil {
+		return err
+	}
+	deadline, err := simDeadline(blk.Info.PlotAccount, blk.Info.NonceNr, round)
+	if err != nil {
+		return err
+	}
+	if deadline != blk.Info.Deadline {
+		return fmt.Errorf("deadline mismatch: %d vs %d", blk.Info.Deadline, deadline)
+	}
+	if !deadlineElapsed(prevBlk, deadline, blk.Block.Header.Timestamp) ||
+		!deadlineElapsed(prevBlk, deadline, self.sim.timestamp(self.sim.now)+deadlineDrift) {
+		return fmt.Errorf("deadline %d of block %d not elapsed", deadline, blk.getBlockNum())
+	}
+	if bt := self.baseTarget(prevBlk, blk.Block.Header.Timestamp); bt != blk.Info.BaseTarget {
+		return fmt.Errorf("base target mismatch: %d vs %d", blk.Info.BaseTarget, bt)
+	}
+	return verifyCumulativeDifficulty(blk, prevBlk)
+}
+
+// receive adds blk to the blocks known to the node, switching to it if it is
+// heavier than the tip and forks at most maxReorgDepth blocks below it.
+func (self *simNode) receive(blk *Block) {
+	hash := blk.Block.Hash()
+	if _, present := self.blocks[hash]; present {
+		return
+	}
+	prevBlk := self.parent(blk)
+	if prevBlk == nil {
+		prevHash := blk.getPrevBlockHash()
+		self.orphans[prevHash] = append(self.orphans[prevHash], blk)
+		return
+	}
+	if err := self.verify(blk, prevBlk); err != nil {
+		self.sim.report.Rejected++
+		return
+	}
+	self.blocks[hash] = blk
+	if heavierBlock(blk, self.tip) && self.forkDepth(blk) <= maxReorgDepth {
+		if prevBlk != self.tip {
+			self.sim.report.Reorgs++
+		}
+		self.setTip(blk)
+	}
+
+	children := self.orphans[hash]
+	delete(self.orphans, hash)
+	for _, child := range children {
+		self.receive(child)
+	}
+}
+
+// forkDepth returns the number of blocks of the chain replaced by switching
+// to blk.
+func (self *simNode) forkDepth(blk *Block) uint32 {
+	onChain := make(map[common.Uint256]bool)
+	for b := self.tip; b != nil; b = self.parent(b) {
+		onChain[b.Block.Hash()] = true
+	}
+	for b := self.parent(blk); b != nil; b = self.parent(b) {
+		if onChain[b.Block.Hash()] {
+			return self.tip.getBlockNum() - b.getBlockNum()
+		}
+	}
+	return self.tip.getBlockNum()
+}
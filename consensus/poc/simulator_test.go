@@ -0,0 +1,107 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func constructSimConfig() *SimConfig {
+	return &SimConfig{
+		Nonces:            []uint64{100, 100, 200, 400},
+		Blocks:            200,
+		TargetBlockTime:   30,
+		InitialBaseTarget: 614891469123651,
+		Latency:           200 * time.Millisecond,
+		Jitter:            300 * time.Millisecond,
+		Seed:              1,
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	cfg := constructSimConfig()
+	report, err := Simulate(cfg)
+	if err != nil {
+		t.Fatalf("simulate: %s", err)
+	}
+	if report.Height != cfg.Blocks || len(report.BlockTimes) != int(cfg.Blocks) {
+		t.Fatalf("simulated height %d, expected %d", report.Height, cfg.Blocks)
+	}
+	if report.Rejected != 0 || report.Splits != 0 {
+		t.Errorf("%d blocks rejected, %d nodes split", report.Rejected, report.Splits)
+	}
+	if mean := report.MeanBlockTime(); mean < 20 || mean > 40 {
+		t.Errorf("mean block time %.1fs, expected about %ds", mean, cfg.TargetBlockTime)
+	}
+	if rate := report.ForkRate(); rate > 0.1 {
+		t.Errorf("fork rate %.2f", rate)
+	}
+	for i := range cfg.Nonces {
+		if math.Abs(report.ProposerShare[i]-report.CapacityShare[i]) > 0.15 {
+			t.Errorf("node %d proposed %.2f of the blocks with %.2f of the capacity",
+				i, report.ProposerShare[i], report.CapacityShare[i])
+		}
+	}
+
+	again, err := Simulate(cfg)
+	if err != nil {
+		t.Fatalf("simulate: %s", err)
+	}
+	if !reflect.DeepEqual(report, again) {
+		t.Errorf("simulation not deterministic")
+	}
+}
+
+func TestSimulateForks(t *testing.T) {
+	cfg := constructSimConfig()
+	cfg.Latency = 5 * time.Second
+	cfg.Jitter = 0
+	report, err := Simulate(cfg)
+	if err != nil {
+		t.Fatalf("simulate: %s", err)
+	}
+	if report.Orphaned == 0 || report.Reorgs == 0 {
+		t.Errorf("no fork with blocks delayed by %v: %d orphaned, %d reorgs", cfg.Latency, report.Orphaned, report.Reorgs)
+	}
+	if report.Splits != 0 {
+		t.Errorf("%d nodes split", report.Splits)
+	}
+}
+
+func TestSimReport(t *testing.T) {
+	report := &SimReport{BlockTimes: []uint32{10, 40, 20, 30}, Produced: 5, Orphaned: 1}
+	if report.MeanBlockTime() != 25 {
+		t.Errorf("mean block time %f", report.MeanBlockTime())
+	}
+	if p := report.BlockTimePercentile(50); p != 20 {
+		t.Errorf("median block time %d", p)
+	}
+	if p := report.BlockTimePercentile(100); p != 40 {
+		t.Errorf("max block time %d", p)
+	}
+	if report.ForkRate() != 0.2 {
+		t.Errorf("fork rate %f", report.ForkRate())
+	}
+	if _, err := Simulate(&SimConfig{}); err == nil {
+		t.Errorf("simulate without node should fail")
+	}
+}
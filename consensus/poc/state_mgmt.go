@@ -112,7 +112,6 @@ func (self *StateMgr) run() {
 	})
 
 	// wait config done
-	self.server.quitWg.Add(1)
 	defer self.server.quitWg.Done()
 
 	for {
@@ -122,6 +121,12 @@ func (self *StateMgr) run() {
 			case ConfigLoaded:
 				if self.currentState == Init {
 					self.currentState = LocalConfigured
+					// the only peer of the chain has no one to sync with
+					if self.server.soleChainPeer() {
+						if err := self.setSyncedReady(); err != nil {
+							log.Warnf("server %d set syncready: %s", self.server.Index, err)
+						}
+					}
 				}
 			case SyncReadyTimeout:
 				if self.currentState == SyncReady {
@@ -307,7 +312,7 @@ func (self *StateMgr) onLiveTick(evt *StateEvent) error {
 }
 
 func (self *StateMgr) getMinActivePeerCount() int {
-	n := int(self.server.config.C) * 2 // plus self
+	n := int(self.server.committeeC()) * 2 // plus self
 	if n > MAX_PEER_CONNECTIONS {
 		// FIXME: C vs. maxConnections
 		return MAX_PEER_CONNECTIONS
@@ -389,7 +394,7 @@ func (self *StateMgr) checkStartSyncing(startBlkNum uint32, forceSync bool) {
 					peers[k] = append(peers[k], p.peerIdx)
 				}
 			}
-			if len(peers[n]) > int(self.server.config.C) {
+			if len(peers[n]) > int(self.server.committeeC()) {
 				maxCommitted = n
 			}
 		}
@@ -417,7 +422,7 @@ func (self *StateMgr) checkStartSyncing(startBlkNum uint32, forceSync bool) {
 
 // return 0 if consensus not reached yet
 func (self *StateMgr) getConsensusedCommittedBlockNum() (uint32, bool) {
-	C := int(self.server.config.C)
+	C := int(self.server.committeeC())
 
 	consensused := false
 	var maxCommitted uint32
@@ -449,8 +454,8 @@ func (self *StateMgr) canFastForward(targetBlkNum uint32) bool {
 		return false
 	}
 
-	C := int(self.server.config.C)
-	N := int(self.server.config.N)
+	C := int(self.server.committeeC())
+	N := int(self.server.committeeN())
 	// one block less than targetBlkNum is also acceptable for fastforward
 	for blkNum := self.server.GetCurrentBlockNo(); blkNum <= targetBlkNum; blkNum++ {
 		// check if pending messages for targetBlkNum reached consensus
@@ -217,7 +217,7 @@ func getChainConfig(memdb *overlaydb.MemDB, blkNum uint32) (*pocconfig.ChainConf
 		return nil, fmt.Errorf("failed to get governanceview failed:%s", err)
 	}
 
-	cfg, err := pocconfig.GenesisChainConfig(config, peersinfo, goverview.TxHash, blkNum)
+	cfg, err := pocconfig.GenesisChainConfig(config, peersinfo)
 	if err != nil {
 		return nil, fmt.Errorf("GenesisChainConfig failed: %s", err)
 	}
@@ -0,0 +1,319 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"OntologyWithPOC/common/config"
+	"OntologyWithPOC/common/log"
+	"OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/consensus/poc/plot"
+	"OntologyWithPOC/consensus/poc/shabal"
+	"OntologyWithPOC/consensus/poc/zkproof"
+	"OntologyWithPOC/smartcontract/service/native/plot_binding"
+)
+
+const (
+	// blocks a registered plot root waits before deadlines can be proved
+	// against it, so that roots cannot be chosen knowing the challenge
+	plotRootDelay = 64
+	// upper bound of an encoded zk deadline, audit paths included
+	maxZKProofSize = 16384
+	// a node audits the plot trees of one in zkAuditSampling of the zk
+	// deadlines gossiped to it, so it regenerates zkproof.AuditCount nonces
+	// every zkAuditSampling deadlines, fewer than the nonce of every plain
+	// deadline. A failed audit is reported as evidence against the signer.
+	zkAuditSampling = 8
+)
+
+// errFailedAudit is returned for a zk deadline whose plot tree audit fails,
+// which its signer is penalized for.
+var errFailedAudit = errors.New("plot tree audit failed")
+
+// zkDeadline is the zero-knowledge proof of a deadline: Scoop is a scoop of
+// the plot tree Root, selected by the previous block, which Proof shows
+// without revealing the nonce it is of. The deadline is the one of the shabal
+// target of Scoop, as for plain deadlines. Audit holds the paths of the leaves
+// the previous block draws for audit, showing the tree commits to the nonces
+// of the plot account, the nonces from StartNonce on the root is registered
+// for.
+type zkDeadline struct {
+	Root       []byte          `json:"root"`
+	StartNonce uint64          `json:"start_nonce"`
+	Nonces     uint64          `json:"nonces"`
+	Scoop      []byte          `json:"scoop"`
+	Proof      json.RawMessage `json:"proof"`
+	Audit      [][][]byte      `json:"audit"`
+}
+
+func decodeZKDeadline(data []byte) (*zkDeadline, error) {
+	if len(data) > maxZKProofSize {
+		return nil, fmt.Errorf("zk proof too large: %d", len(data))
+	}
+	zk := &zkDeadline{}
+	if err := json.Unmarshal(data, zk); err != nil {
+		return nil, fmt.Errorf("invalid zk proof: %s", err)
+	}
+	if len(zk.Root) != plot_binding.PLOT_ROOT_SIZE || len(zk.Scoop) != shabal.ScoopSize {
+		return nil, fmt.Errorf("invalid zk proof root or scoop")
+	}
+	return zk, nil
+}
+
+func fieldBytes(v *big.Int) []byte {
+	buf := make([]byte, plot_binding.PLOT_ROOT_SIZE)
+	b := v.Bytes()
+	copy(buf[len(buf)-len(b):], b)
+	return buf
+}
+
+func encodeAudit(paths [][]*big.Int) [][][]byte {
+	audit := make([][][]byte, 0, len(paths))
+	for _, path := range paths {
+		nodes := make([][]byte, 0, len(path))
+		for _, v := range path {
+			nodes = append(nodes, fieldBytes(v))
+		}
+		audit = append(audit, nodes)
+	}
+	return audit
+}
+
+func decodeAudit(audit [][][]byte) ([][]*big.Int, error) {
+	paths := make([][]*big.Int, 0, len(audit))
+	for _, nodes := range audit {
+		path := make([]*big.Int, 0, len(nodes))
+		for _, b := range nodes {
+			if len(b) != plot_binding.PLOT_ROOT_SIZE {
+				return nil, fmt.Errorf("invalid audit node")
+			}
+			path = append(path, new(big.Int).SetBytes(b))
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// verifyPlotAudit checks the audit of zk opens the leaves prevBlk draws to the
+// scoops of the nonces of accountID zk claims its root commits to.
+func verifyPlotAudit(params zkproof.Params, zk *zkDeadline, accountID string, prevBlk *Block) error {
+	gensig, _, err := miningSeed(prevBlk)
+	if err != nil {
+		return err
+	}
+	paths, err := decodeAudit(zk.Audit)
+	if err != nil {
+		return err
+	}
+	root := new(big.Int).SetBytes(zk.Root)
+	positions := zkproof.AuditPositions(gensig, root, zk.Nonces)
+	if len(positions) == 0 {
+		return fmt.Errorf("plot root %x commits to no nonces", zk.Root)
+	}
+	return zkproof.VerifyAudit(params, root, accountID, zk.StartNonce, positions, paths)
+}
+
+// zkStatement returns the statement zk claims on top of prevBlk, along with
+// the deadline of its scoop.
+func zkStatement(params zkproof.Params, zk *zkDeadline, prevBlk *Block) (*zkproof.Statement, uint64, error) {
+	gensig, baseTarget, err := miningSeed(prevBlk)
+	if err != nil {
+		return nil, 0, err
+	}
+	deadline, err := pocconfig.CalcDeadline(shabal.Target256(gensig, zk.Scoop), baseTarget)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &zkproof.Statement{
+		Root:       new(big.Int).SetBytes(zk.Root),
+		ScoopIndex: shabal.ScoopNum256(gensig),
+		Digest:     params.ScoopDigest(zk.Scoop),
+	}, deadline, nil
+}
+
+// genesisVerifyingKey returns the zk verifying key pinned in the genesis
+// config, nil if deadlines are not proved with zk proofs on the chain.
+func genesisVerifyingKey() (*zkproof.VerifyingKey, error) {
+	cfg := config.DefConfig.Genesis.POC
+	if cfg == nil || cfg.ZKVerifyingKey == "" {
+		return nil, nil
+	}
+	return zkproof.ParseVerifyingKeyHex(cfg.ZKVerifyingKey)
+}
+
+// checkPlotVerification checks a deadline proved by a zk proof, or by its
+// nonce if not zk, is accepted under the plot verification mode of the chain.
+func checkPlotVerification(mode string, zk bool) error {
+	switch {
+	case mode == config.POC_PLOT_VERIFY_NONCE && zk:
+		return fmt.Errorf("zk deadline proofs not accepted, plot verification is %s", mode)
+	case mode == config.POC_PLOT_VERIFY_ZK && !zk:
+		return fmt.Errorf("nonce deadline proofs not accepted, plot verification is %s", mode)
+	}
+	return nil
+}
+
+func genesisPlotVerification() string {
+	return pocconfig.PlotVerification(config.DefConfig.Genesis.POC)
+}
+
+// loadProvingKey loads the proving key at path, which must match vk.
+func loadProvingKey(path string, vk *zkproof.VerifyingKey) (*zkproof.ProvingKey, error) {
+	if vk == nil {
+		return nil, fmt.Errorf("no zk verifying key in genesis config")
+	}
+	pk, err := zkproof.LoadProvingKey(path)
+	if err != nil {
+		return nil, err
+	}
+	pkVk, err := pk.Vk.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	genesisVk, err := vk.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(pkVk, genesisVk) {
+		return nil, fmt.Errorf("proving key %s not of the genesis verifying key", path)
+	}
+	return pk, nil
+}
+
+// checkPlotRoot checks the root of zk was registered by its plot account for
+// the nonces zk claims, long enough before block blkNum.
+func checkPlotRoot(registered *plot_binding.PlotRoot, zk *zkDeadline, blkNum uint32) error {
+	if registered == nil || !bytes.Equal(registered.Root, zk.Root) {
+		return fmt.Errorf("plot root %x not registered", zk.Root)
+	}
+	if registered.StartNonce != zk.StartNonce || registered.Nonces != zk.Nonces {
+		return fmt.Errorf("plot root %x registered for %d nonces from %d", zk.Root, registered.Nonces, registered.StartNonce)
+	}
+	if registered.Height+plotRootDelay > blkNum {
+		return fmt.Errorf("plot root registered at %d not usable before %d", registered.Height, registered.Height+plotRootDelay)
+	}
+	return nil
+}
+
+// verifyZKDeadline checks the zk proof data of a deadline of plot account
+// accountID for block blkNum, and returns the deadline it proves. With audit
+// set, the plot tree audit is checked too, errFailedAudit being returned if
+// it fails.
+func verifyZKDeadline(vk *zkproof.VerifyingKey, data []byte, registered *plot_binding.PlotRoot, accountID string, blkNum uint32, prevBlk *Block, audit bool) (uint64, error) {
+	if vk == nil {
+		return 0, fmt.Errorf("zk proofs not enabled")
+	}
+	zk, err := decodeZKDeadline(data)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkPlotRoot(registered, zk, blkNum); err != nil {
+		return 0, err
+	}
+	st, deadline, err := zkStatement(vk.Params, zk, prevBlk)
+	if err != nil {
+		return 0, err
+	}
+	if err := zkproof.Verify(vk, st, zk.Proof); err != nil {
+		return 0, err
+	}
+	if audit {
+		if err := verifyPlotAudit(vk.Params, zk, accountID, prevBlk); err != nil {
+			log.Warnf("zk deadline of account %s, blk %d: %s", accountID, blkNum, err)
+			return 0, errFailedAudit
+		}
+	}
+	return deadline, nil
+}
+
+// verifyZKProof checks the zk proof data of a deadline of accountID for
+// block blkNum, against the plot root the account registered as of the
+// previous block.
+func (self *Server) verifyZKProof(blkNum uint32, accountID string, data []byte, prevBlk *Block, audit bool) (uint64, error) {
+	registered, err := plotRoot(self.blockPool.getExecWriteSet(blkNum-1), accountID)
+	if err != nil {
+		return 0, err
+	}
+	return verifyZKDeadline(self.zkVerifier, data, registered, accountID, blkNum, prevBlk, audit)
+}
+
+// zkPlotTree returns whether the plot at path has a plot tree of params
+// covering its n nonces.
+func zkPlotTree(path string, params zkproof.Params, n uint64) bool {
+	tree, err := zkproof.OpenTree(zkproof.TreeFile(path))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warnf("plot tree of %s: %s", path, err)
+		}
+		return false
+	}
+	defer tree.Close()
+	return tree.Params == params && tree.Nonces == n
+}
+
+// proveDeadline proves the deadline of proof, scanned from a plot with a
+// plot tree, with pk.
+func proveDeadline(pk *zkproof.ProvingKey, proof *deadlineProof, round *miningRound) error {
+	p, err := plot.Open(proof.PlotPath)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	tree, err := zkproof.OpenTree(zkproof.TreeFile(proof.PlotPath))
+	if err != nil {
+		return err
+	}
+	defer tree.Close()
+	offset := proof.NonceNr - p.StartNonce
+	digest, path, err := tree.Path(p, proof.ScoopIndex, offset)
+	if err != nil {
+		return err
+	}
+	if digest.Cmp(pk.Params.ScoopDigest(proof.Scoop)) != 0 {
+		return fmt.Errorf("plot tree of %s does not match the plot", proof.PlotPath)
+	}
+	st := &zkproof.Statement{
+		Root:       tree.Root,
+		ScoopIndex: proof.ScoopIndex,
+		Digest:     digest,
+	}
+	data, err := zkproof.Prove(pk, st, &zkproof.Witness{Offset: offset, Path: path})
+	if err != nil {
+		return err
+	}
+	audit, err := tree.Audit(p, zkproof.AuditPositions(round.GenSig, tree.Root, tree.Nonces))
+	if err != nil {
+		return err
+	}
+	proof.ZKProof, err = json.Marshal(&zkDeadline{
+		Root:       fieldBytes(st.Root),
+		StartNonce: p.StartNonce,
+		Nonces:     tree.Nonces,
+		Scoop:      proof.Scoop,
+		Proof:      data,
+		Audit:      encodeAudit(audit),
+	})
+	return err
+}
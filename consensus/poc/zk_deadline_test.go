@@ -0,0 +1,181 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package poc
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"OntologyWithPOC/account"
+	"OntologyWithPOC/common/config"
+	"OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/consensus/poc/plot"
+	"OntologyWithPOC/consensus/poc/zkproof"
+	"OntologyWithPOC/smartcontract/service/native/plot_binding"
+)
+
+func TestZKDeadline(t *testing.T) {
+	params := zkproof.Params{Depth: 2, Rounds: 2}
+	pk, err := zkproof.Setup(params, []byte("seed"))
+	if err != nil {
+		t.Fatalf("setup: %s", err)
+	}
+	dir, err := ioutil.TempDir("", "poc-plot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	acc := account.NewAccount("SHA256withECDSA")
+	accountID := pocconfig.PubkeyID(acc.PublicKey)
+	path, err := plot.Generate(dir, accountID, 100, 3)
+	if err != nil {
+		t.Fatalf("generate plot: %s", err)
+	}
+	prevBlk := constructPrevBlock()
+	round := constructMiningRound(t, plotRootDelay, prevBlk)
+
+	// plots without a plot tree are scanned for shabal deadlines
+	proof, err := scanPlotFile(path, accountID, round, nil, nil, nil, &params)
+	if err != nil {
+		t.Fatalf("scan plot: %s", err)
+	}
+	if proof.PlotPath != "" {
+		t.Errorf("plot without tree scanned for zk deadline")
+	}
+
+	p, err := plot.Open(path)
+	if err != nil {
+		t.Fatalf("open plot: %s", err)
+	}
+	tree, err := zkproof.BuildTree(p, params, 2, nil)
+	p.Close()
+	if err != nil {
+		t.Fatalf("build tree: %s", err)
+	}
+	tree.Close()
+	if proof, err = scanPlotFile(path, accountID, round, nil, nil, nil, &params); err != nil {
+		t.Fatalf("scan plot: %s", err)
+	}
+	if proof.PlotPath != path {
+		t.Fatalf("plot with tree not scanned for zk deadline")
+	}
+	if err := proveDeadline(pk, proof, round); err != nil {
+		t.Fatalf("prove deadline: %s", err)
+	}
+
+	registered := &plot_binding.PlotRoot{Root: fieldBytes(tree.Root), StartNonce: 100, Nonces: 3}
+	deadline, err := verifyZKDeadline(&pk.Vk, proof.ZKProof, registered, accountID, round.BlockNum, prevBlk, true)
+	if err != nil {
+		t.Fatalf("verify zk deadline: %s", err)
+	}
+	if deadline != proof.Deadline {
+		t.Errorf("zk deadline %d, scanned %d", deadline, proof.Deadline)
+	}
+	// the deadline proved is the shabal one of the nonce
+	if _, _, nonceDl, _ := nonceDeadline(accountID, proof.NonceNr, prevBlk); deadline != nonceDl {
+		t.Errorf("zk deadline %d, nonce deadline %d", deadline, nonceDl)
+	}
+	if _, err := verifyZKDeadline(nil, proof.ZKProof, registered, accountID, round.BlockNum, prevBlk, true); err == nil {
+		t.Errorf("zk deadline without verifying key should fail")
+	}
+	if _, err := verifyZKDeadline(&pk.Vk, proof.ZKProof, nil, accountID, round.BlockNum, prevBlk, true); err == nil {
+		t.Errorf("zk deadline of unregistered root should fail")
+	}
+	if _, err := verifyZKDeadline(&pk.Vk, proof.ZKProof, registered, accountID, round.BlockNum-1, prevBlk, true); err == nil {
+		t.Errorf("zk deadline of root registered too recently should fail")
+	}
+	other := &plot_binding.PlotRoot{Root: make([]byte, plot_binding.PLOT_ROOT_SIZE), StartNonce: 100, Nonces: 3}
+	if _, err := verifyZKDeadline(&pk.Vk, proof.ZKProof, other, accountID, round.BlockNum, prevBlk, true); err == nil {
+		t.Errorf("zk deadline of other root should fail")
+	}
+
+	shifted := &plot_binding.PlotRoot{Root: registered.Root, StartNonce: 101, Nonces: 3}
+	if _, err := verifyZKDeadline(&pk.Vk, proof.ZKProof, shifted, accountID, round.BlockNum, prevBlk, true); err == nil {
+		t.Errorf("zk deadline of a root registered for other nonces should fail")
+	}
+
+	// the tree has to commit to the nonces of the plot account it is registered
+	// for, which only audits check
+	otherAccount := pocconfig.PubkeyID(account.NewAccount("SHA256withECDSA").PublicKey)
+	if _, err := verifyZKDeadline(&pk.Vk, proof.ZKProof, registered, otherAccount, round.BlockNum, prevBlk, false); err != nil {
+		t.Errorf("verify zk deadline without audit: %s", err)
+	}
+	if _, err := verifyZKDeadline(&pk.Vk, proof.ZKProof, registered, otherAccount, round.BlockNum, prevBlk, true); err != errFailedAudit {
+		t.Errorf("audit of a tree of another account should fail: %v", err)
+	}
+	zk, err := decodeZKDeadline(proof.ZKProof)
+	if err != nil {
+		t.Fatalf("decode zk deadline: %s", err)
+	}
+	zk.StartNonce, zk.Nonces = shifted.StartNonce, shifted.Nonces
+	if _, err := verifyZKDeadline(&pk.Vk, mustMarshal(t, zk), shifted, accountID, round.BlockNum, prevBlk, true); err != errFailedAudit {
+		t.Errorf("audit of a tree of other nonces should fail: %v", err)
+	}
+	zk.StartNonce, zk.Nonces = registered.StartNonce, registered.Nonces
+	scoop := zk.Scoop
+	zk.Scoop = append([]byte{}, scoop...)
+	zk.Scoop[0] ^= 1
+	if _, err := verifyZKDeadline(&pk.Vk, mustMarshal(t, zk), registered, accountID, round.BlockNum, prevBlk, true); err == nil {
+		t.Errorf("zk deadline of a scoop not in the tree should fail")
+	}
+	zk.Scoop = scoop
+	zk.Audit = zk.Audit[1:]
+	if _, err := verifyZKDeadline(&pk.Vk, mustMarshal(t, zk), registered, accountID, round.BlockNum, prevBlk, true); err != errFailedAudit {
+		t.Errorf("zk deadline with a missing audit path should fail")
+	}
+
+	entry := &deadlineEntry{BlockNum: round.BlockNum, ScoopIndex: proof.ScoopIndex, ZKProof: proof.ZKProof}
+	if err := entry.verifyFormat(); err != nil {
+		t.Errorf("zk deadline entry format: %s", err)
+	}
+	entry.Scoop = proof.Scoop
+	if err := entry.verifyFormat(); err == nil {
+		t.Errorf("zk deadline entry revealing its scoop should fail")
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestCheckPlotVerification(t *testing.T) {
+	cases := []struct {
+		mode string
+		zk   bool
+		ok   bool
+	}{
+		{config.POC_PLOT_VERIFY_NONCE, false, true},
+		{config.POC_PLOT_VERIFY_NONCE, true, false},
+		{config.POC_PLOT_VERIFY_ZK, false, false},
+		{config.POC_PLOT_VERIFY_ZK, true, true},
+		{config.POC_PLOT_VERIFY_ANY, false, true},
+		{config.POC_PLOT_VERIFY_ANY, true, true},
+	}
+	for _, c := range cases {
+		if err := checkPlotVerification(c.mode, c.zk); (err == nil) != c.ok {
+			t.Errorf("mode %s, zk %v: %v", c.mode, c.zk, err)
+		}
+	}
+}
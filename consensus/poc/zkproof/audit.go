@@ -0,0 +1,94 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package zkproof
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"OntologyWithPOC/consensus/poc/plot"
+	"OntologyWithPOC/consensus/poc/shabal"
+)
+
+// The circuit only shows a scoop is a leaf of the plot tree, not that the
+// leaf is a scoop of a nonce of the plot account. Every proof therefore
+// opens AuditCount leaves drawn from the challenge, whose scoops the verifiers
+// auditing it regenerate from the nonces the leaves stand for. A tree with a
+// share f of leaves not plotted from the account fails an audit with
+// probability 1-(1-f)^AuditCount, and a failed audit is evidence against the
+// signer of the proof.
+const AuditCount = 4
+
+// AuditPosition is a leaf of a plot tree to open: the scoop of the nonce at
+// Offset of the plot.
+type AuditPosition struct {
+	Scoop  uint32
+	Offset uint64
+}
+
+// AuditPositions draws the leaves a proof for gensig against root, a tree of
+// nonces nonces, has to open.
+func AuditPositions(gensig []byte, root *big.Int, nonces uint64) []AuditPosition {
+	if nonces == 0 {
+		return nil
+	}
+	seed := append(append([]byte{}, gensig...), root.Bytes()...)
+	positions := make([]AuditPosition, AuditCount)
+	var buf [4]byte
+	for i := range positions {
+		binary.BigEndian.PutUint32(buf[:], uint32(i))
+		h := sha256.Sum256(append(seed, buf[:]...))
+		positions[i] = AuditPosition{
+			Scoop:  binary.BigEndian.Uint32(h[0:4]) % shabal.ScoopCount,
+			Offset: binary.BigEndian.Uint64(h[8:16]) % nonces,
+		}
+	}
+	return positions
+}
+
+// Audit returns the paths of the leaves at positions, from plot p.
+func (t *PlotTree) Audit(p *plot.Plot, positions []AuditPosition) ([][]*big.Int, error) {
+	paths := make([][]*big.Int, 0, len(positions))
+	for _, pos := range positions {
+		_, path, err := t.Path(p, pos.Scoop, pos.Offset)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// VerifyAudit checks paths open the leaves at positions of root to the scoops
+// of the nonces of accountID from startNonce on.
+func VerifyAudit(params Params, root *big.Int, accountID string, startNonce uint64, positions []AuditPosition, paths [][]*big.Int) error {
+	if len(paths) != len(positions) {
+		return fmt.Errorf("%d audit paths for %d leaves", len(paths), len(positions))
+	}
+	for i, pos := range positions {
+		nonce := shabal.GenNonce256(startNonce+pos.Offset, accountID)
+		scoop := nonce[pos.Scoop*shabal.ScoopSize : (pos.Scoop+1)*shabal.ScoopSize]
+		if !VerifyPath(params, root, params.ScoopDigest(scoop), pos.Scoop, pos.Offset, paths[i]) {
+			return fmt.Errorf("audit of scoop %d of nonce %d failed", pos.Scoop, startNonce+pos.Offset)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package zkproof
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"OntologyWithPOC/consensus/poc/shabal"
+)
+
+func TestAuditPositions(t *testing.T) {
+	root := big.NewInt(12345)
+	positions := AuditPositions([]byte("gensig"), root, 3)
+	if len(positions) != AuditCount {
+		t.Fatalf("%d audit positions", len(positions))
+	}
+	for _, pos := range positions {
+		if pos.Offset >= 3 || pos.Scoop >= shabal.ScoopCount {
+			t.Errorf("audit position %v out of tree", pos)
+		}
+	}
+	if !reflect.DeepEqual(positions, AuditPositions([]byte("gensig"), root, 3)) {
+		t.Errorf("audit positions not deterministic")
+	}
+	if reflect.DeepEqual(positions, AuditPositions([]byte("other"), root, 3)) {
+		t.Errorf("audit positions independent of the challenge")
+	}
+	if AuditPositions([]byte("gensig"), root, 0) != nil {
+		t.Errorf("audit positions of empty tree")
+	}
+}
+
+func TestAudit(t *testing.T) {
+	p, tree := generateTestTree(t, 3)
+	positions := AuditPositions([]byte("gensig"), tree.Root, tree.Nonces)
+	paths, err := tree.Audit(p, positions)
+	if err != nil {
+		t.Fatalf("audit: %s", err)
+	}
+	if err := VerifyAudit(testParams, tree.Root, testAccountID, p.StartNonce, positions, paths); err != nil {
+		t.Errorf("verify audit: %s", err)
+	}
+	if err := VerifyAudit(testParams, tree.Root, testAccountID, p.StartNonce+1, positions, paths); err == nil {
+		t.Errorf("audit of other nonces should fail")
+	}
+	if err := VerifyAudit(testParams, tree.Root, testAccountID[:len(testAccountID)-2]+"00", p.StartNonce, positions, paths); err == nil {
+		t.Errorf("audit of other account should fail")
+	}
+	if err := VerifyAudit(testParams, tree.Root, testAccountID, p.StartNonce, positions, paths[1:]); err == nil {
+		t.Errorf("audit with missing path should fail")
+	}
+	paths[0][0] = new(big.Int).Add(paths[0][0], big.NewInt(1))
+	if err := VerifyAudit(testParams, tree.Root, testAccountID, p.StartNonce, positions, paths); err == nil {
+		t.Errorf("audit with altered path should fail")
+	}
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package zkproof
+
+import (
+	"fmt"
+	"math/big"
+
+	"OntologyWithPOC/consensus/poc/shabal"
+)
+
+const (
+	// levels of the plot tree above the scoop subtrees, log2 shabal.ScoopCount
+	scoopLevels = 12
+	// root, scoop index and scoop digest
+	numPublic = 3
+)
+
+// Params size the circuit: a plot tree holds up to 2^Depth nonces, and the
+// MiMC hash runs Rounds rounds. The circuit has about 3*Rounds constraints
+// per tree level, and proving takes time linear in it.
+type Params struct {
+	Depth  int `json:"depth"`
+	Rounds int `json:"rounds"`
+}
+
+// DefaultParams cover plots of up to 2^20 nonces, 256 GiB, with the MiMC-5
+// rounds the BN128 scalar field needs.
+var DefaultParams = Params{Depth: 20, Rounds: 110}
+
+func (p Params) validate() error {
+	if p.Depth < 1 || p.Depth > 32 {
+		return fmt.Errorf("invalid tree depth %d", p.Depth)
+	}
+	if p.Rounds < 1 {
+		return fmt.Errorf("invalid mimc rounds %d", p.Rounds)
+	}
+	return nil
+}
+
+// pathLen is the number of siblings from a leaf to the root of a plot tree.
+func (p Params) pathLen() int {
+	return p.Depth + scoopLevels
+}
+
+// Statement is what a proof shows: that Digest is the digest of scoop
+// ScoopIndex of a nonce committed to by Root. The scoop itself is disclosed,
+// its shabal target is the deadline as for plain deadlines, while the nonce
+// it is of stays hidden.
+type Statement struct {
+	Root       *big.Int
+	ScoopIndex uint32
+	Digest     *big.Int
+}
+
+func (st *Statement) publicInputs() []*big.Int {
+	return []*big.Int{st.Root, big.NewInt(int64(st.ScoopIndex)), st.Digest}
+}
+
+func (st *Statement) validate() error {
+	for _, v := range []*big.Int{st.Root, st.Digest} {
+		if v == nil || v.Sign() < 0 || v.Cmp(fr.Q) >= 0 {
+			return fmt.Errorf("statement value out of field")
+		}
+	}
+	if st.ScoopIndex >= shabal.ScoopCount {
+		return fmt.Errorf("invalid scoop index %d", st.ScoopIndex)
+	}
+	return nil
+}
+
+// Witness is the private part of a proof: the offset of the nonce of the
+// scoop in the plot, and the siblings from its leaf up.
+type Witness struct {
+	Offset uint64
+	Path   []*big.Int
+}
+
+func hash2Gadget(cs *constraintSystem, p Params, l, r lc) lc {
+	x := r
+	for _, c := range roundConstants(p.Rounds) {
+		u := add(x, l, constant(c))
+		u2 := cs.mul(u, u)
+		u4 := cs.mul(u2, u2)
+		x = cs.mul(u4, u)
+	}
+	return add(x, l, r)
+}
+
+// buildCircuit lays out the constraints of st and w, computing the values of
+// all variables along. The layout only depends on p.
+func buildCircuit(p Params, st *Statement, w *Witness) (*constraintSystem, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	if len(w.Path) != p.pathLen() {
+		return nil, fmt.Errorf("invalid path len %d", len(w.Path))
+	}
+	if w.Offset>>uint(p.Depth) != 0 {
+		return nil, fmt.Errorf("nonce offset %d out of tree", w.Offset)
+	}
+	cs := newConstraintSystem()
+	root := cs.public(st.Root)
+	scoop := cs.public(big.NewInt(int64(st.ScoopIndex)))
+	digest := cs.public(st.Digest)
+	cs.bindPublic()
+
+	// the leaf of the scoop of a nonce is at scoop<<Depth | offset
+	cur := digest
+	scoopSum := lc{}
+	for level := 0; level < p.pathLen(); level++ {
+		var bit uint64
+		if level < p.Depth {
+			bit = w.Offset >> uint(level) & 1
+		} else {
+			bit = uint64(st.ScoopIndex) >> uint(level-p.Depth) & 1
+		}
+		b := cs.private(new(big.Int).SetUint64(bit))
+		cs.assertBool(b)
+		if level >= p.Depth {
+			scoopSum = add(scoopSum, scale(b, new(big.Int).Lsh(big.NewInt(1), uint(level-p.Depth))))
+		}
+		sibling := cs.private(w.Path[level])
+		// left is cur if b is 0, sibling otherwise
+		left := add(cur, cs.mul(b, sub(sibling, cur)))
+		right := sub(add(cur, sibling), left)
+		cur = hash2Gadget(cs, p, left, right)
+	}
+	cs.assertEqual(scoopSum, scoop)
+	cs.assertEqual(cur, root)
+	return cs, nil
+}
+
+// emptyCircuit lays out the constraints of p with all values zero.
+func emptyCircuit(p Params) (*constraintSystem, error) {
+	st := &Statement{Root: big.NewInt(0), Digest: big.NewInt(0)}
+	w := &Witness{Path: make([]*big.Int, p.pathLen())}
+	for i := range w.Path {
+		w.Path[i] = big.NewInt(0)
+	}
+	return buildCircuit(p, st, w)
+}
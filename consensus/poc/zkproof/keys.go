@@ -0,0 +1,202 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package zkproof
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/arnaucube/go-snark/groth16"
+)
+
+// ProvingKey is what a miner proves deadlines of its plots with.
+type ProvingKey struct {
+	Params Params       `json:"params"`
+	Pk     groth16.Pk   `json:"pk"`
+	Vk     VerifyingKey `json:"vk"`
+}
+
+// VerifyingKey is what deadline proofs are verified against. The one of the
+// chain is pinned in its genesis config.
+type VerifyingKey struct {
+	Params  Params     `json:"params"`
+	NPublic int        `json:"npublic"`
+	Vk      groth16.Vk `json:"vk"`
+}
+
+// toxic derives the setup secret label from seed: the first nonzero
+// SHA256(seed || label || counter) taken in the field, counter being a big
+// endian uint32 counting from 0.
+func toxic(seed []byte, label string) *big.Int {
+	var buf [4]byte
+	for i := uint32(0); ; i++ {
+		binary.BigEndian.PutUint32(buf[:], i)
+		data := append(append(append([]byte{}, seed...), label...), buf[:]...)
+		h := sha256.Sum256(data)
+		v := fr.Affine(new(big.Int).SetBytes(h[:]))
+		if !fr.IsZero(v) {
+			return v
+		}
+	}
+}
+
+// Setup generates the keys of the circuit of p from seed. The setup is
+// deterministic, the same params and seed always yield the same keys, so the
+// verifying key pinned in a genesis config can be regenerated and checked by
+// the holders of the seed. The seed is the toxic waste of the setup: whoever
+// knows it can forge proofs, so it is kept secret and discarded once the keys
+// are generated, and a chain trusts whoever ran the setup to have done so.
+func Setup(p Params, seed []byte) (*ProvingKey, error) {
+	cs, err := emptyCircuit(p)
+	if err != nil {
+		return nil, err
+	}
+	tau := toxic(seed, "tau")
+	alpha := toxic(seed, "alpha")
+	beta := toxic(seed, "beta")
+	gamma := toxic(seed, "gamma")
+	delta := toxic(seed, "delta")
+
+	n := len(cs.constraints)
+	ls, zt, err := lagrangeAt(n, tau)
+	if err != nil {
+		return nil, err
+	}
+	// the polynomials of every variable evaluated at tau
+	at := make([]*big.Int, cs.nVars())
+	bt := make([]*big.Int, cs.nVars())
+	ct := make([]*big.Int, cs.nVars())
+	for i := range at {
+		at[i], bt[i], ct[i] = big.NewInt(0), big.NewInt(0), big.NewInt(0)
+	}
+	for j, c := range cs.constraints {
+		for i, k := range c.a {
+			at[i] = fr.Add(at[i], fr.Mul(k, ls[j]))
+		}
+		for i, k := range c.b {
+			bt[i] = fr.Add(bt[i], fr.Mul(k, ls[j]))
+		}
+		for i, k := range c.c {
+			ct[i] = fr.Add(ct[i], fr.Mul(k, ls[j]))
+		}
+	}
+
+	bn := groth16.Utils.Bn
+	g1 := func(v *big.Int) [3]*big.Int { return bn.G1.MulScalar(bn.G1.G, v) }
+	g2 := func(v *big.Int) [3][2]*big.Int { return bn.G2.MulScalar(bn.G2.G, v) }
+
+	pk := &ProvingKey{Params: p}
+	pk.Pk.Z = vanishing(n)
+	pk.Pk.G1.Alpha = g1(alpha)
+	pk.Pk.G1.Beta = g1(beta)
+	pk.Pk.G1.Delta = g1(delta)
+	pk.Pk.G2.Beta = g2(beta)
+	pk.Pk.G2.Gamma = g2(gamma)
+	pk.Pk.G2.Delta = g2(delta)
+
+	invDelta := fr.Inverse(delta)
+	invGamma := fr.Inverse(gamma)
+	ztDelta := fr.Mul(zt, invDelta)
+	powTau := big.NewInt(1)
+	for i := 0; i < n-1; i++ {
+		pk.Pk.PowersTauDelta = append(pk.Pk.PowersTauDelta, g1(fr.Mul(powTau, ztDelta)))
+		powTau = fr.Mul(powTau, tau)
+	}
+
+	vk := &pk.Vk
+	vk.Params = p
+	vk.NPublic = cs.nPublic
+	vk.Vk.G1.Alpha = pk.Pk.G1.Alpha
+	vk.Vk.G2.Beta = pk.Pk.G2.Beta
+	vk.Vk.G2.Gamma = pk.Pk.G2.Gamma
+	vk.Vk.G2.Delta = pk.Pk.G2.Delta
+	for i := 0; i < cs.nVars(); i++ {
+		pk.Pk.G1.At = append(pk.Pk.G1.At, g1(at[i]))
+		pk.Pk.G1.BACGamma = append(pk.Pk.G1.BACGamma, g1(bt[i]))
+		pk.Pk.G2.BACGamma = append(pk.Pk.G2.BACGamma, g2(bt[i]))
+		// beta*A(tau) + alpha*B(tau) + C(tau)
+		sum := fr.Add(fr.Add(fr.Mul(beta, at[i]), fr.Mul(alpha, bt[i])), ct[i])
+		if i <= cs.nPublic {
+			vk.Vk.IC = append(vk.Vk.IC, g1(fr.Mul(sum, invGamma)))
+			pk.Pk.BACDelta = append(pk.Pk.BACDelta, g1(big.NewInt(0)))
+		} else {
+			pk.Pk.BACDelta = append(pk.Pk.BACDelta, g1(fr.Mul(sum, invDelta)))
+		}
+	}
+	return pk, nil
+}
+
+// Bytes encodes vk as pinned in genesis config.
+func (vk *VerifyingKey) Bytes() ([]byte, error) {
+	return json.Marshal(vk)
+}
+
+// ParseVerifyingKey decodes a verifying key encoded by Bytes.
+func ParseVerifyingKey(data []byte) (*VerifyingKey, error) {
+	vk := &VerifyingKey{}
+	if err := json.Unmarshal(data, vk); err != nil {
+		return nil, fmt.Errorf("invalid verifying key: %s", err)
+	}
+	if err := vk.Params.validate(); err != nil {
+		return nil, err
+	}
+	if len(vk.Vk.IC) != vk.NPublic+1 || vk.NPublic != numPublic {
+		return nil, fmt.Errorf("invalid verifying key: %d public inputs", len(vk.Vk.IC))
+	}
+	return vk, nil
+}
+
+// ParseVerifyingKeyHex decodes a hex verifying key, as found in genesis config.
+func ParseVerifyingKeyHex(s string) (*VerifyingKey, error) {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid verifying key hex: %s", err)
+	}
+	return ParseVerifyingKey(data)
+}
+
+// Save writes pk to path.
+func (pk *ProvingKey) Save(path string) error {
+	data, err := json.Marshal(pk)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// LoadProvingKey reads a proving key written by Save.
+func LoadProvingKey(path string) (*ProvingKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pk := &ProvingKey{}
+	if err := json.Unmarshal(data, pk); err != nil {
+		return nil, fmt.Errorf("invalid proving key %s: %s", path, err)
+	}
+	if err := pk.Params.validate(); err != nil {
+		return nil, err
+	}
+	return pk, nil
+}
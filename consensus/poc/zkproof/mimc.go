@@ -0,0 +1,90 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package zkproof proves with Groth16 over BN128 that a scoop is of a plot
+// committed to by a Merkle root, without revealing the nonce it is of.
+// Random leaves of the root are opened alongside, tying it to the nonces of
+// the plot account.
+package zkproof
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+	"sync"
+
+	"github.com/arnaucube/go-snark/groth16"
+)
+
+// the scalar field of BN128 the circuit works in
+var fr = groth16.Utils.FqR
+
+// scoop limbs hashed into a scoop digest, 16 bytes each so they fit the field
+const limbSize = 16
+
+var constants = struct {
+	sync.Mutex
+	rounds map[int][]*big.Int
+}{rounds: make(map[int][]*big.Int)}
+
+// roundConstants derives the MiMC round constants, the first one being zero.
+func roundConstants(rounds int) []*big.Int {
+	constants.Lock()
+	defer constants.Unlock()
+	if cs, ok := constants.rounds[rounds]; ok {
+		return cs
+	}
+	cs := make([]*big.Int, rounds)
+	cs[0] = big.NewInt(0)
+	var buf [4]byte
+	for i := 1; i < rounds; i++ {
+		binary.BigEndian.PutUint32(buf[:], uint32(i))
+		h := sha256.Sum256(append([]byte("OntologyWithPOC/zkproof/mimc"), buf[:]...))
+		cs[i] = fr.Affine(new(big.Int).SetBytes(h[:]))
+	}
+	constants.rounds[rounds] = cs
+	return cs
+}
+
+// mimc encrypts x under key k with MiMC-5.
+func mimc(cs []*big.Int, k, x *big.Int) *big.Int {
+	for _, c := range cs {
+		u := fr.Add(fr.Add(x, k), c)
+		u2 := fr.Square(u)
+		x = fr.Mul(fr.Square(u2), u)
+	}
+	return fr.Add(x, k)
+}
+
+// Hash2 compresses l and r with MiMC-5 in Miyaguchi-Preneel mode.
+func (p Params) Hash2(l, r *big.Int) *big.Int {
+	return fr.Add(mimc(roundConstants(p.Rounds), l, r), r)
+}
+
+// ScoopDigest hashes a scoop into the field element committed to as a leaf.
+func (p Params) ScoopDigest(scoop []byte) *big.Int {
+	h := big.NewInt(0)
+	for i := 0; i < len(scoop); i += limbSize {
+		end := i + limbSize
+		if end > len(scoop) {
+			end = len(scoop)
+		}
+		h = p.Hash2(h, new(big.Int).SetBytes(scoop[i:end]))
+	}
+	return h
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package zkproof
+
+import (
+	"math/big"
+	"testing"
+
+	"OntologyWithPOC/consensus/poc/shabal"
+)
+
+var testParams = Params{Depth: 2, Rounds: 2}
+
+func TestHash2Gadget(t *testing.T) {
+	l, r := big.NewInt(3), big.NewInt(5)
+	cs := newConstraintSystem()
+	out := hash2Gadget(cs, testParams, cs.private(l), cs.private(r))
+	if err := cs.check(); err != nil {
+		t.Fatalf("hash2 gadget: %s", err)
+	}
+	if !fr.Equal(cs.eval(out), testParams.Hash2(l, r)) {
+		t.Errorf("hash2 gadget differs from native hash")
+	}
+	if len(cs.constraints) != 3*testParams.Rounds {
+		t.Errorf("hash2 gadget constraints: %d", len(cs.constraints))
+	}
+}
+
+func TestScoopDigest(t *testing.T) {
+	scoop := make([]byte, shabal.ScoopSize)
+	d1 := testParams.ScoopDigest(scoop)
+	scoop[shabal.ScoopSize-1] = 1
+	d2 := testParams.ScoopDigest(scoop)
+	if d1.Cmp(d2) == 0 {
+		t.Errorf("scoop digest ignores last byte")
+	}
+	if d1.Cmp(DefaultParams.ScoopDigest(make([]byte, shabal.ScoopSize))) == 0 {
+		t.Errorf("scoop digest ignores rounds")
+	}
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package zkproof
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/arnaucube/go-snark/groth16"
+)
+
+// Prove proves st with w under pk, returning the encoded proof.
+func Prove(pk *ProvingKey, st *Statement, w *Witness) ([]byte, error) {
+	if err := st.validate(); err != nil {
+		return nil, err
+	}
+	cs, err := buildCircuit(pk.Params, st, w)
+	if err != nil {
+		return nil, err
+	}
+	if err := cs.check(); err != nil {
+		return nil, fmt.Errorf("invalid witness: %s", err)
+	}
+	if cs.nVars() != len(pk.Pk.G1.At) || len(cs.constraints)+1 != len(pk.Pk.Z) {
+		return nil, fmt.Errorf("proving key does not match circuit")
+	}
+
+	// h = (A*B - C) / Z over the values of the constraints
+	n := len(cs.constraints)
+	av := make([]*big.Int, n)
+	bv := make([]*big.Int, n)
+	cv := make([]*big.Int, n)
+	for j, c := range cs.constraints {
+		av[j], bv[j], cv[j] = cs.eval(c.a), cs.eval(c.b), cs.eval(c.c)
+	}
+	polys := interpolate(pk.Pk.Z, av, bv, cv)
+	px := polyMul(polys[0], polys[1])
+	for k, v := range polys[2] {
+		px[k] = fr.Sub(px[k], v)
+	}
+	hx, err := divideVanishing(px, pk.Pk.Z)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := generateProof(&pk.Pk, cs, hx)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(proof)
+}
+
+// generateProof computes the proof elements as groth16.GenerateProofs does,
+// from a quotient polynomial computed beforehand and skipping zero values.
+func generateProof(pk *groth16.Pk, cs *constraintSystem, hx []*big.Int) (*groth16.Proof, error) {
+	bn := groth16.Utils.Bn
+	r, err := fr.Rand()
+	if err != nil {
+		return nil, err
+	}
+	s, err := fr.Rand()
+	if err != nil {
+		return nil, err
+	}
+	zero := [3]*big.Int{bn.G1.F.Zero(), bn.G1.F.Zero(), bn.G1.F.Zero()}
+	proof := &groth16.Proof{PiA: zero, PiB: bn.Fq6.Zero(), PiC: zero}
+	piBG1 := zero
+	for i, w := range cs.values {
+		if fr.IsZero(w) {
+			continue
+		}
+		proof.PiA = bn.G1.Add(proof.PiA, bn.G1.MulScalar(pk.G1.At[i], w))
+		piBG1 = bn.G1.Add(piBG1, bn.G1.MulScalar(pk.G1.BACGamma[i], w))
+		proof.PiB = bn.G2.Add(proof.PiB, bn.G2.MulScalar(pk.G2.BACGamma[i], w))
+		if i > cs.nPublic {
+			proof.PiC = bn.G1.Add(proof.PiC, bn.G1.MulScalar(pk.BACDelta[i], w))
+		}
+	}
+	proof.PiA = bn.G1.Add(proof.PiA, pk.G1.Alpha)
+	proof.PiA = bn.G1.Add(proof.PiA, bn.G1.MulScalar(pk.G1.Delta, r))
+	piBG1 = bn.G1.Add(piBG1, pk.G1.Beta)
+	piBG1 = bn.G1.Add(piBG1, bn.G1.MulScalar(pk.G1.Delta, s))
+	proof.PiB = bn.G2.Add(proof.PiB, pk.G2.Beta)
+	proof.PiB = bn.G2.Add(proof.PiB, bn.G2.MulScalar(pk.G2.Delta, s))
+
+	for i, h := range hx {
+		if fr.IsZero(h) {
+			continue
+		}
+		proof.PiC = bn.G1.Add(proof.PiC, bn.G1.MulScalar(pk.PowersTauDelta[i], h))
+	}
+	proof.PiC = bn.G1.Add(proof.PiC, bn.G1.MulScalar(proof.PiA, s))
+	proof.PiC = bn.G1.Add(proof.PiC, bn.G1.MulScalar(piBG1, r))
+	proof.PiC = bn.G1.Add(proof.PiC, bn.G1.MulScalar(pk.G1.Delta, fr.Neg(fr.Mul(r, s))))
+	return proof, nil
+}
+
+// Verify checks proof shows st under vk.
+func Verify(vk *VerifyingKey, st *Statement, proof []byte) error {
+	if err := st.validate(); err != nil {
+		return err
+	}
+	p := groth16.Proof{}
+	if err := json.Unmarshal(proof, &p); err != nil {
+		return fmt.Errorf("invalid proof: %s", err)
+	}
+	for _, v := range append(p.PiA[:], p.PiC[:]...) {
+		if v == nil {
+			return fmt.Errorf("invalid proof")
+		}
+	}
+	for _, v := range p.PiB {
+		if v[0] == nil || v[1] == nil {
+			return fmt.Errorf("invalid proof")
+		}
+	}
+	if !groth16.VerifyProof(vk.Vk, p, st.publicInputs(), false) {
+		return fmt.Errorf("proof not verified")
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package zkproof
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"OntologyWithPOC/consensus/poc/shabal"
+)
+
+func TestProveVerify(t *testing.T) {
+	pk, err := Setup(testParams, []byte("seed"))
+	if err != nil {
+		t.Fatalf("setup: %s", err)
+	}
+	data, err := pk.Vk.Bytes()
+	if err != nil {
+		t.Fatalf("encode verifying key: %s", err)
+	}
+	vk, err := ParseVerifyingKey(data)
+	if err != nil {
+		t.Fatalf("parse verifying key: %s", err)
+	}
+
+	p, tree := generateTestTree(t, 3)
+	gensig := shabal.GenSig256(nil, []byte("genesis"))
+	scoop := shabal.ScoopNum256(gensig)
+	digest, path, err := tree.Path(p, scoop, 1)
+	if err != nil {
+		t.Fatalf("path: %s", err)
+	}
+	st := &Statement{
+		Root:       tree.Root,
+		ScoopIndex: scoop,
+		Digest:     digest,
+	}
+	w := &Witness{Offset: 1, Path: path}
+	proof, err := Prove(pk, st, w)
+	if err != nil {
+		t.Fatalf("prove: %s", err)
+	}
+	if err := Verify(vk, st, proof); err != nil {
+		t.Errorf("verify: %s", err)
+	}
+
+	forged := *st
+	forged.Digest = fr.Add(st.Digest, big.NewInt(1))
+	if err := Verify(vk, &forged, proof); err == nil {
+		t.Errorf("proof of other scoop digest should fail")
+	}
+	if _, err := Prove(pk, &forged, w); err == nil {
+		t.Errorf("prove with witness of other scoop digest should fail")
+	}
+	forged = *st
+	forged.ScoopIndex = scoop ^ 1
+	if err := Verify(vk, &forged, proof); err == nil {
+		t.Errorf("proof of other scoop should fail")
+	}
+	if _, err := Prove(pk, &forged, w); err == nil {
+		t.Errorf("prove with witness of other scoop should fail")
+	}
+	if err := Verify(vk, st, proof[:len(proof)/2]); err == nil {
+		t.Errorf("truncated proof should fail")
+	}
+}
+
+func TestSetupReproducible(t *testing.T) {
+	params := Params{Depth: 1, Rounds: 1}
+	pk1, err := Setup(params, []byte("seed"))
+	if err != nil {
+		t.Fatalf("setup: %s", err)
+	}
+	pk2, _ := Setup(params, []byte("seed"))
+	b1, _ := pk1.Vk.Bytes()
+	b2, _ := pk2.Vk.Bytes()
+	if !bytes.Equal(b1, b2) {
+		t.Errorf("setup of same seed differs")
+	}
+	if toxic([]byte("seed"), "tau").Cmp(toxic([]byte("other"), "tau")) == 0 {
+		t.Errorf("setup secret of other seed equal")
+	}
+}
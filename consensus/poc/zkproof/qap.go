@@ -0,0 +1,163 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package zkproof
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// The constraints are interpolated at the points 1..n: the polynomials of
+// a variable take its coefficient in constraint j at point j+1, and the
+// vanishing polynomial is Z(x) = (x-1)(x-2)...(x-n).
+
+// vanishing returns the coefficients of Z, lowest degree first.
+func vanishing(n int) []*big.Int {
+	z := []*big.Int{big.NewInt(1)}
+	for j := 1; j <= n; j++ {
+		// z *= (x - j)
+		next := make([]*big.Int, len(z)+1)
+		next[len(z)] = z[len(z)-1]
+		for k := len(z) - 1; k > 0; k-- {
+			next[k] = fr.Sub(z[k-1], fr.Mul(z[k], big.NewInt(int64(j))))
+		}
+		next[0] = fr.Neg(fr.Mul(z[0], big.NewInt(int64(j))))
+		z = next
+	}
+	return z
+}
+
+// derivatives returns Z'(j) for the points j in 1..n, that is
+// (j-1)! * (n-j)! * (-1)^(n-j).
+func derivatives(n int) []*big.Int {
+	fact := make([]*big.Int, n)
+	fact[0] = big.NewInt(1)
+	for i := 1; i < n; i++ {
+		fact[i] = fr.Mul(fact[i-1], big.NewInt(int64(i)))
+	}
+	ds := make([]*big.Int, n)
+	for j := 0; j < n; j++ {
+		d := fr.Mul(fact[j], fact[n-1-j])
+		if (n-1-j)%2 == 1 {
+			d = fr.Neg(d)
+		}
+		ds[j] = d
+	}
+	return ds
+}
+
+// lagrangeAt returns the Lagrange basis polynomials of the n points evaluated
+// at tau, along with Z(tau).
+func lagrangeAt(n int, tau *big.Int) ([]*big.Int, *big.Int, error) {
+	zt := big.NewInt(1)
+	for j := 1; j <= n; j++ {
+		zt = fr.Mul(zt, fr.Sub(tau, big.NewInt(int64(j))))
+	}
+	if fr.IsZero(zt) {
+		return nil, nil, fmt.Errorf("setup secret on interpolation domain")
+	}
+	ls := make([]*big.Int, n)
+	for j, d := range derivatives(n) {
+		ls[j] = fr.Div(zt, fr.Mul(fr.Sub(tau, big.NewInt(int64(j+1))), d))
+	}
+	return ls, zt, nil
+}
+
+// interpolate returns the polynomials taking the values of every vector at the
+// points 1..n, given the vanishing polynomial z of the points.
+func interpolate(z []*big.Int, vectors ...[]*big.Int) [][]*big.Int {
+	n := len(z) - 1
+	polys := make([][]*big.Int, len(vectors))
+	for i := range polys {
+		polys[i] = make([]*big.Int, n)
+		for k := range polys[i] {
+			polys[i][k] = big.NewInt(0)
+		}
+	}
+	q := make([]*big.Int, n)
+	for j, d := range derivatives(n) {
+		x := big.NewInt(int64(j + 1))
+		// q = z / (x - j - 1)
+		q[n-1] = z[n]
+		for k := n - 1; k > 0; k-- {
+			q[k-1] = fr.Add(z[k], fr.Mul(x, q[k]))
+		}
+		inv := fr.Inverse(d)
+		for i, v := range vectors {
+			if fr.IsZero(v[j]) {
+				continue
+			}
+			coef := fr.Mul(v[j], inv)
+			for k := range q {
+				polys[i][k].Add(polys[i][k], new(big.Int).Mul(coef, q[k]))
+			}
+		}
+	}
+	for _, p := range polys {
+		for k := range p {
+			p[k] = fr.Affine(p[k])
+		}
+	}
+	return polys
+}
+
+func polyMul(a, b []*big.Int) []*big.Int {
+	res := make([]*big.Int, len(a)+len(b)-1)
+	for k := range res {
+		res[k] = big.NewInt(0)
+	}
+	for i, x := range a {
+		if x.Sign() == 0 {
+			continue
+		}
+		for j, y := range b {
+			res[i+j].Add(res[i+j], new(big.Int).Mul(x, y))
+		}
+	}
+	for k := range res {
+		res[k] = fr.Affine(res[k])
+	}
+	return res
+}
+
+// divideVanishing divides p by the monic z, failing on a remainder.
+func divideVanishing(p, z []*big.Int) ([]*big.Int, error) {
+	n := len(z) - 1
+	if len(p) <= n {
+		return nil, fmt.Errorf("polynomial degree below vanishing degree")
+	}
+	rem := make([]*big.Int, len(p))
+	copy(rem, p)
+	h := make([]*big.Int, len(p)-n)
+	for k := len(h) - 1; k >= 0; k-- {
+		h[k] = rem[k+n]
+		if fr.IsZero(h[k]) {
+			continue
+		}
+		for i := 0; i <= n; i++ {
+			rem[k+i] = fr.Sub(rem[k+i], fr.Mul(h[k], z[i]))
+		}
+	}
+	for _, r := range rem[:n] {
+		if !fr.IsZero(r) {
+			return nil, fmt.Errorf("polynomial not divisible by vanishing polynomial")
+		}
+	}
+	return h, nil
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package zkproof
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestLagrangeAt(t *testing.T) {
+	n := 5
+	tau := big.NewInt(12345)
+	ls, zt, err := lagrangeAt(n, tau)
+	if err != nil {
+		t.Fatalf("lagrange: %s", err)
+	}
+	z := vanishing(n)
+	if !fr.Equal(zt, evalPoly(z, tau)) {
+		t.Errorf("vanishing polynomial at tau mismatch")
+	}
+	// the basis interpolates any values
+	values := []*big.Int{big.NewInt(7), big.NewInt(0), big.NewInt(3), big.NewInt(11), big.NewInt(2)}
+	poly := interpolate(z, values)[0]
+	expected := big.NewInt(0)
+	for j, v := range values {
+		expected = fr.Add(expected, fr.Mul(v, ls[j]))
+		if !fr.Equal(evalPoly(poly, big.NewInt(int64(j+1))), v) {
+			t.Errorf("interpolated polynomial at %d mismatch", j+1)
+		}
+	}
+	if !fr.Equal(evalPoly(poly, tau), expected) {
+		t.Errorf("lagrange basis at tau mismatch")
+	}
+	if _, _, err := lagrangeAt(n, big.NewInt(3)); err == nil {
+		t.Errorf("lagrange on the domain should fail")
+	}
+}
+
+func TestDivideVanishing(t *testing.T) {
+	z := vanishing(3)
+	h := []*big.Int{big.NewInt(4), big.NewInt(9)}
+	q, err := divideVanishing(polyMul(h, z), z)
+	if err != nil {
+		t.Fatalf("divide: %s", err)
+	}
+	if len(q) != 2 || !fr.Equal(q[0], h[0]) || !fr.Equal(q[1], h[1]) {
+		t.Errorf("divide: %v", q)
+	}
+	p := polyMul(h, z)
+	p[0] = fr.Add(p[0], big.NewInt(1))
+	if _, err := divideVanishing(p, z); err == nil {
+		t.Errorf("divide with remainder should fail")
+	}
+}
+
+func evalPoly(p []*big.Int, x *big.Int) *big.Int {
+	res := big.NewInt(0)
+	for k := len(p) - 1; k >= 0; k-- {
+		res = fr.Add(fr.Mul(res, x), p[k])
+	}
+	return res
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package zkproof
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// lc is a linear combination of the variables of a constraint system, by
+// variable index. Variable 0 is the constant one.
+type lc map[int]*big.Int
+
+type constraint struct {
+	a, b, c lc
+}
+
+// constraintSystem is a rank-1 constraint system built together with the
+// values of its variables: the public inputs first, then the private ones.
+type constraintSystem struct {
+	nPublic     int
+	values      []*big.Int
+	constraints []constraint
+}
+
+func newConstraintSystem() *constraintSystem {
+	return &constraintSystem{values: []*big.Int{big.NewInt(1)}}
+}
+
+func (cs *constraintSystem) nVars() int {
+	return len(cs.values)
+}
+
+func (cs *constraintSystem) alloc(v *big.Int) lc {
+	cs.values = append(cs.values, fr.Affine(v))
+	return lc{len(cs.values) - 1: big.NewInt(1)}
+}
+
+func (cs *constraintSystem) public(v *big.Int) lc {
+	if cs.nPublic != len(cs.values)-1 {
+		panic("public input allocated after private ones")
+	}
+	cs.nPublic++
+	return cs.alloc(v)
+}
+
+func (cs *constraintSystem) private(v *big.Int) lc {
+	return cs.alloc(v)
+}
+
+func constant(v *big.Int) lc {
+	return lc{0: fr.Affine(v)}
+}
+
+func add(ls ...lc) lc {
+	res := make(lc)
+	for _, l := range ls {
+		for i, k := range l {
+			if v, ok := res[i]; ok {
+				res[i] = fr.Add(v, k)
+			} else {
+				res[i] = k
+			}
+		}
+	}
+	return res
+}
+
+func scale(l lc, s *big.Int) lc {
+	res := make(lc, len(l))
+	for i, k := range l {
+		res[i] = fr.Mul(k, s)
+	}
+	return res
+}
+
+func sub(a, b lc) lc {
+	return add(a, scale(b, fr.Neg(big.NewInt(1))))
+}
+
+func (cs *constraintSystem) eval(l lc) *big.Int {
+	res := big.NewInt(0)
+	for i, k := range l {
+		res = fr.Add(res, fr.Mul(k, cs.values[i]))
+	}
+	return res
+}
+
+func (cs *constraintSystem) constrain(a, b, c lc) {
+	cs.constraints = append(cs.constraints, constraint{a, b, c})
+}
+
+// mul returns a new variable constrained to a*b.
+func (cs *constraintSystem) mul(a, b lc) lc {
+	out := cs.private(fr.Mul(cs.eval(a), cs.eval(b)))
+	cs.constrain(a, b, out)
+	return out
+}
+
+func (cs *constraintSystem) assertEqual(a, b lc) {
+	cs.constrain(sub(a, b), constant(big.NewInt(1)), lc{})
+}
+
+func (cs *constraintSystem) assertBool(b lc) {
+	cs.constrain(b, sub(b, constant(big.NewInt(1))), lc{})
+}
+
+// bindPublic adds a constraint per public input so their polynomials are
+// linearly independent, which the soundness of Groth16 relies on.
+func (cs *constraintSystem) bindPublic() {
+	for i := 1; i <= cs.nPublic; i++ {
+		cs.constrain(lc{i: big.NewInt(1)}, lc{}, lc{})
+	}
+}
+
+// check returns the first constraint the values do not satisfy.
+func (cs *constraintSystem) check() error {
+	for j, c := range cs.constraints {
+		if !fr.Equal(fr.Mul(cs.eval(c.a), cs.eval(c.b)), cs.eval(c.c)) {
+			return fmt.Errorf("constraint %d not satisfied", j)
+		}
+	}
+	return nil
+}
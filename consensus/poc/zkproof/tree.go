@@ -0,0 +1,382 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package zkproof
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+
+	"OntologyWithPOC/consensus/poc/plot"
+	"OntologyWithPOC/consensus/poc/shabal"
+)
+
+// A plot tree commits to the scoops of a plot: its leaves are the digests of
+// the scoops of the nonces, ordered by scoop then by nonce, each scoop having
+// a subtree of 2^Depth leaves padded with zeros. The tree file keeps the
+// nodes of the scoop subtrees from level cachedLevel up and the top levels
+// above them, so a path only needs 2^cachedLevel scoops read from the plot.
+
+const (
+	cachedLevel    = 6
+	treeHeaderSize = 48
+	nodeSize       = 32
+)
+
+// TreeFile returns the path of the tree file of the plot at plotPath.
+func TreeFile(plotPath string) string {
+	return plotPath + ".zkt"
+}
+
+type PlotTree struct {
+	Params Params
+	Nonces uint64
+	Root   *big.Int
+	zeros  []*big.Int
+	file   *os.File
+}
+
+func newPlotTree(params Params, nonces uint64) (*PlotTree, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+	if nonces == 0 || nonces > 1<<uint(params.Depth) {
+		return nil, fmt.Errorf("%d nonces out of tree depth %d", nonces, params.Depth)
+	}
+	zeros := []*big.Int{big.NewInt(0)}
+	for l := 0; l < params.pathLen(); l++ {
+		zeros = append(zeros, params.Hash2(zeros[l], zeros[l]))
+	}
+	return &PlotTree{Params: params, Nonces: nonces, zeros: zeros}, nil
+}
+
+func (t *PlotTree) cached() int {
+	if t.Params.Depth < cachedLevel {
+		return t.Params.Depth
+	}
+	return cachedLevel
+}
+
+// width returns the number of nodes of a scoop subtree at level that are not
+// zero padding.
+func (t *PlotTree) width(level int) uint64 {
+	return (t.Nonces-1)>>uint(level) + 1
+}
+
+func (t *PlotTree) scoopSize() int64 {
+	var size int64
+	for l := t.cached(); l <= t.Params.Depth; l++ {
+		size += int64(t.width(l)) * nodeSize
+	}
+	return size
+}
+
+// topOffset returns the offset of node index of the top level above the
+// scoop subtrees, level 0 being their roots.
+func (t *PlotTree) topOffset(level int, index uint64) int64 {
+	off := int64(treeHeaderSize)
+	for l := 0; l < level; l++ {
+		off += int64(shabal.ScoopCount>>uint(l)) * nodeSize
+	}
+	return off + int64(index)*nodeSize
+}
+
+func (t *PlotTree) nodeOffset(scoop uint32, level int, index uint64) int64 {
+	off := t.topOffset(scoopLevels+1, 0) + int64(scoop)*t.scoopSize()
+	for l := t.cached(); l < level; l++ {
+		off += int64(t.width(l)) * nodeSize
+	}
+	return off + int64(index)*nodeSize
+}
+
+func encodeNode(buf []byte, v *big.Int) {
+	b := v.Bytes()
+	for i := range buf[:nodeSize-len(b)] {
+		buf[i] = 0
+	}
+	copy(buf[nodeSize-len(b):], b)
+}
+
+func (t *PlotTree) readNode(off int64) (*big.Int, error) {
+	buf := make([]byte, nodeSize)
+	if _, err := t.file.ReadAt(buf, off); err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(buf), nil
+}
+
+// hashLevel returns the parents of nodes at level, padded with zeros.
+func (t *PlotTree) hashLevel(nodes []*big.Int, level int) []*big.Int {
+	parents := make([]*big.Int, (len(nodes)+1)/2)
+	for i := range parents {
+		right := t.zeros[level]
+		if 2*i+1 < len(nodes) {
+			right = nodes[2*i+1]
+		}
+		parents[i] = t.Params.Hash2(nodes[2*i], right)
+	}
+	return parents
+}
+
+func (t *PlotTree) digests(scoops []byte) []*big.Int {
+	ds := make([]*big.Int, len(scoops)/shabal.ScoopSize)
+	for i := range ds {
+		ds[i] = t.Params.ScoopDigest(scoops[i*shabal.ScoopSize : (i+1)*shabal.ScoopSize])
+	}
+	return ds
+}
+
+// buildScoop writes the cached levels of the subtree of scoop and returns
+// its root.
+func (t *PlotTree) buildScoop(p *plot.Plot, scoop uint32) (*big.Int, error) {
+	scoops, err := p.ReadScoopRange(scoop, 0, t.Nonces)
+	if err != nil {
+		return nil, err
+	}
+	nodes := t.digests(scoops)
+	for l := 0; l <= t.Params.Depth; l++ {
+		if l >= t.cached() {
+			buf := make([]byte, len(nodes)*nodeSize)
+			for i, v := range nodes {
+				encodeNode(buf[i*nodeSize:], v)
+			}
+			if _, err := t.file.WriteAt(buf, t.nodeOffset(scoop, l, 0)); err != nil {
+				return nil, err
+			}
+		}
+		if l < t.Params.Depth {
+			nodes = t.hashLevel(nodes, l)
+		}
+	}
+	return nodes[0], nil
+}
+
+// BuildTree writes the tree file of the complete plot p with workers
+// goroutines, calling progress, if not nil, with the number of scoops done.
+// Every scoop of every nonce is hashed, which takes far longer than plotting.
+func BuildTree(p *plot.Plot, params Params, workers int, progress func(scoops int)) (*PlotTree, error) {
+	n, err := p.NoncesWritten()
+	if err != nil {
+		return nil, err
+	}
+	if n != p.NonceCount {
+		return nil, fmt.Errorf("plot %s incomplete: %d of %d nonces", p.Path, n, p.NonceCount)
+	}
+	t, err := newPlotTree(params, n)
+	if err != nil {
+		return nil, err
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	path := TreeFile(p.Path)
+	if t.file, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600); err != nil {
+		return nil, err
+	}
+
+	roots := make([]*big.Int, shabal.ScoopCount)
+	scoopC := make(chan uint32)
+	errC := make(chan error, workers)
+	quitC := make(chan struct{})
+	var quit sync.Once
+	var lock sync.Mutex
+	done := 0
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for scoop := range scoopC {
+				root, err := t.buildScoop(p, scoop)
+				if err != nil {
+					errC <- err
+					quit.Do(func() { close(quitC) })
+					return
+				}
+				lock.Lock()
+				roots[scoop] = root
+				done++
+				if progress != nil {
+					progress(done)
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+feed:
+	for scoop := uint32(0); scoop < shabal.ScoopCount; scoop++ {
+		select {
+		case scoopC <- scoop:
+		case <-quitC:
+			break feed
+		}
+	}
+	close(scoopC)
+	wg.Wait()
+	select {
+	case err = <-errC:
+	default:
+	}
+	if err == nil {
+		err = t.writeTop(roots)
+	}
+	if err != nil {
+		t.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	return t, nil
+}
+
+// writeTop writes the top levels over the roots of the scoop subtrees and
+// the header.
+func (t *PlotTree) writeTop(nodes []*big.Int) error {
+	for l := 0; l <= scoopLevels; l++ {
+		buf := make([]byte, len(nodes)*nodeSize)
+		for i, v := range nodes {
+			encodeNode(buf[i*nodeSize:], v)
+		}
+		if _, err := t.file.WriteAt(buf, t.topOffset(l, 0)); err != nil {
+			return err
+		}
+		if l < scoopLevels {
+			nodes = t.hashLevel(nodes, t.Params.Depth+l)
+		}
+	}
+	t.Root = nodes[0]
+	header := make([]byte, treeHeaderSize)
+	binary.BigEndian.PutUint32(header[0:], uint32(t.Params.Depth))
+	binary.BigEndian.PutUint32(header[4:], uint32(t.Params.Rounds))
+	binary.BigEndian.PutUint64(header[8:], t.Nonces)
+	encodeNode(header[16:], t.Root)
+	if _, err := t.file.WriteAt(header, 0); err != nil {
+		return err
+	}
+	return t.file.Sync()
+}
+
+// OpenTree opens the tree file at path.
+func OpenTree(path string) (*PlotTree, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, treeHeaderSize)
+	if _, err := f.ReadAt(header, 0); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("read tree header of %s: %s", path, err)
+	}
+	params := Params{
+		Depth:  int(binary.BigEndian.Uint32(header[0:])),
+		Rounds: int(binary.BigEndian.Uint32(header[4:])),
+	}
+	t, err := newPlotTree(params, binary.BigEndian.Uint64(header[8:]))
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("invalid tree %s: %s", path, err)
+	}
+	t.Root = new(big.Int).SetBytes(header[16:])
+	t.file = f
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if fi.Size() != t.nodeOffset(shabal.ScoopCount, t.cached(), 0) {
+		f.Close()
+		return nil, fmt.Errorf("tree %s truncated", path)
+	}
+	return t, nil
+}
+
+func (t *PlotTree) Close() error {
+	return t.file.Close()
+}
+
+// Path returns the digest of scoop of the nonce at offset of plot p and its
+// siblings up to the root of the tree.
+func (t *PlotTree) Path(p *plot.Plot, scoop uint32, offset uint64) (*big.Int, []*big.Int, error) {
+	if offset >= t.Nonces {
+		return nil, nil, fmt.Errorf("nonce offset %d out of tree of %d nonces", offset, t.Nonces)
+	}
+	if scoop >= shabal.ScoopCount {
+		return nil, nil, fmt.Errorf("invalid scoop %d", scoop)
+	}
+	// the levels below the cached one are hashed from the scoops
+	from := offset >> uint(t.cached()) << uint(t.cached())
+	to := from + 1<<uint(t.cached())
+	if to > t.Nonces {
+		to = t.Nonces
+	}
+	scoops, err := p.ReadScoopRange(scoop, from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+	nodes := t.digests(scoops)
+	digest := nodes[offset-from]
+	path := make([]*big.Int, 0, t.Params.pathLen())
+	for l := 0; l < t.cached(); l++ {
+		i := (offset-from)>>uint(l) ^ 1
+		if i < uint64(len(nodes)) {
+			path = append(path, nodes[i])
+		} else {
+			path = append(path, t.zeros[l])
+		}
+		nodes = t.hashLevel(nodes, l)
+	}
+	for l := t.cached(); l < t.Params.Depth; l++ {
+		i := offset>>uint(l) ^ 1
+		if i >= t.width(l) {
+			path = append(path, t.zeros[l])
+			continue
+		}
+		node, err := t.readNode(t.nodeOffset(scoop, l, i))
+		if err != nil {
+			return nil, nil, err
+		}
+		path = append(path, node)
+	}
+	for l := 0; l < scoopLevels; l++ {
+		node, err := t.readNode(t.topOffset(l, uint64(scoop>>uint(l)^1)))
+		if err != nil {
+			return nil, nil, err
+		}
+		path = append(path, node)
+	}
+	return digest, path, nil
+}
+
+// VerifyPath checks path leads from digest at scoop and offset to root.
+func VerifyPath(params Params, root, digest *big.Int, scoop uint32, offset uint64, path []*big.Int) bool {
+	if len(path) != params.pathLen() {
+		return false
+	}
+	pos := uint64(scoop)<<uint(params.Depth) | offset
+	cur := digest
+	for l, sibling := range path {
+		if pos>>uint(l)&1 == 0 {
+			cur = params.Hash2(cur, sibling)
+		} else {
+			cur = params.Hash2(sibling, cur)
+		}
+	}
+	return cur.Cmp(root) == 0
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package zkproof
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"OntologyWithPOC/consensus/poc/plot"
+	"OntologyWithPOC/consensus/poc/shabal"
+)
+
+const testAccountID = "03a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f90"
+
+func generateTestTree(t *testing.T, nonces uint64) (*plot.Plot, *PlotTree) {
+	dir, err := ioutil.TempDir("", "poc-zkproof")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path, err := plot.Generate(dir, testAccountID, 100, nonces)
+	if err != nil {
+		t.Fatalf("generate plot: %s", err)
+	}
+	p, err := plot.Open(path)
+	if err != nil {
+		t.Fatalf("open plot: %s", err)
+	}
+	t.Cleanup(func() { p.Close() })
+	tree, err := BuildTree(p, testParams, 4, nil)
+	if err != nil {
+		t.Fatalf("build tree: %s", err)
+	}
+	tree.Close()
+	tree, err = OpenTree(TreeFile(path))
+	if err != nil {
+		t.Fatalf("open tree: %s", err)
+	}
+	t.Cleanup(func() { tree.Close() })
+	return p, tree
+}
+
+func TestPlotTreePath(t *testing.T) {
+	p, tree := generateTestTree(t, 3)
+	if tree.Nonces != 3 || tree.Params != testParams {
+		t.Errorf("tree header: %d nonces, params %v", tree.Nonces, tree.Params)
+	}
+	for _, scoop := range []uint32{0, 1, shabal.ScoopCount - 1} {
+		for offset := uint64(0); offset < 3; offset++ {
+			digest, path, err := tree.Path(p, scoop, offset)
+			if err != nil {
+				t.Fatalf("path of scoop %d offset %d: %s", scoop, offset, err)
+			}
+			data, _ := p.ReadScoop(offset, scoop)
+			if digest.Cmp(testParams.ScoopDigest(data)) != 0 {
+				t.Errorf("digest of scoop %d offset %d mismatch", scoop, offset)
+			}
+			if !VerifyPath(testParams, tree.Root, digest, scoop, offset, path) {
+				t.Errorf("path of scoop %d offset %d not verified", scoop, offset)
+			}
+			if VerifyPath(testParams, tree.Root, digest, scoop^1, offset, path) {
+				t.Errorf("path verified at other scoop")
+			}
+		}
+	}
+	if _, _, err := tree.Path(p, 0, 3); err == nil {
+		t.Errorf("path of nonce out of tree should fail")
+	}
+	if _, err := BuildTree(p, Params{Depth: 1, Rounds: 2}, 1, nil); err == nil {
+		t.Errorf("tree of plot larger than its depth should fail")
+	}
+}
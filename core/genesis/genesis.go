@@ -157,6 +157,7 @@ func BuildGenesisBlock(defaultBookkeeper []keypair.PublicKey, genesisConfig *con
 		param := newParamContract()
 		oid := deployOntIDContract()
 		auth := deployAuthContract()
+		plotBinding := deployPlotBindingContract()
 		govConfigTx := newGovConfigTx()
 
 		genesisBlock := &types.Block{
@@ -167,6 +168,7 @@ func BuildGenesisBlock(defaultBookkeeper []keypair.PublicKey, genesisConfig *con
 				param,
 				oid,
 				auth,
+				plotBinding,
 				govConfigTx,
 				newGoverningInit(),
 				newUtilityInit(),
@@ -232,6 +234,16 @@ func deployAuthContract() *types.Transaction {
 	return tx
 }
 
+func deployPlotBindingContract() *types.Transaction {
+	mutable := utils.NewDeployTransaction(nutils.PlotBindingContractAddress[:], "PlotBinding", "1.0",
+		"Ontology Team", "contact@ont.io", "Ontology Network PoC Plot Binding Contract", true)
+	tx, err := mutable.IntoImmutable()
+	if err != nil {
+		panic("construct genesis plot binding transaction error ")
+	}
+	return tx
+}
+
 func deployOntIDContract() *types.Transaction {
 	mutable := utils.NewDeployTransaction(nutils.OntIDContractAddress[:], "OID", "1.0",
 		"Ontology Team", "contact@ont.io", "Ontology Network ONT ID", true)
@@ -76,6 +76,10 @@ func (self *Ledger) ExecuteBlock(b *types.Block) (store.ExecuteResult, error) {
 	return self.ldgStore.ExecuteBlock(b)
 }
 
+func (self *Ledger) ExecutePendingBlock(b *types.Block, pending []store.ExecuteResult) (store.ExecuteResult, error) {
+	return self.ldgStore.ExecutePendingBlock(b, pending)
+}
+
 func (self *Ledger) SubmitBlock(b *types.Block, exec store.ExecuteResult) error {
 	return self.ldgStore.SubmitBlock(b, exec)
 }
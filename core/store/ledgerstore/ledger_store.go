@@ -625,6 +625,30 @@ func (this *LedgerStoreImp) ExecuteBlock(block *types.Block) (result store.Execu
 	return
 }
 
+//ExecutePendingBlock execute the block on top of the pending blocks before it, which are executed but not submitted yet.
+//The results of the pending blocks are ordered by height, and the first one is the next block of the ledger.
+func (this *LedgerStoreImp) ExecutePendingBlock(block *types.Block, pending []store.ExecuteResult) (result store.ExecuteResult, err error) {
+	if len(pending) == 0 {
+		return this.ExecuteBlock(block)
+	}
+	this.getSavingBlockLock()
+	defer this.releaseSavingBlockLock()
+	currBlockHeight := this.GetCurrentBlockHeight()
+	blockHeight := block.Header.Height
+	nextBlockHeight := currBlockHeight + uint32(len(pending)) + 1
+	if blockHeight != nextBlockHeight {
+		err = fmt.Errorf("block height %d not equal next pending block height %d", blockHeight, nextBlockHeight)
+		return
+	}
+	if currBlockHeight < this.stateHashCheckHeight {
+		err = fmt.Errorf("block %d pending on blocks before state hash check height %d", blockHeight, this.stateHashCheckHeight)
+		return
+	}
+
+	result, err = this.executeBlockOn(block, pending)
+	return
+}
+
 func (this *LedgerStoreImp) SubmitBlock(block *types.Block, result store.ExecuteResult) error {
 	this.getSavingBlockLock()
 	defer this.releaseSavingBlockLock()
@@ -720,7 +744,22 @@ func (this *LedgerStoreImp) saveBlockToBlockStore(block *types.Block) error {
 }
 
 func (this *LedgerStoreImp) executeBlock(block *types.Block) (result store.ExecuteResult, err error) {
-	overlay := this.stateStore.NewOverlayDB()
+	return this.executeBlockOn(block, nil)
+}
+
+//executeBlockOn execute the block on the state of the ledger with the write sets of the pending blocks applied
+func (this *LedgerStoreImp) executeBlockOn(block *types.Block, pending []store.ExecuteResult) (result store.ExecuteResult, err error) {
+	writeSets := make([]*overlaydb.MemDB, 0, len(pending))
+	for _, res := range pending {
+		writeSets = append(writeSets, res.WriteSet)
+	}
+	newOverlay := func() *overlaydb.OverlayDB {
+		if len(writeSets) == 0 {
+			return this.stateStore.NewOverlayDB()
+		}
+		return this.stateStore.NewPendingOverlayDB(writeSets)
+	}
+	overlay := newOverlay()
 	if block.Header.Height != 0 {
 		config := &smartcontract.Config{
 			Time:   block.Header.Timestamp,
@@ -728,13 +767,14 @@ func (this *LedgerStoreImp) executeBlock(block *types.Block) (result store.Execu
 			Tx:     &types.Transaction{},
 		}
 
-		err = refreshGlobalParam(config, storage.NewCacheDB(this.stateStore.NewOverlayDB()), this)
+		err = refreshGlobalParam(config, storage.NewCacheDB(newOverlay()), this)
 		if err != nil {
 			return
 		}
 	}
 
 	cache := storage.NewCacheDB(overlay)
+	var fees uint64
 	for _, tx := range block.Transactions {
 		cache.Reset()
 		notify, e := this.handleTransaction(overlay, cache, block, tx)
@@ -744,6 +784,19 @@ func (this *LedgerStoreImp) executeBlock(block *types.Block) (result store.Execu
 		}
 
 		result.Notify = append(result.Notify, notify)
+		fees += notify.GasConsumed
+	}
+	if fees > 0 && config.DefConfig.Genesis.ConsensusType == config.CONSENSUS_TYPE_POC {
+		cache.Reset()
+		config := &smartcontract.Config{
+			Time:   block.Header.Timestamp,
+			Height: block.Header.Height,
+			Tx:     &types.Transaction{},
+		}
+		if e := collectPocFees(fees, config, cache, this); e != nil {
+			err = fmt.Errorf("collect PoC fees of block %d error %s", block.Header.Height, e)
+			return
+		}
 	}
 
 	result.Hash = overlay.ChangeHash()
@@ -759,8 +812,14 @@ func (this *LedgerStoreImp) executeBlock(block *types.Block) (result store.Execu
 
 		result.MerkleRoot = res
 		result.Hash = result.MerkleRoot
-	} else {
+	} else if len(pending) == 0 {
 		result.MerkleRoot = this.stateStore.GetStateMerkleRootWithNewHash(result.Hash)
+	} else {
+		hashes := make([]common.Uint256, 0, len(pending)+1)
+		for _, res := range pending {
+			hashes = append(hashes, res.Hash)
+		}
+		result.MerkleRoot = this.stateStore.GetStateMerkleRootWithNewHashes(append(hashes, result.Hash))
 	}
 
 	return
@@ -1023,6 +1082,10 @@ func (this *LedgerStoreImp) GetBlockRootWithNewTxRoots(startHeight uint32, txRoo
 		// or return error?
 		return common.UINT256_EMPTY
 	}
+	//the tx roots of the blocks between ledger and startHeight are missing
+	if this.currBlockHeight+1 < startHeight {
+		return common.UINT256_EMPTY
+	}
 
 	needs := txRoots[this.currBlockHeight+1-startHeight:]
 	return this.stateStore.GetBlockRootWithNewTxRoots(needs)
@@ -20,9 +20,12 @@ package ledgerstore
 
 import (
 	"OntologyWithPOC/account"
+	"OntologyWithPOC/common"
 	"OntologyWithPOC/common/config"
 	"OntologyWithPOC/common/log"
 	"OntologyWithPOC/core/genesis"
+	"OntologyWithPOC/core/store"
+	"OntologyWithPOC/core/types"
 	"fmt"
 	"github.com/ontio/ontology-crypto/keypair"
 	"os"
@@ -137,3 +140,58 @@ func TestInitLedgerStoreWithGenesisBlock(t *testing.T) {
 		return
 	}
 }
+
+func TestExecutePendingBlock(t *testing.T) {
+	ledgerStore, err := NewLedgerStore("test/pending", 0)
+	if err != nil {
+		t.Fatalf("NewLedgerStore error %s", err)
+	}
+	defer ledgerStore.Close()
+	acc := account.NewAccount("")
+	bookkeepers := []keypair.PublicKey{acc.PublicKey}
+	genesisBlock, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	if err != nil {
+		t.Fatalf("BuildGenesisBlock error %s", err)
+	}
+	if err := ledgerStore.InitLedgerStoreWithGenesisBlock(genesisBlock, bookkeepers); err != nil {
+		t.Fatalf("InitLedgerStoreWithGenesisBlock error %s", err)
+	}
+
+	newBlock := func(height uint32, prevHash common.Uint256, txRoots []common.Uint256) *types.Block {
+		return &types.Block{
+			Header: &types.Header{
+				PrevBlockHash:    prevHash,
+				TransactionsRoot: txRoots[len(txRoots)-1],
+				BlockRoot:        ledgerStore.GetBlockRootWithNewTxRoots(1, txRoots),
+				Timestamp:        genesisBlock.Header.Timestamp + height,
+				Height:           height,
+			},
+		}
+	}
+	txRoots := []common.Uint256{{1}, {2}}
+	block1 := newBlock(1, genesisBlock.Hash(), txRoots[:1])
+	block2 := newBlock(2, block1.Hash(), txRoots)
+
+	result1, err := ledgerStore.ExecuteBlock(block1)
+	if err != nil {
+		t.Fatalf("ExecuteBlock error %s", err)
+	}
+	if _, err := ledgerStore.ExecutePendingBlock(block1, []store.ExecuteResult{result1}); err == nil {
+		t.Errorf("ExecutePendingBlock should fail on the height of a pending block")
+	}
+	result2, err := ledgerStore.ExecutePendingBlock(block2, []store.ExecuteResult{result1})
+	if err != nil {
+		t.Fatalf("ExecutePendingBlock error %s", err)
+	}
+
+	if err := ledgerStore.submitBlock(block1, result1); err != nil {
+		t.Fatalf("submitBlock error %s", err)
+	}
+	result, err := ledgerStore.ExecuteBlock(block2)
+	if err != nil {
+		t.Fatalf("ExecuteBlock error %s", err)
+	}
+	if result.MerkleRoot != result2.MerkleRoot || result.Hash != result2.Hash {
+		t.Errorf("pending execution root %x, executed on the ledger %x", result2.MerkleRoot, result.MerkleRoot)
+	}
+}
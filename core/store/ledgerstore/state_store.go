@@ -235,6 +235,11 @@ func (self *StateStore) NewOverlayDB() *overlaydb.OverlayDB {
 	return overlaydb.NewOverlayDB(self.store)
 }
 
+//NewPendingOverlayDB return an overlay on the state store with the write sets of pending blocks applied
+func (self *StateStore) NewPendingOverlayDB(writeSets []*overlaydb.MemDB) *overlaydb.OverlayDB {
+	return overlaydb.NewOverlayDB(overlaydb.NewPendingStore(self.store, writeSets))
+}
+
 //CommitTo commit state batch to state store
 func (self *StateStore) CommitTo() error {
 	return self.store.BatchCommit()
@@ -376,6 +381,10 @@ func (self *StateStore) GetStateMerkleRootWithNewHash(writeSetHash common.Uint25
 	return self.deltaMerkleTree.GetRootWithNewLeaf(writeSetHash)
 }
 
+func (self *StateStore) GetStateMerkleRootWithNewHashes(writeSetHashes []common.Uint256) common.Uint256 {
+	return self.deltaMerkleTree.GetRootWithNewLeaves(writeSetHashes)
+}
+
 func (self *StateStore) GetBlockRootWithNewTxRoots(txRoots []common.Uint256) common.Uint256 {
 	return self.merkleTree.GetRootWithNewLeaves(txRoots)
 }
@@ -37,6 +37,7 @@ import (
 	"OntologyWithPOC/smartcontract"
 	"OntologyWithPOC/smartcontract/event"
 	"OntologyWithPOC/smartcontract/service/native/global_params"
+	"OntologyWithPOC/smartcontract/service/native/governance"
 	ninit "OntologyWithPOC/smartcontract/service/native/init"
 	"OntologyWithPOC/smartcontract/service/native/ont"
 	"OntologyWithPOC/smartcontract/service/native/utils"
@@ -206,6 +207,10 @@ func (self *StateStore) HandleInvokeTransaction(store store.LedgerStore, overlay
 	}
 
 	costGas = costGasLimit * tx.GasPrice
+	if !isCharge {
+		//system transactions are not charged, nor counted as fees
+		costGas = 0
+	}
 	if err != nil {
 		if isCharge {
 			if err := costInvalidGas(tx.Payer, costGas, config, overlay, store, notify); err != nil {
@@ -292,6 +297,24 @@ func chargeCostGas(payer common.Address, gas uint64, config *smartcontract.Confi
 	return sc.Notifications, nil
 }
 
+//collectPocFees adds the gas fees charged by the transactions of a block to the fees of the PoC block rewards,
+//once per block after its transactions are executed
+func collectPocFees(fees uint64, config *smartcontract.Config, cache *storage.CacheDB, store store.LedgerStore) error {
+	sc := smartcontract.SmartContract{
+		Config:  config,
+		CacheDB: cache,
+		Store:   store,
+		Gas:     math.MaxUint64,
+	}
+
+	service, _ := sc.NewNativeService()
+	if err := governance.CollectPocFee(service, fees); err != nil {
+		return err
+	}
+	cache.Commit()
+	return nil
+}
+
 func refreshGlobalParam(config *smartcontract.Config, cache *storage.CacheDB, store store.LedgerStore) error {
 	bf := new(bytes.Buffer)
 	if err := utils.WriteVarUint(bf, uint64(len(neovm.GAS_TABLE_KEYS))); err != nil {
@@ -23,6 +23,18 @@ import (
 	"strconv"
 	"sync"
 	"testing"
+
+	"OntologyWithPOC/account"
+	"OntologyWithPOC/core/signature"
+	"OntologyWithPOC/core/types"
+	cutils "OntologyWithPOC/core/utils"
+	"OntologyWithPOC/smartcontract"
+	"OntologyWithPOC/smartcontract/event"
+	"OntologyWithPOC/smartcontract/service/native/governance"
+	"OntologyWithPOC/smartcontract/service/native/ont"
+	"OntologyWithPOC/smartcontract/service/native/utils"
+	"OntologyWithPOC/smartcontract/storage"
+	"github.com/ontio/ontology-crypto/keypair"
 )
 
 func TestSyncMapRange(t *testing.T) {
@@ -75,3 +87,63 @@ func TestSyncMapRW(t *testing.T) {
 func addsync(m *sync.Map, va int) {
 	m.Store("key", va)
 }
+
+func TestChargeGasWithoutPocFeePool(t *testing.T) {
+	overlay := testStateStore.NewOverlayDB()
+	cache := storage.NewCacheDB(overlay)
+	payer := account.NewAccount("")
+	cache.Put(ont.GenBalanceKey(utils.OngContractAddress, payer.Address), utils.GenUInt64StorageItem(1000000).ToArray())
+	cache.Commit()
+
+	to := account.NewAccount("")
+	sts := []ont.State{{From: payer.Address, To: to.Address, Value: 100}}
+	code, err := cutils.BuildNativeInvokeCode(utils.OngContractAddress, 0, ont.TRANSFER_NAME, []interface{}{sts})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mutable := cutils.NewInvokeTransaction(code)
+	mutable.GasPrice = 1
+	mutable.GasLimit = 30000
+	mutable.Payer = payer.Address
+	hash := mutable.Hash()
+	sig, err := signature.Sign(payer, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	mutable.Sigs = []types.Sig{{PubKeys: []keypair.PublicKey{payer.PublicKey}, M: 1, SigData: [][]byte{sig}}}
+	tx, err := mutable.IntoImmutable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := &types.Block{Header: &types.Header{Height: 1}}
+	notify := &event.ExecuteNotify{TxHash: tx.Hash(), State: event.CONTRACT_STATE_FAIL}
+	if err := testStateStore.HandleInvokeTransaction(testLedgerStore, overlay, cache, tx, block, notify); err != nil {
+		t.Fatalf("HandleInvokeTransaction error %s", err)
+	}
+	if notify.State != event.CONTRACT_STATE_SUCCESS || notify.GasConsumed == 0 {
+		t.Fatalf("transaction state %d, gas consumed %d", notify.State, notify.GasConsumed)
+	}
+
+	config := &smartcontract.Config{Height: 1, Tx: &types.Transaction{}}
+	cache.Reset()
+	balance, err := getBalanceFromNative(config, cache, testLedgerStore, payer.Address)
+	if err != nil {
+		t.Fatalf("getBalanceFromNative error %s", err)
+	}
+	if balance != 1000000-100-notify.GasConsumed {
+		t.Errorf("payer balance %d after paying %d gas", balance, notify.GasConsumed)
+	}
+
+	// no PoC reward schedule, the fees stay out of a fee pool
+	if err := collectPocFees(notify.GasConsumed, config, cache, testLedgerStore); err != nil {
+		t.Fatalf("collectPocFees error %s", err)
+	}
+	feePool, err := overlay.Get(utils.ConcatKey(utils.GovernanceContractAddress, []byte(governance.POC_FEE_POOL)))
+	if err != nil || feePool != nil {
+		t.Errorf("PoC fee pool %x, error %v", feePool, err)
+	}
+	governanceBalance, err := getBalanceFromNative(config, cache, testLedgerStore, utils.GovernanceContractAddress)
+	if err != nil || governanceBalance != notify.GasConsumed {
+		t.Errorf("governance balance %d, error %v", governanceBalance, err)
+	}
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package overlaydb
+
+import (
+	"errors"
+
+	"OntologyWithPOC/core/store/common"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+var errReadOnly = errors.New("pending store is read only")
+
+// PendingStore is a read only view of a store with the write sets of blocks
+// executed on top of it but not committed yet.
+type PendingStore struct {
+	store   common.PersistStore
+	pending *MemDB
+}
+
+// NewPendingStore applies writeSets, ordered by block height, on top of store.
+func NewPendingStore(store common.PersistStore, writeSets []*MemDB) *PendingStore {
+	return &PendingStore{store: store, pending: MergeWriteSets(writeSets)}
+}
+
+// MergeWriteSets returns the changes of writeSets applied in order, deletions
+// included.
+func MergeWriteSets(writeSets []*MemDB) *MemDB {
+	merged := NewMemDB(initCap, initkvNum)
+	for _, writeSet := range writeSets {
+		writeSet.ForEach(func(key, val []byte) {
+			if len(val) == 0 {
+				merged.Delete(key)
+			} else {
+				merged.Put(key, val)
+			}
+		})
+	}
+	return merged
+}
+
+func (self *PendingStore) Get(key []byte) ([]byte, error) {
+	value, unknown := self.pending.Get(key)
+	if unknown {
+		return self.store.Get(key)
+	}
+	if len(value) == 0 {
+		return nil, common.ErrNotFound
+	}
+	return value, nil
+}
+
+func (self *PendingStore) Has(key []byte) (bool, error) {
+	value, unknown := self.pending.Get(key)
+	if unknown {
+		return self.store.Has(key)
+	}
+	return len(value) != 0, nil
+}
+
+// param prefix is referenced by iterator
+func (self *PendingStore) NewIterator(prefix []byte) common.StoreIterator {
+	return NewJoinIter(self.pending.NewIterator(util.BytesPrefix(prefix)), self.store.NewIterator(prefix))
+}
+
+func (self *PendingStore) Put(key []byte, value []byte) error {
+	return errReadOnly
+}
+
+func (self *PendingStore) Delete(key []byte) error {
+	return errReadOnly
+}
+
+func (self *PendingStore) NewBatch() {
+	panic(errReadOnly)
+}
+
+func (self *PendingStore) BatchPut(key []byte, value []byte) {
+	panic(errReadOnly)
+}
+
+func (self *PendingStore) BatchDelete(key []byte) {
+	panic(errReadOnly)
+}
+
+func (self *PendingStore) BatchCommit() error {
+	return errReadOnly
+}
+
+func (self *PendingStore) Close() error {
+	return nil
+}
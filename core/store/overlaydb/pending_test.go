@@ -0,0 +1,67 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package overlaydb
+
+import (
+	"testing"
+
+	"OntologyWithPOC/core/store/leveldbstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPendingStore(t *testing.T) {
+	store, err := leveldbstore.NewMemLevelDBStore()
+	assert.Nil(t, err)
+	for i := 0; i < 4; i++ {
+		assert.Nil(t, store.Put(makeKey(i), []byte("store")))
+	}
+
+	first := NewOverlayDB(store)
+	first.Put(makeKey(1), []byte("first"))
+	first.Delete(makeKey(2))
+	first.Put(makeKey(4), []byte("first"))
+
+	pending := NewPendingStore(store, []*MemDB{first.GetWriteSet()})
+	second := NewOverlayDB(pending)
+	second.Put(makeKey(2), []byte("second"))
+	second.Delete(makeKey(3))
+
+	view := NewOverlayDB(NewPendingStore(store, []*MemDB{first.GetWriteSet(), second.GetWriteSet()}))
+	expected := []string{"store", "first", "second", "", "first"}
+	for i, exp := range expected {
+		val, err := view.Get(makeKey(i))
+		assert.Nil(t, err)
+		assert.Equal(t, exp, string(val))
+	}
+
+	iter := view.NewIterator([]byte("key"))
+	var keys [][]byte
+	for has := iter.First(); has; has = iter.Next() {
+		keys = append(keys, append([]byte{}, iter.Key()...))
+	}
+	iter.Release()
+	assert.Equal(t, [][]byte{makeKey(0), makeKey(1), makeKey(2), makeKey(4)}, keys)
+
+	// only the changes of its own block are in a write set
+	assert.Equal(t, 2, second.GetWriteSet().Len())
+	val, err := store.Get(makeKey(3))
+	assert.Nil(t, err)
+	assert.Equal(t, "store", string(val))
+	assert.NotNil(t, pending.Put(makeKey(5), []byte("put")))
+}
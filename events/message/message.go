@@ -29,6 +29,8 @@ const (
 	TOPIC_NODE_DISCONNECT           = "noddis"
 	TOPIC_NODE_CONSENSUS_DISCONNECT = "nodcnsdis"
 	TOPIC_SMART_CODE_EVENT          = "scevt"
+	TOPIC_POC_MINING_ROUND          = "pocround"
+	TOPIC_POC_BEST_DEADLINE         = "pocdeadline"
 )
 
 type SaveBlockCompleteMsg struct {
@@ -19,7 +19,12 @@
 package actor
 
 import (
+	"errors"
+	"time"
+
+	"OntologyWithPOC/common/log"
 	cactor "OntologyWithPOC/consensus/actor"
+	"OntologyWithPOC/consensus/poc/history"
 	"github.com/ontio/ontology-eventbus/actor"
 )
 
@@ -44,3 +49,58 @@ func ConsensusSrvHalt() error {
 	}
 	return nil
 }
+
+//send mining control msg to consensus actor
+func MiningControl(msg interface{}) (*cactor.MiningStatus, error) {
+	if consensusSrvPid == nil {
+		return nil, errors.New("consensus not started")
+	}
+	future := consensusSrvPid.RequestFuture(msg, REQ_TIMEOUT*time.Second)
+	result, err := future.Result()
+	if err != nil {
+		log.Errorf(ERR_ACTOR_COMM, err)
+		return nil, err
+	}
+	r, ok := result.(*cactor.MiningStatusRsp)
+	if !ok {
+		return nil, errors.New("fail")
+	}
+	return r.Status, r.Error
+}
+
+//get PoC mining info from consensus actor
+func GetMiningInfo() (*cactor.MiningInfo, error) {
+	if consensusSrvPid == nil {
+		return nil, errors.New("consensus not started")
+	}
+	future := consensusSrvPid.RequestFuture(&cactor.GetMiningInfo{}, REQ_TIMEOUT*time.Second)
+	result, err := future.Result()
+	if err != nil {
+		log.Errorf(ERR_ACTOR_COMM, err)
+		return nil, err
+	}
+	r, ok := result.(*cactor.MiningInfoRsp)
+	if !ok {
+		return nil, errors.New("fail")
+	}
+	return r.Info, r.Error
+}
+
+//get PoC deadline history from consensus actor
+func GetDeadlineHistory(start, end uint32, accountID string) ([]*history.Record, error) {
+	if consensusSrvPid == nil {
+		return nil, errors.New("consensus not started")
+	}
+	req := &cactor.GetDeadlineHistory{Start: start, End: end, AccountID: accountID}
+	future := consensusSrvPid.RequestFuture(req, REQ_TIMEOUT*time.Second)
+	result, err := future.Result()
+	if err != nil {
+		log.Errorf(ERR_ACTOR_COMM, err)
+		return nil, err
+	}
+	r, ok := result.(*cactor.DeadlineHistoryRsp)
+	if !ok {
+		return nil, errors.New("fail")
+	}
+	return r.Records, r.Error
+}
@@ -19,6 +19,7 @@
 package actor
 
 import (
+	cactor "OntologyWithPOC/consensus/actor"
 	"OntologyWithPOC/events"
 	"OntologyWithPOC/events/message"
 	"github.com/ontio/ontology-eventbus/actor"
@@ -27,6 +28,8 @@ import (
 type EventActor struct {
 	blockPersistCompleted func(v interface{})
 	smartCodeEvt          func(v interface{})
+	miningRound           func(v interface{})
+	bestDeadline          func(v interface{})
 }
 
 //receive from subscribed actor
@@ -36,17 +39,25 @@ func (t *EventActor) Receive(c actor.Context) {
 		t.blockPersistCompleted(*msg.Block)
 	case *message.SmartCodeEventMsg:
 		t.smartCodeEvt(*msg.Event)
+	case *cactor.MiningRoundInfo:
+		t.miningRound(msg)
+	case *cactor.DeadlineInfo:
+		t.bestDeadline(msg)
 	default:
 	}
 }
 
-//Subscribe save block complete and smartcontract Event
+//Subscribe save block complete, smartcontract and PoC mining Event
 func SubscribeEvent(topic string, handler func(v interface{})) {
 	var props = actor.FromProducer(func() actor.Actor {
 		if topic == message.TOPIC_SAVE_BLOCK_COMPLETE {
 			return &EventActor{blockPersistCompleted: handler}
 		} else if topic == message.TOPIC_SMART_CODE_EVENT {
 			return &EventActor{smartCodeEvt: handler}
+		} else if topic == message.TOPIC_POC_MINING_ROUND {
+			return &EventActor{miningRound: handler}
+		} else if topic == message.TOPIC_POC_BEST_DEADLINE {
+			return &EventActor{bestDeadline: handler}
 		} else {
 			return &EventActor{}
 		}
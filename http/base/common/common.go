@@ -21,9 +21,11 @@ package common
 
 import (
 	"OntologyWithPOC/common"
+	"OntologyWithPOC/common/config"
 	"OntologyWithPOC/common/constants"
 	"OntologyWithPOC/common/log"
 	"OntologyWithPOC/common/serialization"
+	"OntologyWithPOC/consensus/poc/config"
 	"OntologyWithPOC/core/ledger"
 	"OntologyWithPOC/core/payload"
 	"OntologyWithPOC/core/types"
@@ -414,6 +416,28 @@ func GetGasPrice() (map[string]interface{}, error) {
 	return result, nil
 }
 
+func GetBaseTarget() (map[string]interface{}, error) {
+	height := bactor.GetCurrentBlockHeight()
+	header, err := bactor.GetHeaderByHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	info, err := pocconfig.PocBlock(header)
+	if err != nil {
+		return nil, err
+	}
+	baseTarget := info.BaseTarget
+	if baseTarget == 0 {
+		baseTarget = pocconfig.InitialBaseTarget(config.DefConfig.Genesis.POC)
+	}
+	result := map[string]interface{}{
+		"basetarget":      baseTarget,
+		"height":          height,
+		"targetblocktime": pocconfig.TargetBlockTime(config.DefConfig.Genesis.POC),
+	}
+	return result, nil
+}
+
 func GetBlockTransactions(block *types.Block) interface{} {
 	trans := make([]string, len(block.Transactions))
 	for i := 0; i < len(block.Transactions); i++ {
@@ -476,6 +476,58 @@ func GetGasPrice(cmd map[string]interface{}) map[string]interface{} {
 	return resp
 }
 
+//get base target of the current block
+func GetBaseTarget(cmd map[string]interface{}) map[string]interface{} {
+	result, err := bcomn.GetBaseTarget()
+	if err != nil {
+		return ResponsePack(berr.INTERNAL_ERROR)
+	}
+	resp := ResponsePack(berr.SUCCESS)
+	resp["Result"] = result
+	return resp
+}
+
+//get PoC mining info of the node
+func GetMiningInfo(cmd map[string]interface{}) map[string]interface{} {
+	result, err := bactor.GetMiningInfo()
+	if err != nil {
+		return ResponsePack(berr.INTERNAL_ERROR)
+	}
+	resp := ResponsePack(berr.SUCCESS)
+	resp["Result"] = result
+	return resp
+}
+
+//get PoC deadline history of a height range
+func GetDeadlineHistory(cmd map[string]interface{}) map[string]interface{} {
+	startStr, ok := cmd["Start"].(string)
+	if !ok {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	endStr, ok := cmd["End"].(string)
+	if !ok {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	start, err := strconv.ParseUint(startStr, 10, 32)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	end, err := strconv.ParseUint(endStr, 10, 32)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	account, _ := cmd["Account"].(string)
+	result, err := bactor.GetDeadlineHistory(uint32(start), uint32(end), account)
+	if err != nil {
+		resp := ResponsePack(berr.INVALID_PARAMS)
+		resp["Result"] = err.Error()
+		return resp
+	}
+	resp := ResponsePack(berr.SUCCESS)
+	resp["Result"] = result
+	return resp
+}
+
 //get allowance
 func GetAllowance(cmd map[string]interface{}) map[string]interface{} {
 	resp := ResponsePack(berr.SUCCESS)
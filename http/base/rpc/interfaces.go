@@ -545,6 +545,50 @@ func GetGasPrice(params []interface{}) map[string]interface{} {
 	return responseSuccess(result)
 }
 
+//get base target of the current block
+func GetBaseTarget(params []interface{}) map[string]interface{} {
+	result, err := bcomn.GetBaseTarget()
+	if err != nil {
+		return responsePack(berr.INTERNAL_ERROR, "")
+	}
+	return responseSuccess(result)
+}
+
+//get PoC mining info of the node
+func GetMiningInfo(params []interface{}) map[string]interface{} {
+	result, err := bactor.GetMiningInfo()
+	if err != nil {
+		return responsePack(berr.INTERNAL_ERROR, "")
+	}
+	return responseSuccess(result)
+}
+
+//get PoC deadline history of the heights start to end, of an account if given
+func GetDeadlineHistory(params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	start, ok := params[0].(float64)
+	if !ok || start < 0 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	end, ok := params[1].(float64)
+	if !ok || end < 0 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	account := ""
+	if len(params) >= 3 {
+		if account, ok = params[2].(string); !ok {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+	}
+	result, err := bactor.GetDeadlineHistory(uint32(start), uint32(end), account)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, err.Error())
+	}
+	return responseSuccess(result)
+}
+
 // get unbound ong of address
 func GetUnboundOng(params []interface{}) map[string]interface{} {
 	if len(params) < 1 {
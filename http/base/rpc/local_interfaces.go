@@ -23,6 +23,7 @@ import (
 	"path/filepath"
 
 	"OntologyWithPOC/common/log"
+	cactor "OntologyWithPOC/consensus/actor"
 	bactor "OntologyWithPOC/http/base/actor"
 	"OntologyWithPOC/http/base/common"
 	berr "OntologyWithPOC/http/base/error"
@@ -122,3 +123,56 @@ func SetDebugInfo(params []interface{}) map[string]interface{} {
 	}
 	return responsePack(berr.SUCCESS, true)
 }
+
+func miningControl(msg interface{}) map[string]interface{} {
+	status, err := bactor.MiningControl(msg)
+	if err != nil {
+		log.Errorf("mining control %T: %s", msg, err)
+		return responsePack(berr.INTERNAL_ERROR, "")
+	}
+	return responseSuccess(status)
+}
+
+func StartMining(params []interface{}) map[string]interface{} {
+	return miningControl(&cactor.StartMining{})
+}
+
+func StopMining(params []interface{}) map[string]interface{} {
+	return miningControl(&cactor.StopMining{})
+}
+
+func PauseMining(params []interface{}) map[string]interface{} {
+	return miningControl(&cactor.PauseMining{})
+}
+
+func GetMiningStatus(params []interface{}) map[string]interface{} {
+	return miningControl(&cactor.GetMiningStatus{})
+}
+
+// params: plot dir, capacity in MB
+func SetMiningCapacity(params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	dir, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	capacity, ok := params[1].(float64)
+	if !ok || capacity < 0 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	return miningControl(&cactor.SetMiningCapacity{PlotDir: dir, Capacity: uint64(capacity)})
+}
+
+// params: base58 reward address
+func SetRewardAddress(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	address, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	return miningControl(&cactor.SetRewardAddress{Address: address})
+}
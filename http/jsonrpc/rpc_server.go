@@ -57,6 +57,9 @@ func StartRPCServer() error {
 	rpc.HandleFunc("getmerkleproof", rpc.GetMerkleProof)
 	rpc.HandleFunc("getblocktxsbyheight", rpc.GetBlockTxsByHeight)
 	rpc.HandleFunc("getgasprice", rpc.GetGasPrice)
+	rpc.HandleFunc("getbasetarget", rpc.GetBaseTarget)
+	rpc.HandleFunc("getmininginfo", rpc.GetMiningInfo)
+	rpc.HandleFunc("getdeadlinehistory", rpc.GetDeadlineHistory)
 	rpc.HandleFunc("getunboundong", rpc.GetUnboundOng)
 	rpc.HandleFunc("getgrantong", rpc.GetGrantOng)
 
@@ -44,6 +44,13 @@ func StartLocalServer() error {
 	rpc.HandleFunc("stopconsensus", rpc.StopConsensus)
 	rpc.HandleFunc("setdebuginfo", rpc.SetDebugInfo)
 
+	rpc.HandleFunc("startmining", rpc.StartMining)
+	rpc.HandleFunc("stopmining", rpc.StopMining)
+	rpc.HandleFunc("pausemining", rpc.PauseMining)
+	rpc.HandleFunc("getminingstatus", rpc.GetMiningStatus)
+	rpc.HandleFunc("setminingcapacity", rpc.SetMiningCapacity)
+	rpc.HandleFunc("setrewardaddress", rpc.SetRewardAddress)
+
 	// TODO: only listen to local host
 	err := http.ListenAndServe(LOCAL_HOST+":"+strconv.Itoa(int(cfg.DefConfig.Rpc.HttpLocalPort)), nil)
 	if err != nil {
@@ -75,6 +75,9 @@ const (
 	GET_MEMPOOL_TXSTATE   = "/api/v1/mempool/txstate/:hash"
 	GET_VERSION           = "/api/v1/version"
 	GET_NETWORKID         = "/api/v1/networkid"
+	GET_BASE_TARGET       = "/api/v1/basetarget"
+	GET_MINING_INFO       = "/api/v1/mining/info"
+	GET_DEADLINE_HISTORY  = "/api/v1/mining/history/:start/:end"
 
 	POST_RAW_TX = "/api/v1/transaction"
 )
@@ -155,6 +158,9 @@ func (this *restServer) registryMethod() {
 		GET_MEMPOOL_TXSTATE:   {name: "getmempooltxstate", handler: rest.GetMemPoolTxState},
 		GET_VERSION:           {name: "getversion", handler: rest.GetNodeVersion},
 		GET_NETWORKID:         {name: "getnetworkid", handler: rest.GetNetworkId},
+		GET_BASE_TARGET:       {name: "getbasetarget", handler: rest.GetBaseTarget},
+		GET_MINING_INFO:       {name: "getmininginfo", handler: rest.GetMiningInfo},
+		GET_DEADLINE_HISTORY:  {name: "getdeadlinehistory", handler: rest.GetDeadlineHistory},
 	}
 
 	postMethodMap := map[string]Action{
@@ -197,6 +203,8 @@ func (this *restServer) getPath(url string) string {
 		return GET_GRANTONG
 	} else if strings.Contains(url, strings.TrimRight(GET_MEMPOOL_TXSTATE, ":hash")) {
 		return GET_MEMPOOL_TXSTATE
+	} else if strings.Contains(url, strings.TrimRight(GET_DEADLINE_HISTORY, ":start/:end")) {
+		return GET_DEADLINE_HISTORY
 	}
 	return url
 }
@@ -241,6 +249,9 @@ func (this *restServer) getParams(r *http.Request, url string, req map[string]in
 		req["Addr"] = getParam(r, "addr")
 	case GET_MEMPOOL_TXSTATE:
 		req["Hash"] = getParam(r, "hash")
+	case GET_DEADLINE_HISTORY:
+		req["Start"], req["End"] = getParam(r, "start"), getParam(r, "end")
+		req["Account"] = r.FormValue("account")
 	default:
 	}
 	return req
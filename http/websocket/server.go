@@ -38,6 +38,8 @@ var ws *websocket.WsServer
 func StartServer() {
 	bactor.SubscribeEvent(message.TOPIC_SAVE_BLOCK_COMPLETE, sendBlock2WSclient)
 	bactor.SubscribeEvent(message.TOPIC_SMART_CODE_EVENT, pushSmartCodeEvent)
+	bactor.SubscribeEvent(message.TOPIC_POC_MINING_ROUND, pushMiningRound)
+	bactor.SubscribeEvent(message.TOPIC_POC_BEST_DEADLINE, pushBestDeadline)
 	go func() {
 		ws = websocket.InitWsServer()
 		ws.Start()
@@ -126,3 +128,23 @@ func pushBlockTransactions(v interface{}) {
 		ws.BroadcastToSubscribers(nil, websocket.WSTOPIC_TXHASHS, resp)
 	}
 }
+
+func pushMiningRound(v interface{}) {
+	if ws == nil {
+		return
+	}
+	resp := rest.ResponsePack(Err.SUCCESS)
+	resp["Action"] = "sendmininground"
+	resp["Result"] = v
+	ws.BroadcastToSubscribers(nil, websocket.WSTOPIC_MINING, resp)
+}
+
+func pushBestDeadline(v interface{}) {
+	if ws == nil {
+		return
+	}
+	resp := rest.ResponsePack(Err.SUCCESS)
+	resp["Action"] = "sendbestdeadline"
+	resp["Result"] = v
+	ws.BroadcastToSubscribers(nil, websocket.WSTOPIC_DEADLINE, resp)
+}
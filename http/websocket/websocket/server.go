@@ -43,6 +43,8 @@ const (
 	WSTOPIC_JSON_BLOCK = 2
 	WSTOPIC_RAW_BLOCK  = 3
 	WSTOPIC_TXHASHS    = 4
+	WSTOPIC_MINING     = 5
+	WSTOPIC_DEADLINE   = 6
 )
 
 type handler func(map[string]interface{}) map[string]interface{}
@@ -58,6 +60,8 @@ type subscribe struct {
 	SubscribeJsonBlock    bool     `json:"SubscribeJsonBlock"`
 	SubscribeRawBlock     bool     `json:"SubscribeRawBlock"`
 	SubscribeBlockTxHashs bool     `json:"SubscribeBlockTxHashs"`
+	SubscribeMiningInfo   bool     `json:"SubscribeMiningInfo"`
+	SubscribeBestDeadline bool     `json:"SubscribeBestDeadline"`
 }
 type WsServer struct {
 	sync.RWMutex
@@ -157,6 +161,12 @@ func (self *WsServer) registryMethod() {
 		if b, ok := cmd["SubscribeBlockTxHashs"].(bool); ok {
 			sub.SubscribeBlockTxHashs = b
 		}
+		if b, ok := cmd["SubscribeMiningInfo"].(bool); ok {
+			sub.SubscribeMiningInfo = b
+		}
+		if b, ok := cmd["SubscribeBestDeadline"].(bool); ok {
+			sub.SubscribeBestDeadline = b
+		}
 		if ctsf, ok := cmd["ContractsFilter"].([]interface{}); ok {
 			sub.ContractsFilter = []string{}
 			for _, v := range ctsf {
@@ -197,6 +207,8 @@ func (self *WsServer) registryMethod() {
 		"getmerkleproof":            {handler: rest.GetMerkleProof},
 		"getblocktxsbyheight":       {handler: rest.GetBlockTxsByHeight},
 		"getgasprice":               {handler: rest.GetGasPrice},
+		"getbasetarget":             {handler: rest.GetBaseTarget},
+		"getmininginfo":             {handler: rest.GetMiningInfo},
 		"getunboundong":             {handler: rest.GetUnboundOng},
 		"getgrantong":               {handler: rest.GetGrantOng},
 		"getmempooltxcount":         {handler: rest.GetMemPoolTxCount},
@@ -429,6 +441,10 @@ func (self *WsServer) BroadcastToSubscribers(contractAddrs map[string]bool, sub
 			s.Send(data)
 		} else if sub == WSTOPIC_TXHASHS && v.SubscribeBlockTxHashs {
 			s.Send(data)
+		} else if sub == WSTOPIC_MINING && v.SubscribeMiningInfo {
+			s.Send(data)
+		} else if sub == WSTOPIC_DEADLINE && v.SubscribeBestDeadline {
+			s.Send(data)
 		} else if sub == WSTOPIC_EVENT && v.SubscribeEvent {
 			if len(v.ContractsFilter) == 0 {
 				s.Send(data)
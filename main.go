@@ -81,6 +81,7 @@ func setupAPP() *cli.App {
 		cmd.MultiSigTxCommand,
 		cmd.SendTxCommand,
 		cmd.ShowTxCommand,
+		cmd.PocCommand,
 	}
 	app.Flags = []cli.Flag{
 		//common setting
@@ -96,6 +97,14 @@ func setupAPP() *cli.App {
 		//consensus setting
 		utils.EnableConsensusFlag,
 		utils.MaxTxInBlockFlag,
+		utils.EnablePoolServerFlag,
+		utils.PoolServerPortFlag,
+		utils.PlotCheckIntervalFlag,
+		utils.PlotRepairFlag,
+		utils.ScanWorkersFlag,
+		utils.ScanRateLimitFlag,
+		utils.PlotDirsFlag,
+		utils.ZKProvingKeyFlag,
 		//txpool setting
 		utils.GasPriceFlag,
 		utils.GasLimitFlag,
@@ -111,9 +120,12 @@ func setupAPP() *cli.App {
 		utils.MaxConnInBoundFlag,
 		utils.MaxConnOutBoundFlag,
 		utils.MaxConnInBoundForSingleIPFlag,
+		utils.EnableDiscoveryFlag,
+		utils.DiscoveryPortFlag,
 		//test mode setting
 		utils.EnableTestModeFlag,
 		utils.TestModeGenBlockTimeFlag,
+		utils.EnablePocTestModeFlag,
 		//rpc setting
 		utils.RPCDisabledFlag,
 		utils.RPCPortFlag,
@@ -184,7 +196,7 @@ func startOntology(ctx *cli.Context) {
 		log.Errorf("initTxPool error: %s", err)
 		return
 	}
-	p2pSvr, p2pPid, err := initP2PNode(ctx, txpool)
+	p2pSvr, p2pPid, err := initP2PNode(ctx, txpool, acc)
 	if err != nil {
 		log.Errorf("initP2PNode error: %s", err)
 		return
@@ -257,6 +269,14 @@ func initAccount(ctx *cli.Context) (*account.Account, error) {
 		curPk := hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey))
 		config.DefConfig.Genesis.SOLO.Bookkeepers = []string{curPk}
 	}
+	if ctx.Bool(utils.GetFlagName(utils.EnablePocTestModeFlag)) {
+		curPk := hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey))
+		config.DefConfig.Genesis.POC.Peers = []*config.POCPeerStakeInfo{{
+			Index:      1,
+			PeerPubkey: curPk,
+			Address:    acc.Address.ToBase58(),
+		}}
+	}
 
 	log.Infof("Account init success")
 	return acc, nil
@@ -311,11 +331,12 @@ func initTxPool(ctx *cli.Context) (*proc.TXPoolServer, error) {
 	return txPoolServer, nil
 }
 
-func initP2PNode(ctx *cli.Context, txpoolSvr *proc.TXPoolServer) (*p2pserver.P2PServer, *actor.PID, error) {
+func initP2PNode(ctx *cli.Context, txpoolSvr *proc.TXPoolServer, acc *account.Account) (*p2pserver.P2PServer, *actor.PID, error) {
 	if config.DefConfig.Genesis.ConsensusType == config.CONSENSUS_TYPE_SOLO {
 		return nil, nil, nil
 	}
 	p2p := p2pserver.NewServer()
+	p2p.SetAccount(acc)
 
 	p2pActor := p2pactor.NewP2PActor(p2p)
 	p2pPID, err := p2pActor.Start()
@@ -103,6 +103,20 @@ const (
 	RECENT_LIMIT     = 10 //recent contact list limit
 )
 
+//discovery const
+const (
+	DISCOVERY_GROUP      = "239.255.20.41" //multicast group of node beacons
+	DISCOVERY_INTERVAL   = 30              //beacon and address exchange interval in sec
+	DISCOVERY_BEACON_TTL = 90              //max age of an accepted beacon in sec
+	DISCOVERY_FANOUT     = 3               //peers asked for addresses each interval
+	MAX_BEACON_LEN       = 1024            //the maximum beacon length
+	MAX_BEACON_KEYS      = 1024            //max beacon keys remembered against replays
+	LEARNED_FILE_NAME    = "peers.learned"
+	LEARNED_LIMIT        = 256       //learned peer list limit
+	LEARNED_EXPIRE       = 7 * 86400 //learned peer expiry in sec
+	LEARNED_DIAL_CNT     = 16        //learned peers dialed at once
+)
+
 //PeerAddr represent peer`s net information
 type PeerAddr struct {
 	Time     int64    //latest timestamp
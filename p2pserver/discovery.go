@@ -0,0 +1,498 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package p2pserver
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"OntologyWithPOC/account"
+	comm "OntologyWithPOC/common"
+	"OntologyWithPOC/common/config"
+	"OntologyWithPOC/common/log"
+	"OntologyWithPOC/core/signature"
+	"OntologyWithPOC/p2pserver/common"
+	"github.com/ontio/ontology-crypto/keypair"
+)
+
+// nodeBeacon announces a node accepting connections on Port of the address
+// the beacon is sent from
+type nodeBeacon struct {
+	Magic  uint32
+	ID     uint64
+	Port   uint16
+	Time   int64
+	PubKey []byte
+	Sig    []byte
+}
+
+func newNodeBeacon(acc *account.Account, magic uint32, id uint64, port uint16, now int64) (*nodeBeacon, error) {
+	b := &nodeBeacon{
+		Magic:  magic,
+		ID:     id,
+		Port:   port,
+		Time:   now,
+		PubKey: keypair.SerializePublicKey(acc.PublicKey),
+	}
+	sig, err := signature.Sign(acc, b.unsigned())
+	if err != nil {
+		return nil, fmt.Errorf("sign beacon: %s", err)
+	}
+	b.Sig = sig
+	return b, nil
+}
+
+func (this *nodeBeacon) serializeUnsigned(sink *comm.ZeroCopySink) {
+	sink.WriteUint32(this.Magic)
+	sink.WriteUint64(this.ID)
+	sink.WriteUint16(this.Port)
+	sink.WriteInt64(this.Time)
+	sink.WriteVarBytes(this.PubKey)
+}
+
+func (this *nodeBeacon) unsigned() []byte {
+	sink := comm.NewZeroCopySink(nil)
+	this.serializeUnsigned(sink)
+	return sink.Bytes()
+}
+
+// Serialization serializes the signed beacon
+func (this *nodeBeacon) Serialization(sink *comm.ZeroCopySink) {
+	this.serializeUnsigned(sink)
+	sink.WriteVarBytes(this.Sig)
+}
+
+// Deserialization deserializes a signed beacon
+func (this *nodeBeacon) Deserialization(source *comm.ZeroCopySource) error {
+	var eof, irregular bool
+	this.Magic, eof = source.NextUint32()
+	this.ID, eof = source.NextUint64()
+	this.Port, eof = source.NextUint16()
+	this.Time, eof = source.NextInt64()
+	this.PubKey, _, irregular, eof = source.NextVarBytes()
+	if irregular {
+		return comm.ErrIrregularData
+	}
+	this.Sig, _, irregular, eof = source.NextVarBytes()
+	if irregular {
+		return comm.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// verify checks the beacon is of network magic, signed by the key it carries,
+// and not older than DISCOVERY_BEACON_TTL at now
+func (this *nodeBeacon) verify(magic uint32, now int64) (keypair.PublicKey, error) {
+	if this.Magic != magic {
+		return nil, fmt.Errorf("beacon of network magic %d", this.Magic)
+	}
+	if this.Port == 0 {
+		return nil, errors.New("beacon without port")
+	}
+	if this.Time < now-common.DISCOVERY_BEACON_TTL || this.Time > now+common.DISCOVERY_BEACON_TTL {
+		return nil, fmt.Errorf("stale beacon of time %d", this.Time)
+	}
+	pub, err := keypair.DeserializePublicKey(this.PubKey)
+	if err != nil {
+		return nil, fmt.Errorf("beacon public key: %s", err)
+	}
+	if err := signature.Verify(pub, this.unsigned(), this.Sig); err != nil {
+		return nil, fmt.Errorf("beacon signature: %s", err)
+	}
+	return pub, nil
+}
+
+// learnedPeer is a peer address found by discovery
+type learnedPeer struct {
+	Addr     string
+	PubKey   string `json:",omitempty"`
+	LastSeen int64
+}
+
+// learnedPeers keeps the addresses found by discovery of each network,
+// persisted across restarts
+type learnedPeers struct {
+	sync.Mutex
+	path    string
+	peers   map[uint32][]*learnedPeer
+	changed bool
+}
+
+func newLearnedPeers(path string) *learnedPeers {
+	return &learnedPeers{
+		path:  path,
+		peers: make(map[uint32][]*learnedPeer),
+	}
+}
+
+// load reads the learned peers persisted at path, if any
+func (this *learnedPeers) load() error {
+	this.Lock()
+	defer this.Unlock()
+	if !comm.FileExisted(this.path) {
+		return nil
+	}
+	buf, err := ioutil.ReadFile(this.path)
+	if err != nil {
+		return err
+	}
+	peers := make(map[uint32][]*learnedPeer)
+	if err := json.Unmarshal(buf, &peers); err != nil {
+		return fmt.Errorf("parse %s: %s", this.path, err)
+	}
+	this.peers = peers
+	return nil
+}
+
+// save persists the learned peers if they changed since the last save
+func (this *learnedPeers) save() error {
+	this.Lock()
+	defer this.Unlock()
+	if !this.changed {
+		return nil
+	}
+	buf, err := json.Marshal(this.peers)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(this.path, buf, os.ModePerm); err != nil {
+		return err
+	}
+	this.changed = false
+	return nil
+}
+
+// add records addr seen at now, dropping the least recently seen peers over
+// LEARNED_LIMIT
+func (this *learnedPeers) add(netID uint32, addr, pubKey string, now int64) {
+	this.Lock()
+	defer this.Unlock()
+	this.changed = true
+	peers := this.peers[netID]
+	for _, p := range peers {
+		if p.Addr == addr {
+			p.LastSeen = now
+			if pubKey != "" {
+				p.PubKey = pubKey
+			}
+			return
+		}
+	}
+	peers = append(peers, &learnedPeer{Addr: addr, PubKey: pubKey, LastSeen: now})
+	if len(peers) > common.LEARNED_LIMIT {
+		sort.Slice(peers, func(i, j int) bool { return peers[i].LastSeen > peers[j].LastSeen })
+		peers = peers[:common.LEARNED_LIMIT]
+	}
+	this.peers[netID] = peers
+}
+
+// list returns the addresses of netID seen within LEARNED_EXPIRE of now, most
+// recently seen first, and forgets the expired ones
+func (this *learnedPeers) list(netID uint32, now int64) []string {
+	this.Lock()
+	defer this.Unlock()
+	peers := make([]*learnedPeer, 0, len(this.peers[netID]))
+	for _, p := range this.peers[netID] {
+		if p.LastSeen >= now-common.LEARNED_EXPIRE {
+			peers = append(peers, p)
+		}
+	}
+	if len(peers) != len(this.peers[netID]) {
+		this.peers[netID] = peers
+		this.changed = true
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].LastSeen > peers[j].LastSeen })
+	addrs := make([]string, 0, len(peers))
+	for _, p := range peers {
+		addrs = append(addrs, p.Addr)
+	}
+	return addrs
+}
+
+// discovery finds peers without a seed list: it announces and listens for
+// signed node beacons by UDP multicast and broadcast on the local networks,
+// walks the network by asking random neighbors for their addresses, and
+// persists the peers it learns to dial them on the next start
+type discovery struct {
+	server     *P2PServer
+	acc        *account.Account
+	learned    *learnedPeers
+	lastBeacon map[string]int64
+	conn       *net.UDPConn
+	quit       chan bool
+}
+
+func newDiscovery(server *P2PServer) *discovery {
+	return &discovery{
+		server:     server,
+		learned:    newLearnedPeers(common.LEARNED_FILE_NAME),
+		lastBeacon: make(map[string]int64),
+		quit:       make(chan bool),
+	}
+}
+
+// start dials the persisted peers and starts the beacon and address exchange
+// services. Beacons are signed by acc, or by a key of this run without one.
+func (this *discovery) start(acc *account.Account) {
+	if err := this.learned.load(); err != nil {
+		log.Warnf("[p2p]load learned peers: %s", err)
+	}
+	this.dialLearned()
+
+	cfg := config.DefConfig.P2PNode
+	if cfg.EnableDiscovery {
+		this.acc = acc
+		if this.acc == nil {
+			this.acc = account.NewAccount("")
+		}
+		group := &net.UDPAddr{IP: net.ParseIP(common.DISCOVERY_GROUP), Port: int(cfg.DiscoveryPort)}
+		conn, err := net.ListenMulticastUDP("udp4", nil, group)
+		if err != nil {
+			log.Warnf("[p2p]join discovery group: %s, listen for broadcast beacons only", err)
+			conn, err = net.ListenUDP("udp4", &net.UDPAddr{Port: int(cfg.DiscoveryPort)})
+		}
+		if err != nil {
+			log.Warnf("[p2p]listen for beacons on port %d: %s", cfg.DiscoveryPort, err)
+		} else {
+			this.conn = conn
+			go this.receiveBeacons()
+		}
+	}
+	go this.discoveryService()
+}
+
+func (this *discovery) stop() {
+	close(this.quit)
+	if this.conn != nil {
+		this.conn.Close()
+	}
+	if err := this.learned.save(); err != nil {
+		log.Warnf("[p2p]write learned peers: %s", err)
+	}
+}
+
+// discoveryService announces the node and exchanges addresses periodically
+func (this *discovery) discoveryService() {
+	t := time.NewTicker(time.Second * common.DISCOVERY_INTERVAL)
+	defer t.Stop()
+	this.announce()
+	for {
+		select {
+		case <-t.C:
+			this.announce()
+			this.exchangeAddrs()
+			if !this.server.reachMinConnection() {
+				this.dialLearned()
+			}
+			if err := this.learned.save(); err != nil {
+				log.Warnf("[p2p]write learned peers: %s", err)
+			}
+		case <-this.quit:
+			return
+		}
+	}
+}
+
+// announce sends a beacon to the discovery group and the broadcast address of
+// each local network
+func (this *discovery) announce() {
+	if this.conn == nil {
+		return
+	}
+	network := this.server.network
+	b, err := newNodeBeacon(this.acc, config.DefConfig.P2PNode.NetworkMagic, network.GetID(),
+		network.GetPort(), time.Now().Unix())
+	if err != nil {
+		log.Warnf("[p2p]%s", err)
+		return
+	}
+	sink := comm.NewZeroCopySink(nil)
+	b.Serialization(sink)
+	buf := sink.Bytes()
+	port := int(config.DefConfig.P2PNode.DiscoveryPort)
+	for _, ip := range beaconTargets() {
+		if _, err := this.conn.WriteToUDP(buf, &net.UDPAddr{IP: ip, Port: port}); err != nil {
+			log.Debugf("[p2p]send beacon to %s: %s", ip, err)
+		}
+	}
+}
+
+// beaconTargets returns the discovery group, the limited broadcast address and
+// the broadcast address of each local IPv4 network
+func beaconTargets() []net.IP {
+	targets := []net.IP{net.ParseIP(common.DISCOVERY_GROUP), net.IPv4bcast}
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return targets
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagBroadcast == 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipnet, ok := addr.(*net.IPNet); ok {
+				if bcast := broadcastAddr(ipnet); bcast != nil {
+					targets = append(targets, bcast)
+				}
+			}
+		}
+	}
+	return targets
+}
+
+// broadcastAddr returns the broadcast address of an IPv4 network, or nil
+func broadcastAddr(ipnet *net.IPNet) net.IP {
+	ip := ipnet.IP.To4()
+	if ip == nil || len(ipnet.Mask) != net.IPv4len {
+		return nil
+	}
+	bcast := make(net.IP, net.IPv4len)
+	for i := range ip {
+		bcast[i] = ip[i] | ^ipnet.Mask[i]
+	}
+	return bcast
+}
+
+// receiveBeacons handles the beacons received until the listener is closed
+func (this *discovery) receiveBeacons() {
+	buf := make([]byte, common.MAX_BEACON_LEN)
+	for {
+		n, from, err := this.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-this.quit:
+				return
+			default:
+			}
+			log.Debugf("[p2p]receive beacon: %s", err)
+			continue
+		}
+		this.handleBeacon(buf[:n], from, time.Now().Unix())
+	}
+}
+
+// handleBeacon learns and dials the node a valid beacon announces
+func (this *discovery) handleBeacon(data []byte, from *net.UDPAddr, now int64) {
+	b := &nodeBeacon{}
+	if err := b.Deserialization(comm.NewZeroCopySource(data)); err != nil {
+		log.Debugf("[p2p]invalid beacon from %s: %s", from, err)
+		return
+	}
+	if b.ID == this.server.network.GetID() {
+		return
+	}
+	if _, err := b.verify(config.DefConfig.P2PNode.NetworkMagic, now); err != nil {
+		log.Debugf("[p2p]invalid beacon from %s: %s", from, err)
+		return
+	}
+	// a beacon is only accepted once, so a replay cannot redirect its node
+	pubKey := hex.EncodeToString(b.PubKey)
+	last, present := this.lastBeacon[pubKey]
+	if present && b.Time <= last {
+		return
+	}
+	if !present && len(this.lastBeacon) >= common.MAX_BEACON_KEYS {
+		this.pruneBeacons(now)
+		if len(this.lastBeacon) >= common.MAX_BEACON_KEYS {
+			log.Debugf("[p2p]too many beacon keys, drop beacon from %s", from)
+			return
+		}
+	}
+	this.lastBeacon[pubKey] = b.Time
+
+	addr := net.JoinHostPort(from.IP.String(), strconv.Itoa(int(b.Port)))
+	this.learned.add(config.DefConfig.P2PNode.NetworkMagic, addr, pubKey, now)
+	this.connect(addr)
+}
+
+// pruneBeacons forgets the beacons older than DISCOVERY_BEACON_TTL, their
+// replays fail verify as stale
+func (this *discovery) pruneBeacons(now int64) {
+	for pubKey, t := range this.lastBeacon {
+		if t < now-common.DISCOVERY_BEACON_TTL {
+			delete(this.lastBeacon, pubKey)
+		}
+	}
+}
+
+// exchangeAddrs asks up to DISCOVERY_FANOUT random neighbors for their
+// neighbors, which AddrHandle dials, and learns the established neighbors
+func (this *discovery) exchangeAddrs() {
+	netID := config.DefConfig.P2PNode.NetworkMagic
+	now := time.Now().Unix()
+	peers := this.server.network.GetNeighbors()
+	rand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+	asked := 0
+	for _, p := range peers {
+		if p.GetState() != common.ESTABLISH {
+			continue
+		}
+		addr, _ := p.GetAddr16()
+		var ip net.IP = addr[:]
+		this.learned.add(netID, net.JoinHostPort(ip.String(), strconv.Itoa(int(p.GetPort()))), "", now)
+		if asked < common.DISCOVERY_FANOUT {
+			this.server.reqNbrList(p)
+			asked++
+		}
+	}
+}
+
+// dialLearned connects to up to LEARNED_DIAL_CNT of the most recently seen
+// learned peers
+func (this *discovery) dialLearned() {
+	addrs := this.learned.list(config.DefConfig.P2PNode.NetworkMagic, time.Now().Unix())
+	if len(addrs) > common.LEARNED_DIAL_CNT {
+		addrs = addrs[:common.LEARNED_DIAL_CNT]
+	}
+	if len(addrs) > 0 {
+		log.Infof("[p2p]try to connect %d learned peers", len(addrs))
+	}
+	for _, addr := range addrs {
+		this.connect(addr)
+	}
+}
+
+func (this *discovery) connect(addr string) {
+	network := this.server.network
+	if network.IsOwnAddress(addr) || network.GetPeerFromAddr(addr) != nil || network.IsAddrFromConnecting(addr) {
+		return
+	}
+	if uint(network.GetOutConnRecordLen()) >= config.DefConfig.P2PNode.MaxConnOutBound {
+		return
+	}
+	go network.Connect(addr)
+}
@@ -0,0 +1,198 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package p2pserver
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"OntologyWithPOC/account"
+	comm "OntologyWithPOC/common"
+	"OntologyWithPOC/common/config"
+	"OntologyWithPOC/p2pserver/common"
+)
+
+func TestNodeBeacon(t *testing.T) {
+	acc := account.NewAccount("")
+	magic := config.DefConfig.P2PNode.NetworkMagic
+	b, err := newNodeBeacon(acc, magic, 7, 20338, 1000)
+	if err != nil {
+		t.Fatalf("new beacon: %s", err)
+	}
+	sink := comm.NewZeroCopySink(nil)
+	b.Serialization(sink)
+	if len(sink.Bytes()) > common.MAX_BEACON_LEN {
+		t.Fatalf("beacon of %d bytes", len(sink.Bytes()))
+	}
+	got := &nodeBeacon{}
+	if err := got.Deserialization(comm.NewZeroCopySource(sink.Bytes())); err != nil {
+		t.Fatalf("deserialize beacon: %s", err)
+	}
+	if got.ID != 7 || got.Port != 20338 || got.Time != 1000 {
+		t.Errorf("beacon mismatch: %v", got)
+	}
+	if _, err := got.verify(magic, 1010); err != nil {
+		t.Errorf("verify beacon: %s", err)
+	}
+	if _, err := got.verify(magic+1, 1010); err == nil {
+		t.Errorf("beacon of other network should fail")
+	}
+	if _, err := got.verify(magic, 1000+common.DISCOVERY_BEACON_TTL+1); err == nil {
+		t.Errorf("stale beacon should fail")
+	}
+	got.Port = 20339
+	if _, err := got.verify(magic, 1010); err == nil {
+		t.Errorf("altered beacon should fail")
+	}
+	if err := got.Deserialization(comm.NewZeroCopySource(sink.Bytes()[:10])); err == nil {
+		t.Errorf("truncated beacon should fail")
+	}
+}
+
+func TestLearnedPeers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "p2p-learned")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, common.LEARNED_FILE_NAME)
+
+	l := newLearnedPeers(path)
+	if err := l.load(); err != nil {
+		t.Fatalf("load missing learned peers: %s", err)
+	}
+	l.add(1, "10.0.0.1:20338", "", 100)
+	l.add(1, "10.0.0.2:20338", "", 200)
+	l.add(1, "10.0.0.1:20338", "02ab", 300)
+	l.add(2, "10.0.0.3:20338", "", 100)
+	addrs := l.list(1, 300)
+	if len(addrs) != 2 || addrs[0] != "10.0.0.1:20338" || addrs[1] != "10.0.0.2:20338" {
+		t.Errorf("learned peers: %v", addrs)
+	}
+	if err := l.save(); err != nil {
+		t.Fatalf("save learned peers: %s", err)
+	}
+
+	loaded := newLearnedPeers(path)
+	if err := loaded.load(); err != nil {
+		t.Fatalf("load learned peers: %s", err)
+	}
+	if addrs := loaded.list(1, 300); len(addrs) != 2 {
+		t.Errorf("loaded learned peers: %v", addrs)
+	}
+	if addrs := loaded.list(2, 300); len(addrs) != 1 {
+		t.Errorf("loaded learned peers of network 2: %v", addrs)
+	}
+	if addrs := loaded.list(1, 200+common.LEARNED_EXPIRE+1); len(addrs) != 1 || addrs[0] != "10.0.0.1:20338" {
+		t.Errorf("expired learned peers listed: %v", addrs)
+	}
+
+	for i := 0; i <= common.LEARNED_LIMIT; i++ {
+		loaded.add(3, fmt.Sprintf("10.1.%d.%d:20338", i/256, i%256), "", int64(i))
+	}
+	if addrs := loaded.list(3, common.LEARNED_LIMIT); len(addrs) != common.LEARNED_LIMIT {
+		t.Errorf("learned peers over limit: %d", len(addrs))
+	}
+}
+
+func TestBroadcastAddr(t *testing.T) {
+	_, ipnet, _ := net.ParseCIDR("192.168.1.0/24")
+	ipnet.IP = net.ParseIP("192.168.1.17")
+	if bcast := broadcastAddr(ipnet); !bcast.Equal(net.ParseIP("192.168.1.255")) {
+		t.Errorf("broadcast addr: %s", bcast)
+	}
+	_, ipnet, _ = net.ParseCIDR("fe80::/64")
+	if bcast := broadcastAddr(ipnet); bcast != nil {
+		t.Errorf("broadcast addr of ipv6 network: %s", bcast)
+	}
+}
+
+func TestHandleBeacon(t *testing.T) {
+	dir, err := ioutil.TempDir("", "p2p-learned")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p2p := NewServer()
+	d := p2p.discovery
+	d.learned = newLearnedPeers(filepath.Join(dir, common.LEARNED_FILE_NAME))
+	magic := config.DefConfig.P2PNode.NetworkMagic
+	from := &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 20341}
+
+	acc := account.NewAccount("")
+	b, err := newNodeBeacon(acc, magic, p2p.GetID()+1, 1, 1000)
+	if err != nil {
+		t.Fatalf("new beacon: %s", err)
+	}
+	sink := comm.NewZeroCopySink(nil)
+	b.Serialization(sink)
+	d.handleBeacon(sink.Bytes(), from, 1000)
+	if addrs := d.learned.list(magic, 1000); len(addrs) != 1 || addrs[0] != "127.0.0.1:1" {
+		t.Errorf("learned peers from beacon: %v", addrs)
+	}
+
+	// a replayed beacon is ignored
+	d.learned = newLearnedPeers(filepath.Join(dir, common.LEARNED_FILE_NAME))
+	d.handleBeacon(sink.Bytes(), &net.UDPAddr{IP: net.ParseIP("127.0.0.2"), Port: 20341}, 1000)
+	if addrs := d.learned.list(magic, 1000); len(addrs) != 0 {
+		t.Errorf("learned peers from replayed beacon: %v", addrs)
+	}
+
+	// the own beacon is ignored
+	own, _ := newNodeBeacon(acc, magic, p2p.GetID(), 1, 1001)
+	sink = comm.NewZeroCopySink(nil)
+	own.Serialization(sink)
+	d.handleBeacon(sink.Bytes(), from, 1001)
+	if addrs := d.learned.list(magic, 1001); len(addrs) != 0 {
+		t.Errorf("learned peers from own beacon: %v", addrs)
+	}
+
+	// stale beacon keys are pruned once the cache is full
+	d.lastBeacon = make(map[string]int64)
+	for i := 0; i < common.MAX_BEACON_KEYS; i++ {
+		d.lastBeacon[fmt.Sprint(i)] = 1000
+	}
+	acc = account.NewAccount("")
+	b, _ = newNodeBeacon(acc, magic, p2p.GetID()+2, 2, 2000)
+	sink = comm.NewZeroCopySink(nil)
+	b.Serialization(sink)
+	d.handleBeacon(sink.Bytes(), from, 2000)
+	if len(d.lastBeacon) != 1 {
+		t.Errorf("beacon keys after prune: %d", len(d.lastBeacon))
+	}
+
+	// a new beacon key is dropped while the cache is full of fresh keys
+	for i := 0; i < common.MAX_BEACON_KEYS; i++ {
+		d.lastBeacon[fmt.Sprint(i)] = 2000
+	}
+	acc = account.NewAccount("")
+	b, _ = newNodeBeacon(acc, magic, p2p.GetID()+3, 3, 2001)
+	sink = comm.NewZeroCopySink(nil)
+	b.Serialization(sink)
+	d.learned = newLearnedPeers(filepath.Join(dir, common.LEARNED_FILE_NAME))
+	d.handleBeacon(sink.Bytes(), from, 2001)
+	if addrs := d.learned.list(magic, 2001); len(addrs) != 0 {
+		t.Errorf("learned peers with full beacon cache: %v", addrs)
+	}
+}
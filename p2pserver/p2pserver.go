@@ -31,6 +31,7 @@ import (
 	"sync"
 	"time"
 
+	"OntologyWithPOC/account"
 	comm "OntologyWithPOC/common"
 	"OntologyWithPOC/common/config"
 	"OntologyWithPOC/common/log"
@@ -53,6 +54,8 @@ type P2PServer struct {
 	pid       *evtActor.PID
 	blockSync *BlockSyncMgr
 	ledger    *ledger.Ledger
+	discovery *discovery
+	acc       *account.Account
 	ReconnectAddrs
 	recentPeers    map[uint32][]string
 	quitSyncRecent chan bool
@@ -77,6 +80,7 @@ func NewServer() *P2PServer {
 
 	p.msgRouter = utils.NewMsgRouter(p.network)
 	p.blockSync = NewBlockSyncMgr(p)
+	p.discovery = newDiscovery(p)
 	p.recentPeers = make(map[uint32][]string)
 	p.quitSyncRecent = make(chan bool)
 	p.quitOnline = make(chan bool)
@@ -102,6 +106,7 @@ func (this *P2PServer) Start() error {
 		return errors.New("[p2p]msg router invalid")
 	}
 	this.tryRecentPeers()
+	this.discovery.start(this.acc)
 	go this.connectSeedService()
 	go this.syncUpRecentPeers()
 	go this.keepOnlineService()
@@ -116,10 +121,17 @@ func (this *P2PServer) Stop() {
 	this.quitSyncRecent <- true
 	this.quitOnline <- true
 	this.quitHeartBeat <- true
+	this.discovery.stop()
 	this.msgRouter.Stop()
 	this.blockSync.Close()
 }
 
+// SetAccount sets the account signing the discovery beacons, to be called
+// before Start
+func (this *P2PServer) SetAccount(acc *account.Account) {
+	this.acc = acc
+}
+
 // GetNetWork returns the low level netserver
 func (this *P2PServer) GetNetWork() p2pnet.P2P {
 	return this.network
@@ -361,7 +373,10 @@ func (this *P2PServer) reachMinConnection() bool {
 		minCount = config.VBFT_MIN_NODE_NUM
 	case "poc":
 		minCount = config.POC_MIN_NODE_NUM
-
+		// small genesis peer sets, as of the test mode, need fewer connections
+		if n := len(config.DefConfig.Genesis.POC.Peers); n < minCount {
+			minCount = n
+		}
 	}
 	return int(this.GetConnectionCnt())+1 >= minCount
 }
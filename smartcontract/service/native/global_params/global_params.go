@@ -44,6 +44,12 @@ const (
 	CREATE_SNAPSHOT_NAME                     = "createSnapshot"
 )
 
+// PoC block reward split, in percent
+const (
+	POC_REWARD_RATIO = "pocRewardRatio" // share of the undistributed transaction fees paid out by each block
+	POC_POOL_SHARE   = "pocPoolShare"   // share of a block reward the proposing pool keeps
+)
+
 func InitGlobalParams() {
 	native.Contracts[utils.ParamContractAddress] = RegisterParamContract
 }
@@ -77,6 +77,17 @@ func getStorageParam(native *native.NativeService, key []byte) (Params, error) {
 	return params, err
 }
 
+// GetParamValue returns the current value of the global param key, ok is
+// false if it is not set.
+func GetParamValue(native *native.NativeService, key string) (value string, ok bool, err error) {
+	params, err := getStorageParam(native, generateParamKey(utils.ParamContractAddress, CURRENT_VALUE))
+	if err != nil {
+		return "", false, err
+	}
+	index, param := params.GetParam(key)
+	return param.Value, index >= 0, nil
+}
+
 func GetStorageRole(native *native.NativeService, key []byte) (common.Address, error) {
 	item, err := utils.GetStorageItem(native, key)
 	var role common.Address
@@ -32,10 +32,13 @@ import (
 	"OntologyWithPOC/common/config"
 	"OntologyWithPOC/common/constants"
 	"OntologyWithPOC/common/serialization"
+	"OntologyWithPOC/consensus/poc/shabal"
+	"OntologyWithPOC/consensus/poc/zkproof"
 	cstates "OntologyWithPOC/core/states"
 	"OntologyWithPOC/smartcontract/service/native"
 	"OntologyWithPOC/smartcontract/service/native/global_params"
 	"OntologyWithPOC/smartcontract/service/native/utils"
+	"OntologyWithPOC/smartcontract/service/neovm"
 )
 
 const (
@@ -77,6 +80,8 @@ const (
 	REDUCE_INIT_POS                  = "reduceInitPos"
 	SET_PROMISE_POS                  = "setPromisePos"
 	SET_GAS_ADDRESS                  = "setGasAddress"
+	DISTRIBUTE_POC_REWARD            = "distributePocReward"
+	SUBMIT_POC_EVIDENCE              = "submitPocEvidence"
 
 	//key prefix
 	GLOBAL_PARAM      = "globalParam"
@@ -96,16 +101,32 @@ const (
 	PROMISE_POS       = "promisePos"
 	PRE_CONFIG        = "preConfig"
 	GAS_ADDRESS       = "gasAddress"
+	POC_REWARD_HEIGHT = "pocRewardHeight"
+	POC_REWARD_STAGES = "pocRewardStages"
+	POC_FEE_POOL      = "pocFeePool"
+	POC_EVIDENCE      = "pocEvidence"
 
 	//global
 	PRECISE            = 1000000
 	NEW_VERSION_VIEW   = 6
 	NEW_VERSION_BLOCK  = 414100
 	NEW_WITHDRAW_BLOCK = 2800000
+
+	//poc evidence type
+	POC_EVIDENCE_DOUBLE_PROPOSAL = 1
+	POC_EVIDENCE_FORGED_DEADLINE = 2
+	POC_EVIDENCE_FAILED_AUDIT    = 3
 )
 
 // candidate fee must >= 1 ONG
 var MIN_CANDIDATE_FEE = uint64(math.Pow(10, constants.ONG_DECIMALS))
+
+// gas of verifying forged deadline evidence, which regenerates a whole nonce of shabal.HashCount hashes over
+// up to 4096 bytes each, priced as a sha256 per 1024 bytes hashed
+var POC_FORGED_DEADLINE_GAS = uint64(shabal.HashCount) * 4 * neovm.SHA256_GAS
+
+// gas of verifying failed audit evidence, which regenerates the nonces of up to zkproof.AuditCount audited leaves
+var POC_FAILED_AUDIT_GAS = uint64(zkproof.AuditCount) * POC_FORGED_DEADLINE_GAS
 var AUTHORIZE_INFO_POOL = []byte{118, 111, 116, 101, 73, 110, 102, 111, 80, 111, 111, 108}
 var Xi = []uint32{
 	0, 100000, 200000, 300000, 400000, 500000, 600000, 700000, 800000, 900000, 1000000, 1100000, 1200000, 1300000, 1400000,
@@ -153,6 +174,8 @@ func RegisterGovernanceContract(native *native.NativeService) {
 	native.Register(TRANSFER_PENALTY, TransferPenalty)
 	native.Register(SET_PROMISE_POS, SetPromisePos)
 	native.Register(SET_GAS_ADDRESS, SetGasAddress)
+	native.Register(DISTRIBUTE_POC_REWARD, DistributePocReward)
+	native.Register(SUBMIT_POC_EVIDENCE, SubmitPocEvidence)
 }
 
 //Init governance contract, include vbft/poc config, global param and ontid admin.
@@ -329,7 +352,7 @@ func InitConfig(native *native.NativeService) ([]byte, error) {
 		//check the configuration
 		err = CheckPOCConfig(configuration)
 		if err != nil {
-			return utils.BYTE_FALSE, fmt.Errorf("checkVBFTConfig failed: %v", err)
+			return utils.BYTE_FALSE, fmt.Errorf("checkPOCConfig failed: %v", err)
 		}
 
 		//init globalParam
@@ -432,6 +455,12 @@ func InitConfig(native *native.NativeService) ([]byte, error) {
 			return utils.BYTE_FALSE, fmt.Errorf("putConfig, put config error: %v", err)
 		}
 
+		//init reward schedule
+		err = putPocRewardSchedule(native, contract, configuration.RewardSchedule)
+		if err != nil {
+			return utils.BYTE_FALSE, fmt.Errorf("putPocRewardSchedule, put reward schedule error: %v", err)
+		}
+
 		//init splitCurve
 		splitCurve := &SplitCurve{
 			Yi: []uint32{
@@ -761,56 +790,9 @@ func BlackNode(native *native.NativeService) ([]byte, error) {
 	}
 	contract := native.ContextRef.CurrentContext().ContractAddress
 
-	//get current view
-	view, err := GetView(native, contract)
-	if err != nil {
-		return utils.BYTE_FALSE, fmt.Errorf("getView, get view error: %v", err)
-	}
-	//get peerPoolMap
-	peerPoolMap, err := GetPeerPoolMap(native, contract, view)
-	if err != nil {
-		return utils.BYTE_FALSE, fmt.Errorf("getPeerPoolMap, get peerPoolMap error: %v", err)
-	}
-	commit := false
-	for _, peerPubkey := range params.PeerPubkeyList {
-		peerPubkeyPrefix, err := hex.DecodeString(peerPubkey)
-		if err != nil {
-			return utils.BYTE_FALSE, fmt.Errorf("hex.DecodeString, peerPubkey format error: %v", err)
-		}
-		peerPoolItem, ok := peerPoolMap.PeerPoolMap[peerPubkey]
-		if !ok {
-			return utils.BYTE_FALSE, fmt.Errorf("blackNode, peerPubkey is not in peerPoolMap")
-		}
-
-		blackListItem := &BlackListItem{
-			PeerPubkey: peerPoolItem.PeerPubkey,
-			Address:    peerPoolItem.Address,
-			InitPos:    peerPoolItem.InitPos,
-		}
-		bf := new(bytes.Buffer)
-		if err := blackListItem.Serialize(bf); err != nil {
-			return utils.BYTE_FALSE, fmt.Errorf("serialize, serialize blackListItem error: %v", err)
-		}
-		//put peer into black list
-		native.CacheDB.Put(utils.ConcatKey(contract, []byte(BLACK_LIST), peerPubkeyPrefix), cstates.GenRawStorageItem(bf.Bytes()))
-		//change peerPool status
-		if peerPoolItem.Status == ConsensusStatus {
-			commit = true
-		}
-		peerPoolItem.Status = BlackStatus
-		peerPoolMap.PeerPoolMap[peerPubkey] = peerPoolItem
-	}
-	err = putPeerPoolMap(native, contract, view, peerPoolMap)
+	err = blackPeers(native, contract, params.PeerPubkeyList)
 	if err != nil {
-		return utils.BYTE_FALSE, fmt.Errorf("putPeerPoolMap, put peerPoolMap error: %v", err)
-	}
-
-	//commitDpos
-	if commit {
-		err = executeCommitDpos(native, contract)
-		if err != nil {
-			return utils.BYTE_FALSE, fmt.Errorf("executeCommitDpos, executeCommitDpos error: %v", err)
-		}
+		return utils.BYTE_FALSE, fmt.Errorf("blackPeers, black peers error: %v", err)
 	}
 	return utils.BYTE_TRUE, nil
 }
@@ -1832,3 +1814,120 @@ func SetGasAddress(native *native.NativeService) ([]byte, error) {
 
 	return utils.BYTE_TRUE, nil
 }
+
+//Pay a share of the transaction fees collected since the last PoC reward to the reward address of the winning plot of a PoC block,
+//the proposing pool keeps its share if it mined the block with a plot of another account.
+//Only invoked by the system transaction of each PoC block.
+func DistributePocReward(native *native.NativeService) ([]byte, error) {
+	params := new(DistributePocRewardParam)
+	if err := params.Deserialize(bytes.NewBuffer(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("deserialize, deserialize distributePocRewardParam error: %v", err)
+	}
+	contract := native.ContextRef.CurrentContext().ContractAddress
+
+	//only system transaction
+	if native.Tx.Payer != common.ADDRESS_EMPTY || len(native.Tx.Sigs) != 0 {
+		return utils.BYTE_FALSE, fmt.Errorf("distributePocReward, only invoked by system transaction")
+	}
+	//once per block
+	heightKey := utils.ConcatKey(contract, []byte(POC_REWARD_HEIGHT))
+	item, err := utils.GetStorageItem(native, heightKey)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("distributePocReward, get reward height error: %v", err)
+	}
+	if item != nil {
+		height, err := serialization.ReadUint32(bytes.NewBuffer(item.Value))
+		if err != nil {
+			return utils.BYTE_FALSE, fmt.Errorf("distributePocReward, deserialize reward height error: %v", err)
+		}
+		if height >= native.Height {
+			return utils.BYTE_FALSE, fmt.Errorf("distributePocReward, reward of block %d already distributed", native.Height)
+		}
+	}
+	native.CacheDB.Put(heightKey, utils.GenUInt32StorageItem(native.Height).ToArray())
+
+	ratio, poolShare, err := getPocRewardSplit(native)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("getPocRewardSplit, get reward split error: %v", err)
+	}
+	fees, err := getPocFeePool(native, contract)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("getPocFeePool, get fee pool error: %v", err)
+	}
+	pooled := params.ProposerAddress != params.PlotRewardAddress
+	poolAmount, plotAmount := splitPocReward(fees, ratio, poolShare, pooled)
+	err = putPocFeePool(native, contract, fees-poolAmount-plotAmount)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("putPocFeePool, put fee pool error: %v", err)
+	}
+	if poolAmount > 0 {
+		err = appCallTransferOng(native, utils.GovernanceContractAddress, params.ProposerAddress, poolAmount)
+		if err != nil {
+			return utils.BYTE_FALSE, fmt.Errorf("distributePocReward, pool share transfer error: %v", err)
+		}
+	}
+	if plotAmount > 0 {
+		err = appCallTransferOng(native, utils.GovernanceContractAddress, params.PlotRewardAddress, plotAmount)
+		if err != nil {
+			return utils.BYTE_FALSE, fmt.Errorf("distributePocReward, reward transfer error: %v", err)
+		}
+	}
+
+	return utils.BYTE_TRUE, nil
+}
+
+//Submit evidence of a PoC peer proposing two conflicting blocks at one height, or signing a deadline
+//its nonce does not yield. Anyone can submit, the peer is put into black list and its stake penalized, members
+//outside the peer pool are black listed only.
+func SubmitPocEvidence(native *native.NativeService) ([]byte, error) {
+	params := new(SubmitPocEvidenceParam)
+	if err := params.Deserialize(bytes.NewBuffer(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("deserialize, deserialize submitPocEvidenceParam error: %v", err)
+	}
+	contract := native.ContextRef.CurrentContext().ContractAddress
+
+	peerPubkeyPrefix, err := hex.DecodeString(params.PeerPubkey)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("hex.DecodeString, peerPubkey format error: %v", err)
+	}
+	var height uint32
+	switch params.EvidenceType {
+	case POC_EVIDENCE_DOUBLE_PROPOSAL:
+		height, err = verifyDoubleProposal(params)
+	case POC_EVIDENCE_FORGED_DEADLINE:
+		height, err = verifyForgedDeadline(native, params)
+	case POC_EVIDENCE_FAILED_AUDIT:
+		height, err = verifyFailedAudit(native, params)
+	default:
+		err = fmt.Errorf("unknown evidence type %d", params.EvidenceType)
+	}
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("submitPocEvidence, invalid evidence: %v", err)
+	}
+
+	//one penalty per offence
+	heightBytes, err := GetUint32Bytes(height)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("getUint32Bytes, get heightBytes error: %v", err)
+	}
+	typeBytes, err := GetUint32Bytes(uint32(params.EvidenceType))
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("getUint32Bytes, get typeBytes error: %v", err)
+	}
+	evidenceKey := utils.ConcatKey(contract, []byte(POC_EVIDENCE), peerPubkeyPrefix, heightBytes, typeBytes)
+	item, err := native.CacheDB.Get(evidenceKey)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("submitPocEvidence, get evidence error: %v", err)
+	}
+	if item != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("submitPocEvidence, evidence %d of block %d already submitted", params.EvidenceType, height)
+	}
+	native.CacheDB.Put(evidenceKey, utils.GenUInt32StorageItem(uint32(params.EvidenceType)).ToArray())
+
+	err = blackPocPeer(native, contract, params.PeerPubkey)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("blackPocPeer, black peer error: %v", err)
+	}
+	utils.AddCommonEvent(native, contract, SUBMIT_POC_EVIDENCE, []interface{}{params.PeerPubkey, height, params.EvidenceType})
+	return utils.BYTE_TRUE, nil
+}
@@ -28,8 +28,10 @@ import (
 	"OntologyWithPOC/common"
 	"OntologyWithPOC/common/constants"
 	cstates "OntologyWithPOC/core/states"
+	"OntologyWithPOC/core/types"
 	"OntologyWithPOC/smartcontract/service/native"
 	"OntologyWithPOC/smartcontract/service/native/utils"
+	"github.com/ontio/ontology-crypto/keypair"
 )
 
 func registerCandidate(native *native.NativeService, flag string) error {
@@ -678,6 +680,100 @@ func withdrawPenaltyStake(native *native.NativeService, contract common.Address,
 	return nil
 }
 
+// blackPeers puts peers into black list, their stake is penalized on the
+// next commitDpos, which is executed at once if one of them is in consensus.
+func blackPeers(native *native.NativeService, contract common.Address, peerPubkeyList []string) error {
+	//get current view
+	view, err := GetView(native, contract)
+	if err != nil {
+		return fmt.Errorf("getView, get view error: %v", err)
+	}
+	//get peerPoolMap
+	peerPoolMap, err := GetPeerPoolMap(native, contract, view)
+	if err != nil {
+		return fmt.Errorf("getPeerPoolMap, get peerPoolMap error: %v", err)
+	}
+	commit := false
+	for _, peerPubkey := range peerPubkeyList {
+		peerPubkeyPrefix, err := hex.DecodeString(peerPubkey)
+		if err != nil {
+			return fmt.Errorf("hex.DecodeString, peerPubkey format error: %v", err)
+		}
+		peerPoolItem, ok := peerPoolMap.PeerPoolMap[peerPubkey]
+		if !ok {
+			return fmt.Errorf("blackNode, peerPubkey is not in peerPoolMap")
+		}
+
+		blackListItem := &BlackListItem{
+			PeerPubkey: peerPoolItem.PeerPubkey,
+			Address:    peerPoolItem.Address,
+			InitPos:    peerPoolItem.InitPos,
+		}
+		bf := new(bytes.Buffer)
+		if err := blackListItem.Serialize(bf); err != nil {
+			return fmt.Errorf("serialize, serialize blackListItem error: %v", err)
+		}
+		//put peer into black list
+		native.CacheDB.Put(utils.ConcatKey(contract, []byte(BLACK_LIST), peerPubkeyPrefix), cstates.GenRawStorageItem(bf.Bytes()))
+		//change peerPool status
+		if peerPoolItem.Status == ConsensusStatus {
+			commit = true
+		}
+		peerPoolItem.Status = BlackStatus
+		peerPoolMap.PeerPoolMap[peerPubkey] = peerPoolItem
+	}
+	err = putPeerPoolMap(native, contract, view, peerPoolMap)
+	if err != nil {
+		return fmt.Errorf("putPeerPoolMap, put peerPoolMap error: %v", err)
+	}
+
+	//commitDpos
+	if commit {
+		err = executeCommitDpos(native, contract)
+		if err != nil {
+			return fmt.Errorf("executeCommitDpos, executeCommitDpos error: %v", err)
+		}
+	}
+	return nil
+}
+
+// blackPocPeer puts a PoC peer convicted by evidence into black list. Members admitted by proving their plots
+// are not in the peer pool and have no stake, the black list keeps the consensus from admitting them again.
+func blackPocPeer(native *native.NativeService, contract common.Address, peerPubkey string) error {
+	//get current view
+	view, err := GetView(native, contract)
+	if err != nil {
+		return fmt.Errorf("getView, get view error: %v", err)
+	}
+	//get peerPoolMap
+	peerPoolMap, err := GetPeerPoolMap(native, contract, view)
+	if err != nil {
+		return fmt.Errorf("getPeerPoolMap, get peerPoolMap error: %v", err)
+	}
+	if _, ok := peerPoolMap.PeerPoolMap[peerPubkey]; ok {
+		return blackPeers(native, contract, []string{peerPubkey})
+	}
+
+	peerPubkeyPrefix, err := hex.DecodeString(peerPubkey)
+	if err != nil {
+		return fmt.Errorf("hex.DecodeString, peerPubkey format error: %v", err)
+	}
+	pub, err := keypair.DeserializePublicKey(peerPubkeyPrefix)
+	if err != nil {
+		return fmt.Errorf("keypair.DeserializePublicKey, deserialize peerPubkey error: %v", err)
+	}
+	blackListItem := &BlackListItem{
+		PeerPubkey: peerPubkey,
+		Address:    types.AddressFromPubKey(pub),
+	}
+	bf := new(bytes.Buffer)
+	if err := blackListItem.Serialize(bf); err != nil {
+		return fmt.Errorf("serialize, serialize blackListItem error: %v", err)
+	}
+	native.CacheDB.Put(utils.ConcatKey(contract, []byte(BLACK_LIST), peerPubkeyPrefix), cstates.GenRawStorageItem(bf.Bytes()))
+	return nil
+}
+
 func executeCommitDpos(native *native.NativeService, contract common.Address) error {
 	governanceView, err := GetGovernanceView(native, contract)
 	if err != nil {
@@ -732,6 +828,15 @@ func executeSplit(native *native.NativeService, contract common.Address, view ui
 	if err != nil {
 		return fmt.Errorf("executeSplit, getOngBalance error: %v", err)
 	}
+	//the fees of PoC block rewards are not split
+	pocFeePool, err := getPocFeePool(native, contract)
+	if err != nil {
+		return fmt.Errorf("getPocFeePool, getPocFeePool error: %v", err)
+	}
+	if balance < pocFeePool {
+		return fmt.Errorf("executeSplit, balance less than PoC fee pool")
+	}
+	balance = balance - pocFeePool
 	//get globalParam
 	globalParam, err := getGlobalParam(native, contract)
 	if err != nil {
@@ -849,10 +954,15 @@ func executeSplit2(native *native.NativeService, contract common.Address, view u
 	if err != nil {
 		return splitSum, fmt.Errorf("getSplitFee, getSplitFee error: %v", err)
 	}
-	if balance < splitFee {
+	//the fees of PoC block rewards are not split
+	pocFeePool, err := getPocFeePool(native, contract)
+	if err != nil {
+		return splitSum, fmt.Errorf("getPocFeePool, getPocFeePool error: %v", err)
+	}
+	if balance < splitFee+pocFeePool {
 		panic("balance less than splitFee to withdraw!")
 	}
-	income := balance - splitFee
+	income := balance - splitFee - pocFeePool
 
 	//fee split to dapp address
 	dappIncome := new(big.Int).Div(new(big.Int).Mul(new(big.Int).SetUint64(income),
@@ -1020,3 +1020,111 @@ func (this *GasAddress) Deserialization(source *common.ZeroCopySource) error {
 	this.Address = address
 	return nil
 }
+
+type DistributePocRewardParam struct {
+	PlotRewardAddress common.Address
+	ProposerAddress   common.Address
+}
+
+func (this *DistributePocRewardParam) Serialize(w io.Writer) error {
+	if err := serialization.WriteVarBytes(w, this.PlotRewardAddress[:]); err != nil {
+		return fmt.Errorf("serialization.WriteVarBytes, serialize plotRewardAddress error: %v", err)
+	}
+	if err := serialization.WriteVarBytes(w, this.ProposerAddress[:]); err != nil {
+		return fmt.Errorf("serialization.WriteVarBytes, serialize proposerAddress error: %v", err)
+	}
+	return nil
+}
+
+func (this *DistributePocRewardParam) Deserialize(r io.Reader) error {
+	plotRewardAddress, err := utils.ReadAddress(r)
+	if err != nil {
+		return fmt.Errorf("utils.ReadAddress, deserialize plotRewardAddress error: %v", err)
+	}
+	proposerAddress, err := utils.ReadAddress(r)
+	if err != nil {
+		return fmt.Errorf("utils.ReadAddress, deserialize proposerAddress error: %v", err)
+	}
+	this.PlotRewardAddress = plotRewardAddress
+	this.ProposerAddress = proposerAddress
+	return nil
+}
+
+type SubmitPocEvidenceParam struct {
+	EvidenceType uint64
+	PeerPubkey   string
+	Messages     [][]byte
+	Sigs         [][]byte
+}
+
+func (this *SubmitPocEvidenceParam) Serialize(w io.Writer) error {
+	if err := utils.WriteVarUint(w, this.EvidenceType); err != nil {
+		return fmt.Errorf("utils.WriteVarUint, serialize evidenceType error: %v", err)
+	}
+	if err := serialization.WriteString(w, this.PeerPubkey); err != nil {
+		return fmt.Errorf("serialization.WriteString, serialize peerPubkey error: %v", err)
+	}
+	if len(this.Messages) != len(this.Sigs) {
+		return fmt.Errorf("messages and sigs length mismatch")
+	}
+	if err := utils.WriteVarUint(w, uint64(len(this.Messages))); err != nil {
+		return fmt.Errorf("utils.WriteVarUint, serialize messages length error: %v", err)
+	}
+	for _, message := range this.Messages {
+		if err := serialization.WriteVarBytes(w, message); err != nil {
+			return fmt.Errorf("serialization.WriteVarBytes, serialize message error: %v", err)
+		}
+	}
+	if err := utils.WriteVarUint(w, uint64(len(this.Sigs))); err != nil {
+		return fmt.Errorf("utils.WriteVarUint, serialize sigs length error: %v", err)
+	}
+	for _, sig := range this.Sigs {
+		if err := serialization.WriteVarBytes(w, sig); err != nil {
+			return fmt.Errorf("serialization.WriteVarBytes, serialize sig error: %v", err)
+		}
+	}
+	return nil
+}
+
+func (this *SubmitPocEvidenceParam) Deserialize(r io.Reader) error {
+	evidenceType, err := utils.ReadVarUint(r)
+	if err != nil {
+		return fmt.Errorf("utils.ReadVarUint, deserialize evidenceType error: %v", err)
+	}
+	peerPubkey, err := serialization.ReadString(r)
+	if err != nil {
+		return fmt.Errorf("serialization.ReadString, deserialize peerPubkey error: %v", err)
+	}
+	n, err := utils.ReadVarUint(r)
+	if err != nil {
+		return fmt.Errorf("utils.ReadVarUint, deserialize messages length error: %v", err)
+	}
+	messages := make([][]byte, 0)
+	for i := 0; uint64(i) < n; i++ {
+		message, err := serialization.ReadVarBytes(r)
+		if err != nil {
+			return fmt.Errorf("serialization.ReadVarBytes, deserialize message error: %v", err)
+		}
+		messages = append(messages, message)
+	}
+	m, err := utils.ReadVarUint(r)
+	if err != nil {
+		return fmt.Errorf("utils.ReadVarUint, deserialize sigs length error: %v", err)
+	}
+	if m != n {
+		return fmt.Errorf("messages and sigs length mismatch")
+	}
+	sigs := make([][]byte, 0)
+	for i := 0; uint64(i) < m; i++ {
+		sig, err := serialization.ReadVarBytes(r)
+		if err != nil {
+			return fmt.Errorf("serialization.ReadVarBytes, deserialize sig error: %v", err)
+		}
+		sigs = append(sigs, sig)
+	}
+	this.EvidenceType = evidenceType
+	this.PeerPubkey = peerPubkey
+	this.Messages = messages
+	this.Sigs = sigs
+	return nil
+}
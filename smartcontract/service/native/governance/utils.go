@@ -20,18 +20,29 @@ package governance
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"math/big"
+	"strconv"
 
 	"OntologyWithPOC/common"
 	"OntologyWithPOC/common/config"
 	"OntologyWithPOC/common/serialization"
+	pocconfig "OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/consensus/poc/zkproof"
 	vbftconfig "OntologyWithPOC/consensus/vbft/config"
+	"OntologyWithPOC/core/signature"
 	cstates "OntologyWithPOC/core/states"
+	"OntologyWithPOC/core/types"
 	"OntologyWithPOC/smartcontract/service/native"
 	"OntologyWithPOC/smartcontract/service/native/auth"
+	"OntologyWithPOC/smartcontract/service/native/global_params"
 	"OntologyWithPOC/smartcontract/service/native/ont"
+	"OntologyWithPOC/smartcontract/service/native/plot_binding"
 	"OntologyWithPOC/smartcontract/service/native/utils"
+	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/ontology-crypto/vrf"
 )
 
@@ -196,6 +207,47 @@ func getOngBalance(native *native.NativeService, address common.Address) (uint64
 	return balance, nil
 }
 
+//get the PoC reward split from global params, in percent of the fee pool, the ratio defaults to the genesis reward schedule
+func getPocRewardSplit(native *native.NativeService) (ratio uint64, poolShare uint64, err error) {
+	schedule, err := getPocRewardSchedule(native, utils.GovernanceContractAddress)
+	if err != nil {
+		return 0, 0, err
+	}
+	ratio, err = getPercentParam(native, global_params.POC_REWARD_RATIO, pocRewardRatio(schedule, native.Height))
+	if err != nil {
+		return 0, 0, err
+	}
+	poolShare, err = getPercentParam(native, global_params.POC_POOL_SHARE, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	return ratio, poolShare, nil
+}
+
+func getPercentParam(native *native.NativeService, key string, defValue uint64) (uint64, error) {
+	value, ok, err := global_params.GetParamValue(native, key)
+	if err != nil {
+		return 0, fmt.Errorf("getPercentParam, get param %s error: %v", key, err)
+	}
+	if !ok || value == "" {
+		return defValue, nil
+	}
+	percent, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || percent > 100 {
+		return 0, fmt.Errorf("getPercentParam, invalid param %s: %s", key, value)
+	}
+	return percent, nil
+}
+
+//split ratio percent of the fee pool between the pool and the plot of a PoC block
+func splitPocReward(fees, ratio, poolShare uint64, pooled bool) (poolAmount uint64, plotAmount uint64) {
+	reward := fees/100*ratio + fees%100*ratio/100
+	if pooled {
+		poolAmount = reward/100*poolShare + reward%100*poolShare/100
+	}
+	return poolAmount, reward - poolAmount
+}
+
 func splitCurve(native *native.NativeService, contract common.Address, pos uint64, avg uint64, yita uint64) (uint64, error) {
 	if avg == 0 {
 		return 0, fmt.Errorf("splitCurve, avg stake is 0")
@@ -390,20 +442,17 @@ func CheckVBFTConfig(configuration *config.VBFTConfig) error {
 }
 
 func CheckPOCConfig(configuration *config.POCConfig) error {
-	if configuration.C == 0 {
-		return fmt.Errorf("initConfig. C can not be 0 in config")
+	if len(configuration.Peers) == 0 {
+		return fmt.Errorf("initConfig. no peer in config")
 	}
 	if int(configuration.K) != len(configuration.Peers) {
 		return fmt.Errorf("initConfig. K must equal to length of peer in config")
 	}
-	if configuration.L < 16*configuration.K || configuration.L%configuration.K != 0 {
-		return fmt.Errorf("initConfig. L can not be less than 16*K and K must be times of L in config")
-	}
-	if configuration.K < 2*configuration.C+1 {
-		return fmt.Errorf("initConfig. K can not be less than 2*C+1 in config")
+	if configuration.N < configuration.K {
+		return fmt.Errorf("initConfig. config not match N >= K")
 	}
-	if configuration.N < configuration.K || configuration.K < 7 {
-		return fmt.Errorf("initConfig. config not match N >= K >= 7")
+	if configuration.K < 3*configuration.C+1 {
+		return fmt.Errorf("initConfig. K can not be less than 3*C+1 in config")
 	}
 	if configuration.BlockMsgDelay < 5000 {
 		return fmt.Errorf("initConfig. BlockMsgDelay must >= 5000")
@@ -418,10 +467,13 @@ func CheckPOCConfig(configuration *config.POCConfig) error {
 		return fmt.Errorf("initConfig. MinInitStake must >= 10000")
 	}
 	if configuration.PocSpace == 0 {
-		return fmt.Errorf("PocSpace is 0")
+		return fmt.Errorf("initConfig. poc_space is 0")
 	}
 	if configuration.NonceDir == "" {
-		return fmt.Errorf("NonceDir is ''")
+		return fmt.Errorf("initConfig. nonce_dir is ''")
+	}
+	if err := checkPOCParams(configuration); err != nil {
+		return fmt.Errorf("initConfig. %v", err)
 	}
 
 	indexMap := make(map[uint32]struct{})
@@ -454,6 +506,94 @@ func CheckPOCConfig(configuration *config.POCConfig) error {
 	return nil
 }
 
+//check the PoC parameters of the genesis config, zero values select the defaults
+func checkPOCParams(configuration *config.POCConfig) error {
+	if configuration.TargetBlockTime > config.POC_MAX_BLOCK_TIME {
+		return fmt.Errorf("target_block_time %d exceeds %d seconds", configuration.TargetBlockTime, config.POC_MAX_BLOCK_TIME)
+	}
+	if configuration.ScoopCount != 0 && configuration.ScoopCount != config.POC_SCOOP_COUNT {
+		return fmt.Errorf("scoop_count %d not supported, plots have %d scoops per nonce", configuration.ScoopCount, config.POC_SCOOP_COUNT)
+	}
+	if configuration.NonceSize != 0 && configuration.NonceSize != config.POC_NONCE_SIZE {
+		return fmt.Errorf("nonce_size %d not supported, plots have nonces of %d bytes", configuration.NonceSize, config.POC_NONCE_SIZE)
+	}
+	if configuration.RetargetWindow > config.POC_MAX_RETARGET_BLOCK {
+		return fmt.Errorf("retarget_window %d exceeds %d blocks", configuration.RetargetWindow, config.POC_MAX_RETARGET_BLOCK)
+	}
+	for i, stage := range configuration.RewardSchedule {
+		if stage.Ratio > 100 {
+			return fmt.Errorf("reward_schedule ratio %d at height %d exceeds 100", stage.Ratio, stage.Height)
+		}
+		if i > 0 && stage.Height <= configuration.RewardSchedule[i-1].Height {
+			return fmt.Errorf("reward_schedule height %d not above the previous stage height %d", stage.Height, configuration.RewardSchedule[i-1].Height)
+		}
+	}
+	switch configuration.PlotVerification {
+	case "", config.POC_PLOT_VERIFY_NONCE:
+	case config.POC_PLOT_VERIFY_ZK, config.POC_PLOT_VERIFY_ANY:
+		if configuration.ZKVerifyingKey == "" {
+			return fmt.Errorf("plot_verification %s needs a zk_verifying_key", configuration.PlotVerification)
+		}
+	default:
+		return fmt.Errorf("unknown plot_verification %s, must be %s, %s or %s", configuration.PlotVerification,
+			config.POC_PLOT_VERIFY_NONCE, config.POC_PLOT_VERIFY_ZK, config.POC_PLOT_VERIFY_ANY)
+	}
+	if _, err := hex.DecodeString(configuration.ZKVerifyingKey); err != nil {
+		return fmt.Errorf("zk_verifying_key is not hex: %v", err)
+	}
+	return nil
+}
+
+//ratio percent of the undistributed transaction fees the reward schedule pays at height, all of them without schedule
+func pocRewardRatio(schedule []*config.POCRewardStage, height uint32) uint64 {
+	ratio := uint64(100)
+	for _, stage := range schedule {
+		if stage.Height > height {
+			break
+		}
+		ratio = uint64(stage.Ratio)
+	}
+	return ratio
+}
+
+func getPocRewardSchedule(native *native.NativeService, contract common.Address) ([]*config.POCRewardStage, error) {
+	item, err := utils.GetStorageItem(native, utils.ConcatKey(contract, []byte(POC_REWARD_STAGES)))
+	if err != nil {
+		return nil, fmt.Errorf("getPocRewardSchedule, get reward schedule error: %v", err)
+	}
+	if item == nil {
+		return nil, nil
+	}
+	bf := bytes.NewBuffer(item.Value)
+	n, err := serialization.ReadVarUint(bf, 0)
+	if err != nil {
+		return nil, fmt.Errorf("getPocRewardSchedule, deserialize stage length error: %v", err)
+	}
+	schedule := make([]*config.POCRewardStage, 0)
+	for i := uint64(0); i < n; i++ {
+		stage := new(config.POCRewardStage)
+		if err := stage.Deserialize(bf); err != nil {
+			return nil, fmt.Errorf("getPocRewardSchedule, deserialize stage error: %v", err)
+		}
+		schedule = append(schedule, stage)
+	}
+	return schedule, nil
+}
+
+func putPocRewardSchedule(native *native.NativeService, contract common.Address, schedule []*config.POCRewardStage) error {
+	bf := new(bytes.Buffer)
+	if err := serialization.WriteVarUint(bf, uint64(len(schedule))); err != nil {
+		return fmt.Errorf("putPocRewardSchedule, serialize stage length error: %v", err)
+	}
+	for _, stage := range schedule {
+		if err := stage.Serialize(bf); err != nil {
+			return fmt.Errorf("putPocRewardSchedule, serialize stage error: %v", err)
+		}
+	}
+	native.CacheDB.Put(utils.ConcatKey(contract, []byte(POC_REWARD_STAGES)), cstates.GenRawStorageItem(bf.Bytes()))
+	return nil
+}
+
 func getConfig(native *native.NativeService, contract common.Address) (*Configuration, error) {
 	config := new(Configuration)
 	configBytes, err := native.CacheDB.Get(utils.ConcatKey(contract, []byte(VBFT_CONFIG)))
@@ -566,6 +706,52 @@ func putSplitFee(native *native.NativeService, contract common.Address, splitFee
 	return nil
 }
 
+func getPocFeePool(native *native.NativeService, contract common.Address) (uint64, error) {
+	feePoolBytes, err := native.CacheDB.Get(utils.ConcatKey(contract, []byte(POC_FEE_POOL)))
+	if err != nil {
+		return 0, fmt.Errorf("native.CacheDB.Get, get feePoolBytes error: %v", err)
+	}
+	var feePool uint64 = 0
+	if feePoolBytes != nil {
+		feePoolStore, err := cstates.GetValueFromRawStorageItem(feePoolBytes)
+		if err != nil {
+			return 0, fmt.Errorf("getPocFeePool, feePoolBytes is not available")
+		}
+		feePool, err = GetBytesUint64(feePoolStore)
+		if err != nil {
+			return 0, fmt.Errorf("GetBytesUint64, get feePool error: %v", err)
+		}
+	}
+	return feePool, nil
+}
+
+func putPocFeePool(native *native.NativeService, contract common.Address, feePool uint64) error {
+	feePoolBytes, err := GetUint64Bytes(feePool)
+	if err != nil {
+		return fmt.Errorf("GetUint64Bytes, get feePoolBytes error: %v", err)
+	}
+	native.CacheDB.Put(utils.ConcatKey(contract, []byte(POC_FEE_POOL)), cstates.GenRawStorageItem(feePoolBytes))
+	return nil
+}
+
+//CollectPocFee adds the transaction fees of a block paid to governance contract to the fees of the PoC block rewards,
+//it does nothing on a chain without PoC reward schedule
+func CollectPocFee(native *native.NativeService, fee uint64) error {
+	contract := utils.GovernanceContractAddress
+	item, err := native.CacheDB.Get(utils.ConcatKey(contract, []byte(POC_REWARD_STAGES)))
+	if err != nil {
+		return fmt.Errorf("CollectPocFee, get reward schedule error: %v", err)
+	}
+	if item == nil || fee == 0 {
+		return nil
+	}
+	feePool, err := getPocFeePool(native, contract)
+	if err != nil {
+		return err
+	}
+	return putPocFeePool(native, contract, feePool+fee)
+}
+
 func getSplitFeeAddress(native *native.NativeService, contract common.Address, address common.Address) (*SplitFeeAddress, error) {
 	splitFeeAddressBytes, err := native.CacheDB.Get(utils.ConcatKey(contract, []byte(SPLIT_FEE_ADDRESS), address[:]))
 	if err != nil {
@@ -885,3 +1071,203 @@ func putGasAddress(native *native.NativeService, contract common.Address, gasAdd
 		cstates.GenRawStorageItem(sink.Bytes()))
 	return nil
 }
+
+//deadline entry signed by a PoC peer, as gossiped by the consensus
+type pocDeadlineEntry struct {
+	BlockNum  uint32         `json:"block_num"`
+	Deadline  uint64         `json:"deadline"`
+	AccountID string         `json:"account_id"`
+	NonceNr   uint64         `json:"nonce_nr"`
+	ZKProof   []byte         `json:"zk_proof"`
+	PrevHash  common.Uint256 `json:"prev_hash"`
+}
+
+//verify the hashes of evidence messages are signed by the peer
+func verifyPocEvidenceSigs(params *SubmitPocEvidenceParam, hashes []common.Uint256) error {
+	pubkeyBytes, err := hex.DecodeString(params.PeerPubkey)
+	if err != nil {
+		return fmt.Errorf("hex.DecodeString, peerPubkey format error: %v", err)
+	}
+	pub, err := keypair.DeserializePublicKey(pubkeyBytes)
+	if err != nil {
+		return fmt.Errorf("keypair.DeserializePublicKey, deserialize peerPubkey error: %v", err)
+	}
+	for i, hash := range hashes {
+		if err := signature.Verify(pub, hash[:], params.Sigs[i]); err != nil {
+			return fmt.Errorf("verify sig of message %d error: %v", i, err)
+		}
+	}
+	return nil
+}
+
+//two block headers of the same height and parent, signed by the peer with different consensus payloads.
+//the empty block a proposer signs alongside its block carries the same payload.
+func verifyDoubleProposal(params *SubmitPocEvidenceParam) (uint32, error) {
+	if len(params.Messages) != 2 || len(params.Sigs) != 2 {
+		return 0, fmt.Errorf("double proposal needs two headers")
+	}
+	headers := make([]*types.Header, 0, 2)
+	hashes := make([]common.Uint256, 0, 2)
+	for _, message := range params.Messages {
+		header, err := types.HeaderFromRawBytes(message)
+		if err != nil {
+			return 0, fmt.Errorf("types.HeaderFromRawBytes, deserialize header error: %v", err)
+		}
+		headers = append(headers, header)
+		hashes = append(hashes, header.Hash())
+	}
+	if headers[0].Height != headers[1].Height || headers[0].PrevBlockHash != headers[1].PrevBlockHash {
+		return 0, fmt.Errorf("headers of different parents")
+	}
+	if bytes.Equal(headers[0].ConsensusPayload, headers[1].ConsensusPayload) {
+		return 0, fmt.Errorf("headers of the same proposal")
+	}
+	if err := verifyPocEvidenceSigs(params, hashes); err != nil {
+		return 0, err
+	}
+	return headers[0].Height, nil
+}
+
+//a deadline entry signed by the peer, whose deadline is not the one of its nonce on top of the block sealed before it
+func verifyForgedDeadline(native *native.NativeService, params *SubmitPocEvidenceParam) (uint32, error) {
+	entry, prevHeader, err := pocEvidenceDeadline(native, params, POC_FORGED_DEADLINE_GAS)
+	if err != nil {
+		return 0, err
+	}
+	genesisHeader, err := native.Store.GetHeaderByHeight(0)
+	if err != nil {
+		return 0, fmt.Errorf("get genesis header error: %v", err)
+	}
+	if err := checkForgedDeadline(entry, prevHeader, genesisHeader); err != nil {
+		return 0, err
+	}
+	return entry.BlockNum, nil
+}
+
+//a zk deadline entry signed by the peer, whose plot tree audit drawn by the block sealed before it fails
+func verifyFailedAudit(native *native.NativeService, params *SubmitPocEvidenceParam) (uint32, error) {
+	cfg := config.DefConfig.Genesis.POC
+	if cfg == nil || cfg.ZKVerifyingKey == "" {
+		return 0, fmt.Errorf("zk deadlines not enabled")
+	}
+	vk, err := zkproof.ParseVerifyingKeyHex(cfg.ZKVerifyingKey)
+	if err != nil {
+		return 0, fmt.Errorf("zkproof.ParseVerifyingKeyHex, parse zk verifying key error: %v", err)
+	}
+	entry, prevHeader, err := pocEvidenceDeadline(native, params, POC_FAILED_AUDIT_GAS)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkFailedAudit(entry, prevHeader, vk.Params); err != nil {
+		return 0, err
+	}
+	return entry.BlockNum, nil
+}
+
+//the deadline entry of evidence signed by the peer and the header of the block sealed before it, charging gas for
+//verifying it further
+func pocEvidenceDeadline(native *native.NativeService, params *SubmitPocEvidenceParam, gas uint64) (*pocDeadlineEntry, *types.Header, error) {
+	if len(params.Messages) != 1 || len(params.Sigs) != 1 {
+		return nil, nil, fmt.Errorf("deadline evidence needs one deadline entry")
+	}
+	entry := new(pocDeadlineEntry)
+	if err := json.Unmarshal(params.Messages[0], entry); err != nil {
+		return nil, nil, fmt.Errorf("json.Unmarshal, deserialize deadline entry error: %v", err)
+	}
+	if entry.BlockNum == 0 || entry.BlockNum > native.Height {
+		return nil, nil, fmt.Errorf("invalid block num %d of deadline entry", entry.BlockNum)
+	}
+	t := sha256.Sum256(params.Messages[0])
+	hash := common.Uint256(sha256.Sum256(t[:]))
+	if err := verifyPocEvidenceSigs(params, []common.Uint256{hash}); err != nil {
+		return nil, nil, err
+	}
+	if native.Store == nil {
+		return nil, nil, fmt.Errorf("no ledger store")
+	}
+	//regenerating nonces is paid by the submitter
+	if !native.ContextRef.CheckUseGas(gas) {
+		return nil, nil, fmt.Errorf("insufficient gas to verify deadline evidence")
+	}
+	prevHeader, err := native.Store.GetHeaderByHeight(entry.BlockNum - 1)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get header %d error: %v", entry.BlockNum-1, err)
+	}
+	return entry, prevHeader, nil
+}
+
+//recompute the deadline of entry from its nonce and the block sealed before it, blocks without a base target
+//use the one of the genesis block
+func checkForgedDeadline(entry *pocDeadlineEntry, prevHeader *types.Header, genesisHeader *types.Header) error {
+	if prevHeader.Hash() != entry.PrevHash {
+		return fmt.Errorf("deadline entry of block %d not on top of the ledger", entry.BlockNum)
+	}
+	//the nonce of a deadline proved by a zk proof is not disclosed, there is nothing to recompute
+	if len(entry.ZKProof) > 0 {
+		return fmt.Errorf("deadline entry of block %d is proved by a zk proof", entry.BlockNum)
+	}
+	info, err := pocconfig.PocBlock(prevHeader)
+	if err != nil {
+		return fmt.Errorf("pocconfig.PocBlock, get poc info of block %d error: %v", prevHeader.Height, err)
+	}
+	baseTarget := info.BaseTarget
+	if baseTarget == 0 {
+		genesisInfo, err := pocconfig.PocBlock(genesisHeader)
+		if err != nil {
+			return fmt.Errorf("pocconfig.PocBlock, get poc info of genesis block error: %v", err)
+		}
+		baseTarget = genesisInfo.BaseTarget
+	}
+	_, _, deadline, err := pocconfig.NonceDeadline(entry.AccountID, entry.NonceNr, info.GenSig, baseTarget)
+	if err != nil {
+		return fmt.Errorf("pocconfig.NonceDeadline, deadline of block %d error: %v", entry.BlockNum, err)
+	}
+	if deadline == entry.Deadline {
+		return fmt.Errorf("deadline %d of nonce %d is valid", entry.Deadline, entry.NonceNr)
+	}
+	return nil
+}
+
+//the plot tree audit of a zk deadline entry, against the nonces the entry claims its tree commits to
+type pocZKDeadline struct {
+	Root       []byte     `json:"root"`
+	StartNonce uint64     `json:"start_nonce"`
+	Nonces     uint64     `json:"nonces"`
+	Audit      [][][]byte `json:"audit"`
+}
+
+//reopen the plot tree leaves the block sealed before entry draws for audit, which have to fail to open to the
+//scoops of the nonces of the plot account
+func checkFailedAudit(entry *pocDeadlineEntry, prevHeader *types.Header, params zkproof.Params) error {
+	if prevHeader.Hash() != entry.PrevHash {
+		return fmt.Errorf("deadline entry of block %d not on top of the ledger", entry.BlockNum)
+	}
+	if len(entry.ZKProof) == 0 {
+		return fmt.Errorf("deadline entry of block %d is not proved by a zk proof", entry.BlockNum)
+	}
+	zk := new(pocZKDeadline)
+	if err := json.Unmarshal(entry.ZKProof, zk); err != nil {
+		return fmt.Errorf("json.Unmarshal, deserialize zk proof error: %v", err)
+	}
+	info, err := pocconfig.PocBlock(prevHeader)
+	if err != nil {
+		return fmt.Errorf("pocconfig.PocBlock, get poc info of block %d error: %v", prevHeader.Height, err)
+	}
+	root := new(big.Int).SetBytes(zk.Root)
+	positions := zkproof.AuditPositions(info.GenSig, root, zk.Nonces)
+	paths := make([][]*big.Int, 0, len(zk.Audit))
+	for _, nodes := range zk.Audit {
+		path := make([]*big.Int, 0, len(nodes))
+		for _, node := range nodes {
+			if len(node) != plot_binding.PLOT_ROOT_SIZE {
+				return nil
+			}
+			path = append(path, new(big.Int).SetBytes(node))
+		}
+		paths = append(paths, path)
+	}
+	if len(positions) > 0 && zkproof.VerifyAudit(params, root, entry.AccountID, zk.StartNonce, positions, paths) == nil {
+		return fmt.Errorf("plot tree audit of deadline entry of block %d passes", entry.BlockNum)
+	}
+	return nil
+}
@@ -0,0 +1,453 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package governance
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"OntologyWithPOC/account"
+	"OntologyWithPOC/common"
+	"OntologyWithPOC/common/config"
+	pocconfig "OntologyWithPOC/consensus/poc/config"
+	"OntologyWithPOC/consensus/poc/plot"
+	"OntologyWithPOC/consensus/poc/shabal"
+	"OntologyWithPOC/consensus/poc/zkproof"
+	"OntologyWithPOC/core/signature"
+	"OntologyWithPOC/core/store/leveldbstore"
+	"OntologyWithPOC/core/store/overlaydb"
+	"OntologyWithPOC/core/types"
+	"OntologyWithPOC/smartcontract/service/native"
+	"OntologyWithPOC/smartcontract/service/native/plot_binding"
+	"OntologyWithPOC/smartcontract/service/native/utils"
+	"OntologyWithPOC/smartcontract/storage"
+	"github.com/ontio/ontology-crypto/keypair"
+)
+
+func TestSplitPocReward(t *testing.T) {
+	cases := []struct {
+		balance, ratio, poolShare uint64
+		pooled                    bool
+		pool, plot                uint64
+	}{
+		{1000, 100, 0, false, 0, 1000},
+		{1000, 50, 0, false, 0, 500},
+		{1000, 100, 20, false, 0, 1000},
+		{1000, 100, 20, true, 200, 800},
+		{1000, 50, 10, true, 50, 450},
+		{999, 100, 33, true, 329, 670},
+		{0, 100, 20, true, 0, 0},
+		// no overflow on large balances
+		{1 << 63, 100, 50, true, 1 << 62, 1 << 62},
+	}
+	for _, c := range cases {
+		pool, plot := splitPocReward(c.balance, c.ratio, c.poolShare, c.pooled)
+		if pool != c.pool || plot != c.plot {
+			t.Errorf("split %d by %d%%, pool %d%%, pooled %v: %d/%d, expected %d/%d",
+				c.balance, c.ratio, c.poolShare, c.pooled, pool, plot, c.pool, c.plot)
+		}
+	}
+}
+
+func TestPocRewardRatio(t *testing.T) {
+	schedule := []*config.POCRewardStage{{Height: 0, Ratio: 100}, {Height: 100, Ratio: 50}, {Height: 200, Ratio: 25}}
+	cases := []struct {
+		height uint32
+		ratio  uint64
+	}{
+		{0, 100}, {99, 100}, {100, 50}, {199, 50}, {200, 25}, {1 << 30, 25},
+	}
+	for _, c := range cases {
+		if ratio := pocRewardRatio(schedule, c.height); ratio != c.ratio {
+			t.Errorf("ratio at %d: %d, expected %d", c.height, ratio, c.ratio)
+		}
+	}
+	if ratio := pocRewardRatio(nil, 10); ratio != 100 {
+		t.Errorf("ratio without schedule: %d", ratio)
+	}
+	if ratio := pocRewardRatio(schedule[1:], 10); ratio != 100 {
+		t.Errorf("ratio before the first stage: %d", ratio)
+	}
+}
+
+func TestCollectPocFee(t *testing.T) {
+	memback, _ := leveldbstore.NewMemLevelDBStore()
+	native := &native.NativeService{CacheDB: storage.NewCacheDB(overlaydb.NewOverlayDB(memback))}
+	contract := utils.GovernanceContractAddress
+
+	if err := CollectPocFee(native, 100); err != nil {
+		t.Fatalf("collect fee without PoC: %s", err)
+	}
+	if fees, _ := getPocFeePool(native, contract); fees != 0 {
+		t.Errorf("fee pool without PoC: %d", fees)
+	}
+	if err := putPocRewardSchedule(native, contract, nil); err != nil {
+		t.Fatalf("put reward schedule: %s", err)
+	}
+	for _, fee := range []uint64{100, 0, 250} {
+		if err := CollectPocFee(native, fee); err != nil {
+			t.Fatalf("collect fee %d: %s", fee, err)
+		}
+	}
+	if fees, _ := getPocFeePool(native, contract); fees != 350 {
+		t.Errorf("fee pool: %d, expected 350", fees)
+	}
+}
+
+func TestBlackPocPeer(t *testing.T) {
+	memback, _ := leveldbstore.NewMemLevelDBStore()
+	native := &native.NativeService{CacheDB: storage.NewCacheDB(overlaydb.NewOverlayDB(memback))}
+	contract := utils.GovernanceContractAddress
+
+	if err := putGovernanceView(native, contract, &GovernanceView{View: 1}); err != nil {
+		t.Fatalf("put governance view: %s", err)
+	}
+	peerPoolMap := &PeerPoolMap{PeerPoolMap: make(map[string]*PeerPoolItem)}
+	if err := putPeerPoolMap(native, contract, 1, peerPoolMap); err != nil {
+		t.Fatalf("put peer pool map: %s", err)
+	}
+
+	// a member admitted by its plots is not in the peer pool
+	member := account.NewAccount("SHA256withECDSA")
+	pubkeyBytes := keypair.SerializePublicKey(member.PublicKey)
+	if err := blackPocPeer(native, contract, hex.EncodeToString(pubkeyBytes)); err != nil {
+		t.Fatalf("black member: %s", err)
+	}
+	item, err := utils.GetStorageItem(native, utils.ConcatKey(contract, []byte(BLACK_LIST), pubkeyBytes))
+	if err != nil || item == nil {
+		t.Fatalf("member not black listed: %v", err)
+	}
+	blackListItem := new(BlackListItem)
+	if err := blackListItem.Deserialize(bytes.NewBuffer(item.Value)); err != nil {
+		t.Fatalf("deserialize black list item: %s", err)
+	}
+	if blackListItem.Address != types.AddressFromPubKey(member.PublicKey) || blackListItem.InitPos != 0 {
+		t.Errorf("black list item of member: %v", blackListItem)
+	}
+	if err := blackPocPeer(native, contract, "zz"); err == nil {
+		t.Errorf("invalid peer pubkey should fail")
+	}
+}
+
+func TestCheckPOCConfig(t *testing.T) {
+	genesis := config.NewPOCTestModeGenesisConfig(6)
+	cfg := genesis.POC
+	cfg.Peers = []*config.POCPeerStakeInfo{{
+		Index:      1,
+		PeerPubkey: "0253ccfd439b29eca0fe90ca7c6eaa1f98572a054aa2d1d56e72ad96c466107a85",
+		Address:    "ARnijerLA5RsGzZ2vyT2YsBcc3L5VodmGJ",
+	}}
+	if err := CheckPOCConfig(cfg); err != nil {
+		t.Fatalf("test mode config: %s", err)
+	}
+	if err := CheckPOCConfig(config.MainNetConfig.POC); err != nil {
+		t.Fatalf("main net config: %s", err)
+	}
+
+	invalid := []func(cfg *config.POCConfig){
+		func(cfg *config.POCConfig) { cfg.Peers = nil },
+		func(cfg *config.POCConfig) { cfg.TargetBlockTime = config.POC_MAX_BLOCK_TIME + 1 },
+		func(cfg *config.POCConfig) { cfg.ScoopCount = 2048 },
+		func(cfg *config.POCConfig) { cfg.NonceSize = 1024 },
+		func(cfg *config.POCConfig) { cfg.RetargetWindow = config.POC_MAX_RETARGET_BLOCK + 1 },
+		func(cfg *config.POCConfig) { cfg.RewardSchedule = []*config.POCRewardStage{{Height: 0, Ratio: 101}} },
+		func(cfg *config.POCConfig) {
+			cfg.RewardSchedule = []*config.POCRewardStage{{Height: 10, Ratio: 50}, {Height: 10, Ratio: 20}}
+		},
+		func(cfg *config.POCConfig) { cfg.PlotVerification = "proof" },
+		func(cfg *config.POCConfig) { cfg.PlotVerification = config.POC_PLOT_VERIFY_ZK },
+		func(cfg *config.POCConfig) { cfg.ZKVerifyingKey = "xyz" },
+	}
+	for i, change := range invalid {
+		c := *cfg
+		change(&c)
+		if err := CheckPOCConfig(&c); err == nil {
+			t.Errorf("invalid config %d accepted", i)
+		}
+	}
+
+	c := *cfg
+	c.ScoopCount, c.NonceSize, c.RetargetWindow, c.RewardSchedule, c.PlotVerification = 0, 0, 0, nil, ""
+	if err := CheckPOCConfig(&c); err != nil {
+		t.Errorf("config with default parameters: %s", err)
+	}
+}
+
+func TestPOCConfigSerialize(t *testing.T) {
+	cfg := config.MainNetConfig.POC
+	bf := new(bytes.Buffer)
+	if err := cfg.Serialize(bf); err != nil {
+		t.Fatal(err)
+	}
+	cfg2 := new(config.POCConfig)
+	if err := cfg2.Deserialize(bf); err != nil {
+		t.Fatal(err)
+	}
+	if cfg2.ScoopCount != cfg.ScoopCount || cfg2.NonceSize != cfg.NonceSize || cfg2.RetargetWindow != cfg.RetargetWindow ||
+		cfg2.PlotVerification != cfg.PlotVerification || cfg2.TargetBlockTime != cfg.TargetBlockTime ||
+		len(cfg2.RewardSchedule) != len(cfg.RewardSchedule) || *cfg2.RewardSchedule[0] != *cfg.RewardSchedule[0] ||
+		len(cfg2.Peers) != len(cfg.Peers) {
+		t.Errorf("poc config mismatch: %+v", cfg2)
+	}
+}
+
+func TestDistributePocRewardParam(t *testing.T) {
+	param := &DistributePocRewardParam{
+		PlotRewardAddress: common.Address{1, 2, 3},
+		ProposerAddress:   common.Address{4, 5, 6},
+	}
+	bf := new(bytes.Buffer)
+	if err := param.Serialize(bf); err != nil {
+		t.Fatal(err)
+	}
+	param2 := new(DistributePocRewardParam)
+	if err := param2.Deserialize(bf); err != nil {
+		t.Fatal(err)
+	}
+	if *param != *param2 {
+		t.Errorf("distribute poc reward param mismatch: %v", param2)
+	}
+}
+
+func TestSubmitPocEvidenceParam(t *testing.T) {
+	param := &SubmitPocEvidenceParam{
+		EvidenceType: POC_EVIDENCE_DOUBLE_PROPOSAL,
+		PeerPubkey:   "0123",
+		Messages:     [][]byte{{1, 2}, {3}},
+		Sigs:         [][]byte{{4}, {5, 6}},
+	}
+	bf := new(bytes.Buffer)
+	if err := param.Serialize(bf); err != nil {
+		t.Fatal(err)
+	}
+	param2 := new(SubmitPocEvidenceParam)
+	if err := param2.Deserialize(bf); err != nil {
+		t.Fatal(err)
+	}
+	if param2.EvidenceType != param.EvidenceType || param2.PeerPubkey != param.PeerPubkey ||
+		len(param2.Messages) != 2 || !bytes.Equal(param2.Messages[1], param.Messages[1]) ||
+		len(param2.Sigs) != 2 || !bytes.Equal(param2.Sigs[1], param.Sigs[1]) {
+		t.Errorf("submit poc evidence param mismatch: %v", param2)
+	}
+
+	param.Sigs = param.Sigs[:1]
+	if err := param.Serialize(new(bytes.Buffer)); err == nil {
+		t.Errorf("serialize evidence with missing sig should fail")
+	}
+}
+
+func signedHeaderEvidence(t *testing.T, acc *account.Account, headers ...*types.Header) *SubmitPocEvidenceParam {
+	param := &SubmitPocEvidenceParam{
+		EvidenceType: POC_EVIDENCE_DOUBLE_PROPOSAL,
+		PeerPubkey:   hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey)),
+	}
+	for _, header := range headers {
+		hash := header.Hash()
+		sig, err := signature.Sign(acc, hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		param.Messages = append(param.Messages, header.ToArray())
+		param.Sigs = append(param.Sigs, sig)
+	}
+	return param
+}
+
+func TestVerifyDoubleProposal(t *testing.T) {
+	acc := account.NewAccount("SHA256withECDSA")
+	header := func(payload string) *types.Header {
+		return &types.Header{
+			Height:           10,
+			PrevBlockHash:    common.Uint256{1},
+			ConsensusPayload: []byte(payload),
+		}
+	}
+
+	height, err := verifyDoubleProposal(signedHeaderEvidence(t, acc, header(`{"nonce_nr":1}`), header(`{"nonce_nr":2}`)))
+	if err != nil {
+		t.Errorf("verify double proposal: %s", err)
+	}
+	if height != 10 {
+		t.Errorf("double proposal height %d, expected 10", height)
+	}
+
+	// block and empty block of one proposal
+	empty := header(`{"nonce_nr":1}`)
+	empty.TransactionsRoot = common.Uint256{2}
+	if _, err := verifyDoubleProposal(signedHeaderEvidence(t, acc, header(`{"nonce_nr":1}`), empty)); err == nil {
+		t.Errorf("block and empty block of one proposal should not be evidence")
+	}
+
+	other := header(`{"nonce_nr":2}`)
+	other.PrevBlockHash = common.Uint256{3}
+	if _, err := verifyDoubleProposal(signedHeaderEvidence(t, acc, header(`{"nonce_nr":1}`), other)); err == nil {
+		t.Errorf("proposals on different parents should not be evidence")
+	}
+
+	param := signedHeaderEvidence(t, acc, header(`{"nonce_nr":1}`), header(`{"nonce_nr":2}`))
+	param.PeerPubkey = hex.EncodeToString(keypair.SerializePublicKey(account.NewAccount("SHA256withECDSA").PublicKey))
+	if _, err := verifyDoubleProposal(param); err == nil {
+		t.Errorf("proposals signed by another peer should not be evidence")
+	}
+}
+
+func TestCheckForgedDeadline(t *testing.T) {
+	info := &pocconfig.PocBlockInfo{
+		BaseTarget: 1000,
+		GenSig:     shabal.GenSig256(nil, []byte("genesis")),
+	}
+	payload, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevHeader := &types.Header{Height: 1, ConsensusPayload: payload}
+
+	scoopIndex := shabal.ScoopNum256(info.GenSig)
+	nonce := shabal.GenNonce256(7, "0123")
+	scoop := nonce[scoopIndex*shabal.ScoopSize : (scoopIndex+1)*shabal.ScoopSize]
+	deadline := binary.LittleEndian.Uint64(shabal.Target256(info.GenSig, scoop)) / info.BaseTarget
+
+	entry := &pocDeadlineEntry{
+		BlockNum:  2,
+		Deadline:  deadline,
+		AccountID: "0123",
+		NonceNr:   7,
+		PrevHash:  prevHeader.Hash(),
+	}
+	if err := checkForgedDeadline(entry, prevHeader, prevHeader); err == nil {
+		t.Errorf("valid deadline should not be evidence")
+	}
+	entry.Deadline = deadline + 1
+	if err := checkForgedDeadline(entry, prevHeader, prevHeader); err != nil {
+		t.Errorf("check forged deadline: %s", err)
+	}
+	// zk deadline entries carry no nonce
+	entry.ZKProof = []byte{1}
+	if err := checkForgedDeadline(entry, prevHeader, prevHeader); err == nil {
+		t.Errorf("zk deadline should not be evidence")
+	}
+	entry.ZKProof = nil
+	// the base target missing in the parent is the one of the genesis block
+	noTarget, err := json.Marshal(&pocconfig.PocBlockInfo{GenSig: info.GenSig})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := &types.Header{Height: 1, ConsensusPayload: noTarget}
+	entry.PrevHash = parent.Hash()
+	entry.Deadline = deadline
+	if err := checkForgedDeadline(entry, parent, prevHeader); err == nil {
+		t.Errorf("valid deadline under the genesis base target should not be evidence")
+	}
+	entry.PrevHash = common.Uint256{1}
+	if err := checkForgedDeadline(entry, prevHeader, prevHeader); err == nil {
+		t.Errorf("deadline on another parent should not be evidence")
+	}
+}
+
+func TestCheckFailedAudit(t *testing.T) {
+	info := &pocconfig.PocBlockInfo{
+		BaseTarget: 1000,
+		GenSig:     shabal.GenSig256(nil, []byte("genesis")),
+	}
+	payload, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevHeader := &types.Header{Height: 1, ConsensusPayload: payload}
+
+	dir, err := ioutil.TempDir("", "poc-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	accountID := pocconfig.PubkeyID(account.NewAccount("SHA256withECDSA").PublicKey)
+	path, err := plot.Generate(dir, accountID, 100, 3)
+	if err != nil {
+		t.Fatalf("generate plot: %s", err)
+	}
+	p, err := plot.Open(path)
+	if err != nil {
+		t.Fatalf("open plot: %s", err)
+	}
+	defer p.Close()
+	params := zkproof.Params{Depth: 2, Rounds: 2}
+	tree, err := zkproof.BuildTree(p, params, 2, nil)
+	if err != nil {
+		t.Fatalf("build tree: %s", err)
+	}
+	defer tree.Close()
+	paths, err := tree.Audit(p, zkproof.AuditPositions(info.GenSig, tree.Root, tree.Nonces))
+	if err != nil {
+		t.Fatalf("audit: %s", err)
+	}
+	node := func(v *big.Int) []byte {
+		buf := make([]byte, plot_binding.PLOT_ROOT_SIZE)
+		b := v.Bytes()
+		copy(buf[len(buf)-len(b):], b)
+		return buf
+	}
+	zk := &pocZKDeadline{Root: node(tree.Root), StartNonce: 100, Nonces: 3}
+	for _, path := range paths {
+		nodes := make([][]byte, 0, len(path))
+		for _, v := range path {
+			nodes = append(nodes, node(v))
+		}
+		zk.Audit = append(zk.Audit, nodes)
+	}
+	zkProof, err := json.Marshal(zk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := &pocDeadlineEntry{
+		BlockNum:  2,
+		AccountID: accountID,
+		ZKProof:   zkProof,
+		PrevHash:  prevHeader.Hash(),
+	}
+	if err := checkFailedAudit(entry, prevHeader, params); err == nil {
+		t.Errorf("passing audit should not be evidence")
+	}
+	entry.AccountID = pocconfig.PubkeyID(account.NewAccount("SHA256withECDSA").PublicKey)
+	if err := checkFailedAudit(entry, prevHeader, params); err != nil {
+		t.Errorf("audit of a tree of another account: %s", err)
+	}
+	entry.AccountID = accountID
+	zk.StartNonce = 101
+	if entry.ZKProof, err = json.Marshal(zk); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkFailedAudit(entry, prevHeader, params); err != nil {
+		t.Errorf("audit of a tree of other nonces: %s", err)
+	}
+	entry.PrevHash = common.Uint256{1}
+	if err := checkFailedAudit(entry, prevHeader, params); err == nil {
+		t.Errorf("audit on another parent should not be evidence")
+	}
+	entry.PrevHash = prevHeader.Hash()
+	entry.ZKProof = nil
+	if err := checkFailedAudit(entry, prevHeader, params); err == nil {
+		t.Errorf("deadline without zk proof should not be audit evidence")
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"OntologyWithPOC/smartcontract/service/native/ong"
 	"OntologyWithPOC/smartcontract/service/native/ont"
 	"OntologyWithPOC/smartcontract/service/native/ontid"
+	"OntologyWithPOC/smartcontract/service/native/plot_binding"
 	"OntologyWithPOC/smartcontract/service/native/utils"
 	"OntologyWithPOC/smartcontract/service/neovm"
 	vm "OntologyWithPOC/vm/neovm"
@@ -45,6 +46,7 @@ func init() {
 	ontid.Init()
 	auth.Init()
 	governance.InitGovernance()
+	plot_binding.InitPlotBinding()
 }
 
 func InitBytes(addr common.Address, method string) []byte {
@@ -22,6 +22,7 @@ import (
 	"fmt"
 
 	"OntologyWithPOC/common"
+	"OntologyWithPOC/core/store"
 	"OntologyWithPOC/core/types"
 	"OntologyWithPOC/errors"
 	"OntologyWithPOC/smartcontract/context"
@@ -53,6 +54,7 @@ type NativeService struct {
 	Time          uint32
 	BlockHash     common.Uint256
 	ContextRef    context.ContextRef
+	Store         store.LedgerStore
 }
 
 func (this *NativeService) Register(methodName string, handler Handler) {
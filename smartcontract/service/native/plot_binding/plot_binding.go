@@ -0,0 +1,172 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package plot_binding records, for every plot account, the address the
+// rewards of its deadlines are paid to. The plot key can then stay offline
+// with the rewards going to cold storage, or be bound to the address of a
+// pool that mines with the plot on its behalf. It also records the root of
+// the plot tree a plot account proves its deadlines against with zk proofs.
+package plot_binding
+
+import (
+	"bytes"
+	"fmt"
+
+	"OntologyWithPOC/common"
+	"OntologyWithPOC/common/serialization"
+	"OntologyWithPOC/errors"
+	"OntologyWithPOC/smartcontract/service/native"
+	"OntologyWithPOC/smartcontract/service/native/utils"
+)
+
+const (
+	BIND_REWARD_ADDRESS   = "bindRewardAddress"
+	UNBIND_REWARD_ADDRESS = "unbindRewardAddress"
+	GET_REWARD_ADDRESS    = "getRewardAddress"
+	REGISTER_PLOT_ROOT    = "registerPlotRoot"
+	GET_PLOT_ROOT         = "getPlotRoot"
+)
+
+func InitPlotBinding() {
+	native.Contracts[utils.PlotBindingContractAddress] = RegisterPlotBindingContract
+}
+
+func RegisterPlotBindingContract(native *native.NativeService) {
+	native.Register(BIND_REWARD_ADDRESS, BindRewardAddress)
+	native.Register(UNBIND_REWARD_ADDRESS, UnbindRewardAddress)
+	native.Register(GET_REWARD_ADDRESS, GetRewardAddress)
+	native.Register(REGISTER_PLOT_ROOT, RegisterPlotRoot)
+	native.Register(GET_PLOT_ROOT, GetPlotRoot)
+}
+
+// bind the rewards of a plot account to an address, witnessed by the plot account
+func BindRewardAddress(native *native.NativeService) ([]byte, error) {
+	param := new(BindRewardAddressParam)
+	if err := param.Deserialize(bytes.NewBuffer(native.Input)); err != nil {
+		return utils.BYTE_FALSE, errors.NewDetailErr(err, errors.ErrNoCode, "bindRewardAddress, deserialize param failed!")
+	}
+	plotAddr, err := plotAccountAddress(param.PlotPubkey)
+	if err != nil {
+		return utils.BYTE_FALSE, errors.NewDetailErr(err, errors.ErrNoCode, "bindRewardAddress, invalid plot pubkey!")
+	}
+	if !native.ContextRef.CheckWitness(plotAddr) {
+		return utils.BYTE_FALSE, errors.NewErr("bindRewardAddress, authentication failed!")
+	}
+	if param.RewardAddress == common.ADDRESS_EMPTY {
+		return utils.BYTE_FALSE, errors.NewErr("bindRewardAddress, empty reward address!")
+	}
+	contract := native.ContextRef.CurrentContext().ContractAddress
+	utils.PutBytes(native, utils.ConcatKey(contract, RewardAddressKey(param.PlotPubkey)), param.RewardAddress[:])
+
+	notifyBinding(native, contract, BIND_REWARD_ADDRESS, plotAddr, param.RewardAddress)
+	return utils.BYTE_TRUE, nil
+}
+
+// drop the reward binding of a plot account, rewards go to the plot account itself again
+func UnbindRewardAddress(native *native.NativeService) ([]byte, error) {
+	plotPubkey, err := serialization.ReadVarBytes(bytes.NewBuffer(native.Input))
+	if err != nil {
+		return utils.BYTE_FALSE, errors.NewDetailErr(err, errors.ErrNoCode, "unbindRewardAddress, deserialize plot pubkey failed!")
+	}
+	plotAddr, err := plotAccountAddress(plotPubkey)
+	if err != nil {
+		return utils.BYTE_FALSE, errors.NewDetailErr(err, errors.ErrNoCode, "unbindRewardAddress, invalid plot pubkey!")
+	}
+	if !native.ContextRef.CheckWitness(plotAddr) {
+		return utils.BYTE_FALSE, errors.NewErr("unbindRewardAddress, authentication failed!")
+	}
+	contract := native.ContextRef.CurrentContext().ContractAddress
+	key := utils.ConcatKey(contract, RewardAddressKey(plotPubkey))
+	rewardAddr, err := getRewardAddress(native, key)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("unbindRewardAddress, get reward address failed: %v", err)
+	}
+	if rewardAddr == common.ADDRESS_EMPTY {
+		return utils.BYTE_FALSE, errors.NewErr("unbindRewardAddress, plot account is not bound!")
+	}
+	native.CacheDB.Delete(key)
+
+	notifyBinding(native, contract, UNBIND_REWARD_ADDRESS, plotAddr, rewardAddr)
+	return utils.BYTE_TRUE, nil
+}
+
+// returns the reward address bound to a plot account, empty if not bound
+func GetRewardAddress(native *native.NativeService) ([]byte, error) {
+	plotPubkey, err := serialization.ReadVarBytes(bytes.NewBuffer(native.Input))
+	if err != nil {
+		return utils.BYTE_FALSE, errors.NewDetailErr(err, errors.ErrNoCode, "getRewardAddress, deserialize plot pubkey failed!")
+	}
+	contract := native.ContextRef.CurrentContext().ContractAddress
+	rewardAddr, err := getRewardAddress(native, utils.ConcatKey(contract, RewardAddressKey(plotPubkey)))
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("getRewardAddress, get reward address failed: %v", err)
+	}
+	if rewardAddr == common.ADDRESS_EMPTY {
+		return []byte{}, nil
+	}
+	return rewardAddr[:], nil
+}
+
+// register the root of the plot tree of a plot account and the nonces it commits
+// to, witnessed by the plot account, replacing the previous one
+func RegisterPlotRoot(native *native.NativeService) ([]byte, error) {
+	param := new(RegisterPlotRootParam)
+	if err := param.Deserialize(bytes.NewBuffer(native.Input)); err != nil {
+		return utils.BYTE_FALSE, errors.NewDetailErr(err, errors.ErrNoCode, "registerPlotRoot, deserialize param failed!")
+	}
+	plotAddr, err := plotAccountAddress(param.PlotPubkey)
+	if err != nil {
+		return utils.BYTE_FALSE, errors.NewDetailErr(err, errors.ErrNoCode, "registerPlotRoot, invalid plot pubkey!")
+	}
+	if !native.ContextRef.CheckWitness(plotAddr) {
+		return utils.BYTE_FALSE, errors.NewErr("registerPlotRoot, authentication failed!")
+	}
+	if len(param.Root) != PLOT_ROOT_SIZE {
+		return utils.BYTE_FALSE, errors.NewErr("registerPlotRoot, invalid root!")
+	}
+	if param.Nonces == 0 || param.StartNonce+param.Nonces < param.StartNonce {
+		return utils.BYTE_FALSE, errors.NewErr("registerPlotRoot, invalid nonce range!")
+	}
+	root := &PlotRoot{Root: param.Root, Height: native.Height, StartNonce: param.StartNonce, Nonces: param.Nonces}
+	bf := new(bytes.Buffer)
+	if err := root.Serialize(bf); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("registerPlotRoot, serialize plot root failed: %v", err)
+	}
+	contract := native.ContextRef.CurrentContext().ContractAddress
+	utils.PutBytes(native, utils.ConcatKey(contract, PlotRootKey(param.PlotPubkey)), bf.Bytes())
+
+	notifyPlotRoot(native, contract, plotAddr, param.Root)
+	return utils.BYTE_TRUE, nil
+}
+
+// returns the serialized plot root registered by a plot account, empty if none
+func GetPlotRoot(native *native.NativeService) ([]byte, error) {
+	plotPubkey, err := serialization.ReadVarBytes(bytes.NewBuffer(native.Input))
+	if err != nil {
+		return utils.BYTE_FALSE, errors.NewDetailErr(err, errors.ErrNoCode, "getPlotRoot, deserialize plot pubkey failed!")
+	}
+	contract := native.ContextRef.CurrentContext().ContractAddress
+	item, err := utils.GetStorageItem(native, utils.ConcatKey(contract, PlotRootKey(plotPubkey)))
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("getPlotRoot, get plot root failed: %v", err)
+	}
+	if item == nil {
+		return []byte{}, nil
+	}
+	return item.Value, nil
+}
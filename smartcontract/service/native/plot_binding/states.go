@@ -0,0 +1,139 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package plot_binding
+
+import (
+	"fmt"
+	"io"
+
+	"OntologyWithPOC/common"
+	"OntologyWithPOC/common/serialization"
+	"OntologyWithPOC/smartcontract/service/native/utils"
+)
+
+type BindRewardAddressParam struct {
+	PlotPubkey    []byte
+	RewardAddress common.Address
+}
+
+func (this *BindRewardAddressParam) Serialize(w io.Writer) error {
+	if err := serialization.WriteVarBytes(w, this.PlotPubkey); err != nil {
+		return err
+	}
+	if err := utils.WriteAddress(w, this.RewardAddress); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (this *BindRewardAddressParam) Deserialize(r io.Reader) error {
+	var err error
+	if this.PlotPubkey, err = serialization.ReadVarBytes(r); err != nil {
+		return err
+	}
+	if this.RewardAddress, err = utils.ReadAddress(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+type RegisterPlotRootParam struct {
+	PlotPubkey []byte
+	Root       []byte
+	StartNonce uint64
+	Nonces     uint64
+}
+
+func (this *RegisterPlotRootParam) Serialize(w io.Writer) error {
+	if err := serialization.WriteVarBytes(w, this.PlotPubkey); err != nil {
+		return err
+	}
+	if err := serialization.WriteVarBytes(w, this.Root); err != nil {
+		return err
+	}
+	if err := serialization.WriteUint64(w, this.StartNonce); err != nil {
+		return err
+	}
+	if err := serialization.WriteUint64(w, this.Nonces); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (this *RegisterPlotRootParam) Deserialize(r io.Reader) error {
+	var err error
+	if this.PlotPubkey, err = serialization.ReadVarBytes(r); err != nil {
+		return err
+	}
+	if this.Root, err = serialization.ReadVarBytes(r); err != nil {
+		return err
+	}
+	if this.StartNonce, err = serialization.ReadUint64(r); err != nil {
+		return err
+	}
+	if this.Nonces, err = serialization.ReadUint64(r); err != nil {
+		return err
+	}
+	return nil
+}
+
+// PlotRoot is the plot tree root of a plot account, the nonces its leaves are
+// the scoops of, and the height it was registered at.
+type PlotRoot struct {
+	Root       []byte
+	Height     uint32
+	StartNonce uint64
+	Nonces     uint64
+}
+
+func (this *PlotRoot) Serialize(w io.Writer) error {
+	if err := serialization.WriteVarBytes(w, this.Root); err != nil {
+		return err
+	}
+	if err := serialization.WriteUint32(w, this.Height); err != nil {
+		return err
+	}
+	if err := serialization.WriteUint64(w, this.StartNonce); err != nil {
+		return err
+	}
+	if err := serialization.WriteUint64(w, this.Nonces); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (this *PlotRoot) Deserialize(r io.Reader) error {
+	var err error
+	if this.Root, err = serialization.ReadVarBytes(r); err != nil {
+		return err
+	}
+	if len(this.Root) != PLOT_ROOT_SIZE {
+		return fmt.Errorf("invalid plot root len %d", len(this.Root))
+	}
+	if this.Height, err = serialization.ReadUint32(r); err != nil {
+		return err
+	}
+	if this.StartNonce, err = serialization.ReadUint64(r); err != nil {
+		return err
+	}
+	if this.Nonces, err = serialization.ReadUint64(r); err != nil {
+		return err
+	}
+	return nil
+}
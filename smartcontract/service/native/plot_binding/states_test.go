@@ -0,0 +1,102 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package plot_binding
+
+import (
+	"bytes"
+	"testing"
+
+	"OntologyWithPOC/account"
+	"github.com/ontio/ontology-crypto/keypair"
+)
+
+func TestBindRewardAddressParam(t *testing.T) {
+	plotAcc := account.NewAccount("SHA256withECDSA")
+	rewardAcc := account.NewAccount("SHA256withECDSA")
+	param := &BindRewardAddressParam{
+		PlotPubkey:    keypair.SerializePublicKey(plotAcc.PublicKey),
+		RewardAddress: rewardAcc.Address,
+	}
+	bf := new(bytes.Buffer)
+	if err := param.Serialize(bf); err != nil {
+		t.Fatal(err)
+	}
+	param2 := new(BindRewardAddressParam)
+	if err := param2.Deserialize(bytes.NewReader(bf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(param.PlotPubkey, param2.PlotPubkey) || param.RewardAddress != param2.RewardAddress {
+		t.Errorf("bind reward address param mismatch")
+	}
+}
+
+func TestPlotAccountAddress(t *testing.T) {
+	plotAcc := account.NewAccount("SHA256withECDSA")
+	addr, err := plotAccountAddress(keypair.SerializePublicKey(plotAcc.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr != plotAcc.Address {
+		t.Errorf("plot account address %s, expected %s", addr.ToBase58(), plotAcc.Address.ToBase58())
+	}
+	if _, err := plotAccountAddress([]byte{1, 2, 3}); err == nil {
+		t.Errorf("invalid plot pubkey should fail")
+	}
+}
+
+func TestPlotRoot(t *testing.T) {
+	plotAcc := account.NewAccount("SHA256withECDSA")
+	param := &RegisterPlotRootParam{
+		PlotPubkey: keypair.SerializePublicKey(plotAcc.PublicKey),
+		Root:       make([]byte, PLOT_ROOT_SIZE),
+		StartNonce: 100,
+		Nonces:     3,
+	}
+	bf := new(bytes.Buffer)
+	if err := param.Serialize(bf); err != nil {
+		t.Fatal(err)
+	}
+	param2 := new(RegisterPlotRootParam)
+	if err := param2.Deserialize(bytes.NewReader(bf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(param.PlotPubkey, param2.PlotPubkey) || !bytes.Equal(param.Root, param2.Root) ||
+		param2.StartNonce != 100 || param2.Nonces != 3 {
+		t.Errorf("register plot root param mismatch")
+	}
+
+	root := &PlotRoot{Root: param.Root, Height: 10, StartNonce: 100, Nonces: 3}
+	bf.Reset()
+	if err := root.Serialize(bf); err != nil {
+		t.Fatal(err)
+	}
+	root2 := new(PlotRoot)
+	if err := root2.Deserialize(bytes.NewReader(bf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(root.Root, root2.Root) || root.Height != root2.Height ||
+		root.StartNonce != root2.StartNonce || root.Nonces != root2.Nonces {
+		t.Errorf("plot root mismatch")
+	}
+	bf.Reset()
+	(&PlotRoot{Root: []byte{1}}).Serialize(bf)
+	if err := root2.Deserialize(bytes.NewReader(bf.Bytes())); err == nil {
+		t.Errorf("short plot root should fail")
+	}
+}
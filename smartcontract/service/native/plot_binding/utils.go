@@ -0,0 +1,86 @@
+/*
+ * Copyright (C) 2018 The ontology Authors
+ * This file is part of The ontology library.
+ *
+ * The ontology is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The ontology is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The ontology.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package plot_binding
+
+import (
+	"encoding/hex"
+
+	"OntologyWithPOC/common"
+	"OntologyWithPOC/common/config"
+	"OntologyWithPOC/core/types"
+	"OntologyWithPOC/smartcontract/event"
+	"OntologyWithPOC/smartcontract/service/native"
+	"OntologyWithPOC/smartcontract/service/native/utils"
+	"github.com/ontio/ontology-crypto/keypair"
+)
+
+const (
+	REWARD_ADDRESS = "rewardAddress"
+	PLOT_ROOT      = "plotRoot"
+	PLOT_ROOT_SIZE = 32
+)
+
+// storage key of the reward address of a plot account, without the contract prefix
+func RewardAddressKey(plotPubkey []byte) []byte {
+	return append([]byte(REWARD_ADDRESS), plotPubkey...)
+}
+
+// storage key of the plot root of a plot account, without the contract prefix
+func PlotRootKey(plotPubkey []byte) []byte {
+	return append([]byte(PLOT_ROOT), plotPubkey...)
+}
+
+func plotAccountAddress(plotPubkey []byte) (common.Address, error) {
+	pub, err := keypair.DeserializePublicKey(plotPubkey)
+	if err != nil {
+		return common.ADDRESS_EMPTY, err
+	}
+	return types.AddressFromPubKey(pub), nil
+}
+
+func getRewardAddress(native *native.NativeService, key []byte) (common.Address, error) {
+	item, err := utils.GetStorageItem(native, key)
+	if err != nil || item == nil {
+		return common.ADDRESS_EMPTY, err
+	}
+	return common.AddressParseFromBytes(item.Value)
+}
+
+func notifyBinding(native *native.NativeService, contract common.Address, functionName string,
+	plotAddr, rewardAddr common.Address) {
+	if !config.DefConfig.Common.EnableEventLog {
+		return
+	}
+	native.Notifications = append(native.Notifications,
+		&event.NotifyEventInfo{
+			ContractAddress: contract,
+			States:          []interface{}{functionName, plotAddr.ToBase58(), rewardAddr.ToBase58()},
+		})
+}
+
+func notifyPlotRoot(native *native.NativeService, contract common.Address, plotAddr common.Address, root []byte) {
+	if !config.DefConfig.Common.EnableEventLog {
+		return
+	}
+	native.Notifications = append(native.Notifications,
+		&event.NotifyEventInfo{
+			ContractAddress: contract,
+			States:          []interface{}{REGISTER_PLOT_ROOT, plotAddr.ToBase58(), hex.EncodeToString(root)},
+		})
+}
@@ -78,6 +78,7 @@ func NativeInvoke(service *NeoVmService, engine *vm.ExecutionEngine) error {
 		Height:      service.Height,
 		Time:        service.Time,
 		ContextRef:  service.ContextRef,
+		Store:       service.Store,
 		ServiceMap:  make(map[string]native.Handler),
 	}
 
@@ -153,6 +153,7 @@ func (this *SmartContract) NewNativeService() (*native.NativeService, error) {
 		Time:       this.Config.Time,
 		Height:     this.Config.Height,
 		BlockHash:  this.Config.BlockHash,
+		Store:      this.Store,
 		ServiceMap: make(map[string]native.Handler),
 	}
 	return service, nil